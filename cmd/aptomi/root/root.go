@@ -48,8 +48,20 @@ func init() {
 	common.AddBoolFlag(Command, "ui.enable", "ui", "", true, envPrefix+"_UI", "Enable server to serve UI")
 	common.AddDurationFlag(Command, "enforcer.interval", "enforcer-interval", "", 60*time.Second, envPrefix+"_ENFORCER_INTERVAL", "Desired state enforcer interval")
 	common.AddIntFlag(Command, "enforcer.maxConcurrentActions", "enforcer-max-concurrent-actions", "", 30, envPrefix+"_ENFORCER_MAX_CONCURRENT_ACTIONS", "Desired state enforcer max concurrent actions")
+	common.AddStringFlag(Command, "enforcer.clusterMaxConcurrentActions", "enforcer-cluster-max-concurrent-actions", "", "", envPrefix+"_ENFORCER_CLUSTER_MAX_CONCURRENT_ACTIONS", "Desired state enforcer max concurrent actions per cluster, as a comma-separated list of cluster=limit pairs (overrides 'maxConcurrentActions' cluster label)")
+	common.AddStringFlag(Command, "enforcer.codeTypeMaxConcurrentActions", "enforcer-code-type-max-concurrent-actions", "", "", envPrefix+"_ENFORCER_CODE_TYPE_MAX_CONCURRENT_ACTIONS", "Desired state enforcer max concurrent actions per code type, as a comma-separated list of type=limit pairs (e.g. helm=10)")
+	common.AddDurationFlag(Command, "enforcer.actionTimeout", "enforcer-action-timeout", "", 15*time.Minute, envPrefix+"_ENFORCER_ACTION_TIMEOUT", "Desired state enforcer timeout for a single action (0 means no timeout)")
+	common.AddDurationFlag(Command, "enforcer.readinessTimeout", "enforcer-readiness-timeout", "", 0, envPrefix+"_ENFORCER_READINESS_TIMEOUT", "Desired state enforcer timeout for a component instance to become ready after create/update, before component instances depending on it get processed (0 means no waiting)")
+	common.AddDurationFlag(Command, "enforcer.retryBackoff", "enforcer-retry-backoff", "", 30*time.Second, envPrefix+"_ENFORCER_RETRY_BACKOFF", "Desired state enforcer delay before retrying a failed component instance (doubles after every failed attempt)")
+	common.AddDurationFlag(Command, "enforcer.retryMaxBackoff", "enforcer-retry-max-backoff", "", 30*time.Minute, envPrefix+"_ENFORCER_RETRY_MAX_BACKOFF", "Desired state enforcer max delay between retries of a failed component instance")
+	common.AddIntFlag(Command, "enforcer.retryMaxAttempts", "enforcer-retry-max-attempts", "", 10, envPrefix+"_ENFORCER_RETRY_MAX_ATTEMPTS", "Desired state enforcer max attempts for a failed component instance, before it's marked as permanently failed (0 means no limit)")
+	common.AddStringFlag(Command, "enforcer.failureBudget", "enforcer-failure-budget", "", "", envPrefix+"_ENFORCER_FAILURE_BUDGET", "Desired state enforcer failure budget, as a number of failed actions (e.g. 10) or a percentage (e.g. 20%), after which apply gets aborted (empty means no limit)")
 	common.AddDurationFlag(Command, "updater.interval", "updater-interval", "", 60*time.Second, envPrefix+"_UPDATER_INTERVAL", "Actual state updater interval")
 	common.AddIntFlag(Command, "updater.maxConcurrentActions", "updater-max-concurrent-actions", "", 30, envPrefix+"_UPDATER_MAX_CONCURRENT_ACTIONS", "Actual state updater max concurrent actions")
+	common.AddDurationFlag(Command, "expirer.interval", "expirer-interval", "", 60*time.Second, envPrefix+"_EXPIRER_INTERVAL", "Dependency expirer interval")
+	common.AddDurationFlag(Command, "drift.interval", "drift-interval", "", 5*time.Minute, envPrefix+"_DRIFT_INTERVAL", "Drift detector interval")
+	common.AddIntFlag(Command, "drift.maxConcurrentActions", "drift-max-concurrent-actions", "", 10, envPrefix+"_DRIFT_MAX_CONCURRENT_ACTIONS", "Drift detector max concurrent actions")
+	common.AddBoolFlag(Command, "drift.repair", "drift-repair", "", false, envPrefix+"_DRIFT_REPAIR", "Mark drifted component instances for re-creation or update during the next enforcement")
 	common.AddStringFlag(Command, "profile.cpu", "cpuprofile", "", "", envPrefix+"_CPU_PROFILE", "File to write debug CPU profiling information using Go runtime/pprof")
 	common.AddStringFlag(Command, "profile.trace", "traceprofile", "", "", envPrefix+"_TRACE_PROFILE", "File to write debug tracing information using Go runtime/trace")
 
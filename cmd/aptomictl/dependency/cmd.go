@@ -16,6 +16,8 @@ func NewCommand(cfg *config.Client) *cobra.Command {
 	cmd.AddCommand(
 		newStatusCommand(cfg),
 		newEndpointsCommand(cfg),
+		newExplainCommand(cfg),
+		newExtendCommand(cfg),
 	)
 
 	return cmd
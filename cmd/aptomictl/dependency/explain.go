@@ -0,0 +1,42 @@
+package dependency
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Aptomi/aptomi/cmd/common"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newExplainCommand(cfg *config.Client) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "explain <namespace/name>",
+		Short: "explain dependency resolution",
+		Long:  "explain how dependency got resolved: matched and rejected contexts, fired rules, label changes, allocation keys and picked clusters",
+		Args:  cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			parts := strings.Split(args[0], "/")
+			if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+				log.Fatalf("dependency should be specified as <namespace/name>, got: %s", args[0])
+			}
+
+			result, err := rest.New(cfg, http.NewClient(cfg)).Dependency().Explain(parts[0], parts[1])
+			if err != nil {
+				log.Fatalf("error while requesting dependency explanation: %s", err)
+			}
+
+			data, err := common.Format(cfg.Output, false, result)
+			if err != nil {
+				log.Fatalf("error while formatting dependency explanation: %s", err)
+			}
+			fmt.Println(string(data))
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,55 @@
+package dependency
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Aptomi/aptomi/cmd/aptomictl/util"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newExtendCommand(cfg *config.Client) *cobra.Command {
+	var duration time.Duration
+	var noop bool
+	var logLevel string
+
+	cmd := &cobra.Command{
+		Use:   "extend <namespace/name>",
+		Short: "extend dependency expiration",
+		Long:  "extend expiration of the dependency by a given duration, starting from its current expiration time",
+		Args:  cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			parts := strings.Split(args[0], "/")
+			if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) == 0 {
+				log.Fatalf("dependency should be specified as <namespace/name>, got: %s", args[0])
+			}
+			if duration <= 0 {
+				log.Fatalf("duration should be positive, got: %s", duration)
+			}
+
+			logLevelObj, err := log.ParseLevel(logLevel)
+			if err != nil {
+				logLevelObj = log.WarnLevel
+			}
+
+			result, err := rest.New(cfg, http.NewClient(cfg)).Dependency().Extend(parts[0], parts[1], duration, noop, logLevelObj)
+			if err != nil {
+				log.Fatalf("error while extending dependency expiration: %s", err)
+			}
+
+			util.PrintPolicyUpdateResult(result, logLevelObj, cfg)
+		},
+	}
+
+	cmd.Flags().DurationVar(&duration, "by", time.Hour, "Duration to extend dependency expiration by")
+	cmd.Flags().BoolVar(&noop, "noop", false, "Produce action plan for extending dependency expiration, but do not update the policy")
+	cmd.Flags().StringVar(&logLevel, "log-level", log.WarnLevel.String(), fmt.Sprintf("Retrieve logs from the server using the specified log level (%s)", log.AllLevels))
+
+	return cmd
+}
@@ -2,6 +2,7 @@ package dependency
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Aptomi/aptomi/cmd/aptomictl/io"
@@ -123,6 +124,7 @@ func getHeader(waitFlag api.DependencyQueryFlag) []interface{} {
 	if waitFlag == api.DependencyQueryDeploymentStatusAndReadiness {
 		result = append(result, "READY")
 	}
+	result = append(result, "ERROR")
 	return result
 }
 
@@ -131,6 +133,7 @@ func getRow(dKey string, dStatus *api.DependencyStatus, waitFlag api.DependencyQ
 	if waitFlag == api.DependencyQueryDeploymentStatusAndReadiness {
 		result = append(result, getReadyStr(dStatus, attempt))
 	}
+	result = append(result, getErrorStr(dStatus))
 	return result
 }
 
@@ -169,11 +172,37 @@ func getReadyStr(dsi *api.DependencyStatus, attempt int) string {
 	return "yes" // nolint: goconst
 }
 
+func getErrorStr(dsi *api.DependencyStatus) string {
+	if len(dsi.Error) > 0 {
+		return dsi.Error
+	}
+	errors := []string{}
+	for _, key := range util.GetSortedStringKeys(dsi.Retries) {
+		state := dsi.Retries[key]
+		if state.Failed {
+			errors = append(errors, fmt.Sprintf("%s permanently failed after %d attempts: %s", key, state.Attempts, state.LastError))
+		} else {
+			errors = append(errors, fmt.Sprintf("%s failed %d time(s), next retry at %s: %s", key, state.Attempts, state.NextRetryAt.Format(time.RFC3339), state.LastError))
+		}
+	}
+	return strings.Join(errors, "\n")
+}
+
 func shouldKeepWaiting(dsi *api.DependencyStatus, waitFlag api.DependencyQueryFlag) (bool, error) {
 	if !dsi.Found {
 		// if dependency has not been found, it does NOT make sense to continue waiting
 		return false, fmt.Errorf("dependency has not been found")
 	}
+	if len(dsi.Error) > 0 {
+		// if dependency could not be resolved, it does NOT make sense to continue waiting
+		return false, fmt.Errorf("dependency could not be resolved: %s", dsi.Error)
+	}
+	for key, state := range dsi.Retries {
+		if state.Failed {
+			// if one of component instances permanently failed, it does NOT make sense to continue waiting
+			return false, fmt.Errorf("component instance %s permanently failed: %s", key, state.LastError)
+		}
+	}
 	if !dsi.Deployed {
 		// if dependency has not been deployed (i.e. still has pending actions), we should continue waiting
 		return true, fmt.Errorf("dependency is not in deployed state")
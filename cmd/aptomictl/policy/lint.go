@@ -0,0 +1,76 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Aptomi/aptomi/cmd/aptomictl/io"
+	"github.com/Aptomi/aptomi/cmd/common"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/spf13/cobra"
+)
+
+func newLintCommand(cfg *config.Client) *cobra.Command {
+	paths := make([]string, 0)
+
+	cmd := &cobra.Command{
+		Use:   "lint",
+		Short: "lint policy",
+		Long:  "lint policy files locally and report unreachable contexts, unused objects, shadowed rules and unknown labels",
+
+		// errors and lint issues are reported via returned error, there is no need to print usage for them
+		SilenceUsage: true,
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			allObjects, err := io.ReadLangObjects(paths)
+			if err != nil {
+				return fmt.Errorf("error while reading policy files: %s", err)
+			}
+
+			policy := lang.NewPolicy()
+			for _, obj := range allObjects {
+				langObj, ok := obj.(lang.Base)
+				if !ok {
+					return fmt.Errorf("object of kind %s is not a policy object", obj.GetKind())
+				}
+				err = policy.AddObject(langObj)
+				if err != nil {
+					return fmt.Errorf("error while adding object to policy: %s", err)
+				}
+			}
+
+			issues := lang.NewPolicyLinter(policy).Lint()
+
+			if len(issues) == 0 && strings.ToLower(cfg.Output) == common.Text {
+				fmt.Println("No issues found")
+				return nil
+			}
+
+			displayable := make([]runtime.Displayable, 0, len(issues))
+			for _, issue := range issues {
+				displayable = append(displayable, issue)
+			}
+
+			data, err := common.Format(cfg.Output, true, displayable...)
+			if err != nil {
+				return fmt.Errorf("error while formatting lint issues: %s", err)
+			}
+			fmt.Println(string(data))
+
+			if len(issues) > 0 {
+				return fmt.Errorf("policy lint found %d issue(s)", len(issues))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&paths, "policyPaths", "f", make([]string, 0), "Paths to files/dirs with policy files")
+	if err := cmd.MarkFlagRequired("policyPaths"); err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
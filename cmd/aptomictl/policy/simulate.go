@@ -0,0 +1,68 @@
+package policy
+
+import (
+	"fmt"
+
+	"github.com/Aptomi/aptomi/cmd/aptomictl/io"
+	"github.com/Aptomi/aptomi/cmd/aptomictl/util"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newSimulateCommand(cfg *config.Client) *cobra.Command {
+	paths := make([]string, 0)
+	deletePaths := make([]string, 0)
+	var logLevel string
+
+	cmd := &cobra.Command{
+		Use:   "simulate",
+		Short: "simulate policy changes",
+		Long:  "simulate adding/updating and removing policy objects, show resulting action plan without making any changes",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(paths) == 0 && len(deletePaths) == 0 {
+				log.Fatalf("at least one of --policyPaths or --deletePaths should be specified")
+			}
+
+			added := make([]runtime.Object, 0)
+			if len(paths) > 0 {
+				var err error
+				added, err = io.ReadLangObjects(paths)
+				if err != nil {
+					log.Fatalf("error while reading policy files: %s", err)
+				}
+			}
+
+			removed := make([]runtime.Object, 0)
+			if len(deletePaths) > 0 {
+				var err error
+				removed, err = io.ReadLangObjects(deletePaths)
+				if err != nil {
+					log.Fatalf("error while reading policy files: %s", err)
+				}
+			}
+
+			logLevelObj, err := log.ParseLevel(logLevel)
+			if err != nil {
+				logLevelObj = log.WarnLevel
+			}
+
+			result, err := rest.New(cfg, http.NewClient(cfg)).Policy().Simulate(added, removed, logLevelObj)
+			if err != nil {
+				log.Fatalf("error while simulating policy changes: %s", err)
+			}
+
+			util.PrintPolicySimulateResult(result, logLevelObj, cfg)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&paths, "policyPaths", "f", make([]string, 0), "Paths to files/dirs with policy objects to be added or updated")
+	cmd.Flags().StringSliceVarP(&deletePaths, "deletePaths", "d", make([]string, 0), "Paths to files/dirs with policy objects to be removed")
+	cmd.Flags().StringVar(&logLevel, "log-level", log.WarnLevel.String(), fmt.Sprintf("Retrieve logs from the server using the specified log level (%s)", log.AllLevels))
+
+	return cmd
+}
@@ -0,0 +1,39 @@
+package revision
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newApproveCommand(cfg *config.Client) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "approve <gen>",
+		Short: "revision approve",
+		Long:  "revision approve allows deletion of protected component instances, which is held in a given revision",
+		Args:  cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			gen, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				log.Fatalf("revision generation should be a number, got: %s", args[0])
+			}
+
+			result, err := rest.New(cfg, http.NewClient(cfg)).Revision().Approve(runtime.Generation(gen))
+			if err != nil {
+				log.Fatalf("error while approving revision: %s", err)
+			}
+
+			fmt.Printf("Revision %d approved. Protected component instances will be deleted:\n  %s\n", result.GetGeneration(), strings.Join(result.PendingApproval, "\n  "))
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,31 @@
+package revision
+
+import (
+	"fmt"
+
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newCancelCommand(cfg *config.Client) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cancel",
+		Short: "revision cancel",
+		Long:  "revision cancel stops applying the revision, which is currently in progress (remaining actions will be skipped)",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			result, err := rest.New(cfg, http.NewClient(cfg)).Revision().Cancel()
+
+			if err != nil {
+				log.Fatalf("error while cancelling revision: %s", err)
+			}
+
+			fmt.Printf("Revision %d is being cancelled\n", result.GetGeneration())
+		},
+	}
+
+	return cmd
+}
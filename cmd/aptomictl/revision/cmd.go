@@ -15,6 +15,9 @@ func NewCommand(cfg *config.Client) *cobra.Command {
 
 	cmd.AddCommand(
 		newShowCommand(cfg),
+		newRollbackCommand(cfg),
+		newCancelCommand(cfg),
+		newApproveCommand(cfg),
 	)
 
 	return cmd
@@ -0,0 +1,89 @@
+package revision
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aptomi/aptomi/cmd/aptomictl/util"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newRollbackCommand(cfg *config.Client) *cobra.Command {
+	var yes bool
+	var wait bool
+	var noop bool
+	var waitInterval time.Duration
+	var waitTime time.Duration
+	var logLevel string
+
+	cmd := &cobra.Command{
+		Use:   "rollback <gen>",
+		Short: "roll back policy to a given revision",
+		Long:  "restore policy objects as they were in the policy behind a given revision, creating a new policy generation",
+		Args:  cobra.ExactArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			gen, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				log.Fatalf("revision generation should be a number, got: %s", args[0])
+			}
+
+			logLevelObj, err := log.ParseLevel(logLevel)
+			if err != nil {
+				logLevelObj = log.WarnLevel
+			}
+
+			// show the action plan first
+			clientObj := rest.New(cfg, http.NewClient(cfg))
+			result, err := clientObj.Revision().Rollback(runtime.Generation(gen), true, logLevelObj)
+			if err != nil {
+				log.Fatalf("error while rolling back to revision %d: %s", gen, err)
+			}
+			util.PrintPolicyUpdateResult(result, logLevelObj, cfg)
+			if noop {
+				return
+			}
+
+			// ask for confirmation
+			if !yes {
+				fmt.Printf("Roll back policy to revision %d? [y/N]: ", gen)
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n') // nolint: errcheck
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				if answer != "y" && answer != "yes" {
+					fmt.Println("Rollback cancelled")
+					return
+				}
+			}
+
+			// roll back
+			result, err = clientObj.Revision().Rollback(runtime.Generation(gen), false, logLevelObj)
+			if err != nil {
+				log.Fatalf("error while rolling back to revision %d: %s", gen, err)
+			}
+			util.PrintPolicyUpdateResult(result, logLevelObj, cfg)
+
+			// wait for actions to finish, if needed
+			if wait {
+				util.WaitForRevisionActionsToFinish(waitTime, waitInterval, clientObj, result)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Roll back without asking for confirmation")
+	cmd.Flags().BoolVar(&noop, "noop", false, "Produce action plan for the rollback, but do not run any actions to update the state")
+	cmd.Flags().BoolVar(&wait, "wait", false, "Wait until all actions are fully applied")
+	cmd.Flags().DurationVar(&waitInterval, "wait-interval", 2*time.Second, "Seconds to sleep between wait attempts")
+	cmd.Flags().DurationVar(&waitTime, "wait-time", 10*time.Minute, "Max time to wait before failing the wait process")
+	cmd.Flags().StringVar(&logLevel, "log-level", log.WarnLevel.String(), fmt.Sprintf("Retrieve logs from the server using the specified log level (%s)", log.AllLevels))
+
+	return cmd
+}
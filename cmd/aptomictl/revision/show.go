@@ -1,11 +1,15 @@
 package revision
 
 import (
+	"encoding/json"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/Aptomi/aptomi/pkg/client/rest"
 	"github.com/Aptomi/aptomi/pkg/client/rest/http"
 	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -13,6 +17,8 @@ import (
 
 func newShowCommand(cfg *config.Client) *cobra.Command {
 	var gen uint64
+	var plan bool
+	var planFormat string
 
 	cmd := &cobra.Command{
 		Use:   "show",
@@ -20,18 +26,73 @@ func newShowCommand(cfg *config.Client) *cobra.Command {
 		Long:  "revision show long",
 
 		Run: func(cmd *cobra.Command, args []string) {
-			result, err := rest.New(cfg, http.NewClient(cfg)).Revision().Show(runtime.Generation(gen))
+			clientObj := rest.New(cfg, http.NewClient(cfg))
+			result, err := clientObj.Revision().Show(runtime.Generation(gen))
 
 			if err != nil {
 				log.Fatalf("error while showing revision: %s", err)
 			}
 
-			// todo(slukjanov): replace with -o yaml / json / etc handler
-			fmt.Println(result)
+			if !plan {
+				// todo(slukjanov): replace with -o yaml / json / etc handler
+				fmt.Println(result)
+				return
+			}
+
+			// show action plan of the revision, together with the outcome of every action
+			revisionPlan, err := clientObj.Revision().ShowPlan(result.Revision.GetGeneration())
+			if err != nil {
+				log.Fatalf("error while showing revision plan: %s", err)
+			}
+
+			switch strings.ToLower(planFormat) {
+			case "text":
+				fmt.Print(planAsText(revisionPlan.Plan))
+			case "json":
+				data, jsonErr := json.MarshalIndent(revisionPlan.Plan, "", "  ")
+				if jsonErr != nil {
+					log.Fatalf("error while marshalling revision plan: %s", jsonErr)
+				}
+				fmt.Println(string(data))
+			case "dot":
+				fmt.Print(revisionPlan.Plan.AsDOT())
+			default:
+				log.Fatalf("unsupported plan format: %s", planFormat)
+			}
 		},
 	}
 
 	cmd.Flags().Uint64VarP(&gen, "generation", "g", 0, "Revision generation")
+	cmd.Flags().BoolVar(&plan, "plan", false, "Show action plan of the revision with the outcome of every action")
+	cmd.Flags().StringVar(&planFormat, "plan-format", "text", "Format of the action plan: text, json or dot (Graphviz)")
 
 	return cmd
 }
+
+// planAsText returns human-readable representation of the action plan record
+func planAsText(plan *action.PlanRecord) string {
+	result := ""
+	for _, node := range plan.Nodes {
+		if len(node.Actions) <= 0 {
+			continue
+		}
+		result += node.Key + "\n"
+		if len(node.Before) > 0 {
+			result += "  after: " + strings.Join(node.Before, ", ") + "\n"
+		}
+		for _, actionRecord := range node.Actions {
+			result += fmt.Sprintf("  %-10s %s", actionRecord.Status, actionRecord.Description)
+			if !actionRecord.StartedAt.IsZero() && !actionRecord.FinishedAt.IsZero() {
+				result += fmt.Sprintf(" (%s)", actionRecord.FinishedAt.Sub(actionRecord.StartedAt).Round(time.Millisecond))
+			}
+			if len(actionRecord.Readiness) > 0 {
+				result += fmt.Sprintf(" [readiness: %s after %s]", actionRecord.Readiness, actionRecord.ReadinessWait.Round(time.Millisecond))
+			}
+			result += "\n"
+			if len(actionRecord.Error) > 0 {
+				result += "             error: " + actionRecord.Error + "\n"
+			}
+		}
+	}
+	return result
+}
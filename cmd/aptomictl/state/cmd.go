@@ -15,6 +15,11 @@ func NewCommand(cfg *config.Client) *cobra.Command {
 
 	cmd.AddCommand(
 		newEnforceCommand(cfg),
+		newResetRetriesCommand(cfg),
+		newDriftCommand(cfg),
+		newOrphansCommand(cfg),
+		newPauseCommand(cfg),
+		newResumeCommand(cfg),
 	)
 
 	return cmd
@@ -0,0 +1,47 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Aptomi/aptomi/cmd/common"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newDriftCommand(cfg *config.Client) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "drift",
+		Short: "state drift",
+		Long:  "state drift shows component instances, for which deployed code doesn't match the actual state (e.g. it has been deleted or changed manually)",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			result, err := rest.New(cfg, http.NewClient(cfg)).State().Drift()
+			if err != nil {
+				log.Fatalf("error while retrieving drifted component instances: %s", err)
+			}
+
+			if len(result.Instances) == 0 && strings.ToLower(cfg.Output) == common.Text {
+				fmt.Println("No drift detected")
+				return
+			}
+
+			displayable := make([]runtime.Displayable, 0, len(result.Instances))
+			for _, instance := range result.Instances {
+				displayable = append(displayable, instance)
+			}
+
+			data, err := common.Format(cfg.Output, true, displayable...)
+			if err != nil {
+				log.Fatalf("error while formatting drifted component instances: %s", err)
+			}
+			fmt.Println(string(data))
+		},
+	}
+
+	return cmd
+}
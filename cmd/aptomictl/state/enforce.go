@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/Aptomi/aptomi/cmd/aptomictl/util"
+	"github.com/Aptomi/aptomi/pkg/api"
 	"github.com/Aptomi/aptomi/pkg/client/rest"
 	"github.com/Aptomi/aptomi/pkg/client/rest/http"
 	"github.com/Aptomi/aptomi/pkg/config"
@@ -14,6 +15,10 @@ import (
 func newEnforceCommand(cfg *config.Client) *cobra.Command {
 	var wait bool
 	var noop bool
+	var failureBudget string
+	var dependencies []string
+	var namespaces []string
+	var clusters []string
 	var waitInterval time.Duration
 	var waitTime time.Duration
 
@@ -25,7 +30,13 @@ func newEnforceCommand(cfg *config.Client) *cobra.Command {
 		Run: func(cmd *cobra.Command, args []string) {
 			// call API (apply or delete), get policy update result
 			clientObj := rest.New(cfg, http.NewClient(cfg))
-			result, err := clientObj.State().Reset(noop)
+			scope := &api.StateEnforceRequest{
+				TypeKind:     api.StateEnforceRequestObject.GetTypeKind(),
+				Dependencies: dependencies,
+				Namespaces:   namespaces,
+				Clusters:     clusters,
+			}
+			result, err := clientObj.State().Reset(noop, failureBudget, scope)
 			if err != nil {
 				log.Fatalf("error while calling state reset: %s", err)
 			}
@@ -42,6 +53,10 @@ func newEnforceCommand(cfg *config.Client) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVar(&noop, "noop", false, "Produce action plan for the given changes in policy, but do not run any actions to update the state")
+	cmd.Flags().StringVar(&failureBudget, "failure-budget", "", "Number of failed actions (e.g. 10) or a percentage of failed actions (e.g. 20%), after which apply gets aborted (overrides server setting)")
+	cmd.Flags().StringSliceVar(&dependencies, "dependency", make([]string, 0), "Only apply pending changes for component instances of given dependencies ('namespace/name'), leaving the rest to the regular enforcement")
+	cmd.Flags().StringSliceVar(&namespaces, "namespace", make([]string, 0), "Only apply pending changes for component instances in given namespaces, leaving the rest to the regular enforcement")
+	cmd.Flags().StringSliceVar(&clusters, "cluster", make([]string, 0), "Only apply pending changes for component instances in given clusters, leaving the rest to the regular enforcement")
 	cmd.Flags().BoolVar(&wait, "wait", false, "Wait until all actions are fully applied")
 	cmd.Flags().DurationVar(&waitInterval, "wait-interval", 2*time.Second, "Seconds to sleep between wait attempts")
 	cmd.Flags().DurationVar(&waitTime, "wait-time", 10*time.Minute, "Max time to wait before failing the wait process")
@@ -0,0 +1,99 @@
+package state
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Aptomi/aptomi/cmd/common"
+	"github.com/Aptomi/aptomi/pkg/api"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newOrphansCommand(cfg *config.Client) *cobra.Command {
+	var cleanup bool
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "orphans",
+		Short: "state orphans",
+		Long:  "state orphans shows code deployed by Aptomi into the clusters (e.g. Helm releases), which doesn't belong to any component instance in the actual state, and optionally deletes it",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			clientObj := rest.New(cfg, http.NewClient(cfg))
+			result, err := clientObj.State().Orphans()
+			if err != nil {
+				log.Fatalf("error while retrieving orphans: %s", err)
+			}
+
+			for _, errMsg := range result.Errors {
+				log.Warnf("%s", errMsg)
+			}
+
+			if len(result.Orphans) == 0 {
+				if strings.ToLower(cfg.Output) == common.Text {
+					fmt.Println("No orphans found")
+				}
+				return
+			}
+
+			printOrphans(cfg, result.Orphans)
+			if !cleanup {
+				return
+			}
+
+			// ask for confirmation
+			if !yes {
+				fmt.Printf("Delete %d orphan(s) listed above? [y/N]: ", len(result.Orphans))
+				answer, _ := bufio.NewReader(os.Stdin).ReadString('\n') // nolint: errcheck
+				answer = strings.ToLower(strings.TrimSpace(answer))
+				if answer != "y" && answer != "yes" {
+					fmt.Println("Cleanup cancelled")
+					return
+				}
+			}
+
+			// only delete the orphans, which have been confirmed
+			deployNames := []string{}
+			for _, orphan := range result.Orphans {
+				deployNames = append(deployNames, orphan.DeployName)
+			}
+			cleanupResult, err := clientObj.State().CleanupOrphans(deployNames)
+			if err != nil {
+				log.Fatalf("error while deleting orphans: %s", err)
+			}
+
+			fmt.Printf("Deleted %d orphan(s)\n", len(cleanupResult.Deleted))
+			for _, orphan := range cleanupResult.Failed {
+				log.Errorf("error while deleting orphan %s: %s", orphan.DeployName, orphan.Error)
+			}
+			if len(cleanupResult.Failed) > 0 {
+				os.Exit(1)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&cleanup, "cleanup", false, "Delete orphans after confirmation")
+	cmd.Flags().BoolVarP(&yes, "yes", "y", false, "Delete orphans without asking for confirmation")
+
+	return cmd
+}
+
+func printOrphans(cfg *config.Client, orphans []*api.Orphan) {
+	displayable := make([]runtime.Displayable, 0, len(orphans))
+	for _, orphan := range orphans {
+		displayable = append(displayable, orphan)
+	}
+
+	data, err := common.Format(cfg.Output, true, displayable...)
+	if err != nil {
+		log.Fatalf("error while formatting orphans: %s", err)
+	}
+	fmt.Println(string(data))
+}
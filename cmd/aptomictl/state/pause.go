@@ -0,0 +1,54 @@
+package state
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/api"
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newPauseCommand(cfg *config.Client) *cobra.Command {
+	var namespace string
+	var cluster string
+	var reason string
+
+	cmd := &cobra.Command{
+		Use:   "pause",
+		Short: "state pause",
+		Long:  "state pause pauses desired state enforcement globally (or for component instances in a given namespace or cluster) until it gets resumed",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			if len(reason) <= 0 {
+				log.Fatalf("reason is required to pause enforcement (--reason)")
+			}
+
+			result, err := rest.New(cfg, http.NewClient(cfg)).State().Pause(namespace, cluster, reason)
+			if err != nil {
+				log.Fatalf("error while pausing enforcement: %s", err)
+			}
+
+			printEnforcerPauses(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Only pause enforcement for component instances in a given namespace")
+	cmd.Flags().StringVar(&cluster, "cluster", "", "Only pause enforcement for component instances in a given cluster")
+	cmd.Flags().StringVar(&reason, "reason", "", "Reason of the pause (required)")
+
+	return cmd
+}
+
+func printEnforcerPauses(result *api.EnforcerPauseStatus) {
+	if len(result.Pauses) == 0 {
+		fmt.Println("Enforcement is not paused")
+		return
+	}
+	for _, pause := range result.Pauses {
+		fmt.Printf("Enforcement is paused for %s by '%s' since %s: %s\n", pause, pause.PausedBy, pause.PausedAt.Format(time.RFC3339), pause.Reason)
+	}
+}
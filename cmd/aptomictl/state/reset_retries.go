@@ -0,0 +1,36 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newResetRetriesCommand(cfg *config.Client) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reset-retries [component-instance-key]",
+		Short: "state reset-retries",
+		Long:  "state reset-retries resets retry state of a failing component instance (or all of them, if key is not given), so it gets retried right away",
+		Args:  cobra.MaximumNArgs(1),
+
+		Run: func(cmd *cobra.Command, args []string) {
+			key := ""
+			if len(args) > 0 {
+				key = args[0]
+			}
+
+			result, err := rest.New(cfg, http.NewClient(cfg)).State().ResetRetries(key)
+			if err != nil {
+				log.Fatalf("error while resetting retry states: %s", err)
+			}
+
+			fmt.Printf("Retry state has been reset for %d component instance(s)\n", result.Reset)
+		},
+	}
+
+	return cmd
+}
@@ -0,0 +1,34 @@
+package state
+
+import (
+	"github.com/Aptomi/aptomi/pkg/client/rest"
+	"github.com/Aptomi/aptomi/pkg/client/rest/http"
+	"github.com/Aptomi/aptomi/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func newResumeCommand(cfg *config.Client) *cobra.Command {
+	var namespace string
+	var cluster string
+
+	cmd := &cobra.Command{
+		Use:   "resume",
+		Short: "state resume",
+		Long:  "state resume resumes desired state enforcement, which has been paused globally (or for a given namespace or cluster)",
+
+		Run: func(cmd *cobra.Command, args []string) {
+			result, err := rest.New(cfg, http.NewClient(cfg)).State().Resume(namespace, cluster)
+			if err != nil {
+				log.Fatalf("error while resuming enforcement: %s", err)
+			}
+
+			printEnforcerPauses(result)
+		},
+	}
+
+	cmd.Flags().StringVar(&namespace, "namespace", "", "Resume enforcement paused for a given namespace")
+	cmd.Flags().StringVar(&cluster, "cluster", "", "Resume enforcement paused for a given cluster")
+
+	return cmd
+}
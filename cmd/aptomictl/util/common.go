@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Aptomi/aptomi/cmd/common"
@@ -32,12 +33,12 @@ func WaitForRevisionActionsToFinish(maxTime time.Duration, interval time.Duratio
 	// query revision status
 	finished := retry.Do2(maxTime, interval, func() bool {
 		// call API
-		var revErr error
-		rev, revErr = clientObj.Revision().Show(result.WaitForRevision)
+		revStatus, revErr := clientObj.Revision().Show(result.WaitForRevision)
 		if revErr != nil {
 			fmt.Print(".")
 			return false
 		}
+		rev = revStatus.Revision
 
 		// if the engine already started processing the revision, show its progress
 		if rev.Status != engine.RevisionStatusWaiting {
@@ -57,7 +58,7 @@ func WaitForRevisionActionsToFinish(maxTime time.Duration, interval time.Duratio
 		}
 
 		// exit when revision is in completed or error status
-		return rev.Status == engine.RevisionStatusCompleted || rev.Status == engine.RevisionStatusError
+		return rev.Status == engine.RevisionStatusCompleted || rev.Status == engine.RevisionStatusAwaitingApproval || rev.Status == engine.RevisionStatusDeferred || rev.Status == engine.RevisionStatusError
 	})
 
 	// stop progress bar
@@ -69,11 +70,30 @@ func WaitForRevisionActionsToFinish(maxTime time.Duration, interval time.Duratio
 	if !finished {
 		log.Fatalf("Revision %d timeout! Has not been applied in %s\n", rev.GetGeneration(), maxTime)
 	} else if rev.Status == engine.RevisionStatusCompleted {
-		if rev.Result.Total > 0 {
+		if rev.Result.Cancelled {
+			fmt.Printf("Revision %d cancelled. Actions: %d succeeded, %d failed, %d skipped\n", rev.GetGeneration(), rev.Result.Success, rev.Result.Failed, rev.Result.Skipped)
+		} else if rev.Result.Aborted {
+			fmt.Printf("Revision %d aborted (%s). Actions: %d succeeded, %d failed, %d skipped\n", rev.GetGeneration(), rev.Result.AbortReason, rev.Result.Success, rev.Result.Failed, rev.Result.Skipped)
+		} else if rev.Result.Total > 0 {
 			fmt.Printf("Revision %d completed. Actions: %d succeeded, %d failed, %d skipped\n", rev.GetGeneration(), rev.Result.Success, rev.Result.Failed, rev.Result.Skipped)
 		} else {
 			fmt.Printf("Revision %d completed\n", rev.GetGeneration())
 		}
+	} else if rev.Status == engine.RevisionStatusAwaitingApproval {
+		fmt.Printf("Revision %d is awaiting approval to delete protected component instances (approve with 'aptomictl revision approve %d'):\n  %s\n", rev.GetGeneration(), rev.GetGeneration(), strings.Join(rev.PendingApproval, "\n  "))
+		fmt.Printf("Other actions: %d succeeded, %d failed, %d skipped\n", rev.Result.Success, rev.Result.Failed, rev.Result.Skipped)
+	} else if rev.Status == engine.RevisionStatusDeferred {
+		until := "unknown"
+		if !rev.DeferredUntil.IsZero() {
+			until = rev.DeferredUntil.Format(time.RFC3339)
+		}
+		if len(rev.Deferred) > 0 {
+			fmt.Printf("Revision %d has changes deferred by maintenance (until %s) for component instances:\n  %s\n", rev.GetGeneration(), until, strings.Join(rev.Deferred, "\n  "))
+		}
+		if len(rev.Paused) > 0 {
+			fmt.Printf("Revision %d has changes held while enforcement is paused (resume with 'aptomictl state resume') for component instances:\n  %s\n", rev.GetGeneration(), strings.Join(rev.Paused, "\n  "))
+		}
+		fmt.Printf("Other actions: %d succeeded, %d failed, %d skipped\n", rev.Result.Success, rev.Result.Failed, rev.Result.Skipped)
 	} else if rev.Status == engine.RevisionStatusError {
 		log.Fatalf("Revision %d failed\n", rev.GetGeneration())
 	} else {
@@ -98,3 +118,20 @@ func PrintPolicyUpdateResult(result *api.PolicyUpdateResult, logLevelObj log.Lev
 	}
 	fmt.Println(string(data))
 }
+
+// PrintPolicySimulateResult prints PolicySimulateResult to the console
+func PrintPolicySimulateResult(result *api.PolicySimulateResult, logLevelObj log.Level, cfg *config.Client) { // nolint: interfacer
+	fmt.Printf("Event Log (>%s):\n", logLevelObj.String())
+	if len(result.EventLog) > 0 {
+		for _, entry := range result.EventLog {
+			fmt.Printf("[%s] %s\n", entry.LogLevel, entry.Message)
+		}
+	} else {
+		fmt.Println("* no entries")
+	}
+	data, err := common.Format(cfg.Output, false, result)
+	if err != nil {
+		panic(fmt.Sprintf("error while formating policy simulate result: %s", err))
+	}
+	fmt.Println(string(data))
+}
@@ -10,7 +10,11 @@ import (
 )
 
 type userRolesWrapper struct {
+	// Data is a map of user name -> role ID -> namespaces, in which the role applies
 	Data interface{}
+
+	// Roles is a list of all roles (built-in and custom ones defined in the policy)
+	Roles []*lang.ACLRole
 }
 
 func (g *userRolesWrapper) GetKind() string {
@@ -26,9 +30,9 @@ func (api *coreAPI) handleUserRoles(writer http.ResponseWriter, request *http.Re
 	systemNamespace := policy.Namespace[runtime.SystemNS]
 	var aclResolver *lang.ACLResolver
 	if systemNamespace != nil {
-		aclResolver = lang.NewACLResolver(systemNamespace.ACLRules)
+		aclResolver = lang.NewACLResolver(systemNamespace.ACLRules, systemNamespace.ACLRoles)
 	} else {
-		aclResolver = lang.NewACLResolver(make(map[string]*lang.ACLRule))
+		aclResolver = lang.NewACLResolver(make(map[string]*lang.ACLRule), make(map[string]*lang.ACLCustomRole))
 	}
 
 	data := make(map[string]map[string]map[string]bool)
@@ -40,5 +44,5 @@ func (api *coreAPI) handleUserRoles(writer http.ResponseWriter, request *http.Re
 		}
 		data[user.Name] = roleMap
 	}
-	api.contentType.WriteOne(writer, request, &userRolesWrapper{Data: data})
+	api.contentType.WriteOne(writer, request, &userRolesWrapper{Data: data, Roles: aclResolver.GetRoles()})
 }
@@ -15,13 +15,34 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+// StateEnforceRequestObject contains Info for the StateEnforceRequest type
+var StateEnforceRequestObject = &runtime.Info{
+	Kind:        "state-enforce-request",
+	Constructor: func() runtime.Object { return &StateEnforceRequest{} },
+}
+
+// StateEnforceRequest represents state enforcement request. If any of the lists is not empty, enforcement will be
+// scoped to the matching component instances only (see diff.Scope)
+type StateEnforceRequest struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Dependencies is a list of dependencies in 'namespace/name' format
+	Dependencies []string `yaml:",omitempty"`
+
+	// Namespaces is a list of namespaces
+	Namespaces []string `yaml:",omitempty"`
+
+	// Clusters is a list of cluster names
+	Clusters []string `yaml:",omitempty"`
+}
+
 func isDomainAdmin(user *lang.User, policy *lang.Policy) bool {
 	systemNamespace := policy.Namespace[runtime.SystemNS]
 	var aclResolver *lang.ACLResolver
 	if systemNamespace != nil {
-		aclResolver = lang.NewACLResolver(systemNamespace.ACLRules)
+		aclResolver = lang.NewACLResolver(systemNamespace.ACLRules, systemNamespace.ACLRoles)
 	} else {
-		aclResolver = lang.NewACLResolver(make(map[string]*lang.ACLRule))
+		aclResolver = lang.NewACLResolver(make(map[string]*lang.ACLRule), make(map[string]*lang.ACLCustomRole))
 	}
 
 	roleMap, errRoleMap := aclResolver.GetUserRoleMap(user)
@@ -55,10 +76,34 @@ func (api *coreAPI) handleStateEnforce(writer http.ResponseWriter, request *http
 		noop = false
 	}
 
-	// See that would happen if we reset the actual state, calculate resolution log and action plan
+	// See if failure budget is set (it will override enforcer's failure budget for the new revision)
+	failureBudget := params.ByName("budget")
+	_, budgetErr := action.ParseFailureBudget(failureBudget)
+	if budgetErr != nil {
+		panic(fmt.Sprintf("error while parsing failure budget: %s", budgetErr))
+	}
+
+	// See if enforcement is scoped to a subset of component instances
+	enforceReq, ok := api.contentType.ReadOne(request).(*StateEnforceRequest)
+	if !ok {
+		panic(fmt.Sprintf("Unexpected object received: %v", enforceReq))
+	}
+	scope := getStateEnforceScope(enforceReq, policy)
+
+	// See that would happen if we reset the actual state, calculate resolution log and action plan. If enforcement is
+	// scoped, only pending changes for the component instances in scope will be applied (instead of resetting them)
+	actualState, actualStateErr := api.store.GetActualState()
+	if actualStateErr != nil {
+		panic(fmt.Sprintf("error while loading actual state: %s", actualStateErr))
+	}
 	resolveLog := event.NewLog(logrus.InfoLevel, "api-state-enforce").AddConsoleHook(api.logLevel)
-	desiredState := resolve.NewPolicyResolver(policy, api.externalData, resolveLog).ResolveAllDependencies()
-	actionPlan := diff.NewPolicyResolutionDiff(desiredState, resolve.NewPolicyResolution()).ActionPlan
+	desiredState := resolve.NewPolicyResolver(policy, api.externalData, resolveLog).WithDeployNames(actualState).ResolveAllDependencies()
+	var actionPlan *action.Plan
+	if scope.IsEmpty() {
+		actionPlan = diff.NewPolicyResolutionDiff(desiredState, resolve.NewPolicyResolution()).ActionPlan
+	} else {
+		actionPlan = diff.NewPolicyResolutionDiff(desiredState, actualState).ScopedActionPlan(scope)
+	}
 
 	// If we are in noop mode, just return expected changes in a form of an action plan
 	if noop {
@@ -74,7 +119,7 @@ func (api *coreAPI) handleStateEnforce(writer http.ResponseWriter, request *http
 	}
 
 	// Keep policy the same, but create another special revision for it to enforce the state
-	revisionGen := api.createStateEnforceRevision(policyGen, desiredState, actionPlan)
+	revisionGen := api.createStateEnforceRevision(policyGen, desiredState, actionPlan, failureBudget, scope)
 
 	api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
 		TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
@@ -89,7 +134,27 @@ func (api *coreAPI) handleStateEnforce(writer http.ResponseWriter, request *http
 	api.runDesiredStateEnforcement <- true
 }
 
-func (api *coreAPI) createStateEnforceRevision(policyGen runtime.Generation, desiredState *resolve.PolicyResolution, actionPlan *action.Plan) runtime.Generation {
+// getStateEnforceScope returns scope for state enforcement request, checking that all referenced dependencies exist.
+// It returns nil if enforcement is not scoped
+func getStateEnforceScope(enforceReq *StateEnforceRequest, policy *lang.Policy) *diff.Scope {
+	scope := &diff.Scope{
+		Namespaces: enforceReq.Namespaces,
+		Clusters:   enforceReq.Clusters,
+	}
+	for _, dependency := range enforceReq.Dependencies {
+		obj, err := policy.GetObject(lang.DependencyObject.Kind, dependency, runtime.SystemNS)
+		if err != nil || obj == nil {
+			panic(fmt.Sprintf("dependency '%s' not found in policy", dependency))
+		}
+		scope.Dependencies = append(scope.Dependencies, runtime.KeyForStorable(obj.(*lang.Dependency)))
+	}
+	if scope.IsEmpty() {
+		return nil
+	}
+	return scope
+}
+
+func (api *coreAPI) createStateEnforceRevision(policyGen runtime.Generation, desiredState *resolve.PolicyResolution, actionPlan *action.Plan, failureBudget string, scope *diff.Scope) runtime.Generation {
 	// Here we need to take mutex to handle policy and revision updates
 	api.policyAndRevisionUpdateMutex.Lock()
 	defer api.policyAndRevisionUpdateMutex.Unlock()
@@ -98,10 +163,19 @@ func (api *coreAPI) createStateEnforceRevision(policyGen runtime.Generation, des
 	var revisionGen = runtime.MaxGeneration
 	if actionPlan.NumberOfActions() > 0 {
 		// If there are changes, create a new revision and say that we should wait for it
-		newRevision, newRevisionErr := api.store.NewRevision(policyGen, desiredState, true)
+		// Scoped revision doesn't recalculate all component instances, it only applies pending changes in its scope
+		newRevision, newRevisionErr := api.store.NewRevision(policyGen, desiredState, scope.IsEmpty())
 		if newRevisionErr != nil {
 			panic(fmt.Errorf("unable to create new revision for policy gen %d", policyGen))
 		}
+		if len(failureBudget) > 0 || !scope.IsEmpty() {
+			newRevision.FailureBudget = failureBudget
+			newRevision.Scope = scope
+			updateErr := api.store.UpdateRevision(newRevision)
+			if updateErr != nil {
+				panic(fmt.Errorf("unable to set failure budget and scope for revision %d: %s", newRevision.GetGeneration(), updateErr))
+			}
+		}
 		revisionGen = newRevision.GetGeneration()
 	}
 
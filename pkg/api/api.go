@@ -4,6 +4,7 @@ import (
 	"sync"
 
 	"github.com/Aptomi/aptomi/pkg/api/codec"
+	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/external"
 	"github.com/Aptomi/aptomi/pkg/plugin"
 	"github.com/Aptomi/aptomi/pkg/runtime"
@@ -21,20 +22,25 @@ type coreAPI struct {
 	secret                       string
 	logLevel                     logrus.Level
 	runDesiredStateEnforcement   chan bool
-	policyAndRevisionUpdateMutex sync.Mutex
+	policyAndRevisionUpdateMutex *sync.Mutex
+	revisionCanceller            *engine.RevisionCanceller
 }
 
-// Serve initializes everything needed by REST API and registers all API endpoints in the provided http router
-func Serve(router *httprouter.Router, store store.Core, externalData *external.Data, pluginRegistryFactory plugin.RegistryFactory, secret string, logLevel logrus.Level, runDesiredStateEnforcement chan bool) {
+// Serve initializes everything needed by REST API and registers all API endpoints in the provided http router.
+// Provided mutex must be taken by everyone making policy and revision changes outside of API. Provided revision
+// canceller is used to cancel the revision, which is currently being applied
+func Serve(router *httprouter.Router, store store.Core, externalData *external.Data, pluginRegistryFactory plugin.RegistryFactory, secret string, logLevel logrus.Level, runDesiredStateEnforcement chan bool, policyAndRevisionUpdateMutex *sync.Mutex, revisionCanceller *engine.RevisionCanceller) {
 	contentTypeHandler := codec.NewContentTypeHandler(runtime.NewRegistry().Append(Objects...))
 	api := &coreAPI{
-		contentType:                contentTypeHandler,
-		store:                      store,
-		externalData:               externalData,
-		pluginRegistryFactory:      pluginRegistryFactory,
-		secret:                     secret,
-		logLevel:                   logLevel,
-		runDesiredStateEnforcement: runDesiredStateEnforcement,
+		contentType:                  contentTypeHandler,
+		store:                        store,
+		externalData:                 externalData,
+		pluginRegistryFactory:        pluginRegistryFactory,
+		secret:                       secret,
+		logLevel:                     logLevel,
+		runDesiredStateEnforcement:   runDesiredStateEnforcement,
+		policyAndRevisionUpdateMutex: policyAndRevisionUpdateMutex,
+		revisionCanceller:            revisionCanceller,
 	}
 	api.serve(router)
 }
@@ -65,6 +71,10 @@ func (api *coreAPI) serve(router *httprouter.Router) {
 	router.DELETE("/api/v1/policy", auth(api.handlePolicyDelete))
 	router.DELETE("/api/v1/policy/noop/:noop/loglevel/:loglevel", auth(api.handlePolicyDelete))
 
+	// simulate policy changes (nothing gets persisted)
+	router.POST("/api/v1/policy/simulate", auth(api.handlePolicySimulate))
+	router.POST("/api/v1/policy/simulate/loglevel/:loglevel", auth(api.handlePolicySimulate))
+
 	// policy & object diagrams
 	router.GET("/api/v1/policy/diagram/object/:ns/:kind/:name", auth(api.handleObjectDiagram))
 	router.GET("/api/v1/policy/diagram/mode/:mode", auth(api.handlePolicyDiagram))
@@ -74,15 +84,43 @@ func (api *coreAPI) serve(router *httprouter.Router) {
 	// retrieve dependency along with its status
 	router.GET("/api/v1/policy/dependency/status/:queryFlag/:idList", auth(api.handleDependencyStatusGet))
 	router.GET("/api/v1/policy/dependency/resources/:ns/:name", auth(api.handleDependencyResourcesGet))
+	router.GET("/api/v1/policy/dependency/explain/:ns/:name", auth(api.handleDependencyExplainGet))
+	router.POST("/api/v1/policy/dependency/extend/:ns/:name/duration/:duration/noop/:noop/loglevel/:loglevel", auth(api.handleDependencyExtend))
 
 	// retrieve revision (latest + by a given generation)
 	router.GET("/api/v1/revision", auth(api.handleRevisionGet))
 	router.GET("/api/v1/revision/gen/:gen", auth(api.handleRevisionGet))
+	router.GET("/api/v1/revision/gen/:gen/plan", auth(api.handleRevisionPlanGet))
 
 	// retrieve revision(s) (for a given policy)
 	router.GET("/api/v1/revisions/policy/:policy", auth(api.handleRevisionsGetByPolicy))
 
+	// roll back policy to the state behind a given revision
+	router.POST("/api/v1/revision/gen/:gen/rollback/noop/:noop/loglevel/:loglevel", auth(api.handleRevisionRollback))
+
+	// cancel revision, which is currently being applied
+	router.POST("/api/v1/revision/cancel", auth(api.handleRevisionCancel))
+
+	// approve deletion of protected component instances in a given revision
+	router.POST("/api/v1/revision/gen/:gen/approve", auth(api.handleRevisionApprove))
+
 	router.POST("/api/v1/state/enforce/noop/:noop", auth(api.handleStateEnforce))
+	router.POST("/api/v1/state/enforce/noop/:noop/budget/:budget", auth(api.handleStateEnforce))
+
+	// reset retry states of failing component instances (all or a given one)
+	router.POST("/api/v1/state/retry/reset", auth(api.handleRetryReset))
+	router.POST("/api/v1/state/retry/reset/:key", auth(api.handleRetryReset))
+
+	// retrieve component instances, for which deployed code doesn't match the actual state
+	router.GET("/api/v1/state/drift", auth(api.handleDriftGet))
+
+	// retrieve code deployed by Aptomi, which doesn't belong to any component instance, and delete it
+	router.GET("/api/v1/state/orphans", auth(api.handleOrphansGet))
+	router.POST("/api/v1/state/orphans/cleanup", auth(api.handleOrphansCleanup))
+
+	// pause and resume enforcement (globally or for a given namespace or cluster)
+	router.POST("/api/v1/state/pause", auth(api.handleEnforcerPause))
+	router.POST("/api/v1/state/resume", auth(api.handleEnforcerResume))
 
 	// return aptomi version
 	router.GET("/version", api.handleVersion)
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"runtime/debug"
@@ -50,6 +51,12 @@ type DependencyStatus struct {
 	Deployed  bool
 	Ready     bool
 	Endpoints map[string]map[string]string
+
+	// Error holds the resolution error, if dependency could not be resolved (e.g. it exceeds a quota)
+	Error string `yaml:",omitempty"`
+
+	// Retries holds retry states of component instances, which failed to be applied (component instance key -> state)
+	Retries map[string]*action.ComponentRetryState `yaml:",omitempty"`
 }
 
 func (api *coreAPI) handleDependencyStatusGet(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
@@ -101,12 +108,13 @@ func (api *coreAPI) handleDependencyStatusGet(writer http.ResponseWriter, reques
 		}
 
 		d := dObj.(*lang.Dependency) // nolint: errcheck
-		resolved := desiredState.GetDependencyResolution(d).Resolved
+		dResolution := desiredState.GetDependencyResolution(d)
 		result.Status[runtime.KeyForStorable(d)] = &DependencyStatus{
 			Found:     true,
-			Deployed:  resolved,
-			Ready:     resolved,
+			Deployed:  dResolution.Resolved,
+			Ready:     dResolution.Resolved,
 			Endpoints: make(map[string]map[string]string),
+			Error:     dResolution.Error,
 		}
 	}
 
@@ -122,6 +130,13 @@ func (api *coreAPI) handleDependencyStatusGet(writer http.ResponseWriter, reques
 	// fetch endpoints for dependencies
 	fetchEndpointsForDependencies(result, actualState)
 
+	// fetch retry states of failing component instances for dependencies
+	retryStates, err := api.store.GetComponentRetryStates()
+	if err != nil {
+		panic(fmt.Sprintf("can't load component retry states from the store: %s", err))
+	}
+	fetchRetryStatesForDependencies(result, desiredState, retryStates, revision.PolicyGen)
+
 	// return the result back
 	api.contentType.WriteOne(writer, request, result)
 }
@@ -130,6 +145,7 @@ func fetchDeploymentStatusForDependencies(result *DependenciesStatus, actualStat
 	// compare desired vs. actual state and see what's the dependency status for every provided dependency ID
 	actionPlan := diff.NewPolicyResolutionDiff(desiredState, actualState).ActionPlan
 	actionPlan.Apply(
+		context.Background(),
 		action.WrapSequential(func(act action.Interface) error {
 			// if it's attach action is pending on component, let's see which particular dependency it affects
 			if dAction, ok := act.(*component.AttachDependencyAction); ok {
@@ -180,6 +196,8 @@ func fetchDeploymentStatusForDependencies(result *DependenciesStatus, actualStat
 			return nil
 		}),
 		action.NewApplyResultUpdaterImpl(),
+		nil,
+		nil,
 	)
 
 	for _, instance := range actualState.ComponentInstanceMap {
@@ -245,6 +263,7 @@ func fetchReadinessStatusForDependencies(result *DependenciesStatus, plugins plu
 			}
 
 			instanceStatus, err := codePlugin.Status(
+				context.Background(),
 				&plugin.CodePluginInvocationParams{
 					DeployName:   instance.GetDeployName(),
 					Params:       instance.CalculatedCodeParams,
@@ -290,3 +309,23 @@ func fetchEndpointsForDependencies(result *DependenciesStatus, actualState *reso
 		}
 	}
 }
+
+func fetchRetryStatesForDependencies(result *DependenciesStatus, desiredState *resolve.PolicyResolution, retryStates map[string]*action.ComponentRetryState, policyGen runtime.Generation) {
+	for key, state := range retryStates {
+		if !state.IsActive(policyGen) {
+			continue
+		}
+		instance, ok := desiredState.ComponentInstanceMap[key]
+		if !ok {
+			continue
+		}
+		for dKey := range instance.DependencyKeys {
+			if _, ok := result.Status[dKey]; ok {
+				if result.Status[dKey].Retries == nil {
+					result.Status[dKey].Retries = make(map[string]*action.ComponentRetryState)
+				}
+				result.Status[dKey].Retries[key] = state
+			}
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// setDependencyCreationTime sets creation time for a dependency being added to the policy. If dependency already
+// exists in the policy, its original creation time will be preserved. Creation time supplied by the client is always
+// ignored, so dependency can't be made to expire earlier or later than it should
+func setDependencyCreationTime(policy *lang.Policy, dependency *lang.Dependency) {
+	obj, err := policy.GetObject(lang.DependencyObject.Kind, dependency.Name, dependency.Namespace)
+	if err == nil && obj != nil {
+		if existing := obj.(*lang.Dependency); !existing.CreatedAt.IsZero() { // nolint: errcheck
+			dependency.CreatedAt = existing.CreatedAt
+			return
+		}
+	}
+	dependency.CreatedAt = time.Now()
+}
+
+func (api *coreAPI) handleDependencyExtend(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	user := api.getUserRequired(request)
+
+	// parse duration, by which expiration should be extended
+	duration, err := time.ParseDuration(params.ByName("duration"))
+	if err != nil || duration <= 0 {
+		panic(fmt.Sprintf("invalid duration to extend dependency expiration by: %s", params.ByName("duration")))
+	}
+
+	// load the latest policy
+	policy, policyGen, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest policy from the store: %s", err))
+	}
+
+	ns := params.ByName("ns")
+	kind := lang.DependencyObject.Kind
+	name := params.ByName("name")
+
+	obj, err := policy.GetObject(kind, name, ns)
+	if err != nil {
+		panic(fmt.Sprintf("error while getting object %s/%s/%s in policy #%d", ns, kind, name, policyGen))
+	}
+	if obj == nil {
+		api.contentType.WriteOneWithStatus(writer, request, nil, http.StatusNotFound)
+		return
+	}
+
+	dependency := obj.(*lang.Dependency) // nolint: errcheck
+	if dependency.Expiration == nil {
+		panic(fmt.Sprintf("dependency %s/%s doesn't have expiration set", ns, name))
+	}
+
+	// extend expiration and update dependency in the policy the same way as any other policy change, so it goes
+	// through the same access checks and validation
+	dependency.ExtendExpiration(time.Now(), duration)
+	api.handlePolicyChanges(writer, request, params, user, &policyChanges{updated: []lang.Base{dependency}}, "api-dependency-extend")
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+)
+
+// DependencyExplainObject is an informational data structure with Kind and Constructor for DependencyExplain
+var DependencyExplainObject = &runtime.Info{
+	Kind:        "dependency-explain",
+	Constructor: func() runtime.Object { return &DependencyExplain{} },
+}
+
+// DependencyExplain is a structured explanation of how a dependency got resolved against the latest policy
+type DependencyExplain struct {
+	runtime.TypeKind `yaml:",inline"`
+	PolicyGeneration runtime.Generation
+	Trace            *resolve.DependencyTrace
+}
+
+// GetDefaultColumns returns default set of columns to be displayed
+func (explain *DependencyExplain) GetDefaultColumns() []string {
+	return []string{"Dependency", "Resolved", "Trace"}
+}
+
+// AsColumns returns DependencyExplain representation as columns
+func (explain *DependencyExplain) AsColumns() map[string]string {
+	trace := explain.Trace
+	resolved := fmt.Sprintf("%t", trace.Resolved)
+	if !trace.Resolved {
+		resolved = fmt.Sprintf("false (%s)", trace.Error)
+	}
+
+	buf := &bytes.Buffer{}
+	for _, step := range trace.Steps {
+		indent := strings.Repeat("  ", step.Depth)
+		fmt.Fprintf(buf, "%scontract %s\n", indent, step.Contract)
+		for _, rejected := range step.RejectedContexts {
+			fmt.Fprintf(buf, "%s  context '%s' rejected: %s\n", indent, rejected.Name, rejected.FailedClause)
+		}
+		if len(step.Context) > 0 {
+			fmt.Fprintf(buf, "%s  context '%s' matched -> service %s\n", indent, step.Context, step.Service)
+		}
+		for _, rule := range step.Rules {
+			if rule.Matched {
+				fmt.Fprintf(buf, "%s  rule %s matched\n", indent, rule.Rule)
+			}
+		}
+		if len(step.AllocationKeys) > 0 {
+			fmt.Fprintf(buf, "%s  allocation keys: %s\n", indent, strings.Join(step.AllocationKeys, ", "))
+		}
+		if len(step.Cluster) > 0 {
+			fmt.Fprintf(buf, "%s  cluster %s, target suffix '%s'\n", indent, step.Cluster, step.TargetSuffix)
+		}
+		if len(step.Labels) > 0 {
+			labels := step.Labels[len(step.Labels)-1].Labels
+			pairs := make([]string, 0, len(labels))
+			for _, k := range util.GetSortedStringKeys(labels) {
+				pairs = append(pairs, fmt.Sprintf("%s=%s", k, labels[k]))
+			}
+			fmt.Fprintf(buf, "%s  labels: %s\n", indent, strings.Join(pairs, ", "))
+		}
+		if len(step.Error) > 0 {
+			fmt.Fprintf(buf, "%s  error: %s\n", indent, step.Error)
+		}
+	}
+
+	return map[string]string{
+		"Dependency": trace.Dependency,
+		"Resolved":   resolved,
+		"Trace":      strings.TrimSuffix(buf.String(), "\n"),
+	}
+}
+
+func (api *coreAPI) handleDependencyExplainGet(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	user := api.getUserRequired(request)
+
+	// load the latest policy
+	policy, policyGen, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest policy from the store: %s", err))
+	}
+
+	ns := params.ByName("ns")
+	kind := lang.DependencyObject.Kind
+	name := params.ByName("name")
+
+	obj, err := policy.GetObject(kind, name, ns)
+	if err != nil {
+		panic(fmt.Sprintf("error while getting object %s/%s/%s in policy #%d", ns, kind, name, policyGen))
+	}
+	if obj == nil {
+		api.contentType.WriteOneWithStatus(writer, request, nil, http.StatusNotFound)
+		return
+	}
+
+	// make sure user is allowed to see the dependency
+	dependency := obj.(*lang.Dependency) // nolint: errcheck
+	err = policy.View(user).ViewObject(dependency)
+	if err != nil {
+		panic(fmt.Sprintf("error while explaining dependency: %s", err))
+	}
+
+	// resolve the dependency, recording every decision made along the way
+	eventLog := event.NewLog(logrus.WarnLevel, "api-dependency-explain")
+	trace := resolve.NewPolicyResolver(policy, api.externalData, eventLog).ExplainDependency(dependency)
+
+	api.contentType.WriteOne(writer, request, &DependencyExplain{
+		TypeKind:         DependencyExplainObject.GetTypeKind(),
+		PolicyGeneration: policyGen,
+		Trace:            trace,
+	})
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"runtime/debug"
@@ -83,6 +84,7 @@ func (api *coreAPI) handleDependencyResourcesGet(writer http.ResponseWriter, req
 				}
 
 				instanceResources, resErr := codePlugin.Resources(
+					context.Background(),
 					&plugin.CodePluginInvocationParams{
 						DeployName:   instance.GetDeployName(),
 						Params:       instance.CalculatedCodeParams,
@@ -0,0 +1,100 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// DriftStatusObject is an informational data structure with Kind and Constructor for DriftStatus
+var DriftStatusObject = &runtime.Info{
+	Kind:        "drift-status",
+	Constructor: func() runtime.Object { return &DriftStatus{} },
+}
+
+// DriftStatus is a list of component instances, for which deployed code doesn't match the actual state
+type DriftStatus struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Instances is a list of drifted component instances, sorted by key
+	Instances []*DriftedComponentInstance
+}
+
+// DriftedComponentInstance represents a component instance, for which drift has been detected
+type DriftedComponentInstance struct {
+	// Key is a key of the component instance
+	Key string
+
+	// Drift is a description of the drift
+	Drift string
+
+	// Missing is true if deployed code no longer exists in the cloud
+	Missing bool
+
+	// DetectedAt is when drift has been detected for the first time
+	DetectedAt time.Time
+
+	// Repair is true if component instance will be re-created or updated during the next enforcement
+	Repair bool
+}
+
+// GetDefaultColumns returns default set of columns to be displayed
+func (instance *DriftedComponentInstance) GetDefaultColumns() []string {
+	return []string{"Component Instance", "Drift", "Detected", "Repair"}
+}
+
+// AsColumns returns DriftedComponentInstance representation as columns
+func (instance *DriftedComponentInstance) AsColumns() map[string]string {
+	return map[string]string{
+		"Component Instance": instance.Key,
+		"Drift":              instance.Drift,
+		"Detected":           instance.DetectedAt.Format(time.RFC3339),
+		"Repair":             strconv.FormatBool(instance.Repair),
+	}
+}
+
+func (api *coreAPI) handleDriftGet(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	// Load current policy
+	policy, _, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest policy: %s", err))
+	}
+
+	// check that user is a domain admin
+	user := api.getUserRequired(request)
+	if !isDomainAdmin(user, policy) {
+		panic(fmt.Sprintf("user is not allowed to view drifted component instances"))
+	}
+
+	actualState, err := api.store.GetActualState()
+	if err != nil {
+		panic(fmt.Sprintf("error while loading actual state: %s", err))
+	}
+
+	result := &DriftStatus{
+		TypeKind:  DriftStatusObject.GetTypeKind(),
+		Instances: []*DriftedComponentInstance{},
+	}
+	for _, instance := range actualState.ComponentInstanceMap {
+		if !instance.IsDrifted() {
+			continue
+		}
+		result.Instances = append(result.Instances, &DriftedComponentInstance{
+			Key:        instance.GetKey(),
+			Drift:      instance.Drift,
+			Missing:    instance.DriftMissing,
+			DetectedAt: instance.DriftDetectedAt,
+			Repair:     instance.DriftRepair,
+		})
+	}
+	sort.Slice(result.Instances, func(i, j int) bool {
+		return result.Instances[i].Key < result.Instances[j].Key
+	})
+
+	api.contentType.WriteOne(writer, request, result)
+}
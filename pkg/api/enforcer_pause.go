@@ -0,0 +1,146 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// EnforcerPauseRequestObject is an informational data structure with Kind and Constructor for EnforcerPauseRequest
+var EnforcerPauseRequestObject = &runtime.Info{
+	Kind:        "enforcer-pause-request",
+	Constructor: func() runtime.Object { return &EnforcerPauseRequest{} },
+}
+
+// EnforcerPauseRequest represents request to pause or resume desired state enforcement, either globally or for
+// component instances in a given namespace or cluster
+type EnforcerPauseRequest struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Namespace is a namespace, for which enforcement is paused or resumed
+	Namespace string `yaml:",omitempty"`
+
+	// Cluster is a name of the cluster, for which enforcement is paused or resumed
+	Cluster string `yaml:",omitempty"`
+
+	// Reason is a reason of the pause (it's required when pausing)
+	Reason string `yaml:",omitempty"`
+}
+
+// EnforcerPauseStatusObject is an informational data structure with Kind and Constructor for EnforcerPauseStatus
+var EnforcerPauseStatusObject = &runtime.Info{
+	Kind:        "enforcer-pause-status",
+	Constructor: func() runtime.Object { return &EnforcerPauseStatus{} },
+}
+
+// EnforcerPauseStatus is a list of pauses of desired state enforcement, which are currently in effect
+type EnforcerPauseStatus struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Pauses is a list of pauses, sorted by name
+	Pauses []*engine.EnforcerPause
+}
+
+func (api *coreAPI) handleEnforcerPause(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	user, policy := api.checkEnforcerPauseAllowed(request)
+
+	pauseReq := api.readEnforcerPauseRequest(request)
+	if len(strings.TrimSpace(pauseReq.Reason)) <= 0 {
+		panic(fmt.Sprintf("reason is required to pause enforcement"))
+	}
+	err := validateEnforcerPauseRequest(pauseReq, policy)
+	if err != nil {
+		panic(fmt.Sprintf("enforcement can't be paused: %s", err))
+	}
+
+	pause := engine.NewEnforcerPause(pauseReq.Namespace, pauseReq.Cluster, strings.TrimSpace(pauseReq.Reason), user.Name)
+	err = api.store.SaveEnforcerPause(pause)
+	if err != nil {
+		panic(fmt.Sprintf("error while pausing enforcement: %s", err))
+	}
+
+	api.contentType.WriteOne(writer, request, api.getEnforcerPauseStatus())
+}
+
+func (api *coreAPI) handleEnforcerResume(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	api.checkEnforcerPauseAllowed(request)
+
+	pauseReq := api.readEnforcerPauseRequest(request)
+	deleted, err := api.store.DeleteEnforcerPause(engine.EnforcerPauseName(pauseReq.Namespace, pauseReq.Cluster))
+	if err != nil {
+		panic(fmt.Sprintf("error while resuming enforcement: %s", err))
+	}
+	if !deleted {
+		panic(fmt.Sprintf("enforcement is not paused for %s", engine.NewEnforcerPause(pauseReq.Namespace, pauseReq.Cluster, "", "")))
+	}
+
+	api.contentType.WriteOne(writer, request, api.getEnforcerPauseStatus())
+
+	// signal to the channel that enforcement has been resumed, that will trigger the enforcement right away
+	api.runDesiredStateEnforcement <- true
+}
+
+// checkEnforcerPauseAllowed makes sure that user is allowed to pause and resume enforcement, returning the user and the
+// latest policy
+func (api *coreAPI) checkEnforcerPauseAllowed(request *http.Request) (*lang.User, *lang.Policy) {
+	policy, _, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest policy: %s", err))
+	}
+
+	// check that user is a domain admin
+	user := api.getUserRequired(request)
+	if !isDomainAdmin(user, policy) {
+		panic(fmt.Sprintf("user is not allowed to pause and resume enforcement"))
+	}
+
+	return user, policy
+}
+
+// readEnforcerPauseRequest reads request to pause or resume enforcement, checking that it's either global or scoped
+// to a single namespace or cluster
+func (api *coreAPI) readEnforcerPauseRequest(request *http.Request) *EnforcerPauseRequest {
+	pauseReq, ok := api.contentType.ReadOne(request).(*EnforcerPauseRequest)
+	if !ok {
+		panic(fmt.Sprintf("Unexpected object received: %v", pauseReq))
+	}
+	if len(pauseReq.Namespace) > 0 && len(pauseReq.Cluster) > 0 {
+		panic(fmt.Sprintf("enforcement can be paused or resumed either for a namespace or for a cluster, but not both"))
+	}
+	return pauseReq
+}
+
+// validateEnforcerPauseRequest checks that namespace or cluster, for which enforcement is being paused, exists in a
+// given policy, so a typo doesn't result in a pause which has no effect
+func validateEnforcerPauseRequest(pauseReq *EnforcerPauseRequest, policy *lang.Policy) error {
+	if len(pauseReq.Namespace) > 0 && policy.Namespace[pauseReq.Namespace] == nil {
+		return fmt.Errorf("namespace '%s' doesn't exist in policy", pauseReq.Namespace)
+	}
+	if len(pauseReq.Cluster) > 0 {
+		for _, obj := range policy.GetObjectsByKind(lang.ClusterObject.Kind) {
+			if obj.GetName() == pauseReq.Cluster {
+				return nil
+			}
+		}
+		return fmt.Errorf("cluster '%s' doesn't exist in policy", pauseReq.Cluster)
+	}
+	return nil
+}
+
+// getEnforcerPauseStatus returns pauses of desired state enforcement, which are currently in effect
+func (api *coreAPI) getEnforcerPauseStatus() *EnforcerPauseStatus {
+	pauses, err := api.store.GetEnforcerPauses()
+	if err != nil {
+		panic(fmt.Sprintf("error while loading enforcer pauses: %s", err))
+	}
+
+	return &EnforcerPauseStatus{
+		TypeKind: EnforcerPauseStatusObject.GetTypeKind(),
+		Pauses:   pauses,
+	}
+}
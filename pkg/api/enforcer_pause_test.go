@@ -0,0 +1,33 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateEnforcerPauseRequest(t *testing.T) {
+	b, contract := makePolicyBuilder()
+	policy := b.Policy()
+	cluster := policy.GetObjectsByKind(lang.ClusterObject.Kind)[0]
+
+	// pauses for existing namespace and cluster, as well as the global one, are valid
+	for _, pauseReq := range []*EnforcerPauseRequest{
+		{},
+		{Namespace: contract.Namespace},
+		{Cluster: cluster.GetName()},
+	} {
+		assert.NoError(t, validateEnforcerPauseRequest(pauseReq, policy), "Pause request should be valid: %v", pauseReq)
+	}
+
+	// pauses for unknown namespace or cluster are invalid
+	err := validateEnforcerPauseRequest(&EnforcerPauseRequest{Namespace: "unknown"}, policy)
+	if assert.Error(t, err, "Pause for unknown namespace should be invalid") {
+		assert.Contains(t, err.Error(), "namespace 'unknown' doesn't exist")
+	}
+	err = validateEnforcerPauseRequest(&EnforcerPauseRequest{Cluster: "unknown"}, policy)
+	if assert.Error(t, err, "Pause for unknown cluster should be invalid") {
+		assert.Contains(t, err.Error(), "cluster 'unknown' doesn't exist")
+	}
+}
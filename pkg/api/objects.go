@@ -11,10 +11,22 @@ var (
 	// Objects is a list of all objects used in API
 	Objects = runtime.AppendAll([]*runtime.Info{
 		DependenciesStatusObject,
+		DependencyExplainObject,
 		PolicyUpdateResultObject,
+		PolicySimulateRemovalObject,
+		PolicySimulateResultObject,
 		AuthSuccessObject,
 		AuthRequestObject,
 		ServerErrorObject,
+		RetryResetResultObject,
+		StateEnforceRequestObject,
+		DriftStatusObject,
+		OrphanStatusObject,
+		OrphanCleanupRequestObject,
+		OrphanCleanupResultObject,
+		EnforcerPauseRequestObject,
+		EnforcerPauseStatusObject,
+		RevisionStatusObject,
 		version.BuildInfoObject,
 	}, lang.PolicyObjects, engine.Objects)
 )
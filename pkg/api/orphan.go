@@ -0,0 +1,272 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+)
+
+// OrphanStatusObject is an informational data structure with Kind and Constructor for OrphanStatus
+var OrphanStatusObject = &runtime.Info{
+	Kind:        "orphan-status",
+	Constructor: func() runtime.Object { return &OrphanStatus{} },
+}
+
+// OrphanStatus is a list of orphans, i.e. code deployed into the clusters by Aptomi, which doesn't belong to any
+// component instance in the actual state (e.g. after actual state has been lost or restored from an old backup)
+type OrphanStatus struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Orphans is a list of orphans, sorted by cluster, code type and deploy name
+	Orphans []*Orphan
+
+	// Errors is a list of errors for the clusters, which couldn't be checked for orphans
+	Errors []string `yaml:",omitempty"`
+}
+
+// Orphan represents code deployed into the cluster by Aptomi, which doesn't belong to any component instance
+type Orphan struct {
+	// Cluster is a cluster in 'namespace/name' format
+	Cluster string
+
+	// CodeType is a type of code
+	CodeType string
+
+	// DeployName is a name, under which the code has been deployed
+	DeployName string
+
+	// Namespace is a k8s namespace, where the code resides
+	Namespace string
+
+	// Description is a human-readable description of the deployed code
+	Description string
+
+	// Error is an error, which happened while deleting the orphan
+	Error string `yaml:",omitempty"`
+
+	code       *plugin.ManagedCode
+	codePlugin plugin.CodePlugin
+}
+
+// GetDefaultColumns returns default set of columns to be displayed
+func (orphan *Orphan) GetDefaultColumns() []string {
+	return []string{"Cluster", "Code Type", "Deploy Name", "Namespace", "Description"}
+}
+
+// AsColumns returns Orphan representation as columns
+func (orphan *Orphan) AsColumns() map[string]string {
+	return map[string]string{
+		"Cluster":     orphan.Cluster,
+		"Code Type":   orphan.CodeType,
+		"Deploy Name": orphan.DeployName,
+		"Namespace":   orphan.Namespace,
+		"Description": orphan.Description,
+	}
+}
+
+// OrphanCleanupRequestObject is an informational data structure with Kind and Constructor for OrphanCleanupRequest
+var OrphanCleanupRequestObject = &runtime.Info{
+	Kind:        "orphan-cleanup-request",
+	Constructor: func() runtime.Object { return &OrphanCleanupRequest{} },
+}
+
+// OrphanCleanupRequest represents request to delete orphans. Only explicitly listed orphans get deleted, so the
+// caller has to confirm what exactly is going to be deleted
+type OrphanCleanupRequest struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// DeployNames is a list of deploy names of orphans to delete
+	DeployNames []string
+}
+
+// OrphanCleanupResultObject is an informational data structure with Kind and Constructor for OrphanCleanupResult
+var OrphanCleanupResultObject = &runtime.Info{
+	Kind:        "orphan-cleanup-result",
+	Constructor: func() runtime.Object { return &OrphanCleanupResult{} },
+}
+
+// OrphanCleanupResult represents result of the orphans cleanup
+type OrphanCleanupResult struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Deleted is a list of deleted orphans
+	Deleted []*Orphan
+
+	// Failed is a list of orphans, which failed to be deleted
+	Failed []*Orphan
+}
+
+func (api *coreAPI) handleOrphansGet(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	policy := api.getPolicyForOrphans(request)
+
+	eventLog := event.NewLog(logrus.WarnLevel, "api-orphans").AddConsoleHook(api.logLevel)
+	result := api.findOrphans(policy, eventLog)
+
+	api.contentType.WriteOne(writer, request, result)
+}
+
+func (api *coreAPI) handleOrphansCleanup(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	policy := api.getPolicyForOrphans(request)
+
+	cleanupReq, ok := api.contentType.ReadOne(request).(*OrphanCleanupRequest)
+	if !ok {
+		panic(fmt.Sprintf("Unexpected object received: %v", cleanupReq))
+	}
+	if len(cleanupReq.DeployNames) <= 0 {
+		panic(fmt.Sprintf("no orphans specified for cleanup"))
+	}
+
+	// Here we need to take mutex, so no new revisions get created while orphans are being deleted
+	api.policyAndRevisionUpdateMutex.Lock()
+	defer api.policyAndRevisionUpdateMutex.Unlock()
+
+	// Code of the revision, which is being applied, may not be in the actual state yet, so it's not safe to delete orphans
+	revision, err := api.store.GetFirstUnprocessedRevision()
+	if err != nil {
+		panic(fmt.Sprintf("error while loading unprocessed revision: %s", err))
+	}
+	if revision != nil {
+		panic(fmt.Sprintf("revision %d is being processed, orphans can't be deleted until it's completed", revision.GetGeneration()))
+	}
+
+	// Revision can also be picked up by the enforcer again (e.g. to retry failed actions), so orphans get deleted only
+	// while no revision is being applied. Enforcer doesn't start applying revisions until cleanup is done
+	result := &OrphanCleanupResult{
+		TypeKind: OrphanCleanupResultObject.GetTypeKind(),
+		Deleted:  []*Orphan{},
+		Failed:   []*Orphan{},
+	}
+	gen, idle := api.revisionCanceller.WhileIdle(func() {
+		api.deleteOrphans(policy, cleanupReq.DeployNames, result)
+	})
+	if !idle {
+		panic(fmt.Sprintf("revision %d is being applied, orphans can't be deleted until it's completed", gen))
+	}
+
+	api.contentType.WriteOne(writer, request, result)
+}
+
+// deleteOrphans finds orphans again, so only the code which is still orphaned gets deleted, and deletes the ones
+// with given deploy names
+func (api *coreAPI) deleteOrphans(policy *lang.Policy, deployNames []string, result *OrphanCleanupResult) {
+	eventLog := event.NewLog(logrus.InfoLevel, "api-orphans-cleanup").AddConsoleHook(api.logLevel)
+	orphans := api.findOrphans(policy, eventLog)
+
+	for _, orphan := range orphans.Orphans {
+		if !util.ContainsString(deployNames, orphan.DeployName) {
+			continue
+		}
+
+		eventLog.NewEntry().Infof("Deleting orphan %s (cluster '%s', code type '%s'): %s", orphan.DeployName, orphan.Cluster, orphan.CodeType, orphan.Description)
+		destroyErr := orphan.codePlugin.Destroy(
+			context.Background(),
+			&plugin.CodePluginInvocationParams{
+				DeployName:   orphan.code.DeployName,
+				Params:       orphan.code.Params,
+				PluginParams: map[string]string{plugin.ParamTargetSuffix: orphan.code.TargetSuffix},
+				EventLog:     eventLog,
+			},
+		)
+		if destroyErr != nil {
+			eventLog.NewEntry().Errorf("Error while deleting orphan %s: %s", orphan.DeployName, destroyErr)
+			orphan.Error = destroyErr.Error()
+			result.Failed = append(result.Failed, orphan)
+		} else {
+			result.Deleted = append(result.Deleted, orphan)
+		}
+	}
+}
+
+// getPolicyForOrphans loads the latest policy and makes sure that user is allowed to manage orphans
+func (api *coreAPI) getPolicyForOrphans(request *http.Request) *lang.Policy {
+	policy, _, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest policy: %s", err))
+	}
+
+	// check that user is a domain admin
+	user := api.getUserRequired(request)
+	if !isDomainAdmin(user, policy) {
+		panic(fmt.Sprintf("user is not allowed to manage orphans"))
+	}
+
+	return policy
+}
+
+// findOrphans asks code plugins of all clusters in the policy for the code they manage and returns the code, which
+// doesn't belong to any component instance in the actual state
+func (api *coreAPI) findOrphans(policy *lang.Policy, eventLog *event.Log) *OrphanStatus {
+	actualState, err := api.store.GetActualState()
+	if err != nil {
+		panic(fmt.Sprintf("error while loading actual state: %s", err))
+	}
+
+	// deploy names are unique across clusters, so code is considered an orphan only if it doesn't belong to any
+	// component instance (it also protects from deleting code if the same cluster is defined in policy twice)
+	deployNames := make(map[string]bool)
+	for _, instance := range actualState.ComponentInstanceMap {
+		deployNames[instance.GetDeployName()] = true
+	}
+
+	clusters := []*lang.Cluster{}
+	for _, obj := range policy.GetObjectsByKind(lang.ClusterObject.Kind) {
+		clusters = append(clusters, obj.(*lang.Cluster))
+	}
+	sort.Slice(clusters, func(i, j int) bool {
+		return runtime.KeyForStorable(clusters[i]) < runtime.KeyForStorable(clusters[j])
+	})
+
+	result := &OrphanStatus{
+		TypeKind: OrphanStatusObject.GetTypeKind(),
+		Orphans:  []*Orphan{},
+	}
+	plugins := api.pluginRegistryFactory()
+	seen := make(map[string]bool)
+	for _, cluster := range clusters {
+		clusterName := fmt.Sprintf("%s/%s", cluster.Namespace, cluster.Name)
+		for _, codeType := range plugins.CodeTypes(cluster) {
+			codePlugin, pluginErr := plugins.ForCodeType(cluster, codeType)
+			if pluginErr != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("can't get plugin for cluster '%s', code type '%s': %s", clusterName, codeType, pluginErr))
+				continue
+			}
+
+			managed, listErr := codePlugin.ListManaged(context.Background(), eventLog)
+			if listErr != nil {
+				result.Errors = append(result.Errors, fmt.Sprintf("can't list code for cluster '%s', code type '%s': %s", clusterName, codeType, listErr))
+				continue
+			}
+
+			sort.Slice(managed, func(i, j int) bool {
+				return managed[i].DeployName < managed[j].DeployName
+			})
+			for _, code := range managed {
+				if deployNames[code.DeployName] || seen[codeType+"#"+code.DeployName] {
+					continue
+				}
+				seen[codeType+"#"+code.DeployName] = true
+
+				result.Orphans = append(result.Orphans, &Orphan{
+					Cluster:     clusterName,
+					CodeType:    codeType,
+					DeployName:  code.DeployName,
+					Namespace:   code.TargetSuffix,
+					Description: code.Description,
+					code:        code,
+					codePlugin:  codePlugin,
+				})
+			}
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/Aptomi/aptomi/pkg/plugin/fake"
+	"github.com/Aptomi/aptomi/pkg/runtime/store"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindOrphans(t *testing.T) {
+	b, _ := makePolicyBuilder()
+	policy := b.Policy()
+	actualState := resolve.NewPolicyResolver(policy, b.External(), event.NewLog(logrus.WarnLevel, "test-resolve")).ResolveAllDependencies()
+
+	var deployName string
+	for _, instance := range actualState.ComponentInstanceMap {
+		if instance.IsCode {
+			deployName = instance.GetDeployName()
+		}
+	}
+	if !assert.NotEmpty(t, deployName, "Code component instance should be resolved") {
+		return
+	}
+
+	// code of running component instance is not an orphan, while the rest of code deployed by Aptomi is
+	registry, tracker := mockRegistryOrphans(
+		&plugin.ManagedCode{DeployName: "a-0000000000002-pre-create", TargetSuffix: "k8ns", Description: "hook"},
+		&plugin.ManagedCode{DeployName: deployName, TargetSuffix: "k8ns", Description: "running"},
+		&plugin.ManagedCode{DeployName: "a-0000000000001", TargetSuffix: "k8ns", Description: "orphan"},
+	)
+	api := &coreAPI{
+		store:                 &actualStateStore{actualState: actualState},
+		pluginRegistryFactory: func() plugin.Registry { return registry },
+	}
+
+	result := api.findOrphans(policy, event.NewLog(logrus.WarnLevel, "test-orphans"))
+	assert.Empty(t, result.Errors, "There should be no errors")
+	if assert.Len(t, result.Orphans, 2, "Code of running component instance should not be an orphan") {
+		assert.Equal(t, "a-0000000000001", result.Orphans[0].DeployName, "Orphans should be sorted by deploy name")
+		assert.Equal(t, "a-0000000000002-pre-create", result.Orphans[1].DeployName, "Orphans should be sorted by deploy name")
+		assert.Equal(t, "helm", result.Orphans[0].CodeType, "Orphan should have code type")
+		assert.Equal(t, "k8ns", result.Orphans[0].Namespace, "Orphan should have namespace")
+	}
+
+	// only requested orphans get deleted
+	deleted := &OrphanCleanupResult{}
+	api.deleteOrphans(policy, []string{"a-0000000000001", deployName}, deleted)
+	if assert.Len(t, deleted.Deleted, 1, "Only requested orphan should be deleted") {
+		assert.Equal(t, "a-0000000000001", deleted.Deleted[0].DeployName, "Requested orphan should be deleted")
+	}
+	assert.Empty(t, deleted.Failed, "Orphan should be deleted without errors")
+	assert.Equal(t, []string{"a-0000000000001"}, tracker.destroyed, "Code of running component instance should not be deleted")
+
+	// clusters, which can't be checked, are reported as errors
+	tracker.listErr = fmt.Errorf("cluster is not available")
+	result = api.findOrphans(policy, event.NewLog(logrus.WarnLevel, "test-orphans"))
+	assert.Empty(t, result.Orphans, "No orphans should be found")
+	if assert.Len(t, result.Errors, 1, "Error should be reported for the cluster") {
+		assert.Contains(t, result.Errors[0], "cluster is not available")
+	}
+}
+
+// actualStateStore is a store, which only returns a given actual state
+type actualStateStore struct {
+	store.Core
+	actualState *resolve.PolicyResolution
+}
+
+func (s *actualStateStore) GetActualState() (*resolve.PolicyResolution, error) {
+	return s.actualState, nil
+}
+
+// orphanTrackingPlugin is a code plugin, which reports given code as managed and records deleted code
+type orphanTrackingPlugin struct {
+	plugin.CodePlugin
+	tracker *orphanTracker
+}
+
+type orphanTracker struct {
+	mutex     sync.Mutex
+	managed   []*plugin.ManagedCode
+	listErr   error
+	destroyed []string
+}
+
+func (p *orphanTrackingPlugin) ListManaged(ctx context.Context, eventLog *event.Log) ([]*plugin.ManagedCode, error) {
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	if p.tracker.listErr != nil {
+		return nil, p.tracker.listErr
+	}
+	return append([]*plugin.ManagedCode{}, p.tracker.managed...), nil
+}
+
+func (p *orphanTrackingPlugin) Destroy(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	p.tracker.destroyed = append(p.tracker.destroyed, invocation.DeployName)
+	return nil
+}
+
+func mockRegistryOrphans(managed ...*plugin.ManagedCode) (plugin.Registry, *orphanTracker) {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+	tracker := &orphanTracker{managed: managed}
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
+	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+		return &orphanTrackingPlugin{CodePlugin: fake.NewNoOpCodePlugin(0), tracker: tracker}, nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes), tracker
+}
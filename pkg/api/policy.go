@@ -12,7 +12,9 @@ import (
 	"github.com/Aptomi/aptomi/pkg/engine/diff"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/external"
 	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/plugin"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/julienschmidt/httprouter"
 	"github.com/sirupsen/logrus"
@@ -119,234 +121,227 @@ func (rs apiObjectSorter) Less(i, j int) bool {
 }
 
 func (rs apiObjectSorter) Weight(obj lang.Base) int { // nolint: interfacer
-	// ACL rules have to come in the first place
-	if obj.GetKind() == lang.ACLRuleObject.Kind {
+	// Custom ACL roles have to come in the first place, so ACL rules can refer to them
+	if obj.GetKind() == lang.ACLCustomRoleObject.Kind {
 		return 0
 	}
 
+	// ACL rules have to come next
+	if obj.GetKind() == lang.ACLRuleObject.Kind {
+		return 1
+	}
+
 	// All other objects can be added in any order
-	return 1
+	return 2
 }
 
-func (api *coreAPI) handlePolicyUpdate(writer http.ResponseWriter, request *http.Request, params httprouter.Params) { // nolint: gocyclo
+func (api *coreAPI) handlePolicyUpdate(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 	objects := api.readLang(request)
 	user := api.getUserRequired(request)
 
-	// Load the latest policy
-	_, policyGen, err := api.store.GetPolicy(runtime.LastGen)
-	if err != nil {
-		panic(fmt.Sprintf("error while loading current policy: %s", err))
-	}
+	api.handlePolicyChanges(writer, request, params, user, &policyChanges{updated: objects}, "api-policy-update")
+}
 
-	// load the latest revision for the given policy
-	revision, err := api.store.GetLastRevisionForPolicy(policyGen)
-	if err != nil {
-		panic(fmt.Sprintf("error while loading latest revision from the store: %s", err))
-	}
+func (api *coreAPI) handlePolicyDelete(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	objects := api.readLang(request)
+	user := api.getUserRequired(request)
 
-	// load desired state
-	desiredState, err := api.store.GetDesiredState(revision)
-	if err != nil {
-		panic(fmt.Sprintf("can't load desired state from revision: %s", err))
-	}
+	api.handlePolicyChanges(writer, request, params, user, &policyChanges{removed: objects}, "api-policy-delete")
+}
 
-	// Make a copy of the latest policy, so we can apply changes to it
-	policyUpdated, _, err := api.store.GetPolicy(policyGen)
-	if err != nil {
-		panic(fmt.Sprintf("error while loading current policy: %s", err))
-	}
+// policyChanges represents a set of changes to be made to the latest policy
+type policyChanges struct {
+	// updated is a list of objects to be added to the policy or updated in the policy
+	updated []lang.Base
 
-	// Add objects to the policy in a sorted order (e.g. make sure ACL Rules go first)
-	sort.Sort(apiObjectSorter(objects))
-	for _, obj := range objects {
-		errManage := policyUpdated.View(user).ManageObject(obj)
-		if errManage != nil {
-			panic(fmt.Sprintf("error while adding updated object to policy: %s", errManage))
-		}
-		errAdd := policyUpdated.AddObject(obj)
-		if errAdd != nil {
-			panic(fmt.Sprintf("error while adding updated object to policy: %s", errAdd))
-		}
-	}
+	// removed is a list of objects to be removed from the policy
+	removed []lang.Base
 
-	// Check that the policy is valid
-	err = policyUpdated.Validate()
-	if err != nil {
-		panic(fmt.Sprintf("updated policy is invalid: %s", err))
-	}
+	// restore indicates that updated objects are previously saved generations of policy objects (e.g. on rollback)
+	restore bool
+}
 
-	// Validate clusters using corresponding cluster plugins and make sure there are no conflicts
-	plugins := api.pluginRegistryFactory()
-	for _, obj := range objects {
-		// if a cluster was supplied, then
-		if cluster, ok := obj.(*lang.Cluster); ok {
-			// validate via plugin that connection to it can be established
-			plugin, pluginErr := plugins.ForCluster(cluster)
-			if pluginErr != nil {
-				panic(fmt.Sprintf("error while getting cluster plugin for cluster %s of type %s: %s", cluster.Name, cluster.Type, pluginErr))
-			}
+// policyChangesResult represents policy changes made in memory, before they get persisted
+type policyChangesResult struct {
+	// policyGen is the generation of the policy, to which changes have been made
+	policyGen runtime.Generation
 
-			valErr := plugin.Validate()
-			if valErr != nil {
-				panic(fmt.Sprintf("error while validating cluster %s of type %s: %s", cluster.Name, cluster.Type, valErr))
-			}
-		}
-	}
+	// desiredState is the desired state of the updated policy
+	desiredState *resolve.PolicyResolution
+
+	// actionPlan is the action plan against the current desired state (i.e. what a new revision would contain)
+	actionPlan *action.Plan
 
+	// eventLog is the policy resolution log
+	eventLog *event.Log
+}
+
+// handlePolicyChanges makes given changes to the latest policy, resolves it and writes the result back via API. Unless
+// noop flag is set, changes get persisted and a new revision gets created
+func (api *coreAPI) handlePolicyChanges(writer http.ResponseWriter, request *http.Request, params httprouter.Params, user *lang.User, changes *policyChanges, logName string) {
 	// See if noop flag is set
 	noop, noopErr := strconv.ParseBool(params.ByName("noop"))
 	if noopErr != nil {
 		noop = false
 	}
 
-	// See what log level is set
-	logLevel, logLevelErr := logrus.ParseLevel(params.ByName("loglevel"))
-	if logLevelErr != nil {
-		logLevel = logrus.WarnLevel
-	}
-
 	// Process policy changes, calculate resolution log and action plan
-	eventLog := event.NewLog(logLevel, "api-policy-update").AddConsoleHook(api.logLevel)
-	desiredStateUpdated := resolve.NewPolicyResolver(policyUpdated, api.externalData, eventLog).ResolveAllDependencies()
-	err = desiredStateUpdated.Validate(policyUpdated)
-	if err != nil {
-		panic(fmt.Sprintf("policy change cannon be made: %s", err))
-	}
-
-	actionPlan := diff.NewPolicyResolutionDiff(desiredStateUpdated, desiredState).ActionPlan
+	result := api.calculatePolicyChanges(user, changes, getLogLevel(params), logName)
 
 	// If we are in noop mode, just return expected changes in a form of an action plan
 	if noop {
 		api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
 			TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-			PolicyGeneration: policyGen,              // policy generation didn't change
-			PolicyChanged:    false,                  // policy has not been updated in the store
-			WaitForRevision:  runtime.MaxGeneration,  // nothing to wait for
-			PlanAsText:       actionPlan.AsText(),    // return action plan, so it can be printed by the client
-			EventLog:         eventLog.AsAPIEvents(), // return policy resolution log
+			PolicyGeneration: result.policyGen,              // policy generation didn't change
+			PolicyChanged:    false,                         // policy has not been updated in the store
+			WaitForRevision:  runtime.MaxGeneration,         // nothing to wait for
+			PlanAsText:       result.actionPlan.AsText(),    // return action plan, so it can be printed by the client
+			EventLog:         result.eventLog.AsAPIEvents(), // return policy resolution log
 		})
 		return
 	}
 
 	// Update policy
-	changed, policyGen, revisionGen := api.changePolicy(objects, user, desiredStateUpdated, false)
+	changed, policyGen, revisionGen := api.changePolicy(changes, user, result.desiredState)
 
 	// Return the result back via API
 	api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
 		TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-		PolicyChanged:    changed,                // have any policy object in the store been changed or not
-		PolicyGeneration: policyGen,              // policy now has a new generation
-		WaitForRevision:  revisionGen,            // which revision to wait for
-		PlanAsText:       actionPlan.AsText(),    // return action plan, so it can be printed by the client
-		EventLog:         eventLog.AsAPIEvents(), // return policy resolution log
+		PolicyChanged:    changed,                       // have any policy object in the store been changed or not
+		PolicyGeneration: policyGen,                     // policy now has a new generation
+		WaitForRevision:  revisionGen,                   // which revision to wait for
+		PlanAsText:       result.actionPlan.AsText(),    // return action plan, so it can be printed by the client
+		EventLog:         result.eventLog.AsAPIEvents(), // return policy resolution log
 	})
 
 	if changed {
 		// signal to the channel that policy has changed, that will trigger the enforcement right away
 		api.runDesiredStateEnforcement <- true
 	}
-
 }
 
-func (api *coreAPI) handlePolicyDelete(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
-	objects := api.readLang(request)
-	user := api.getUserRequired(request)
+// getLogLevel returns log level requested via API, defaulting to warning
+func getLogLevel(params httprouter.Params) logrus.Level {
+	logLevel, logLevelErr := logrus.ParseLevel(params.ByName("loglevel"))
+	if logLevelErr != nil {
+		logLevel = logrus.WarnLevel
+	}
+	return logLevel
+}
 
-	// Load the latest policy gen
+// calculatePolicyChanges loads the latest policy, makes given changes to it and calculates desired state of the
+// updated policy, as well as the action plan against the current desired state. Nothing gets persisted
+func (api *coreAPI) calculatePolicyChanges(user *lang.User, changes *policyChanges, logLevel logrus.Level, logName string) *policyChangesResult {
+	// Load the latest policy
 	_, policyGen, err := api.store.GetPolicy(runtime.LastGen)
 	if err != nil {
 		panic(fmt.Sprintf("error while loading current policy: %s", err))
 	}
 
-	// Load the latest revision for the given policy
+	// load the latest revision for the given policy
 	revision, err := api.store.GetLastRevisionForPolicy(policyGen)
 	if err != nil {
 		panic(fmt.Sprintf("error while loading latest revision from the store: %s", err))
 	}
 
-	// Load desired state
+	// load desired state
 	desiredState, err := api.store.GetDesiredState(revision)
 	if err != nil {
 		panic(fmt.Sprintf("can't load desired state from revision: %s", err))
 	}
 
+	// load actual state, so discovery points to the running copies of code
+	actualState, err := api.store.GetActualState()
+	if err != nil {
+		panic(fmt.Sprintf("error while loading actual state: %s", err))
+	}
+
 	// Make a copy of the latest policy, so we can apply changes to it
 	policyUpdated, _, err := api.store.GetPolicy(policyGen)
 	if err != nil {
 		panic(fmt.Sprintf("error while loading current policy: %s", err))
 	}
 
-	// Delete objects from the policy in a reversed sorted order (e.g. make sure ACL Rules go last)
-	sort.Sort(sort.Reverse(apiObjectSorter(objects)))
-	for _, obj := range objects {
-		errManage := policyUpdated.View(user).ManageObject(obj)
-		if errManage != nil {
-			panic(fmt.Sprintf("Error while removing object from policy: %s", errManage))
-		}
-		policyUpdated.RemoveObject(obj)
+	// Make changes to the policy and resolve it
+	eventLog := event.NewLog(logLevel, logName).AddConsoleHook(api.logLevel)
+	desiredStateUpdated, err := makePolicyChanges(policyUpdated, user, changes, api.externalData, actualState, api.pluginRegistryFactory(), eventLog)
+	if err != nil {
+		panic(fmt.Sprintf("policy change cannot be made: %s", err))
 	}
 
-	err = policyUpdated.Validate()
-	if err != nil {
-		panic(fmt.Sprintf("Updated policy is invalid: %s", err))
+	return &policyChangesResult{
+		policyGen:    policyGen,
+		desiredState: desiredStateUpdated,
+		actionPlan:   diff.NewPolicyResolutionDiff(desiredStateUpdated, desiredState).ActionPlan,
+		eventLog:     eventLog,
 	}
+}
 
-	// See if noop flag is set
-	noop, noopErr := strconv.ParseBool(params.ByName("noop"))
-	if noopErr != nil {
-		noop = false
+// makePolicyChanges makes given changes to the policy, checking that user is allowed to manage every changed object.
+// Then it validates the updated policy together with all updated clusters and resolves all dependencies in it, taking
+// into account deploy names recorded in a given actual state
+func makePolicyChanges(policy *lang.Policy, user *lang.User, changes *policyChanges, externalData *external.Data, actualState *resolve.PolicyResolution, plugins plugin.Registry, eventLog *event.Log) (*resolve.PolicyResolution, error) {
+	// Remove objects from the policy in a reversed sorted order (e.g. make sure ACL Rules go last)
+	sort.Sort(sort.Reverse(apiObjectSorter(changes.removed)))
+	for _, obj := range changes.removed {
+		errManage := policy.View(user).ManageObject(obj)
+		if errManage != nil {
+			return nil, fmt.Errorf("error while removing object from policy: %s", errManage)
+		}
+		policy.RemoveObject(obj)
 	}
 
-	// See what log level is set
-	logLevel, logLevelErr := logrus.ParseLevel(params.ByName("loglevel"))
-	if logLevelErr != nil {
-		logLevel = logrus.WarnLevel
+	// Add objects to the policy in a sorted order (e.g. make sure ACL Rules go first)
+	sort.Sort(apiObjectSorter(changes.updated))
+	for _, obj := range changes.updated {
+		errManage := policy.View(user).ManageObject(obj)
+		if errManage != nil {
+			return nil, fmt.Errorf("error while adding updated object to policy: %s", errManage)
+		}
+		if dependency, ok := obj.(*lang.Dependency); ok {
+			// dependency creation time is needed to calculate its expiration
+			setDependencyCreationTime(policy, dependency)
+		}
+		errAdd := policy.AddObject(obj)
+		if errAdd != nil {
+			return nil, fmt.Errorf("error while adding updated object to policy: %s", errAdd)
+		}
 	}
 
-	// Process policy changes, calculate and return resolution log + action plan
-	eventLog := event.NewLog(logLevel, "api-policy-delete").AddConsoleHook(api.logLevel)
-	desiredStateUpdated := resolve.NewPolicyResolver(policyUpdated, api.externalData, eventLog).ResolveAllDependencies()
-	err = desiredStateUpdated.Validate(policyUpdated)
+	// Check that the policy is valid
+	err := policy.Validate()
 	if err != nil {
-		panic(fmt.Sprintf("policy change cannon be made: %s", err))
+		return nil, fmt.Errorf("updated policy is invalid: %s", err)
 	}
 
-	actionPlan := diff.NewPolicyResolutionDiff(desiredStateUpdated, desiredState).ActionPlan
+	// Validate clusters using corresponding cluster plugins and make sure there are no conflicts
+	for _, obj := range changes.updated {
+		// if a cluster was supplied, then
+		if cluster, ok := obj.(*lang.Cluster); ok {
+			// validate via plugin that connection to it can be established
+			plugin, pluginErr := plugins.ForCluster(cluster)
+			if pluginErr != nil {
+				return nil, fmt.Errorf("error while getting cluster plugin for cluster %s of type %s: %s", cluster.Name, cluster.Type, pluginErr)
+			}
 
-	// If we are in noop mode, just return expected changes in a form of an action plan
-	if noop {
-		api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
-			TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-			PolicyGeneration: policyGen,              // policy generation didn't change
-			PolicyChanged:    false,                  // policy has not been updated in the store
-			WaitForRevision:  runtime.MaxGeneration,  // nothing to wait for
-			PlanAsText:       actionPlan.AsText(),    // return action plan, so it can be printed by the client
-			EventLog:         eventLog.AsAPIEvents(), // return policy resolution log
-		})
-		return
+			valErr := plugin.Validate()
+			if valErr != nil {
+				return nil, fmt.Errorf("error while validating cluster %s of type %s: %s", cluster.Name, cluster.Type, valErr)
+			}
+		}
 	}
 
-	// Update policy
-	changed, policyGen, revisionGen := api.changePolicy(objects, user, desiredStateUpdated, true)
-
-	// Return the result back via API
-	api.contentType.WriteOne(writer, request, &PolicyUpdateResult{
-		TypeKind:         PolicyUpdateResultObject.GetTypeKind(),
-		PolicyChanged:    changed,                // have any policy object in the store been changed or not
-		PolicyGeneration: policyGen,              // policy now has a new generation
-		WaitForRevision:  revisionGen,            // which revision to wait for
-		PlanAsText:       actionPlan.AsText(),    // return action plan, so it can be printed by the client
-		EventLog:         eventLog.AsAPIEvents(), // return policy resolution log
-	})
-
-	if changed {
-		// signal to the channel that policy has changed, that will trigger the enforcement right away
-		api.runDesiredStateEnforcement <- true
+	// Resolve updated policy
+	desiredState := resolve.NewPolicyResolver(policy, externalData, eventLog).WithDeployNames(actualState).ResolveAllDependencies()
+	err = desiredState.Validate(policy)
+	if err != nil {
+		return nil, err
 	}
 
+	return desiredState, nil
 }
 
-func (api *coreAPI) changePolicy(objects []lang.Base, user *lang.User, desiredStateUpdated *resolve.PolicyResolution, delete bool) (bool, runtime.Generation, runtime.Generation) {
+func (api *coreAPI) changePolicy(changes *policyChanges, user *lang.User, desiredStateUpdated *resolve.PolicyResolution) (bool, runtime.Generation, runtime.Generation) {
 	// Make sure to take the mutex, before making any policy and revision changes
 	api.policyAndRevisionUpdateMutex.Lock()
 	defer api.policyAndRevisionUpdateMutex.Unlock()
@@ -355,10 +350,13 @@ func (api *coreAPI) changePolicy(objects []lang.Base, user *lang.User, desiredSt
 	var changed bool
 	var policyData *engine.PolicyData
 	var err error
-	if delete {
-		changed, policyData, err = api.store.DeleteFromPolicy(objects, user.Name)
-	} else {
-		changed, policyData, err = api.store.UpdatePolicy(objects, user.Name)
+	switch {
+	case changes.restore:
+		changed, policyData, err = api.store.RestorePolicy(changes.updated, changes.removed, user.Name)
+	case len(changes.removed) > 0:
+		changed, policyData, err = api.store.DeleteFromPolicy(changes.removed, user.Name)
+	default:
+		changed, policyData, err = api.store.UpdatePolicy(changes.updated, user.Name)
 	}
 	if err != nil {
 		panic(fmt.Sprintf("error while making changes to objects in the policy: %s", err))
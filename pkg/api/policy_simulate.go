@@ -0,0 +1,155 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/diff"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PolicySimulateRemovalObject is an informational data structure with Kind and Constructor for PolicySimulateRemoval
+var PolicySimulateRemovalObject = &runtime.Info{
+	Kind:        "policy-simulate-removal",
+	Constructor: func() runtime.Object { return &PolicySimulateRemoval{} },
+}
+
+// PolicySimulateRemoval is a reference to the policy object, which should be removed from the policy during
+// simulation. It is sent to the policy simulate API along with policy objects, which should be added or updated
+type PolicySimulateRemoval struct {
+	runtime.TypeKind `yaml:",inline"`
+	Namespace        string
+	ObjectKind       string
+	Name             string
+}
+
+// NewPolicySimulateRemoval returns a reference for the removal of a given policy object during simulation
+func NewPolicySimulateRemoval(obj lang.Base) *PolicySimulateRemoval {
+	return &PolicySimulateRemoval{
+		TypeKind:   PolicySimulateRemovalObject.GetTypeKind(),
+		Namespace:  obj.GetNamespace(),
+		ObjectKind: obj.GetKind(),
+		Name:       obj.GetName(),
+	}
+}
+
+// PolicySimulateResultObject is an informational data structure with Kind and Constructor for PolicySimulateResult
+var PolicySimulateResultObject = &runtime.Info{
+	Kind:        "policy-simulate-result",
+	Constructor: func() runtime.Object { return &PolicySimulateResult{} },
+}
+
+// PolicySimulateResult represents results of the policy simulation, including action plans and event log.
+// Nothing gets persisted during the simulation, so PolicyGeneration is the current generation of the policy
+type PolicySimulateResult struct {
+	runtime.TypeKind `yaml:",inline"`
+	PolicyGeneration runtime.Generation
+
+	// PlanAsText is the action plan against the current desired state (i.e. what a new revision would contain)
+	PlanAsText *action.PlanAsText
+
+	// ActualPlanAsText is the action plan against the current actual state (i.e. what enforcement would execute)
+	ActualPlanAsText *action.PlanAsText
+
+	EventLog []*event.APIEvent
+}
+
+// GetDefaultColumns returns default set of columns to be displayed
+func (result *PolicySimulateResult) GetDefaultColumns() []string {
+	return []string{"Policy Generation", "Action Plan", "Action Plan (Actual State)"}
+}
+
+// AsColumns returns PolicySimulateResult representation as columns
+func (result *PolicySimulateResult) AsColumns() map[string]string {
+	planStr := func(plan *action.PlanAsText) string {
+		str := plan.String()
+		if len(str) <= 0 {
+			return "(none)"
+		}
+		return str
+	}
+	return map[string]string{
+		"Policy Generation":          fmt.Sprintf("%d", result.PolicyGeneration),
+		"Action Plan":                planStr(result.PlanAsText),
+		"Action Plan (Actual State)": planStr(result.ActualPlanAsText),
+	}
+}
+
+func (api *coreAPI) handlePolicySimulate(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	user := api.getUserRequired(request)
+
+	// Split request into objects to be added/updated and objects to be removed
+	added := make([]lang.Base, 0)
+	removed := make([]*PolicySimulateRemoval, 0)
+	exists := make(map[string]bool)
+	for _, obj := range api.contentType.Read(request) {
+		var objKey string
+		if removal, ok := obj.(*PolicySimulateRemoval); ok {
+			objKey = runtime.KeyFromParts(removal.Namespace, removal.ObjectKind, removal.Name)
+			removed = append(removed, removal)
+		} else if langObj, ok := obj.(lang.Base); ok {
+			objKey = runtime.KeyForStorable(langObj)
+			added = append(added, langObj)
+		} else {
+			panic(fmt.Sprintf("Trying to simulate policy changes while non-lang objects found: %s", obj.GetKind()))
+		}
+
+		if exists[objKey] {
+			panic(fmt.Sprintf("Duplicate objects with key %s detected in the request", objKey))
+		}
+		exists[objKey] = true
+	}
+
+	// Look up objects to be removed in the latest policy
+	policy, _, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading current policy: %s", err))
+	}
+	changes := &policyChanges{
+		updated: added,
+		removed: getSimulatedRemovals(policy, removed),
+	}
+
+	// Resolve updated policy and calculate action plans against both desired and actual state
+	result := api.calculatePolicyChanges(user, changes, getLogLevel(params), "api-policy-simulate")
+
+	// load actual state
+	actualState, err := api.store.GetActualState()
+	if err != nil {
+		panic(fmt.Sprintf("can't load actual state: %s", err))
+	}
+	actualActionPlan := diff.NewPolicyResolutionDiff(result.desiredState, actualState).ActionPlan
+
+	// Return expected changes, nothing has been persisted
+	api.contentType.WriteOne(writer, request, &PolicySimulateResult{
+		TypeKind:         PolicySimulateResultObject.GetTypeKind(),
+		PolicyGeneration: result.policyGen,              // policy generation didn't change
+		PlanAsText:       result.actionPlan.AsText(),    // changes against the current desired state
+		ActualPlanAsText: actualActionPlan.AsText(),     // changes against the current actual state
+		EventLog:         result.eventLog.AsAPIEvents(), // return policy resolution log
+	})
+}
+
+// getSimulatedRemovals returns policy objects, which are referred by given removals
+func getSimulatedRemovals(policy *lang.Policy, removed []*PolicySimulateRemoval) []lang.Base {
+	result := []lang.Base{}
+	for _, removal := range removed {
+		obj, err := policy.GetObject(removal.ObjectKind, removal.Name, removal.Namespace)
+		if err != nil {
+			panic(fmt.Sprintf("error while looking up object to be removed from policy: %s", err))
+		}
+		if obj == nil {
+			panic(fmt.Sprintf("object %s/%s/%s to be removed doesn't exist in policy", removal.Namespace, removal.ObjectKind, removal.Name))
+		}
+		langObj, ok := obj.(lang.Base)
+		if !ok {
+			panic(fmt.Sprintf("object %s/%s/%s to be removed is not a policy object", removal.Namespace, removal.ObjectKind, removal.Name))
+		}
+		result = append(result, langObj)
+	}
+	return result
+}
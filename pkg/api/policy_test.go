@@ -0,0 +1,188 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/builder"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/Aptomi/aptomi/pkg/plugin/fake"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakePolicyChanges(t *testing.T) {
+	b, contract := makePolicyBuilder()
+	user := b.AddUserDomainAdmin()
+	policy := b.Policy()
+
+	// new dependency gets resolved and gets creation time set
+	dependency := makeDependency(b, user, contract, "new")
+	desiredState, err := makePolicyChanges(policy, user, &policyChanges{updated: []lang.Base{dependency}}, b.External(), resolve.NewPolicyResolution(), mockRegistry(), event.NewLog(logrus.WarnLevel, "test"))
+	if !assert.NoError(t, err, "Policy changes should be made") {
+		return
+	}
+	assert.False(t, dependency.CreatedAt.IsZero(), "Creation time should be set for a new dependency")
+	assert.True(t, desiredState.GetDependencyResolution(dependency).Resolved, "New dependency should be resolved")
+
+	// creation time supplied for a new dependency gets ignored
+	for _, createdAt := range []time.Time{time.Now().Add(-24 * time.Hour), time.Now().Add(24 * time.Hour)} {
+		dependencyBackdated := makeDependency(b, user, contract, "backdated")
+		dependencyBackdated.CreatedAt = createdAt
+		_, err = makePolicyChanges(policy, user, &policyChanges{updated: []lang.Base{dependencyBackdated}}, b.External(), resolve.NewPolicyResolution(), mockRegistry(), event.NewLog(logrus.WarnLevel, "test"))
+		assert.NoError(t, err, "Policy changes should be made")
+		assert.WithinDuration(t, time.Now(), dependencyBackdated.CreatedAt, time.Minute, "Creation time supplied by the client should be ignored for a new dependency")
+		policy.RemoveObject(dependencyBackdated)
+	}
+
+	// updated dependency keeps its creation time, even if a different one is supplied by the client
+	createdAt := dependency.CreatedAt
+	dependencyUpdated := makeDependency(b, user, contract, "new")
+	dependencyUpdated.CreatedAt = createdAt.Add(-24 * time.Hour)
+	_, err = makePolicyChanges(policy, user, &policyChanges{updated: []lang.Base{dependencyUpdated}}, b.External(), resolve.NewPolicyResolution(), mockRegistry(), event.NewLog(logrus.WarnLevel, "test"))
+	assert.NoError(t, err, "Policy changes should be made")
+	assert.Equal(t, createdAt, dependencyUpdated.CreatedAt, "Creation time should be preserved for an updated dependency")
+
+	// removed dependency doesn't get resolved
+	desiredState, err = makePolicyChanges(policy, user, &policyChanges{removed: []lang.Base{dependencyUpdated}}, b.External(), resolve.NewPolicyResolution(), mockRegistry(), event.NewLog(logrus.WarnLevel, "test"))
+	assert.NoError(t, err, "Policy changes should be made")
+	assert.False(t, desiredState.GetDependencyResolution(dependencyUpdated).Resolved, "Removed dependency should not be resolved")
+}
+
+func TestMakePolicyChangesErrors(t *testing.T) {
+	// removing contract, which is still being used by a dependency, makes policy invalid
+	b, contract := makePolicyBuilder()
+	user := b.AddUserDomainAdmin()
+	_, err := makePolicyChanges(b.Policy(), user, &policyChanges{removed: []lang.Base{contract}}, b.External(), resolve.NewPolicyResolution(), mockRegistry(), event.NewLog(logrus.WarnLevel, "test"))
+	assert.Error(t, err, "Policy should be invalid after removing contract")
+
+	// cluster gets validated by the corresponding plugin
+	b, _ = makePolicyBuilder()
+	user = b.AddUserDomainAdmin()
+	cluster := &lang.Cluster{
+		TypeKind: lang.ClusterObject.GetTypeKind(),
+		Metadata: lang.Metadata{
+			Namespace: runtime.SystemNS,
+			Name:      "new",
+		},
+		Type: "kubernetes",
+		Config: struct {
+			DefaultNamespace string
+		}{
+			DefaultNamespace: "k8ns",
+		},
+	}
+	noPlugins := plugin.NewRegistry(config.Plugins{}, make(map[string]plugin.ClusterPluginConstructor), make(map[string]map[string]plugin.CodePluginConstructor))
+	_, err = makePolicyChanges(b.Policy(), user, &policyChanges{updated: []lang.Base{cluster}}, b.External(), resolve.NewPolicyResolution(), noPlugins, event.NewLog(logrus.WarnLevel, "test"))
+	if assert.Error(t, err, "Cluster without plugin should fail validation") {
+		assert.Contains(t, err.Error(), "error while getting cluster plugin for cluster new")
+	}
+	_, err = makePolicyChanges(b.Policy(), user, &policyChanges{updated: []lang.Base{cluster}}, b.External(), resolve.NewPolicyResolution(), mockRegistry(), event.NewLog(logrus.WarnLevel, "test"))
+	assert.NoError(t, err, "Cluster with plugin should pass validation")
+
+	// user, who is not allowed to manage objects, can't change the policy
+	b, contract = makePolicyBuilder()
+	user = &lang.User{Name: "regular"}
+	dependency := makeDependency(b, user, contract, "new")
+	_, err = makePolicyChanges(b.Policy(), user, &policyChanges{updated: []lang.Base{dependency}}, b.External(), resolve.NewPolicyResolution(), mockRegistry(), event.NewLog(logrus.WarnLevel, "test"))
+	assert.Error(t, err, "Regular user should not be able to add dependency into the main namespace")
+}
+
+func TestGetRollbackChanges(t *testing.T) {
+	b, contract := makePolicyBuilder()
+	user := b.AddUserDomainAdmin()
+
+	// target policy contains a service and a dependency
+	service := b.AddService()
+	service.Generation = 1
+	dependency := makeDependency(b, user, contract, "rolledback")
+	dependency.Generation = 1
+	dependency.CreatedAt = time.Now().Add(-time.Hour)
+	targetPolicy := lang.NewPolicy()
+	targetPolicyData := &engine.PolicyData{Objects: make(map[string]map[string]map[string]runtime.Generation)}
+	for _, obj := range []lang.Base{service, dependency} {
+		assert.NoError(t, targetPolicy.AddObject(obj), "Object should be added to target policy")
+		targetPolicyData.Add(obj)
+	}
+
+	// current policy contains a newer generation of the service and a contract, while the dependency has been deleted
+	serviceUpdated := &lang.Service{
+		TypeKind: lang.ServiceObject.GetTypeKind(),
+		Metadata: lang.Metadata{Namespace: service.Namespace, Name: service.Name, Generation: 2},
+	}
+	contract.Generation = 1
+	policy := lang.NewPolicy()
+	policyData := &engine.PolicyData{Objects: make(map[string]map[string]map[string]runtime.Generation)}
+	for _, obj := range []lang.Base{serviceUpdated, contract} {
+		assert.NoError(t, policy.AddObject(obj), "Object should be added to current policy")
+		policyData.Add(obj)
+	}
+
+	restored, deleted := getRollbackChanges(targetPolicyData, targetPolicy, policyData, policy)
+
+	if assert.Len(t, restored, 2, "Service and dependency should be restored") {
+		for _, obj := range restored {
+			assert.Equal(t, runtime.Generation(1), obj.GetGeneration(), "Restored object should have generation from the target policy")
+			if restoredDependency, ok := obj.(*lang.Dependency); ok {
+				assert.True(t, restoredDependency.CreatedAt.IsZero(), "Creation time of restored dependency should be reset")
+			}
+		}
+	}
+	if assert.Len(t, deleted, 1, "Contract should be deleted") {
+		assert.Equal(t, contract, deleted[0], "Contract should be deleted")
+	}
+
+	// nothing to do when rolling back to the same policy
+	restored, deleted = getRollbackChanges(policyData, policy, policyData, policy)
+	assert.Empty(t, restored, "Nothing should be restored")
+	assert.Empty(t, deleted, "Nothing should be deleted")
+}
+
+func makePolicyBuilder() (*builder.PolicyBuilder, *lang.Contract) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service
+	service := b.AddService()
+	b.AddServiceComponent(service, b.CodeComponent(util.NestedParameterMap{"param": "value"}, nil))
+	contract := b.AddContract(service, b.CriteriaTrue())
+
+	// add rule to set cluster
+	clusterObj := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelTarget, clusterObj.Name)))
+
+	// add dependency
+	b.AddDependency(b.AddUser(), contract)
+
+	return b, contract
+}
+
+func makeDependency(b *builder.PolicyBuilder, user *lang.User, contract *lang.Contract, name string) *lang.Dependency {
+	return &lang.Dependency{
+		TypeKind: lang.DependencyObject.GetTypeKind(),
+		Metadata: lang.Metadata{
+			Namespace: b.Namespace(),
+			Name:      name,
+		},
+		User:     user.Name,
+		Contract: contract.Namespace + "/" + contract.Name,
+		Labels:   make(map[string]string),
+	}
+}
+
+func mockRegistry() plugin.Registry {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes)
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/julienschmidt/httprouter"
+)
+
+// RetryResetResultObject is an informational data structure with Kind and Constructor for RetryResetResult
+var RetryResetResultObject = &runtime.Info{
+	Kind:        "retry-reset-result",
+	Constructor: func() runtime.Object { return &RetryResetResult{} },
+}
+
+// RetryResetResult is a result of resetting retry states of failing component instances
+type RetryResetResult struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Reset is the number of component instances, for which retry state has been reset
+	Reset int
+}
+
+func (api *coreAPI) handleRetryReset(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	// Load current policy
+	policy, _, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest policy: %s", err))
+	}
+
+	// check that user is a domain admin
+	user := api.getUserRequired(request)
+	if !isDomainAdmin(user, policy) {
+		panic(fmt.Sprintf("user is not allowed to reset retry states"))
+	}
+
+	// reset retry state for a given component instance, or for all component instances if key is not specified
+	keys := []string{}
+	if key := params.ByName("key"); len(key) > 0 {
+		keys = append(keys, key)
+	}
+	reset, err := api.store.ResetComponentRetryStates(keys...)
+	if err != nil {
+		panic(fmt.Sprintf("error while resetting retry states: %s", err))
+	}
+
+	api.contentType.WriteOne(writer, request, &RetryResetResult{
+		TypeKind: RetryResetResultObject.GetTypeKind(),
+		Reset:    reset,
+	})
+
+	if reset > 0 {
+		// signal to the channel that retry states have changed, that will trigger the enforcement right away
+		api.runDesiredStateEnforcement <- true
+	}
+}
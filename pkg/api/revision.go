@@ -4,11 +4,33 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/julienschmidt/httprouter"
 )
 
+// RevisionStatusObject is an informational data structure with Kind and Constructor for RevisionStatus
+var RevisionStatusObject = &runtime.Info{
+	Kind:        "revision-status",
+	Constructor: func() runtime.Object { return &RevisionStatus{} },
+}
+
+// RevisionStatus is a revision together with pauses of desired state enforcement, which are currently in effect (so
+// it's clear why revision isn't getting applied)
+type RevisionStatus struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Revision is the requested revision
+	Revision *engine.Revision
+
+	// EnforcerPauses is a list of pauses of desired state enforcement, which are currently in effect
+	EnforcerPauses []*engine.EnforcerPause `yaml:",omitempty"`
+}
+
 func (api *coreAPI) handleRevisionGet(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
 	gen := params.ByName("gen")
 
@@ -24,10 +46,34 @@ func (api *coreAPI) handleRevisionGet(writer http.ResponseWriter, request *http.
 	if revision == nil {
 		api.contentType.WriteOneWithStatus(writer, request, nil, http.StatusNotFound)
 	} else {
-		api.contentType.WriteOne(writer, request, revision)
+		api.contentType.WriteOne(writer, request, &RevisionStatus{
+			TypeKind:       RevisionStatusObject.GetTypeKind(),
+			Revision:       revision,
+			EnforcerPauses: api.getEnforcerPauseStatus().Pauses,
+		})
 	}
 }
 
+func (api *coreAPI) handleRevisionPlanGet(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	revision, err := api.store.GetRevision(runtime.ParseGeneration(params.ByName("gen")))
+	if err != nil {
+		panic(fmt.Sprintf("error while getting requested revision: %s", err))
+	}
+	if revision == nil {
+		panic(fmt.Sprintf("revision %s not found", params.ByName("gen")))
+	}
+
+	revisionPlan, err := api.store.GetRevisionPlan(revision.GetGeneration())
+	if err != nil {
+		panic(fmt.Sprintf("error while getting action plan for revision %d: %s", revision.GetGeneration(), err))
+	}
+	if revisionPlan == nil {
+		panic(fmt.Sprintf("revision %d doesn't have action plan recorded (it hasn't been applied yet)", revision.GetGeneration()))
+	}
+
+	api.contentType.WriteOne(writer, request, revisionPlan)
+}
+
 type revisionsWrapper struct {
 	Data interface{}
 }
@@ -54,3 +100,122 @@ func (api *coreAPI) handleRevisionsGetByPolicy(writer http.ResponseWriter, reque
 		api.contentType.WriteOne(writer, request, &revisionsWrapper{Data: revisions})
 	}
 }
+
+func (api *coreAPI) handleRevisionCancel(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	// Load current policy
+	policy, _, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest policy: %s", err))
+	}
+
+	// check that user is a domain admin
+	user := api.getUserRequired(request)
+	if !isDomainAdmin(user, policy) {
+		panic(fmt.Sprintf("user is not allowed to cancel revisions"))
+	}
+
+	// cancel the revision, which is being applied right now. remaining actions will be skipped
+	gen, cancelled := api.revisionCanceller.Cancel()
+	if !cancelled {
+		panic(fmt.Sprintf("there is no revision being applied at the moment"))
+	}
+
+	revision, err := api.store.GetRevision(gen)
+	if err != nil {
+		panic(fmt.Sprintf("error while getting cancelled revision: %s", err))
+	}
+
+	if revision == nil {
+		api.contentType.WriteOneWithStatus(writer, request, nil, http.StatusNotFound)
+	} else {
+		api.contentType.WriteOne(writer, request, revision)
+	}
+}
+
+func (api *coreAPI) handleRevisionApprove(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	// Load current policy
+	policy, _, err := api.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest policy: %s", err))
+	}
+
+	// Load actual state, so it can be checked whether user is allowed to approve deletions
+	user := api.getUserRequired(request)
+	actualState, err := api.store.GetActualState()
+	if err != nil {
+		panic(fmt.Sprintf("error while loading actual state: %s", err))
+	}
+
+	// Mark revision as approved, so it will be processed again with all actions
+	gen := runtime.ParseGeneration(params.ByName("gen"))
+	revision := api.approveRevision(gen, user, policy, actualState)
+
+	api.contentType.WriteOne(writer, request, revision)
+
+	// signal to the channel that revision has been approved, that will trigger the enforcement right away
+	api.runDesiredStateEnforcement <- true
+}
+
+// canApproveDeletion returns true if user is allowed to approve deletion of a given protected component instance. User
+// has to be able to manage the service of the component instance. If the service is no longer present in the policy,
+// only domain admin can approve deletion
+func canApproveDeletion(user *lang.User, policy *lang.Policy, instance *resolve.ComponentInstance) bool {
+	if isDomainAdmin(user, policy) {
+		return true
+	}
+	if instance == nil {
+		return false
+	}
+	serviceObj, err := policy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
+	if err != nil || serviceObj == nil {
+		return false
+	}
+	return policy.View(user).ManageObject(serviceObj.(*lang.Service)) == nil
+}
+
+// approveRevision approves held deletions of a given revision. Revision gets loaded and checked while holding the
+// mutex, so it can't be superseded by a newer revision or changed in between
+func (api *coreAPI) approveRevision(gen runtime.Generation, user *lang.User, policy *lang.Policy, actualState *resolve.PolicyResolution) *engine.Revision {
+	// Make sure to take the mutex, before making any revision changes
+	api.policyAndRevisionUpdateMutex.Lock()
+	defer api.policyAndRevisionUpdateMutex.Unlock()
+
+	// Load the revision we are approving
+	revision, err := api.store.GetRevision(gen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading revision %d: %s", gen, err))
+	}
+	if revision == nil {
+		panic(fmt.Sprintf("revision %d not found", gen))
+	}
+	if revision.Status != engine.RevisionStatusAwaitingApproval {
+		panic(fmt.Sprintf("revision %d is not awaiting approval (status: %s)", gen, revision.Status))
+	}
+
+	// only the latest revision can be approved, otherwise an outdated desired state would be enforced
+	lastRevision, err := api.store.GetRevision(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading latest revision: %s", err))
+	}
+	if lastRevision == nil || lastRevision.GetGeneration() != revision.GetGeneration() {
+		panic(fmt.Sprintf("revision %d has been superseded by a newer revision and can't be approved", gen))
+	}
+
+	// check that user is allowed to manage services of all protected component instances
+	for _, key := range revision.PendingApproval {
+		if !canApproveDeletion(user, policy, actualState.ComponentInstanceMap[key]) {
+			panic(fmt.Sprintf("user is not allowed to approve deletion of protected component instance: %s", key))
+		}
+	}
+
+	revision.Status = engine.RevisionStatusWaiting
+	revision.Approved = append(revision.Approved, revision.PendingApproval...)
+	revision.ApprovedBy = user.Name
+	revision.ApprovedAt = time.Now()
+	err = api.store.UpdateRevision(revision)
+	if err != nil {
+		panic(fmt.Sprintf("error while approving revision %d: %s", revision.GetGeneration(), err))
+	}
+
+	return revision
+}
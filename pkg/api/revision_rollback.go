@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/julienschmidt/httprouter"
+)
+
+func (api *coreAPI) handleRevisionRollback(writer http.ResponseWriter, request *http.Request, params httprouter.Params) {
+	user := api.getUserRequired(request)
+
+	// Load the revision we are rolling back to
+	gen := runtime.ParseGeneration(params.ByName("gen"))
+	targetRevision, err := api.store.GetRevision(gen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading revision %d: %s", gen, err))
+	}
+	if targetRevision == nil {
+		panic(fmt.Sprintf("revision %d not found", gen))
+	}
+
+	// Load policy data for the policy generation behind the revision, as well as the policy itself
+	targetPolicyData, err := api.store.GetPolicyData(targetRevision.PolicyGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading policy data for policy gen %d: %s", targetRevision.PolicyGen, err))
+	}
+	targetPolicy, _, err := api.store.GetPolicy(targetRevision.PolicyGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading policy gen %d: %s", targetRevision.PolicyGen, err))
+	}
+
+	// Load the latest policy data
+	policyData, err := api.store.GetPolicyData(runtime.LastGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading current policy data: %s", err))
+	}
+	policyGen := policyData.GetGeneration()
+
+	// Load the latest policy, so we can look up objects in it
+	policy, _, err := api.store.GetPolicy(policyGen)
+	if err != nil {
+		panic(fmt.Sprintf("error while loading current policy: %s", err))
+	}
+
+	// Figure out which objects need to be restored and which need to be deleted, then process them as policy changes
+	restored, deleted := getRollbackChanges(targetPolicyData, targetPolicy, policyData, policy)
+	changes := &policyChanges{
+		updated: restored,
+		removed: deleted,
+		restore: true,
+	}
+
+	api.handlePolicyChanges(writer, request, params, user, changes, "api-revision-rollback")
+}
+
+// getRollbackChanges compares target policy data with the current policy data and returns the list of objects, which
+// need to be restored (from the target policy), as well as the list of objects which need to be deleted (from the
+// current policy). Creation time of restored dependencies gets reset, so their expiration is counted from the moment
+// of rollback, unless they still exist in the current policy
+func getRollbackChanges(targetPolicyData *engine.PolicyData, targetPolicy *lang.Policy, policyData *engine.PolicyData, policy *lang.Policy) ([]lang.Base, []lang.Base) {
+	restored := []lang.Base{}
+	for ns, kindNameGen := range targetPolicyData.Objects {
+		for kind, nameGen := range kindNameGen {
+			for name, gen := range nameGen {
+				if policyData.Objects[ns][kind][name] == gen {
+					continue
+				}
+				obj := getPolicyObject(targetPolicy, kind, name, ns)
+				if dependency, ok := obj.(*lang.Dependency); ok {
+					dependency.CreatedAt = time.Time{}
+				}
+				restored = append(restored, obj)
+			}
+		}
+	}
+
+	deleted := []lang.Base{}
+	for ns, kindNameGen := range policyData.Objects {
+		for kind, nameGen := range kindNameGen {
+			for name := range nameGen {
+				if _, exists := targetPolicyData.Objects[ns][kind][name]; exists {
+					continue
+				}
+				deleted = append(deleted, getPolicyObject(policy, kind, name, ns))
+			}
+		}
+	}
+
+	return restored, deleted
+}
+
+func getPolicyObject(policy *lang.Policy, kind string, name string, ns string) lang.Base {
+	obj, err := policy.GetObject(kind, name, ns)
+	if err != nil || obj == nil {
+		panic(fmt.Sprintf("error while getting object %s/%s/%s from policy: %s", ns, kind, name, err))
+	}
+	return obj.(lang.Base) // nolint: errcheck
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/runtime/store"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApproveRevision(t *testing.T) {
+	b, _ := makePolicyBuilder()
+	user := b.AddUserDomainAdmin()
+	policy := b.Policy()
+	actualState := resolve.NewPolicyResolution()
+
+	newAPI := func(revisions ...*engine.Revision) (*coreAPI, *revisionStore) {
+		revisionStore := &revisionStore{revisions: make(map[runtime.Generation]*engine.Revision)}
+		for _, revision := range revisions {
+			revisionStore.save(revision)
+		}
+		return &coreAPI{store: revisionStore, policyAndRevisionUpdateMutex: &sync.Mutex{}}, revisionStore
+	}
+	newRevision := func(gen runtime.Generation, status string) *engine.Revision {
+		revision := engine.NewRevision(gen, 1, false)
+		revision.Status = status
+		revision.PendingApproval = []string{"instance"}
+		return revision
+	}
+	approve := func(api *coreAPI, gen runtime.Generation) (revision *engine.Revision, err error) {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				err = fmt.Errorf("%s", recovered)
+			}
+		}()
+		return api.approveRevision(gen, user, policy, actualState), nil
+	}
+
+	// the latest revision, which awaits approval, gets approved
+	api, revisionStore := newAPI(newRevision(1, engine.RevisionStatusAwaitingApproval))
+	revision, err := approve(api, 1)
+	if assert.NoError(t, err, "Revision should be approved") {
+		assert.Equal(t, engine.RevisionStatusWaiting, revision.Status, "Approved revision should be waiting to be processed")
+		assert.Equal(t, []string{"instance"}, revisionStore.revisions[1].Approved, "Approval should be saved")
+		assert.Equal(t, user.Name, revisionStore.revisions[1].ApprovedBy, "Approval should be saved")
+	}
+
+	// revision, which doesn't await approval, can't be approved
+	api, _ = newAPI(newRevision(1, engine.RevisionStatusInProgress))
+	_, err = approve(api, 1)
+	assert.Error(t, err, "Revision, which is not awaiting approval, should not be approved")
+
+	// superseded revision can't be approved
+	api, _ = newAPI(newRevision(1, engine.RevisionStatusAwaitingApproval), newRevision(2, engine.RevisionStatusWaiting))
+	_, err = approve(api, 1)
+	assert.Error(t, err, "Superseded revision should not be approved")
+
+	// revision gets checked and approved only once mutex is taken, so changes made in between are taken into account
+	api, revisionStore = newAPI(newRevision(1, engine.RevisionStatusAwaitingApproval))
+	api.policyAndRevisionUpdateMutex.Lock()
+	done := make(chan error)
+	go func() {
+		_, approveErr := approve(api, 1)
+		done <- approveErr
+	}()
+	revisionStore.save(newRevision(2, engine.RevisionStatusWaiting))
+	api.policyAndRevisionUpdateMutex.Unlock()
+	assert.Error(t, <-done, "Revision superseded while waiting for mutex should not be approved")
+	assert.Empty(t, revisionStore.revisions[1].Approved, "Superseded revision should not be approved")
+
+	// changes made to the revision in between are not overwritten by approval
+	api, revisionStore = newAPI(newRevision(1, engine.RevisionStatusAwaitingApproval))
+	api.policyAndRevisionUpdateMutex.Lock()
+	go func() {
+		_, approveErr := approve(api, 1)
+		done <- approveErr
+	}()
+	updated := newRevision(1, engine.RevisionStatusAwaitingApproval)
+	updated.Result.Success = 5
+	revisionStore.save(updated)
+	api.policyAndRevisionUpdateMutex.Unlock()
+	if assert.NoError(t, <-done, "Revision should be approved") {
+		assert.Equal(t, uint32(5), revisionStore.revisions[1].Result.Success, "Changes made to revision before approval should be preserved")
+		assert.Equal(t, []string{"instance"}, revisionStore.revisions[1].Approved, "Approval should be saved")
+	}
+}
+
+// revisionStore is a store, which only keeps revisions in memory
+type revisionStore struct {
+	store.Core
+	mutex     sync.Mutex
+	revisions map[runtime.Generation]*engine.Revision
+}
+
+func (s *revisionStore) save(revision *engine.Revision) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	revisionCopy := *revision
+	resultCopy := *revision.Result
+	revisionCopy.Result = &resultCopy
+	s.revisions[revision.GetGeneration()] = &revisionCopy
+}
+
+func (s *revisionStore) GetRevision(gen runtime.Generation) (*engine.Revision, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if gen == runtime.LastGen {
+		var last runtime.Generation
+		for revisionGen := range s.revisions {
+			if revisionGen > last {
+				last = revisionGen
+			}
+		}
+		gen = last
+	}
+	revision, exist := s.revisions[gen]
+	if !exist {
+		return nil, nil
+	}
+	revisionCopy := *revision
+	resultCopy := *revision.Result
+	revisionCopy.Result = &resultCopy
+	return &revisionCopy, nil
+}
+
+func (s *revisionStore) UpdateRevision(revision *engine.Revision) error {
+	s.save(revision)
+	return nil
+}
@@ -1,6 +1,8 @@
 package client
 
 import (
+	"time"
+
 	"github.com/Aptomi/aptomi/pkg/api"
 	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/lang"
@@ -24,21 +26,35 @@ type Policy interface {
 	Show(gen runtime.Generation) (*engine.PolicyData, error)
 	Apply([]runtime.Object, bool, logrus.Level) (*api.PolicyUpdateResult, error)
 	Delete([]runtime.Object, bool, logrus.Level) (*api.PolicyUpdateResult, error)
+	Simulate(added []runtime.Object, removed []runtime.Object, logLevel logrus.Level) (*api.PolicySimulateResult, error)
 }
 
 // Dependency is the interface for managing Dependency
 type Dependency interface {
 	Status([]*lang.Dependency, api.DependencyQueryFlag) (*api.DependenciesStatus, error)
+	Explain(namespace string, name string) (*api.DependencyExplain, error)
+	Extend(namespace string, name string, duration time.Duration, noop bool, logLevel logrus.Level) (*api.PolicyUpdateResult, error)
 }
 
-// Revision is the interface for getting Revisions
+// Revision is the interface for getting Revisions, rolling back to them, cancelling and approving them
 type Revision interface {
-	Show(gen runtime.Generation) (*engine.Revision, error)
+	Show(gen runtime.Generation) (*api.RevisionStatus, error)
+	ShowPlan(gen runtime.Generation) (*engine.RevisionPlan, error)
+	Rollback(gen runtime.Generation, noop bool, logLevel logrus.Level) (*api.PolicyUpdateResult, error)
+	Cancel() (*engine.Revision, error)
+	Approve(gen runtime.Generation) (*engine.Revision, error)
 }
 
-// State is the interface for resetting Actual State
+// State is the interface for resetting Actual State and retry states of failing component instances, for retrieving
+// drifted component instances and cleaning up orphaned code, as well as for pausing and resuming enforcement
 type State interface {
-	Reset(bool) (*api.PolicyUpdateResult, error)
+	Reset(noop bool, failureBudget string, scope *api.StateEnforceRequest) (*api.PolicyUpdateResult, error)
+	ResetRetries(componentKey string) (*api.RetryResetResult, error)
+	Drift() (*api.DriftStatus, error)
+	Orphans() (*api.OrphanStatus, error)
+	CleanupOrphans(deployNames []string) (*api.OrphanCleanupResult, error)
+	Pause(namespace string, cluster string, reason string) (*api.EnforcerPauseStatus, error)
+	Resume(namespace string, cluster string) (*api.EnforcerPauseStatus, error)
 }
 
 // User is the interface for auth and user management
@@ -7,9 +7,11 @@ import (
 	"github.com/Aptomi/aptomi/pkg/client/rest/http"
 
 	"strings"
+	"time"
 
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/sirupsen/logrus"
 )
 
 type dependencyClient struct {
@@ -34,3 +36,29 @@ func (client *dependencyClient) Status(dependencies []*lang.Dependency, queryFla
 
 	return response.(*api.DependenciesStatus), nil
 }
+
+func (client *dependencyClient) Explain(namespace string, name string) (*api.DependencyExplain, error) {
+	response, err := client.httpClient.GET(fmt.Sprintf("/policy/dependency/explain/%s/%s", namespace, name), api.DependencyExplainObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.DependencyExplain), nil
+}
+
+func (client *dependencyClient) Extend(namespace string, name string, duration time.Duration, noop bool, logLevel logrus.Level) (*api.PolicyUpdateResult, error) {
+	response, err := client.httpClient.POST(fmt.Sprintf("/policy/dependency/extend/%s/%s/duration/%s/noop/%t/loglevel/%s", namespace, name, duration, noop, logLevel.String()), api.PolicyUpdateResultObject, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.PolicyUpdateResult), nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/client/rest/http"
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/sirupsen/logrus"
 )
@@ -54,3 +55,26 @@ func (client *policyClient) Delete(updated []runtime.Object, noop bool, logLevel
 
 	return response.(*api.PolicyUpdateResult), nil
 }
+
+func (client *policyClient) Simulate(added []runtime.Object, removed []runtime.Object, logLevel logrus.Level) (*api.PolicySimulateResult, error) {
+	body := make([]runtime.Object, 0, len(added)+len(removed))
+	body = append(body, added...)
+	for _, obj := range removed {
+		langObj, ok := obj.(lang.Base)
+		if !ok {
+			return nil, fmt.Errorf("only policy objects can be removed, but got: %s", obj.GetKind())
+		}
+		body = append(body, api.NewPolicySimulateRemoval(langObj))
+	}
+
+	response, err := client.httpClient.POSTSlice(fmt.Sprintf("/policy/simulate/loglevel/%s", logLevel.String()), api.PolicySimulateResultObject, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.PolicySimulateResult), nil
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/client/rest/http"
 	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/sirupsen/logrus"
 
 	"github.com/Aptomi/aptomi/pkg/config"
 )
@@ -16,8 +17,60 @@ type revisionClient struct {
 	httpClient http.Client
 }
 
-func (client *revisionClient) Show(gen runtime.Generation) (*engine.Revision, error) {
-	response, err := client.httpClient.GET(fmt.Sprintf("/revision/gen/%d", gen), engine.RevisionObject)
+func (client *revisionClient) Show(gen runtime.Generation) (*api.RevisionStatus, error) {
+	response, err := client.httpClient.GET(fmt.Sprintf("/revision/gen/%d", gen), api.RevisionStatusObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.RevisionStatus), nil
+}
+
+func (client *revisionClient) ShowPlan(gen runtime.Generation) (*engine.RevisionPlan, error) {
+	response, err := client.httpClient.GET(fmt.Sprintf("/revision/gen/%d/plan", gen), engine.RevisionPlanObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*engine.RevisionPlan), nil
+}
+
+func (client *revisionClient) Rollback(gen runtime.Generation, noop bool, logLevel logrus.Level) (*api.PolicyUpdateResult, error) {
+	response, err := client.httpClient.POST(fmt.Sprintf("/revision/gen/%d/rollback/noop/%t/loglevel/%s", gen, noop, logLevel.String()), api.PolicyUpdateResultObject, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.PolicyUpdateResult), nil
+}
+
+func (client *revisionClient) Cancel() (*engine.Revision, error) {
+	response, err := client.httpClient.POST("/revision/cancel", engine.RevisionObject, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*engine.Revision), nil
+}
+
+func (client *revisionClient) Approve(gen runtime.Generation) (*engine.Revision, error) {
+	response, err := client.httpClient.POST(fmt.Sprintf("/revision/gen/%d/approve", gen), engine.RevisionObject, nil)
 	if err != nil {
 		return nil, err
 	}
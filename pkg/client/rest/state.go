@@ -2,11 +2,11 @@ package rest
 
 import (
 	"fmt"
+	"net/url"
 
 	"github.com/Aptomi/aptomi/pkg/api"
 	"github.com/Aptomi/aptomi/pkg/client/rest/http"
 	"github.com/Aptomi/aptomi/pkg/config"
-	"github.com/Aptomi/aptomi/pkg/runtime"
 )
 
 type stateClient struct {
@@ -14,18 +14,114 @@ type stateClient struct {
 	httpClient http.Client
 }
 
-type stateEnforceObj struct {
+func (client *stateClient) Reset(noop bool, failureBudget string, scope *api.StateEnforceRequest) (*api.PolicyUpdateResult, error) {
+	path := fmt.Sprintf("/state/enforce/noop/%t", noop)
+	if len(failureBudget) > 0 {
+		path += "/budget/" + url.PathEscape(failureBudget)
+	}
+	if scope == nil {
+		scope = &api.StateEnforceRequest{TypeKind: api.StateEnforceRequestObject.GetTypeKind()}
+	}
+	revision, err := client.httpClient.POST(path, api.PolicyUpdateResultObject, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := revision.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return revision.(*api.PolicyUpdateResult), nil
 }
 
-func (stateEnforce *stateEnforceObj) GetKind() runtime.Kind {
-	return "state-enforce-obj"
+func (client *stateClient) ResetRetries(componentKey string) (*api.RetryResetResult, error) {
+	path := "/state/retry/reset"
+	if len(componentKey) > 0 {
+		path += "/" + url.PathEscape(componentKey)
+	}
+	response, err := client.httpClient.POST(path, api.RetryResetResultObject, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.RetryResetResult), nil
 }
 
-func (client *stateClient) Reset(noop bool) (*api.PolicyUpdateResult, error) {
-	revision, err := client.httpClient.POST(fmt.Sprintf("/state/enforce/noop/%t", noop), api.PolicyUpdateResultObject, &stateEnforceObj{})
+func (client *stateClient) Drift() (*api.DriftStatus, error) {
+	response, err := client.httpClient.GET("/state/drift", api.DriftStatusObject)
 	if err != nil {
 		return nil, err
 	}
 
-	return revision.(*api.PolicyUpdateResult), nil
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.DriftStatus), nil
+}
+
+func (client *stateClient) Orphans() (*api.OrphanStatus, error) {
+	response, err := client.httpClient.GET("/state/orphans", api.OrphanStatusObject)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.OrphanStatus), nil
+}
+
+func (client *stateClient) CleanupOrphans(deployNames []string) (*api.OrphanCleanupResult, error) {
+	request := &api.OrphanCleanupRequest{
+		TypeKind:    api.OrphanCleanupRequestObject.GetTypeKind(),
+		DeployNames: deployNames,
+	}
+	response, err := client.httpClient.POST("/state/orphans/cleanup", api.OrphanCleanupResultObject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.OrphanCleanupResult), nil
+}
+
+func (client *stateClient) Pause(namespace string, cluster string, reason string) (*api.EnforcerPauseStatus, error) {
+	request := &api.EnforcerPauseRequest{
+		TypeKind:  api.EnforcerPauseRequestObject.GetTypeKind(),
+		Namespace: namespace,
+		Cluster:   cluster,
+		Reason:    reason,
+	}
+	return client.pauseOrResume("/state/pause", request)
+}
+
+func (client *stateClient) Resume(namespace string, cluster string) (*api.EnforcerPauseStatus, error) {
+	request := &api.EnforcerPauseRequest{
+		TypeKind:  api.EnforcerPauseRequestObject.GetTypeKind(),
+		Namespace: namespace,
+		Cluster:   cluster,
+	}
+	return client.pauseOrResume("/state/resume", request)
+}
+
+func (client *stateClient) pauseOrResume(path string, request *api.EnforcerPauseRequest) (*api.EnforcerPauseStatus, error) {
+	response, err := client.httpClient.POST(path, api.EnforcerPauseStatusObject, request)
+	if err != nil {
+		return nil, err
+	}
+
+	if serverError, ok := response.(*api.ServerError); ok {
+		return nil, fmt.Errorf("server error: %s", serverError.Error)
+	}
+
+	return response.(*api.EnforcerPauseStatus), nil
 }
@@ -17,6 +17,8 @@ type Server struct {
 	SecretsDir           string               `validate:"omitempty,dir"` // secrets is not a first-class citizen yet, so it's not required
 	Enforcer             DesiredStateEnforcer `validate:"required"`
 	Updater              ActualStateUpdater   `validate:"required"`
+	Expirer              DependencyExpirer    `validate:"-"`
+	Drift                DriftDetector        `validate:"-"`
 	DomainAdminOverrides map[string]bool      `validate:"-"`
 	Auth                 ServerAuth           `validate:"-"`
 	Profile              Profile              `validate:"-"`
@@ -49,11 +51,19 @@ type DB struct {
 // DesiredStateEnforcer represents config for desired state enforcer background process that periodically gets latest policy, calculating
 // difference between it and actual state and then applying calculated actions
 type DesiredStateEnforcer struct {
-	Disabled             bool          `validate:"-"`
-	Interval             time.Duration `validate:"-"`
-	Noop                 bool          `validate:"-"`
-	NoopSleep            time.Duration `validate:"-"`
-	MaxConcurrentActions int           `validate:"-"`
+	Disabled                     bool          `validate:"-"`
+	Interval                     time.Duration `validate:"-"`
+	Noop                         bool          `validate:"-"`
+	NoopSleep                    time.Duration `validate:"-"`
+	MaxConcurrentActions         int           `validate:"-"`
+	ClusterMaxConcurrentActions  string        `validate:"-"`
+	CodeTypeMaxConcurrentActions string        `validate:"-"`
+	ActionTimeout                time.Duration `validate:"-"`
+	RetryBackoff                 time.Duration `validate:"-"`
+	RetryMaxBackoff              time.Duration `validate:"-"`
+	RetryMaxAttempts             int           `validate:"-"`
+	FailureBudget                string        `validate:"-"`
+	ReadinessTimeout             time.Duration `validate:"-"`
 }
 
 // ActualStateUpdater represents config for actual state updater background process that periodically refreshes actual state
@@ -66,6 +76,23 @@ type ActualStateUpdater struct {
 	MaxConcurrentActions int           `validate:"-"`
 }
 
+// DependencyExpirer represents config for dependency expirer background process that periodically removes expired
+// dependencies from the policy
+type DependencyExpirer struct {
+	Disabled bool          `validate:"-"`
+	Interval time.Duration `validate:"-"`
+}
+
+// DriftDetector represents config for drift detector background process that periodically checks whether deployed
+// code still matches the actual state (e.g. it hasn't been deleted or changed manually) and optionally marks drifted
+// component instances for re-creation or update during the next enforcement
+type DriftDetector struct {
+	Disabled             bool          `validate:"-"`
+	Interval             time.Duration `validate:"-"`
+	MaxConcurrentActions int           `validate:"-"`
+	Repair               bool          `validate:"-"`
+}
+
 // ServerAuth represents server auth config
 type ServerAuth struct {
 	Secret string `validate:"-"`
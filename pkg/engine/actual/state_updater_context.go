@@ -0,0 +1,64 @@
+package actual
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+)
+
+type contextStateUpdater struct {
+	ctx     context.Context
+	updater StateUpdater
+}
+
+// NewContextStateUpdater wraps a given state updater, so that changes to actual state get rejected once a given
+// context is done. It makes sure that interrupted actions (e.g. timed out or cancelled) don't change actual state
+// after their outcome has already been recorded
+func NewContextStateUpdater(ctx context.Context, updater StateUpdater) StateUpdater {
+	return &contextStateUpdater{
+		ctx:     ctx,
+		updater: updater,
+	}
+}
+
+// GetComponentInstance returns component instance by key
+func (updater *contextStateUpdater) GetComponentInstance(key string) *resolve.ComponentInstance {
+	return updater.updater.GetComponentInstance(key)
+}
+
+// CreateComponentInstance creates a new component instance in the actual state, unless context is done
+func (updater *contextStateUpdater) CreateComponentInstance(instance *resolve.ComponentInstance) error {
+	if err := updater.checkContext(); err != nil {
+		return err
+	}
+	return updater.updater.CreateComponentInstance(instance)
+}
+
+// UpdateComponentInstance updates component instance in the actual state, unless context is done
+func (updater *contextStateUpdater) UpdateComponentInstance(key string, update func(obj *resolve.ComponentInstance)) error {
+	if err := updater.checkContext(); err != nil {
+		return err
+	}
+	return updater.updater.UpdateComponentInstance(key, update)
+}
+
+// DeleteComponentInstance deletes component instance from the actual state, unless context is done
+func (updater *contextStateUpdater) DeleteComponentInstance(key string) error {
+	if err := updater.checkContext(); err != nil {
+		return err
+	}
+	return updater.updater.DeleteComponentInstance(key)
+}
+
+// GetUpdatedActualState returns the updated actual state
+func (updater *contextStateUpdater) GetUpdatedActualState() *resolve.PolicyResolution {
+	return updater.updater.GetUpdatedActualState()
+}
+
+func (updater *contextStateUpdater) checkContext() error {
+	if err := updater.ctx.Err(); err != nil {
+		return fmt.Errorf("actual state can't be changed by interrupted action: %s", err)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package action
 
 import (
+	"context"
 	"fmt"
 	"runtime/debug"
 	"sync"
@@ -30,10 +31,57 @@ func (plan *Plan) GetActionGraphNode(key string) *GraphNode {
 	return result
 }
 
-// Apply applies the action plan. It may call fn in multiple go routines, executing the plan in parallel
-func (plan *Plan) Apply(fn ApplyFunction, resultUpdater ApplyResultUpdater) *ApplyResult {
-	// make sure we are converting panics into errors
+// Filter returns a new action plan, which only contains nodes accepted by a given function, as well as all nodes
+// which have to be applied before them. Relative order of nodes is preserved, while actions of the nodes which are not
+// included remain unapplied
+func (plan *Plan) Filter(accept func(key string) bool) *Plan {
+	// find accepted nodes and all of their predecessors
+	included := make(map[string]bool)
+	var include func(node *GraphNode)
+	include = func(node *GraphNode) {
+		if included[node.Key] {
+			return
+		}
+		included[node.Key] = true
+		for _, before := range node.Before {
+			include(before)
+		}
+	}
+	for key, node := range plan.NodeMap {
+		if accept(key) {
+			include(node)
+		}
+	}
+
+	// copy included nodes with their actions and dependencies between them
+	result := NewPlan()
+	for key := range included {
+		node := plan.NodeMap[key]
+		resultNode := result.GetActionGraphNode(key)
+		resultNode.Actions = append(resultNode.Actions, node.Actions...)
+		for _, before := range node.Before {
+			resultNode.AddBefore(result.GetActionGraphNode(before.Key))
+		}
+	}
+	return result
+}
+
+// Apply applies the action plan. It may call fn in multiple go routines, executing the plan in parallel.
+// If ctx gets cancelled, actions which haven't been started yet will be marked as skipped. If failure budget tracker
+// is given and the budget gets exceeded, no new actions will be started and they will be marked as skipped as well.
+// Function fn may return ErrSkipped to mark an action as skipped (e.g. if it had been waiting for its turn while the
+// plan got cancelled). If concurrency limiter is given, actions will only be started when it doesn't lead to
+// exceeding any of concurrency limits (global, per cluster and per code type)
+func (plan *Plan) Apply(ctx context.Context, fn ApplyFunction, resultUpdater ApplyResultUpdater, budgetTracker *FailureBudgetTracker, limiter *ConcurrencyLimiter) *ApplyResult {
+	// make sure we are converting panics into errors and respecting concurrency limits
 	fnModified := func(act Interface) (errResult error) {
+		release, err := limiter.acquire(ctx, act)
+		if err != nil {
+			// plan got cancelled while action was waiting for its turn
+			return ErrSkipped
+		}
+		defer release()
+
 		defer func() {
 			if err := recover(); err != nil {
 				errResult = fmt.Errorf("panic: %s\n%s", err, string(debug.Stack()))
@@ -43,17 +91,27 @@ func (plan *Plan) Apply(fn ApplyFunction, resultUpdater ApplyResultUpdater) *App
 	}
 
 	// update total number of actions and start the revision
-	resultUpdater.SetTotal(plan.NumberOfActions())
+	total := plan.NumberOfActions()
+	resultUpdater.SetTotal(total)
 
 	// apply the plan and calculate result (success/failed/skipped actions)
-	plan.applyInternal(fnModified, resultUpdater)
+	budgetTracker.setTotal(total)
+	plan.applyInternal(ctx, fnModified, resultUpdater, budgetTracker)
+
+	// let results updater know if the plan has been cancelled or aborted
+	if ctx.Err() != nil {
+		resultUpdater.SetCancelled()
+	}
+	if budgetTracker.IsExceeded() {
+		resultUpdater.SetAborted(budgetTracker.Reason())
+	}
 
 	// tell results updater that we are done and return the results
 	return resultUpdater.Done()
 }
 
 // Apply applies the action plan. It may call fn in multiple go routines, executing the plan in parallel
-func (plan *Plan) applyInternal(fn ApplyFunction, resultUpdater ApplyResultUpdater) {
+func (plan *Plan) applyInternal(ctx context.Context, fn ApplyFunction, resultUpdater ApplyResultUpdater, budgetTracker *FailureBudgetTracker) {
 	deg := make(map[string]int)
 	wasError := make(map[string]error)
 	queue := make(chan string, len(plan.NodeMap))
@@ -78,7 +136,7 @@ func (plan *Plan) applyInternal(fn ApplyFunction, resultUpdater ApplyResultUpdat
 			// Take element off the queue, apply the block of actions and put into queue 0-degree nodes which are waiting on us
 			go func(key string) {
 				defer wg.Done()
-				plan.applyActions(key, fn, queue, deg, wasError, mutex, resultUpdater)
+				plan.applyActions(ctx, key, fn, queue, deg, wasError, mutex, resultUpdater, budgetTracker)
 			}(key)
 		}
 		done.Done()
@@ -95,7 +153,7 @@ func (plan *Plan) applyInternal(fn ApplyFunction, resultUpdater ApplyResultUpdat
 }
 
 // This function applies a block of actions and updates nodes which are waiting on this node
-func (plan *Plan) applyActions(key string, fn ApplyFunction, queue chan string, deg map[string]int, wasError map[string]error, mutex *sync.RWMutex, resultUpdater ApplyResultUpdater) {
+func (plan *Plan) applyActions(ctx context.Context, key string, fn ApplyFunction, queue chan string, deg map[string]int, wasError map[string]error, mutex *sync.RWMutex, resultUpdater ApplyResultUpdater, budgetTracker *FailureBudgetTracker) {
 	// locate the node
 	node := plan.NodeMap[key]
 
@@ -105,14 +163,19 @@ func (plan *Plan) applyActions(key string, fn ApplyFunction, queue chan string,
 	foundErr := wasError[key]
 	mutex.RUnlock()
 	for _, action := range node.Actions {
-		// if an error happened before, all subsequent actions are getting marked as skipped
-		if foundErr != nil {
+		// if an error happened before, the plan has been cancelled or the failure budget has been exceeded, all
+		// subsequent actions are getting marked as skipped
+		if foundErr != nil || ctx.Err() != nil || budgetTracker.IsExceeded() {
 			resultUpdater.AddSkipped()
 		} else {
 			// Otherwise, let's run the action and see if it failed or not
 			err := fn(action)
-			if err != nil {
+			if err == ErrSkipped {
+				resultUpdater.AddSkipped()
+				foundErr = err
+			} else if err != nil {
 				resultUpdater.AddFailed()
+				budgetTracker.recordFailure()
 				foundErr = err
 			} else {
 				resultUpdater.AddSuccess()
@@ -153,7 +216,7 @@ func (plan *Plan) NumberOfActions() uint32 {
 	resultUpdater := NewApplyResultUpdaterImpl()
 
 	// apply the plan and calculate result (success/failed/skipped actions)
-	plan.applyInternal(Noop(), resultUpdater)
+	plan.applyInternal(context.Background(), Noop(), resultUpdater, nil)
 
 	// return the number of success actions (all of them will be success due to Noop() action)
 	return resultUpdater.Result.Success
@@ -164,10 +227,10 @@ func (plan *Plan) AsText() *PlanAsText {
 	result := NewPlanAsText()
 
 	// apply the plan and capture actions as text
-	plan.applyInternal(WrapSequential(func(act Interface) error {
+	plan.applyInternal(context.Background(), WrapSequential(func(act Interface) error {
 		result.Actions = append(result.Actions, act.DescribeChanges())
 		return nil
-	}), NewApplyResultUpdaterImpl())
+	}), NewApplyResultUpdaterImpl(), nil)
 
 	return result
 }
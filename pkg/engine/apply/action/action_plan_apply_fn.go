@@ -1,12 +1,17 @@
 package action
 
 import (
+	"errors"
 	"sync"
 )
 
 // ApplyFunction is a function which applies an action
 type ApplyFunction func(Interface) error
 
+// ErrSkipped can be returned by ApplyFunction to indicate that an action has not been applied and has to be counted
+// as skipped, rather than failed
+var ErrSkipped = errors.New("action skipped")
+
 // WrapSequential wraps apply function to be sequential
 func WrapSequential(fn ApplyFunction) ApplyFunction {
 	mutex := sync.Mutex{}
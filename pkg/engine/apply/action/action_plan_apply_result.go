@@ -11,6 +11,15 @@ type ApplyResult struct {
 	Failed  uint32
 	Skipped uint32
 	Total   uint32
+
+	// Cancelled is set to true if applying actions has been cancelled before all of them got processed
+	Cancelled bool
+
+	// Aborted is set to true if applying actions has been aborted, because too many of them failed
+	Aborted bool
+
+	// AbortReason holds the reason why applying actions has been aborted
+	AbortReason string `yaml:",omitempty"`
 }
 
 // ApplyResultUpdater is an interface for handling revision progress stats (# of processed actions) when applying action plan
@@ -19,6 +28,8 @@ type ApplyResultUpdater interface {
 	AddSuccess()
 	AddFailed()
 	AddSkipped()
+	SetCancelled()
+	SetAborted(reason string)
 	Done() *ApplyResult
 }
 
@@ -54,6 +65,17 @@ func (updater *ApplyResultUpdaterImpl) AddSkipped() {
 	atomic.AddUint32(&updater.Result.Skipped, 1)
 }
 
+// SetCancelled marks the result as cancelled
+func (updater *ApplyResultUpdaterImpl) SetCancelled() {
+	updater.Result.Cancelled = true
+}
+
+// SetAborted marks the result as aborted, recording the reason
+func (updater *ApplyResultUpdaterImpl) SetAborted(reason string) {
+	updater.Result.Aborted = true
+	updater.Result.AbortReason = reason
+}
+
 // Done does nothing except doing an integrity check for default implementation
 func (updater *ApplyResultUpdaterImpl) Done() *ApplyResult {
 	if updater.Result.Success+updater.Result.Failed+updater.Result.Skipped != updater.Result.Total {
@@ -0,0 +1,224 @@
+package action
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// ActionStatusPending represents status of an action, which hasn't been applied yet
+	ActionStatusPending = "pending"
+	// ActionStatusInProgress represents status of an action, which is being applied
+	ActionStatusInProgress = "inprogress"
+	// ActionStatusSuccess represents status of an action, which has been successfully applied
+	ActionStatusSuccess = "success"
+	// ActionStatusFailed represents status of an action, which failed to be applied
+	ActionStatusFailed = "failed"
+	// ActionStatusSkipped represents status of an action, which has been skipped
+	ActionStatusSkipped = "skipped"
+)
+
+const (
+	// ReadinessReady represents component instance, which became ready after the action
+	ReadinessReady = "ready"
+	// ReadinessTimeout represents component instance, which didn't become ready in time after the action
+	ReadinessTimeout = "timeout"
+	// ReadinessCancelled represents component instance, waiting for readiness of which has been interrupted
+	ReadinessCancelled = "cancelled"
+)
+
+// PlanRecord is a record of the action plan, which includes the graph of action nodes as well as the outcome of
+// every action. It's safe to update from multiple go routines
+type PlanRecord struct {
+	// Nodes is a list of action graph nodes, sorted by key
+	Nodes []*PlanRecordNode
+
+	mutex   sync.Mutex
+	actions map[string]*ActionRecord
+}
+
+// PlanRecordNode is a record of a single node of the action graph
+type PlanRecordNode struct {
+	// Key is a key of the node (i.e. component instance key)
+	Key string
+
+	// Before is a list of keys of nodes, which have to be applied before this node
+	Before []string `yaml:",omitempty"`
+
+	// Actions is a list of actions of this node, in the order they are applied
+	Actions []*ActionRecord
+}
+
+// ActionRecord is a record of a single action and its outcome
+type ActionRecord struct {
+	// Name is a unique name of the action
+	Name string
+
+	// Kind is a kind of the action
+	Kind string
+
+	// Description is a human-readable description of the action
+	Description string
+
+	// Status is a status of the action (pending, inprogress, success, failed, skipped)
+	Status string
+
+	// Error is an error returned by the action, if it failed
+	Error string `yaml:",omitempty"`
+
+	// StartedAt is a time when the action has been started
+	StartedAt time.Time
+
+	// FinishedAt is a time when the action has been finished
+	FinishedAt time.Time
+
+	// Readiness is an outcome of waiting for the component instance to become ready after the action (ready,
+	// timeout, cancelled). Empty if readiness hasn't been checked
+	Readiness string `yaml:",omitempty"`
+
+	// ReadinessWait is how long it took to wait for the component instance to become ready after the action
+	ReadinessWait time.Duration `yaml:",omitempty"`
+}
+
+// NewPlanRecord creates a new record for a given action plan, with all actions in pending status
+func NewPlanRecord(plan *Plan) *PlanRecord {
+	result := &PlanRecord{
+		Nodes:   []*PlanRecordNode{},
+		actions: make(map[string]*ActionRecord),
+	}
+	for key, node := range plan.NodeMap {
+		nodeRecord := &PlanRecordNode{
+			Key:     key,
+			Before:  []string{},
+			Actions: []*ActionRecord{},
+		}
+		for _, before := range node.Before {
+			nodeRecord.Before = append(nodeRecord.Before, before.Key)
+		}
+		sort.Strings(nodeRecord.Before)
+		for _, act := range node.Actions {
+			description, _ := act.DescribeChanges()["pretty"].(string)
+			actionRecord := &ActionRecord{
+				Name:        act.GetName(),
+				Kind:        act.GetKind(),
+				Description: description,
+				Status:      ActionStatusPending,
+			}
+			nodeRecord.Actions = append(nodeRecord.Actions, actionRecord)
+			result.actions[actionRecord.Name] = actionRecord
+		}
+		result.Nodes = append(result.Nodes, nodeRecord)
+	}
+	sort.Slice(result.Nodes, func(i, j int) bool {
+		return result.Nodes[i].Key < result.Nodes[j].Key
+	})
+	return result
+}
+
+// Started records that a given action has been started
+func (record *PlanRecord) Started(act Interface) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+
+	if actionRecord, ok := record.actions[act.GetName()]; ok {
+		actionRecord.Status = ActionStatusInProgress
+		actionRecord.StartedAt = time.Now()
+	}
+}
+
+// ReadinessChecked records the outcome of waiting for the component instance to become ready after a given action
+func (record *PlanRecord) ReadinessChecked(act Interface, readiness string, wait time.Duration) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+
+	if actionRecord, ok := record.actions[act.GetName()]; ok {
+		actionRecord.Readiness = readiness
+		actionRecord.ReadinessWait = wait
+	}
+}
+
+// Finished records the outcome of a given action
+func (record *PlanRecord) Finished(act Interface, err error) {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+
+	actionRecord, ok := record.actions[act.GetName()]
+	if !ok {
+		return
+	}
+	actionRecord.FinishedAt = time.Now()
+	if err == ErrSkipped {
+		actionRecord.Status = ActionStatusSkipped
+	} else if err != nil {
+		actionRecord.Status = ActionStatusFailed
+		actionRecord.Error = err.Error()
+	} else {
+		actionRecord.Status = ActionStatusSuccess
+	}
+}
+
+// Done marks all actions, which haven't been applied, as skipped
+func (record *PlanRecord) Done() {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+
+	for _, actionRecord := range record.actions {
+		if actionRecord.Status == ActionStatusPending || actionRecord.Status == ActionStatusInProgress {
+			actionRecord.Status = ActionStatusSkipped
+		}
+	}
+}
+
+// AsDOT returns the action graph in Graphviz DOT format, with nodes colored according to the outcome of their actions
+func (record *PlanRecord) AsDOT() string {
+	record.mutex.Lock()
+	defer record.mutex.Unlock()
+
+	// node gets the color of its "worst" action
+	colors := []struct {
+		status string
+		color  string
+	}{
+		{ActionStatusFailed, "red"},
+		{ActionStatusSkipped, "orange"},
+		{ActionStatusInProgress, "blue"},
+		{ActionStatusPending, "gray"},
+		{ActionStatusSuccess, "green"},
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("digraph plan {\n")
+	buf.WriteString("  node [shape=box];\n")
+	for _, node := range record.Nodes {
+		label := node.Key
+		statuses := make(map[string]bool)
+		for _, actionRecord := range node.Actions {
+			label += fmt.Sprintf("\\n%s (%s)", actionRecord.Description, actionRecord.Status)
+			statuses[actionRecord.Status] = true
+		}
+		color := "green"
+		for _, c := range colors {
+			if statuses[c.status] {
+				color = c.color
+				break
+			}
+		}
+		buf.WriteString(fmt.Sprintf("  %s [label=%s, color=%s];\n", dotQuote(node.Key), dotQuote(label), color))
+	}
+	for _, node := range record.Nodes {
+		for _, before := range node.Before {
+			buf.WriteString(fmt.Sprintf("  %s -> %s;\n", dotQuote(before), dotQuote(node.Key)))
+		}
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
+
+// dotQuote returns a given string as a quoted DOT identifier
+func dotQuote(value string) string {
+	return "\"" + strings.Replace(value, "\"", "\\\"", -1) + "\""
+}
@@ -0,0 +1,119 @@
+package component
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+)
+
+const (
+	// defaultReadinessTimeout is how long to wait for the new copy of code to become ready during blue/green update
+	// (or for hook code to become ready), if it's not specified for the code
+	defaultReadinessTimeout = 5 * time.Minute
+
+	// readinessCheckInterval is how often readiness of the new copy of code (or hook code) gets checked
+	readinessCheckInterval = 5 * time.Second
+)
+
+// blueGreenSwap is a result of blue/green update, which has to be recorded in actual state before the old copy of code
+// gets deleted
+type blueGreenSwap struct {
+	// deployName is a deploy name of the new copy of code
+	deployName string
+
+	// endpoints are endpoints of the new copy of code
+	endpoints map[string]string
+
+	// prev is an invocation for the old copy of code
+	prev       *plugin.CodePluginInvocationParams
+	codePlugin plugin.CodePlugin
+}
+
+// blueGreenUpdate deploys a second copy of code with new parameters and waits for it to become ready. If it fails to
+// get deployed or doesn't become ready in time, the new copy gets deleted (i.e. update gets rolled back) and the old
+// copy keeps running untouched
+func blueGreenUpdate(context *action.Context, p plugin.CodePlugin, prev, next *plugin.CodePluginInvocationParams, readinessTimeout time.Duration) (*blueGreenSwap, error) {
+	context.EventLog.NewEntry().Infof("Deploying new copy of code '%s' (blue/green update of '%s')", next.DeployName, prev.DeployName)
+
+	err := p.Create(context.Ctx, next)
+	if err == nil {
+		err = waitForReadiness(context, p, next, readinessTimeout)
+	}
+	var endpoints map[string]string
+	if err == nil {
+		endpoints, err = p.Endpoints(context.Ctx, next)
+	}
+	if err != nil {
+		rollbackBlueGreenUpdate(context, p, next)
+		return nil, fmt.Errorf("blue/green update rolled back: %s", err)
+	}
+
+	context.EventLog.NewEntry().Infof("New copy of code '%s' is ready, switching to it from '%s'", next.DeployName, prev.DeployName)
+
+	return &blueGreenSwap{
+		deployName: next.DeployName,
+		endpoints:  endpoints,
+		prev:       prev,
+		codePlugin: p,
+	}, nil
+}
+
+// waitForReadiness waits until a given copy of code becomes ready, returning an error if it doesn't happen in time or
+// action gets cancelled
+func waitForReadiness(context *action.Context, p plugin.CodePlugin, invocation *plugin.CodePluginInvocationParams, readinessTimeout time.Duration) error {
+	if readinessTimeout <= 0 {
+		readinessTimeout = defaultReadinessTimeout
+	}
+	deadline := time.Now().Add(readinessTimeout)
+
+	for {
+		ready, err := p.Status(context.Ctx, invocation)
+		if err != nil {
+			context.EventLog.NewEntry().Debugf("Error while checking readiness of '%s': %s", invocation.DeployName, err)
+		} else if ready {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("code '%s' didn't become ready in %s", invocation.DeployName, readinessTimeout)
+		}
+
+		timer := time.NewTimer(readinessCheckInterval)
+		select {
+		case <-timer.C:
+		case <-context.Ctx.Done():
+			timer.Stop()
+			return context.Ctx.Err()
+		}
+	}
+}
+
+// rollbackBlueGreenUpdate deletes the new copy of code. It happens even if action has been cancelled or timed out,
+// otherwise the new copy would be left running without being recorded in actual state
+func rollbackBlueGreenUpdate(context *action.Context, p plugin.CodePlugin, next *plugin.CodePluginInvocationParams) {
+	context.EventLog.NewEntry().Warningf("Rolling back blue/green update, deleting new copy of code '%s'", next.DeployName)
+
+	err := p.Destroy(contextWithoutCancel(), next)
+	if err != nil {
+		context.EventLog.NewEntry().Errorf("Error while deleting new copy of code '%s', it has to be deleted manually (see 'aptomictl state orphans'): %s", next.DeployName, err)
+	}
+}
+
+// destroyPrev deletes the old copy of code after actual state has been switched to the new one. Update is already
+// completed at this point, so the error is only reported and the old copy is left to be deleted manually
+func (swap *blueGreenSwap) destroyPrev(context *action.Context) {
+	context.EventLog.NewEntry().Infof("Deleting old copy of code '%s' (blue/green update)", swap.prev.DeployName)
+
+	err := swap.codePlugin.Destroy(contextWithoutCancel(), swap.prev)
+	if err != nil {
+		context.EventLog.NewEntry().Errorf("Error while deleting old copy of code '%s', it has to be deleted manually (see 'aptomictl state orphans'): %s", swap.prev.DeployName, err)
+	}
+}
+
+// contextWithoutCancel returns a context for cleaning up after blue/green update or hook, which doesn't get cancelled
+func contextWithoutCancel() context.Context {
+	return context.Background()
+}
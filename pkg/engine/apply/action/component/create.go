@@ -56,6 +56,11 @@ func (a *CreateAction) Apply(context *action.Context) (errResult error) {
 		return fmt.Errorf("unable to deploy component instance '%s': %s", a.ComponentKey, err)
 	}
 
+	// post-create hook has to be executed even if apply gets interrupted right after component instance gets created
+	if instance.HasHook(lang.HookPostCreate) {
+		instance.PendingHooks = []string{lang.HookPostCreate}
+	}
+
 	// update actual state
 	return context.ActualStateUpdater.CreateComponentInstance(instance)
 }
@@ -110,11 +115,15 @@ func (a *CreateAction) processDeployment(context *action.Context) (*resolve.Comp
 	}
 
 	return instance, p.Create(
+		context.Ctx,
 		&plugin.CodePluginInvocationParams{
-			DeployName:   instance.GetDeployName(),
-			Params:       instance.CalculatedCodeParams,
-			PluginParams: map[string]string{plugin.ParamTargetSuffix: instance.Metadata.Key.TargetSuffix},
-			EventLog:     context.EventLog,
+			DeployName: instance.GetDeployName(),
+			Params:     instance.CalculatedCodeParams,
+			PluginParams: map[string]string{
+				plugin.ParamTargetSuffix: instance.Metadata.Key.TargetSuffix,
+				plugin.ParamAllowIngress: instance.DataForPlugins[resolve.AllowIngres],
+			},
+			EventLog: context.EventLog,
 		},
 	)
 }
@@ -109,6 +109,7 @@ func (a *DeleteAction) processDeployment(context *action.Context) (*resolve.Comp
 	}
 
 	return instance, p.Destroy(
+		context.Ctx,
 		&plugin.CodePluginInvocationParams{
 			DeployName:   instance.GetDeployName(),
 			Params:       instance.CalculatedCodeParams,
@@ -0,0 +1,157 @@
+package component
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+)
+
+// DriftActionObject is an informational data structure with Kind and Constructor for the action
+var DriftActionObject = &runtime.Info{
+	Kind:        "action-component-drift",
+	Constructor: func() runtime.Object { return &DriftAction{} },
+}
+
+// DriftAction is a action which checks whether deployed code still matches the component instance recorded in the
+// actual state (i.e. it hasn't been deleted or changed manually) and records detected drift in the actual state
+type DriftAction struct {
+	runtime.TypeKind `yaml:",inline"`
+	*action.Metadata
+	ComponentKey string
+
+	// Repair defines whether drifted component instance should be marked for re-creation or update
+	Repair bool
+}
+
+// NewDriftAction creates new DriftAction
+func NewDriftAction(componentKey string, repair bool) *DriftAction {
+	return &DriftAction{
+		TypeKind:     DriftActionObject.GetTypeKind(),
+		Metadata:     action.NewMetadata(DriftActionObject.Kind, componentKey),
+		ComponentKey: componentKey,
+		Repair:       repair,
+	}
+}
+
+// Apply applies the action
+func (a *DriftAction) Apply(context *action.Context) (errResult error) {
+	start := time.Now()
+	defer func() {
+		if err := recover(); err != nil {
+			errResult = fmt.Errorf("panic: %s\n%s", err, string(debug.Stack()))
+		}
+
+		action.CollectMetricsFor(a, start, errResult)
+	}()
+
+	context.EventLog.NewEntry().Debugf("Detecting drift for component instance: %s", a.ComponentKey)
+
+	// ask plugin whether deployed code still matches component instance
+	instance, drift, err := a.processDrift(context)
+	if err != nil {
+		return fmt.Errorf("unable to detect drift for component instance '%s': %s", a.ComponentKey, err)
+	}
+
+	// nothing to update in actual state, if drift status hasn't changed
+	if drift == nil && !instance.IsDrifted() {
+		return nil
+	}
+	if drift != nil && instance.Drift == drift.Details && instance.DriftMissing == drift.Missing && instance.DriftRepair == a.Repair {
+		return nil
+	}
+
+	if drift != nil {
+		context.EventLog.NewEntry().Warningf("Drift detected for component instance %s: %s", a.ComponentKey, drift.Details)
+	} else {
+		context.EventLog.NewEntry().Infof("Drift is no longer detected for component instance %s", a.ComponentKey)
+	}
+
+	// update drift in actual state
+	return context.ActualStateUpdater.UpdateComponentInstance(instance.GetKey(), func(obj *resolve.ComponentInstance) {
+		// component instance itself doesn't get updated by drift detection
+		obj.UpdatedAt = instance.UpdatedAt
+
+		// if component instance has been updated while drift was being detected, results are no longer valid
+		if !obj.CalculatedCodeParams.DeepEqual(instance.CalculatedCodeParams) {
+			return
+		}
+
+		if drift == nil {
+			obj.ResetDrift()
+			return
+		}
+		if !obj.IsDrifted() {
+			obj.DriftDetectedAt = time.Now()
+		}
+		obj.Drift = drift.Details
+		obj.DriftMissing = drift.Missing
+		obj.DriftRepair = a.Repair
+	})
+}
+
+// DescribeChanges returns text-based description of changes that will be applied
+func (a *DriftAction) DescribeChanges() util.NestedParameterMap {
+	return util.NestedParameterMap{
+		"kind":       a.Kind,
+		"key":        a.ComponentKey,
+		"pretty":     fmt.Sprintf("[?] %s", a.ComponentKey),
+		"prettyOmit": "true", // do not print drift lines in pretty output
+	}
+}
+
+func (a *DriftAction) processDrift(context *action.Context) (*resolve.ComponentInstance, *plugin.Drift, error) {
+	instance := context.ActualStateUpdater.GetComponentInstance(a.ComponentKey)
+	if instance == nil {
+		return nil, nil, fmt.Errorf("component instance not found in actual state: %s", a.ComponentKey)
+	}
+
+	serviceObj, err := context.DesiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if serviceObj == nil {
+		return nil, nil, fmt.Errorf("service '%s/%s' in not present in policy", instance.Metadata.Key.Namespace, instance.Metadata.Key.ServiceName)
+	}
+	component := serviceObj.(*lang.Service).GetComponentsMap()[instance.Metadata.Key.ComponentName] // nolint: errcheck
+
+	// drift could be detected only for components with code
+	if component == nil || component.Code == nil {
+		return nil, nil, fmt.Errorf("detecting drift for non-code components is not supported")
+	}
+
+	clusterObj, err := context.DesiredPolicy.GetObject(lang.ClusterObject.Kind, instance.Metadata.Key.ClusterName, instance.Metadata.Key.ClusterNameSpace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if clusterObj == nil {
+		return nil, nil, fmt.Errorf("cluster '%s/%s' in not present in policy", instance.Metadata.Key.ClusterNameSpace, instance.Metadata.Key.ClusterName)
+	}
+	cluster := clusterObj.(*lang.Cluster) // nolint: errcheck
+
+	p, err := context.Plugins.ForCodeType(cluster, component.Code.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	drift, err := p.Drift(
+		context.Ctx,
+		&plugin.CodePluginInvocationParams{
+			DeployName:   instance.GetDeployName(),
+			Params:       instance.CalculatedCodeParams,
+			PluginParams: map[string]string{plugin.ParamTargetSuffix: instance.Metadata.Key.TargetSuffix},
+			EventLog:     context.EventLog,
+		},
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return instance, drift, nil
+}
@@ -107,6 +107,7 @@ func (a *EndpointsAction) processEndpoints(context *action.Context) (*resolve.Co
 	}
 
 	endpoints, err := p.Endpoints(
+		context.Ctx,
 		&plugin.CodePluginInvocationParams{
 			DeployName:   instance.GetDeployName(),
 			Params:       instance.CalculatedCodeParams,
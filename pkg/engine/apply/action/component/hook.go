@@ -0,0 +1,154 @@
+package component
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+)
+
+// HookActionObject is an informational data structure with Kind and Constructor for the action
+var HookActionObject = &runtime.Info{
+	Kind:        "action-component-hook",
+	Constructor: func() runtime.Object { return &HookAction{} },
+}
+
+// HookAction is a action which executes a hook of component instance at a certain point of its lifecycle (e.g. before
+// it gets updated or deleted). Hook code gets deployed, considered successful once it becomes ready, and then deleted
+type HookAction struct {
+	runtime.TypeKind `yaml:",inline"`
+	*action.Metadata
+	ComponentKey string
+	Hook         string
+	Params       util.NestedParameterMap
+}
+
+// NewHookAction creates new HookAction
+func NewHookAction(componentKey string, hook string, params util.NestedParameterMap) *HookAction {
+	return &HookAction{
+		TypeKind:     HookActionObject.GetTypeKind(),
+		Metadata:     action.NewMetadata(HookActionObject.Kind, componentKey, hook),
+		ComponentKey: componentKey,
+		Hook:         hook,
+		Params:       params,
+	}
+}
+
+// Apply applies the action
+func (a *HookAction) Apply(context *action.Context) (errResult error) {
+	start := time.Now()
+	defer func() {
+		if err := recover(); err != nil {
+			errResult = fmt.Errorf("panic: %s\n%s", err, string(debug.Stack()))
+		}
+
+		action.CollectMetricsFor(a, start, errResult)
+	}()
+
+	context.EventLog.NewEntry().Debugf("Executing %s hook for component instance: %s", a.Hook, a.ComponentKey)
+
+	// execute hook code in the cloud
+	err := a.processHook(context)
+	if err != nil {
+		return fmt.Errorf("%s hook failed for component instance '%s': %s", a.Hook, a.ComponentKey, err)
+	}
+
+	// once hook succeeded, it doesn't have to be executed again for already created component instance
+	instance := context.ActualStateUpdater.GetComponentInstance(a.ComponentKey)
+	if instance != nil && util.ContainsString(instance.PendingHooks, a.Hook) {
+		return context.ActualStateUpdater.UpdateComponentInstance(a.ComponentKey, func(obj *resolve.ComponentInstance) {
+			pendingHooks := []string{}
+			for _, hook := range obj.PendingHooks {
+				if hook != a.Hook {
+					pendingHooks = append(pendingHooks, hook)
+				}
+			}
+			obj.PendingHooks = pendingHooks
+		})
+	}
+
+	return nil
+}
+
+// DescribeChanges returns text-based description of changes that will be applied
+func (a *HookAction) DescribeChanges() util.NestedParameterMap {
+	return util.NestedParameterMap{
+		"kind":   a.Kind,
+		"key":    a.ComponentKey,
+		"hook":   a.Hook,
+		"params": a.Params,
+		"pretty": fmt.Sprintf("[!] %s (%s hook)", a.ComponentKey, a.Hook),
+	}
+}
+
+func (a *HookAction) processHook(context *action.Context) error {
+	// pre-delete hook gets executed for component instance, which is only present in actual state
+	instance := context.DesiredState.ComponentInstanceMap[a.ComponentKey]
+	if instance == nil || a.Hook == lang.HookPreDelete {
+		instance = context.ActualStateUpdater.GetComponentInstance(a.ComponentKey)
+	}
+	if instance == nil {
+		return fmt.Errorf("component instance not found: %s", a.ComponentKey)
+	}
+
+	serviceObj, err := context.DesiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
+	if err != nil {
+		return err
+	}
+	if serviceObj == nil {
+		return fmt.Errorf("service '%s/%s' in not present in policy", instance.Metadata.Key.Namespace, instance.Metadata.Key.ServiceName)
+	}
+	component := serviceObj.(*lang.Service).GetComponentsMap()[instance.Metadata.Key.ComponentName] // nolint: errcheck
+
+	var code *lang.Code
+	if component != nil {
+		code = component.Hooks.Get(a.Hook)
+	}
+	if code == nil {
+		// hook has been removed from the policy since component instance had been created or updated
+		context.EventLog.NewEntry().Warningf("Skipping %s hook for component instance, it's no longer defined in policy: %s", a.Hook, a.ComponentKey)
+		return nil
+	}
+
+	clusterObj, err := context.DesiredPolicy.GetObject(lang.ClusterObject.Kind, instance.Metadata.Key.ClusterName, instance.Metadata.Key.ClusterNameSpace)
+	if err != nil {
+		return err
+	}
+	if clusterObj == nil {
+		return fmt.Errorf("cluster '%s/%s' in not present in policy", instance.Metadata.Key.ClusterNameSpace, instance.Metadata.Key.ClusterName)
+	}
+	cluster := clusterObj.(*lang.Cluster) // nolint: errcheck
+
+	p, err := context.Plugins.ForCodeType(cluster, code.Type)
+	if err != nil {
+		return err
+	}
+
+	invocation := &plugin.CodePluginInvocationParams{
+		DeployName:   instance.GetHookDeployName(a.Hook),
+		Params:       a.Params,
+		PluginParams: map[string]string{plugin.ParamTargetSuffix: instance.Metadata.Key.TargetSuffix},
+		EventLog:     context.EventLog,
+	}
+
+	context.EventLog.NewEntry().Infof("Executing %s hook '%s' for component instance: %s", a.Hook, invocation.DeployName, a.ComponentKey)
+
+	err = p.Create(context.Ctx, invocation)
+	if err == nil {
+		err = waitForReadiness(context, p, invocation, code.ReadinessTimeout)
+	}
+
+	// hook code gets deleted after execution (even if it failed), so it can be executed again next time
+	destroyErr := p.Destroy(contextWithoutCancel(), invocation)
+	if destroyErr != nil {
+		context.EventLog.NewEntry().Errorf("Error while deleting code of %s hook '%s', it has to be deleted manually (see 'aptomictl state orphans'): %s", a.Hook, invocation.DeployName, destroyErr)
+	}
+
+	return err
+}
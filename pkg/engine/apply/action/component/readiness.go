@@ -0,0 +1,60 @@
+package component
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+)
+
+// ReadinessActionObject is an informational data structure with Kind and Constructor for the action
+var ReadinessActionObject = &runtime.Info{
+	Kind:        "action-component-readiness",
+	Constructor: func() runtime.Object { return &ReadinessAction{} },
+}
+
+// ReadinessAction is a action which makes sure that component instance, which didn't become ready after it had been
+// created or updated, is ready before component instances depending on it get processed. Waiting for readiness itself
+// is done by the applier (see apply.WaitForReadiness), as it shouldn't take up any of concurrency slots
+type ReadinessAction struct {
+	runtime.TypeKind `yaml:",inline"`
+	*action.Metadata
+	ComponentKey string
+}
+
+// NewReadinessAction creates new ReadinessAction
+func NewReadinessAction(componentKey string) *ReadinessAction {
+	return &ReadinessAction{
+		TypeKind:     ReadinessActionObject.GetTypeKind(),
+		Metadata:     action.NewMetadata(ReadinessActionObject.Kind, componentKey),
+		ComponentKey: componentKey,
+	}
+}
+
+// Apply applies the action
+func (a *ReadinessAction) Apply(context *action.Context) (errResult error) {
+	start := time.Now()
+	defer func() {
+		if err := recover(); err != nil {
+			errResult = fmt.Errorf("panic: %s\n%s", err, string(debug.Stack()))
+		}
+
+		action.CollectMetricsFor(a, start, errResult)
+	}()
+
+	context.EventLog.NewEntry().Debugf("Component instance hasn't become ready since it had been created or updated: %s", a.ComponentKey)
+
+	return nil
+}
+
+// DescribeChanges returns text-based description of changes that will be applied
+func (a *ReadinessAction) DescribeChanges() util.NestedParameterMap {
+	return util.NestedParameterMap{
+		"kind":   a.Kind,
+		"key":    a.ComponentKey,
+		"pretty": fmt.Sprintf("[~] %s (waiting for readiness)", a.ComponentKey),
+	}
+}
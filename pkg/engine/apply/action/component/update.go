@@ -2,6 +2,7 @@ package component
 
 import (
 	"fmt"
+	"reflect"
 	"runtime/debug"
 	"time"
 
@@ -53,17 +54,35 @@ func (a *UpdateAction) Apply(context *action.Context) (errResult error) {
 	context.EventLog.NewEntry().Debugf("Updating component instance: %s", a.ComponentKey)
 
 	// update in the cloud
-	instance, err := a.processDeployment(context)
+	instance, swap, err := a.processDeployment(context)
 	if err != nil {
 		return fmt.Errorf("unable to update component instance '%s': %s", a.ComponentKey, err)
 	}
 
 	// update component instance code params in actual state
 	if instance.CalculatedCodeParams != nil {
-		return context.ActualStateUpdater.UpdateComponentInstance(instance.GetKey(), func(obj *resolve.ComponentInstance) {
-			obj.EndpointsUpToDate = false // invalidate endpoints, so we retrieve them again later
+		err = context.ActualStateUpdater.UpdateComponentInstance(instance.GetKey(), func(obj *resolve.ComponentInstance) {
+			if swap != nil {
+				// switch to the new copy of code after blue/green update
+				obj.DeployName = swap.deployName
+				obj.EndpointsUpToDate = true
+				obj.Endpoints = swap.endpoints
+			} else {
+				obj.EndpointsUpToDate = false // invalidate endpoints, so we retrieve them again later
+			}
 			obj.CalculatedCodeParams = instance.CalculatedCodeParams
+			obj.CalculatedHookParams = instance.CalculatedHookParams
+			obj.DataForPlugins = instance.DataForPlugins
+			obj.ResetDrift() // deployed code matches component instance after the update
 		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// old copy of code can be deleted only after actual state points to the new one
+	if swap != nil {
+		swap.destroyPrev(context)
 	}
 
 	return nil
@@ -81,50 +100,80 @@ func (a *UpdateAction) DescribeChanges() util.NestedParameterMap {
 	}
 }
 
-func (a *UpdateAction) processDeployment(context *action.Context) (*resolve.ComponentInstance, error) {
+func (a *UpdateAction) processDeployment(context *action.Context) (*resolve.ComponentInstance, *blueGreenSwap, error) {
 	instance := context.DesiredState.ComponentInstanceMap[a.ComponentKey]
 	if instance == nil {
-		return nil, fmt.Errorf("component instance not found desired state: %s", a.ComponentKey)
+		return nil, nil, fmt.Errorf("component instance not found desired state: %s", a.ComponentKey)
 	}
 
 	serviceObj, err := context.DesiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	component := serviceObj.(*lang.Service).GetComponentsMap()[instance.Metadata.Key.ComponentName] // nolint: errcheck
 
 	if component == nil {
 		// This is a service instance. Do nothing and proceed with object update
-		return instance, nil
+		return instance, nil, nil
 	}
 
 	if component.Code == nil {
 		// This is a service instance. Do nothing and proceed with object update
-		return instance, nil
+		return instance, nil, nil
+	}
+
+	// code is deployed under the name recorded in actual state, which may differ from the default one
+	actualInstance := context.ActualStateUpdater.GetComponentInstance(a.ComponentKey)
+	if actualInstance == nil {
+		return nil, nil, fmt.Errorf("component instance not found in actual state: %s", a.ComponentKey)
+	}
+
+	// if only hook params have changed, code doesn't have to be deployed again. They just get recorded in actual state
+	if !actualInstance.IsDrifted() && actualInstance.CalculatedCodeParams.DeepEqual(instance.CalculatedCodeParams) && reflect.DeepEqual(actualInstance.DataForPlugins, instance.DataForPlugins) {
+		return instance, nil, nil
 	}
 
 	context.EventLog.NewEntry().Infof("Updating a running component instance: %s ", instance.GetKey())
 
 	clusterObj, err := context.DesiredPolicy.GetObject(lang.ClusterObject.Kind, instance.Metadata.Key.ClusterName, instance.Metadata.Key.ClusterNameSpace)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	if clusterObj == nil {
-		return nil, fmt.Errorf("cluster '%s/%s' in not present in policy", instance.Metadata.Key.ClusterNameSpace, instance.Metadata.Key.ClusterName)
+		return nil, nil, fmt.Errorf("cluster '%s/%s' in not present in policy", instance.Metadata.Key.ClusterNameSpace, instance.Metadata.Key.ClusterName)
 	}
 	cluster := clusterObj.(*lang.Cluster) // nolint: errcheck
 
 	p, err := context.Plugins.ForCodeType(cluster, component.Code.Type)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return instance, p.Update(
-		&plugin.CodePluginInvocationParams{
-			DeployName:   instance.GetDeployName(),
-			Params:       instance.CalculatedCodeParams,
-			PluginParams: map[string]string{plugin.ParamTargetSuffix: instance.Metadata.Key.TargetSuffix},
-			EventLog:     context.EventLog,
+	invocation := &plugin.CodePluginInvocationParams{
+		DeployName: actualInstance.GetDeployName(),
+		Params:     instance.CalculatedCodeParams,
+		PluginParams: map[string]string{
+			plugin.ParamTargetSuffix: instance.Metadata.Key.TargetSuffix,
+			plugin.ParamAllowIngress: instance.DataForPlugins[resolve.AllowIngres],
 		},
-	)
+		EventLog: context.EventLog,
+	}
+
+	if component.Code.IsBlueGreen() {
+		// new copy of code gets deployed with new parameters, while the old one has to be deleted with the old ones
+		prev := &plugin.CodePluginInvocationParams{
+			DeployName: actualInstance.GetDeployName(),
+			Params:     actualInstance.CalculatedCodeParams,
+			PluginParams: map[string]string{
+				plugin.ParamTargetSuffix: actualInstance.Metadata.Key.TargetSuffix,
+				plugin.ParamAllowIngress: actualInstance.DataForPlugins[resolve.AllowIngres],
+			},
+			EventLog: context.EventLog,
+		}
+		invocation.DeployName = actualInstance.GetNextDeployName()
+		swap, err := blueGreenUpdate(context, p, prev, invocation, component.Code.ReadinessTimeout)
+		return instance, swap, err
+	}
+
+	return instance, nil, p.Update(context.Ctx, invocation)
 }
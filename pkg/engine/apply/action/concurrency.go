@@ -0,0 +1,170 @@
+package action
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConcurrencyLimits defines how many actions can be applied concurrently. Limits for clusters and code types get
+// enforced in addition to the global limit. Zero means no limit
+type ConcurrencyLimits struct {
+	// Global is a max number of actions applied concurrently
+	Global int
+
+	// Cluster is a map from cluster name to a max number of actions applied concurrently in that cluster
+	Cluster map[string]int
+
+	// CodeType is a map from code type (e.g. 'helm') to a max number of actions applied concurrently by the
+	// corresponding code plugin
+	CodeType map[string]int
+}
+
+// ParseConcurrencyLimits parses a comma-separated list of 'name=limit' pairs (e.g. 'cluster-us-east=5,cluster-eu=10')
+// into a map from name to limit. Empty string results into an empty map
+func ParseConcurrencyLimits(value string) (map[string]int, error) {
+	result := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if len(pair) <= 0 {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || len(strings.TrimSpace(parts[0])) <= 0 {
+			return nil, fmt.Errorf("invalid concurrency limit '%s': expected 'name=limit'", pair)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || limit < 0 {
+			return nil, fmt.Errorf("invalid concurrency limit '%s': limit should be a non-negative number", pair)
+		}
+		result[strings.TrimSpace(parts[0])] = limit
+	}
+	return result, nil
+}
+
+// ConcurrencyGroupsFunc returns cluster name and code type for a given action, so that the corresponding concurrency
+// limits can be applied to it. Empty values mean that the action doesn't belong to any cluster or code type
+type ConcurrencyGroupsFunc func(act Interface) (cluster string, codeType string)
+
+// ConcurrencyLimiter makes sure that concurrency limits are respected while action plan is being applied. It's safe
+// to use from multiple go routines, as well as to call its methods on nil (which means that there are no limits)
+type ConcurrencyLimiter struct {
+	limits   ConcurrencyLimits
+	groupsFn ConcurrencyGroupsFunc
+
+	mutex      sync.Mutex
+	semaphores map[string]chan struct{}
+	releases   map[Interface]func()
+}
+
+// NewConcurrencyLimiter creates a new concurrency limiter for given limits. Function groupsFn is used to determine
+// which cluster and code type an action belongs to (if it's nil, only the global limit will be enforced)
+func NewConcurrencyLimiter(limits ConcurrencyLimits, groupsFn ConcurrencyGroupsFunc) *ConcurrencyLimiter {
+	return &ConcurrencyLimiter{
+		limits:     limits,
+		groupsFn:   groupsFn,
+		semaphores: make(map[string]chan struct{}),
+		releases:   make(map[Interface]func()),
+	}
+}
+
+// concurrencyGroup is a group of actions, which share the same concurrency limit
+type concurrencyGroup struct {
+	kind  string
+	name  string
+	limit int
+}
+
+// getSemaphore returns a semaphore for a given concurrency group, creating it if needed
+func (limiter *ConcurrencyLimiter) getSemaphore(group concurrencyGroup) chan struct{} {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	key := group.kind + "/" + group.name
+	semaphore, ok := limiter.semaphores[key]
+	if !ok {
+		semaphore = make(chan struct{}, group.limit)
+		limiter.semaphores[key] = semaphore
+	}
+	return semaphore
+}
+
+// getGroups returns the list of concurrency groups with limits for a given action. Groups are always returned in the
+// same order (cluster, code type, global), so that actions can't deadlock while waiting for each other
+func (limiter *ConcurrencyLimiter) getGroups(act Interface) []concurrencyGroup {
+	result := []concurrencyGroup{}
+	if limiter.groupsFn != nil {
+		cluster, codeType := limiter.groupsFn(act)
+		if limit := limiter.limits.Cluster[cluster]; len(cluster) > 0 && limit > 0 {
+			result = append(result, concurrencyGroup{"cluster", cluster, limit})
+		}
+		if limit := limiter.limits.CodeType[codeType]; len(codeType) > 0 && limit > 0 {
+			result = append(result, concurrencyGroup{"code", codeType, limit})
+		}
+	}
+	if limiter.limits.Global > 0 {
+		result = append(result, concurrencyGroup{"global", "", limiter.limits.Global})
+	}
+	return result
+}
+
+// acquire waits until a given action can be started without exceeding any of concurrency limits. It returns a
+// function, which must be called once action is done. If ctx gets cancelled while waiting, an error is returned
+func (limiter *ConcurrencyLimiter) acquire(ctx context.Context, act Interface) (func(), error) {
+	if limiter == nil {
+		return func() {}, nil
+	}
+
+	acquired := []chan struct{}{}
+	released := false
+	release := func() {
+		limiter.mutex.Lock()
+		defer limiter.mutex.Unlock()
+		if released {
+			return
+		}
+		released = true
+		delete(limiter.releases, act)
+		for _, semaphore := range acquired {
+			<-semaphore
+		}
+	}
+
+	for _, group := range limiter.getGroups(act) {
+		semaphore := limiter.getSemaphore(group)
+		start := time.Now()
+		select {
+		case semaphore <- struct{}{}:
+			acquired = append(acquired, semaphore)
+			collectQueueMetricsFor(group, start)
+		case <-ctx.Done():
+			release()
+			return nil, ctx.Err()
+		}
+	}
+
+	limiter.mutex.Lock()
+	limiter.releases[act] = release
+	limiter.mutex.Unlock()
+
+	return release, nil
+}
+
+// Release makes concurrency slots taken by a given action available to other actions before the action is done (e.g.
+// when it's only waiting for something to happen and not doing any work)
+func (limiter *ConcurrencyLimiter) Release(act Interface) {
+	if limiter == nil {
+		return
+	}
+
+	limiter.mutex.Lock()
+	release := limiter.releases[act]
+	limiter.mutex.Unlock()
+
+	if release != nil {
+		release()
+	}
+}
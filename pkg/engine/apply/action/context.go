@@ -1,6 +1,8 @@
 package action
 
 import (
+	"context"
+
 	"github.com/Aptomi/aptomi/pkg/engine/actual"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
@@ -12,6 +14,9 @@ import (
 // Context is a data struct that will be passed into all state update actions, giving actions access to desired
 // policy/state, and actual state and a way to updatae it, list of plugins, event log, etc
 type Context struct {
+	// Ctx gets cancelled when action is no longer expected to run (e.g. it timed out or revision got cancelled)
+	Ctx context.Context
+
 	DesiredPolicy      *lang.Policy
 	DesiredState       *resolve.PolicyResolution
 	ActualStateUpdater actual.StateUpdater
@@ -21,8 +26,9 @@ type Context struct {
 }
 
 // NewContext creates a new instance of Context
-func NewContext(desiredPolicy *lang.Policy, desiredState *resolve.PolicyResolution, actualStateUpdater actual.StateUpdater, externalData *external.Data, plugins plugin.Registry, eventLog *event.Log) *Context {
+func NewContext(ctx context.Context, desiredPolicy *lang.Policy, desiredState *resolve.PolicyResolution, actualStateUpdater actual.StateUpdater, externalData *external.Data, plugins plugin.Registry, eventLog *event.Log) *Context {
 	return &Context{
+		Ctx:                ctx,
 		DesiredPolicy:      desiredPolicy,
 		DesiredState:       desiredState,
 		ActualStateUpdater: actualStateUpdater,
@@ -0,0 +1,114 @@
+package action
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+// FailureBudget defines how many actions are allowed to fail while applying action plan. Once it gets exceeded, no
+// new action nodes are started and the remaining actions get skipped
+type FailureBudget struct {
+	// MaxFailed is the max number of failed actions (or the max percentage of failed actions, if Percentage is set)
+	MaxFailed int
+
+	// Percentage defines whether MaxFailed is a percentage of the total number of actions
+	Percentage bool
+}
+
+// ParseFailureBudget parses failure budget from a string, which is either an absolute number of actions (e.g. '10')
+// or a percentage of the total number of actions (e.g. '20%'). Empty string means no failure budget (nil)
+func ParseFailureBudget(value string) (*FailureBudget, error) {
+	value = strings.TrimSpace(value)
+	if len(value) <= 0 {
+		return nil, nil
+	}
+
+	result := &FailureBudget{}
+	if strings.HasSuffix(value, "%") {
+		result.Percentage = true
+		value = strings.TrimSuffix(value, "%")
+	}
+
+	maxFailed, err := strconv.Atoi(value)
+	if err != nil || maxFailed < 0 || (result.Percentage && maxFailed > 100) {
+		return nil, fmt.Errorf("invalid failure budget '%s': expected a non-negative number of actions or a percentage", value)
+	}
+	result.MaxFailed = maxFailed
+
+	return result, nil
+}
+
+// String returns failure budget as a string
+func (budget *FailureBudget) String() string {
+	if budget == nil {
+		return ""
+	}
+	if budget.Percentage {
+		return fmt.Sprintf("%d%%", budget.MaxFailed)
+	}
+	return strconv.Itoa(budget.MaxFailed)
+}
+
+// IsExceeded returns true if a given number of failed actions out of total exceeds the failure budget
+func (budget *FailureBudget) IsExceeded(failed uint32, total uint32) bool {
+	if budget == nil {
+		return false
+	}
+	if budget.Percentage {
+		return uint64(failed)*100 > uint64(budget.MaxFailed)*uint64(total)
+	}
+	return failed > uint32(budget.MaxFailed)
+}
+
+// FailureBudgetTracker counts failed actions while action plan is being applied and tells when the failure budget
+// gets exceeded. It's safe to use from multiple go routines, as well as to call its methods on nil (which means
+// that there is no failure budget)
+type FailureBudgetTracker struct {
+	budget *FailureBudget
+	total  uint32
+	failed uint32
+}
+
+// NewFailureBudgetTracker creates a new tracker for a given failure budget. If budget is nil, nil is returned
+func NewFailureBudgetTracker(budget *FailureBudget) *FailureBudgetTracker {
+	if budget == nil {
+		return nil
+	}
+	return &FailureBudgetTracker{
+		budget: budget,
+	}
+}
+
+// setTotal sets the total number of actions in action plan
+func (tracker *FailureBudgetTracker) setTotal(total uint32) {
+	if tracker == nil {
+		return
+	}
+	atomic.StoreUint32(&tracker.total, total)
+}
+
+// recordFailure records a failed action
+func (tracker *FailureBudgetTracker) recordFailure() {
+	if tracker == nil {
+		return
+	}
+	atomic.AddUint32(&tracker.failed, 1)
+}
+
+// IsExceeded returns true if failure budget has been exceeded
+func (tracker *FailureBudgetTracker) IsExceeded() bool {
+	if tracker == nil {
+		return false
+	}
+	return tracker.budget.IsExceeded(atomic.LoadUint32(&tracker.failed), atomic.LoadUint32(&tracker.total))
+}
+
+// Reason returns a human-readable reason of why applying action plan has been aborted
+func (tracker *FailureBudgetTracker) Reason() string {
+	if tracker == nil {
+		return ""
+	}
+	return fmt.Sprintf("failure budget exceeded: %d out of %d actions failed (budget: %s)", atomic.LoadUint32(&tracker.failed), atomic.LoadUint32(&tracker.total), tracker.budget)
+}
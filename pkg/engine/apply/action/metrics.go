@@ -26,11 +26,22 @@ var (
 		},
 		[]string{"kind", "name", "success"},
 	)
+
+	mActionQueueDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:        "aptomi_action_queue_duration_seconds",
+			Help:        "Time spent by actions waiting for a concurrency limit, labeled with limit kind (global, cluster, code) and name.",
+			ConstLabels: prometheus.Labels{"service": "aptomi"},
+			Buckets:     []float64{.01, .05, .1, .5, 1, 2.5, 5, 10, 20, 30, 50, 100, 300},
+		},
+		[]string{"limit", "name"},
+	)
 )
 
 func init() {
 	prometheus.MustRegister(mActionCount)
 	prometheus.MustRegister(mActionDuration)
+	prometheus.MustRegister(mActionQueueDuration)
 
 }
 
@@ -41,3 +52,8 @@ func CollectMetricsFor(action Interface, start time.Time, err error) {
 	mActionCount.WithLabelValues(labels...).Inc()
 	mActionDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
 }
+
+// collectQueueMetricsFor collects metrics for the time spent by an action waiting for a given concurrency limit
+func collectQueueMetricsFor(group concurrencyGroup, start time.Time) {
+	mActionQueueDuration.WithLabelValues(group.kind, group.name).Observe(time.Since(start).Seconds())
+}
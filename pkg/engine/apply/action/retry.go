@@ -0,0 +1,196 @@
+package action
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util/retry"
+)
+
+// ComponentRetryStateObject is an informational data structure with Kind and Constructor for ComponentRetryState
+var ComponentRetryStateObject = &runtime.Info{
+	Kind:        "component-retry-state",
+	Storable:    true,
+	Versioned:   false,
+	Constructor: func() runtime.Object { return &ComponentRetryState{} },
+}
+
+// RetryConfig defines how actions for failing component instances get retried
+type RetryConfig struct {
+	// Backoff is a delay before the first retry. It doubles after every failed attempt
+	Backoff time.Duration
+
+	// MaxBackoff is a maximum delay between retries (zero means no limit)
+	MaxBackoff time.Duration
+
+	// MaxAttempts is a number of failed attempts, after which component instance is considered permanently failed
+	// (zero means no limit)
+	MaxAttempts int
+}
+
+// ComponentRetryState is a retry state of a component instance, for which actions failed to be applied
+type ComponentRetryState struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// ComponentKey is a key of the component instance
+	ComponentKey string
+
+	// PolicyGen is a policy generation, under which attempts were made. Once policy changes, retry state gets reset
+	PolicyGen runtime.Generation
+
+	// Attempts is a number of failed attempts
+	Attempts int
+
+	// LastError is an error returned by the last failed attempt
+	LastError string
+
+	// LastAttemptAt is a time of the last failed attempt
+	LastAttemptAt time.Time
+
+	// NextRetryAt is a time, after which actions for the component instance can be retried
+	NextRetryAt time.Time
+
+	// Failed is set to true when the maximum number of attempts has been reached. Component instance will not be
+	// retried until policy changes or retry state gets reset
+	Failed bool
+}
+
+// NewComponentRetryState creates a new empty ComponentRetryState for a given component instance and policy generation
+func NewComponentRetryState(componentKey string, policyGen runtime.Generation) *ComponentRetryState {
+	return &ComponentRetryState{
+		TypeKind:     ComponentRetryStateObject.GetTypeKind(),
+		ComponentKey: componentKey,
+		PolicyGen:    policyGen,
+	}
+}
+
+// GetName returns name of the ComponentRetryState
+func (state *ComponentRetryState) GetName() string {
+	return state.ComponentKey
+}
+
+// GetNamespace returns namespace of the ComponentRetryState
+func (state *ComponentRetryState) GetNamespace() string {
+	return runtime.SystemNS
+}
+
+// IsActive returns true if retry state applies to a given policy generation. Retry states recorded under older
+// policy generations are ignored
+func (state *ComponentRetryState) IsActive(policyGen runtime.Generation) bool {
+	return state.PolicyGen == policyGen
+}
+
+// IsDue returns true if actions for the component instance can be retried at a given time
+func (state *ComponentRetryState) IsDue(now time.Time) bool {
+	return !state.Failed && !now.Before(state.NextRetryAt)
+}
+
+// CheckAllowed returns an error if actions for the component instance can't be attempted at a given time
+func (state *ComponentRetryState) CheckAllowed(now time.Time) error {
+	if state.Failed {
+		return fmt.Errorf("component instance permanently failed after %d attempts, last error: %s", state.Attempts, state.LastError)
+	}
+	if now.Before(state.NextRetryAt) {
+		return fmt.Errorf("component instance failed %d time(s), next retry at %s", state.Attempts, state.NextRetryAt.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// RecordFailure records a failed attempt, calculating when the next attempt can be made
+func (state *ComponentRetryState) RecordFailure(err error, now time.Time, config RetryConfig) {
+	state.Attempts++
+	state.LastError = err.Error()
+	state.LastAttemptAt = now
+	state.NextRetryAt = now.Add(retry.Backoff(state.Attempts, config.Backoff, config.MaxBackoff))
+	state.Failed = config.MaxAttempts > 0 && state.Attempts >= config.MaxAttempts
+}
+
+// RetryTracker keeps track of failed attempts to apply actions for component instances, so that failing component
+// instances get retried with an exponential backoff and eventually get marked as permanently failed
+type RetryTracker interface {
+	// CheckAllowed returns an error if actions for a given component instance can't be attempted right now
+	CheckAllowed(componentKey string) error
+
+	// RecordSuccess resets retry state for a given component instance
+	RecordSuccess(componentKey string)
+
+	// RecordFailure records a failed attempt for a given component instance
+	RecordFailure(componentKey string, err error)
+}
+
+// RetryTrackerImpl is a default thread-safe in-memory implementation of RetryTracker
+type RetryTrackerImpl struct {
+	mutex     sync.Mutex
+	config    RetryConfig
+	policyGen runtime.Generation
+
+	// States is a map from component instance key to its retry state
+	States map[string]*ComponentRetryState
+}
+
+// NewRetryTrackerImpl creates a new default thread-safe in-memory implementation of RetryTracker
+func NewRetryTrackerImpl(config RetryConfig, policyGen runtime.Generation) *RetryTrackerImpl {
+	return &RetryTrackerImpl{
+		config:    config,
+		policyGen: policyGen,
+		States:    make(map[string]*ComponentRetryState),
+	}
+}
+
+// CheckAllowed returns an error if actions for a given component instance can't be attempted right now
+func (tracker *RetryTrackerImpl) CheckAllowed(componentKey string) error {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	state, ok := tracker.States[componentKey]
+	if !ok || !state.IsActive(tracker.policyGen) {
+		return nil
+	}
+	return state.CheckAllowed(time.Now())
+}
+
+// RecordSuccess resets retry state for a given component instance
+func (tracker *RetryTrackerImpl) RecordSuccess(componentKey string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	delete(tracker.States, componentKey)
+}
+
+// RecordFailure records a failed attempt for a given component instance
+func (tracker *RetryTrackerImpl) RecordFailure(componentKey string, err error) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	state, ok := tracker.States[componentKey]
+	if !ok || !state.IsActive(tracker.policyGen) {
+		state = NewComponentRetryState(componentKey, tracker.policyGen)
+		tracker.States[componentKey] = state
+	}
+	state.RecordFailure(err, time.Now(), tracker.config)
+}
+
+// HasState returns true if there is a retry state for a given component instance, including the ones recorded for
+// previous policy generations
+func (tracker *RetryTrackerImpl) HasState(componentKey string) bool {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	_, ok := tracker.States[componentKey]
+	return ok
+}
+
+// GetState returns a copy of retry state for a given component instance, or nil if there is no active retry state
+func (tracker *RetryTrackerImpl) GetState(componentKey string) *ComponentRetryState {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+
+	state, ok := tracker.States[componentKey]
+	if !ok || !state.IsActive(tracker.policyGen) {
+		return nil
+	}
+	result := *state
+	return &result
+}
@@ -1,6 +1,7 @@
 package apply
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
 	"strconv"
@@ -412,6 +413,7 @@ func RunEngine(b *testing.B, testName string, desiredPolicy *lang.Policy, extern
 		actions,
 		event.NewLog(logrus.DebugLevel, "test-apply"),
 		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
 	)
 	actualState = applyAndCheckBenchmark(b, applier, action.ApplyResult{Success: applier.actionPlan.NumberOfActions(), Failed: 0, Skipped: 0})
 
@@ -434,6 +436,7 @@ func RunEngine(b *testing.B, testName string, desiredPolicy *lang.Policy, extern
 		actions,
 		event.NewLog(logrus.DebugLevel, "test-apply"),
 		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
 	)
 	_ = applyAndCheckBenchmark(b, applier, action.ApplyResult{Success: applier.actionPlan.NumberOfActions(), Failed: 0, Skipped: 0})
 
@@ -442,7 +445,7 @@ func RunEngine(b *testing.B, testName string, desiredPolicy *lang.Policy, extern
 
 func applyAndCheckBenchmark(b *testing.B, apply *EngineApply, expectedResult action.ApplyResult) *resolve.PolicyResolution {
 	b.Helper()
-	actualState, result := apply.Apply(50)
+	actualState, result := apply.Apply(context.Background(), action.ConcurrencyLimits{Global: 50}, 0, nil)
 
 	t := &testing.T{}
 	ok := assert.Equal(t, expectedResult.Success, result.Success, "Number of successfully executed actions")
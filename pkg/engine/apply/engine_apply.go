@@ -1,8 +1,15 @@
 package apply
 
 import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
 	"github.com/Aptomi/aptomi/pkg/engine/actual"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/external"
@@ -27,21 +34,48 @@ type EngineApply struct {
 
 	// Result/progress updater
 	updater action.ApplyResultUpdater
+
+	// Tracker of failed attempts, which makes failing component instances to be retried with an exponential backoff
+	retryTracker action.RetryTracker
+
+	// Keys of component instances, deletion of which is being held until approved
+	heldDeletions map[string]bool
+
+	// Keys of component instances, changes to which are being deferred until allowed by maintenance windows and freezes
+	// (or until enforcement gets resumed)
+	deferredChanges map[string]bool
+
+	// Checker of whether changes to component instances have been paused while actions are being applied, as well as
+	// keys of component instances, changes to which have been skipped because of that
+	pauseChecker  PauseChecker
+	pausedMutex   sync.Mutex
+	pausedChanges map[string]bool
+
+	// Record of the action plan with the outcome of every action
+	planRecord *action.PlanRecord
+
+	// How long to wait for code component instances to become ready after create/update (zero means no waiting) and
+	// how often their readiness gets checked
+	readinessTimeout       time.Duration
+	readinessCheckInterval time.Duration
 }
 
 // NewEngineApply creates an instance of EngineApply
 // todo(slukjanov): make sure that plugins are created once per revision, b/c we need to cache only for single policy, when it changed some credentials could change as well
 // todo(slukjanov): run cleanup on all plugins after apply done for the revision
-func NewEngineApply(desiredPolicy *lang.Policy, desiredState *resolve.PolicyResolution, actualStateUpdater actual.StateUpdater, externalData *external.Data, plugins plugin.Registry, actionPlan *action.Plan, eventLog *event.Log, updater action.ApplyResultUpdater) *EngineApply {
+func NewEngineApply(desiredPolicy *lang.Policy, desiredState *resolve.PolicyResolution, actualStateUpdater actual.StateUpdater, externalData *external.Data, plugins plugin.Registry, actionPlan *action.Plan, eventLog *event.Log, updater action.ApplyResultUpdater, retryTracker action.RetryTracker) *EngineApply {
 	return &EngineApply{
-		desiredPolicy:      desiredPolicy,
-		desiredState:       desiredState,
-		actualStateUpdater: actualStateUpdater,
-		externalData:       externalData,
-		plugins:            plugins,
-		actionPlan:         actionPlan,
-		eventLog:           eventLog,
-		updater:            updater,
+		desiredPolicy:          desiredPolicy,
+		desiredState:           desiredState,
+		actualStateUpdater:     actualStateUpdater,
+		externalData:           externalData,
+		plugins:                plugins,
+		actionPlan:             actionPlan,
+		eventLog:               eventLog,
+		updater:                updater,
+		retryTracker:           retryTracker,
+		planRecord:             action.NewPlanRecord(actionPlan),
+		readinessCheckInterval: defaultReadinessCheckInterval,
 	}
 }
 
@@ -52,9 +86,18 @@ func NewEngineApply(desiredPolicy *lang.Policy, desiredState *resolve.PolicyReso
 // As actions get executed, they will instantiate/update/delete components according to the resolved
 // policy, as well as configure the underlying cloud components appropriately. In case of errors (e.g. cloud is not
 // available), actual state may not be equal to desired state after performing all the actions.
-func (apply *EngineApply) Apply(maxConcurrentActions int) (*resolve.PolicyResolution, *action.ApplyResult) {
+//
+// Actions are applied concurrently, respecting the global concurrency limit as well as limits for clusters and code
+// types. Limits for clusters can also be declared in cluster labels (see lang.LabelMaxConcurrentActions), while limits
+// from the config take precedence.
+//
+// Every action is given at most actionTimeout to complete (zero means no timeout). If ctx gets cancelled, actions
+// in progress get interrupted and the remaining actions get skipped. If failure budget is given and too many actions
+// fail, apply gets aborted and the remaining actions get skipped as well.
+func (apply *EngineApply) Apply(ctx context.Context, limits action.ConcurrencyLimits, actionTimeout time.Duration, failureBudget *action.FailureBudget) (*resolve.PolicyResolution, *action.ApplyResult) {
 	// process all actions
 	context := action.NewContext(
+		ctx,
 		apply.desiredPolicy,
 		apply.desiredState,
 		apply.actualStateUpdater,
@@ -64,14 +107,183 @@ func (apply *EngineApply) Apply(maxConcurrentActions int) (*resolve.PolicyResolu
 	)
 
 	// Note that the action plan will call function in different go routines by apply
-	result := apply.actionPlan.Apply(action.WrapParallelWithLimit(maxConcurrentActions, func(act action.Interface) error {
-		err := act.Apply(context)
+	budgetTracker := action.NewFailureBudgetTracker(failureBudget)
+	limiter := action.NewConcurrencyLimiter(apply.getConcurrencyLimits(limits), apply.getConcurrencyGroups)
+	result := apply.actionPlan.Apply(ctx, func(act action.Interface) (err error) {
+		defer func() {
+			apply.planRecord.Finished(act, err)
+		}()
+
+		// actions, which have been waiting for their turn while apply got cancelled or aborted, don't get started
+		if ctx.Err() != nil || budgetTracker.IsExceeded() {
+			return action.ErrSkipped
+		}
+		if apply.isHeld(act) {
+			context.EventLog.NewEntry().Warningf("action '%s' is held until deletion of protected component instance gets approved", act)
+			return action.ErrSkipped
+		}
+		if apply.isDeferred(act) {
+			context.EventLog.NewEntry().Warningf("action '%s' is deferred until changes to component instance are allowed by maintenance (or enforcement is resumed)", act)
+			return action.ErrSkipped
+		}
+		if apply.isPaused(act) {
+			context.EventLog.NewEntry().Warningf("action '%s' is deferred until enforcement is resumed (it has been paused while actions were being applied)", act)
+			return action.ErrSkipped
+		}
+		apply.planRecord.Started(act)
+		err = apply.applyWithRetries(act, context, actionTimeout)
+		if err == nil {
+			// component instances, which depend on this one, don't get processed until it becomes ready. Waiting
+			// doesn't take up concurrency slots, so other actions can proceed in the meantime
+			limiter.Release(act)
+			err = apply.waitForReadiness(act, context)
+		}
 		if err != nil {
 			context.EventLog.NewEntry().Errorf("error while applying action '%s': %s", act, err)
 		}
 		return err
-	}), apply.updater)
+	}, apply.updater, budgetTracker, limiter)
+	apply.planRecord.Done()
 
 	// No errors occurred
 	return apply.actualStateUpdater.GetUpdatedActualState(), result
 }
+
+// GetPlanRecord returns the record of the action plan, which includes the outcome of every action once it's applied
+func (apply *EngineApply) GetPlanRecord() *action.PlanRecord {
+	return apply.planRecord
+}
+
+// getConcurrencyLimits returns concurrency limits, adding limits declared in cluster labels to the given ones
+func (apply *EngineApply) getConcurrencyLimits(limits action.ConcurrencyLimits) action.ConcurrencyLimits {
+	result := action.ConcurrencyLimits{
+		Global:   limits.Global,
+		Cluster:  make(map[string]int),
+		CodeType: limits.CodeType,
+	}
+	for _, obj := range apply.desiredPolicy.GetObjectsByKind(lang.ClusterObject.Kind) {
+		cluster := obj.(*lang.Cluster) // nolint: errcheck
+		limit, err := cluster.GetMaxConcurrentActions()
+		if err != nil {
+			apply.eventLog.NewEntry().Warningf("ignoring concurrency limit for cluster '%s': %s", cluster.Name, err)
+			continue
+		}
+		if limit > 0 {
+			result.Cluster[cluster.Name] = limit
+		}
+	}
+	for name, limit := range limits.Cluster {
+		result.Cluster[name] = limit
+	}
+	return result
+}
+
+// getConcurrencyGroups returns cluster name and code type for a given action, so that the corresponding concurrency
+// limits can be applied to it. Only actions for code component instances belong to a cluster and a code type
+func (apply *EngineApply) getConcurrencyGroups(act action.Interface) (string, string) {
+	key, ok := act.DescribeChanges()["key"].(string)
+	if !ok || len(key) <= 0 {
+		return "", ""
+	}
+
+	// component instance may only be present in actual state (e.g. when it's being deleted)
+	instance := apply.desiredState.ComponentInstanceMap[key]
+	if instance == nil {
+		instance = apply.actualStateUpdater.GetComponentInstance(key)
+	}
+	if instance == nil || !instance.IsCode {
+		return "", ""
+	}
+
+	hook := ""
+	if hookAction, isHook := act.(*component.HookAction); isHook {
+		hook = hookAction.Hook
+	}
+
+	codeType := ""
+	serviceObj, err := apply.desiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
+	if err == nil && serviceObj != nil {
+		component := serviceObj.(*lang.Service).GetComponentsMap()[instance.Metadata.Key.ComponentName] // nolint: errcheck
+		if component != nil && component.Code != nil {
+			codeType = component.Code.Type
+		}
+
+		// hook code may be of a different type than code of the component itself
+		if component != nil && component.Hooks.Get(hook) != nil {
+			codeType = component.Hooks.Get(hook).Type
+		}
+	}
+
+	return instance.Metadata.Key.ClusterName, codeType
+}
+
+// applyWithRetries applies an action, unless the corresponding component instance has failed before and is still
+// waiting for its next retry. It records the outcome, so failing component instances get retried with a backoff
+func (apply *EngineApply) applyWithRetries(act action.Interface, context *action.Context, timeout time.Duration) error {
+	key, ok := act.DescribeChanges()["key"].(string)
+	if !ok || len(key) <= 0 {
+		return applyWithTimeout(act, context, timeout)
+	}
+
+	err := apply.retryTracker.CheckAllowed(key)
+	if err != nil {
+		return err
+	}
+
+	err = applyWithTimeout(act, context, timeout)
+	if err != nil {
+		// interrupted actions don't count as failed attempts
+		if context.Ctx.Err() == nil {
+			apply.retryTracker.RecordFailure(key, err)
+		}
+		return err
+	}
+
+	// successful hook doesn't mean that the main action of component instance is going to succeed as well
+	if _, isHook := act.(*component.HookAction); !isHook {
+		apply.retryTracker.RecordSuccess(key)
+	}
+	return nil
+}
+
+// applyWithTimeout applies an action, giving it at most a specified amount of time to complete. If the action doesn't
+// complete in time or the parent context gets cancelled, an error is returned right away, even if the action is still
+// running (e.g. a plugin ignores context). Changes to actual state are not allowed after the action got interrupted,
+// so it can't change anything after its outcome has been recorded
+func applyWithTimeout(act action.Interface, parent *action.Context, timeout time.Duration) error {
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent.Ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent.Ctx)
+	}
+	defer cancel()
+
+	// every action gets its own copy of context, with its own deadline
+	actContext := *parent
+	actContext.Ctx = ctx
+	actContext.ActualStateUpdater = actual.NewContextStateUpdater(ctx, parent.ActualStateUpdater)
+
+	// run action in a separate go routine, so it can be interrupted while a plugin is still working
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				done <- fmt.Errorf("panic: %s\n%s", err, string(debug.Stack()))
+			}
+		}()
+		done <- act.Apply(&actContext)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		// action keeps running in background until it returns, its result gets dropped into the buffered channel
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("action timed out after %s", timeout)
+		}
+		return fmt.Errorf("action cancelled: %s", ctx.Err())
+	}
+}
@@ -1,12 +1,17 @@
 package apply
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/engine/actual"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
 	"github.com/Aptomi/aptomi/pkg/engine/diff"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/event"
@@ -38,6 +43,7 @@ func TestApplyComponentCreateSuccess(t *testing.T) {
 		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
 		event.NewLog(logrus.DebugLevel, "test-apply"),
 		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
 	)
 
 	// check actual state
@@ -76,6 +82,7 @@ func checkApplyComponentCreateFail(t *testing.T, failAsPanic bool) {
 		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
 		event.NewLog(logrus.DebugLevel, "test-apply"),
 		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
 	)
 	// check actual state
 	assert.Equal(t, 0, len(actualState.ComponentInstanceMap), "Actual state should be empty")
@@ -87,6 +94,413 @@ func checkApplyComponentCreateFail(t *testing.T, failAsPanic bool) {
 	assert.Equal(t, 0, len(actualState.ComponentInstanceMap), "Actual state should not be touched by apply()")
 }
 
+func TestApplyPlanRecord(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy
+	desired := newTestData(t, makePolicyBuilder())
+
+	// process all actions (and make component fail deployment)
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		mockRegistry(false, false),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	)
+
+	// before apply, all actions are pending
+	countByStatus := func() map[string]int {
+		result := make(map[string]int)
+		for _, node := range applier.GetPlanRecord().Nodes {
+			for _, actionRecord := range node.Actions {
+				result[actionRecord.Status]++
+			}
+		}
+		return result
+	}
+	assert.Equal(t, map[string]int{action.ActionStatusPending: 4}, countByStatus(), "All actions should be pending before apply")
+
+	// after apply, outcome of every action should be recorded
+	applyAndCheck(t, applier, action.ApplyResult{Success: 0, Failed: 1, Skipped: 3})
+	assert.Equal(t, map[string]int{action.ActionStatusFailed: 1, action.ActionStatusSkipped: 3}, countByStatus(), "Outcome of every action should be recorded")
+	for _, node := range applier.GetPlanRecord().Nodes {
+		for _, actionRecord := range node.Actions {
+			if actionRecord.Status == action.ActionStatusFailed {
+				assert.NotEmpty(t, actionRecord.Error, "Error should be recorded for failed action")
+				assert.False(t, actionRecord.StartedAt.After(actionRecord.FinishedAt), "Start and finish time should be recorded for failed action")
+			}
+		}
+	}
+
+	// action graph can be exported in DOT format
+	dot := applier.GetPlanRecord().AsDOT()
+	assert.Contains(t, dot, "digraph plan {", "Action graph should be exported in DOT format")
+	assert.Contains(t, dot, "->", "Action graph should contain edges")
+}
+
+func TestApplyComponentCreateRetryBackoff(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy
+	desired := newTestData(t, makePolicyBuilder())
+
+	// component instance will be permanently failed after 2 attempts
+	retryTracker := action.NewRetryTrackerImpl(action.RetryConfig{Backoff: time.Hour, MaxBackoff: 2 * time.Hour, MaxAttempts: 2}, 0)
+	newApplier := func() *EngineApply {
+		return NewEngineApply(
+			desired.policy(),
+			desired.resolution(),
+			actual.NewNoOpActionStateUpdater(actualState),
+			desired.external(),
+			mockRegistry(false, false),
+			diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+			event.NewLog(logrus.DebugLevel, "test-apply"),
+			action.NewApplyResultUpdaterImpl(),
+			retryTracker,
+		)
+	}
+
+	// first attempt fails and gets recorded
+	applyAndCheck(t, newApplier(), action.ApplyResult{Success: 0, Failed: 1, Skipped: 3})
+	assert.Equal(t, 1, len(retryTracker.States), "Failed component instance should have retry state")
+	var key string
+	for k := range retryTracker.States {
+		key = k
+	}
+	state := retryTracker.GetState(key)
+	assert.Equal(t, 1, state.Attempts, "Number of attempts should be correct")
+	assert.False(t, state.Failed, "Component instance should not be permanently failed yet")
+	assert.WithinDuration(t, time.Now().Add(time.Hour), state.NextRetryAt, time.Minute, "Next retry time should be correct")
+
+	// second apply happens before the next retry time, so the attempt doesn't get made
+	applyAndCheck(t, newApplier(), action.ApplyResult{Success: 0, Failed: 1, Skipped: 3})
+	assert.Equal(t, 1, retryTracker.GetState(key).Attempts, "Attempt should not be made before the next retry time")
+
+	// once the next retry time comes, the attempt gets made and component instance becomes permanently failed
+	retryTracker.States[key].NextRetryAt = time.Now()
+	applyAndCheck(t, newApplier(), action.ApplyResult{Success: 0, Failed: 1, Skipped: 3})
+	state = retryTracker.GetState(key)
+	assert.Equal(t, 2, state.Attempts, "Number of attempts should be correct")
+	assert.True(t, state.Failed, "Component instance should be permanently failed")
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), state.NextRetryAt, time.Minute, "Backoff should be capped")
+
+	// permanently failed component instance doesn't get retried
+	retryTracker.States[key].NextRetryAt = time.Now()
+	applyAndCheck(t, newApplier(), action.ApplyResult{Success: 0, Failed: 1, Skipped: 3})
+	assert.Equal(t, 2, retryTracker.GetState(key).Attempts, "Permanently failed component instance should not be retried")
+
+	// retry state gets ignored once policy changes
+	retryTracker = action.NewRetryTrackerImpl(action.RetryConfig{}, 1)
+	retryTracker.States[key] = state
+	assert.NoError(t, retryTracker.CheckAllowed(key), "Retry state from the previous policy should be ignored")
+}
+
+func TestApplyComponentCreateTimeout(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy
+	desired := newTestData(t, makePolicyBuilder())
+
+	// process all actions (and make component deployment take longer than allowed)
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		mockRegistrySleep(time.Minute),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	)
+
+	// check that component deployment timed out and the rest got skipped
+	start := time.Now()
+	actualState, result := applier.Apply(context.Background(), action.ConcurrencyLimits{Global: 50}, 50*time.Millisecond, nil)
+	assert.True(t, time.Since(start) < 10*time.Second, "Apply should not wait for timed out actions")
+	assert.Equal(t, action.ApplyResult{Success: 0, Failed: 1, Skipped: 3, Total: 4}, *result, "Apply result should be correct")
+
+	// check that actual state didn't get updated
+	assert.Equal(t, 0, len(actualState.ComponentInstanceMap), "Actual state should not be touched by apply()")
+}
+
+func TestApplyComponentCreateTimeoutIgnoredByPlugin(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy
+	desired := newTestData(t, makePolicyBuilder())
+
+	// process all actions (and make component deployment ignore the timeout and never return until released)
+	release := make(chan struct{})
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		mockRegistryIgnoringContext(release),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	)
+
+	// check that apply returned once the action timed out without waiting for it to return and the rest got skipped
+	start := time.Now()
+	actualState, result := applier.Apply(context.Background(), action.ConcurrencyLimits{Global: 50}, 50*time.Millisecond, nil)
+	assert.True(t, time.Since(start) < time.Second, "Apply should not wait for timed out actions to return")
+	assert.Equal(t, action.ApplyResult{Success: 0, Failed: 1, Skipped: 3, Total: 4}, *result, "Apply result should be correct")
+
+	// let the timed out action return and check that actual state didn't get updated by it
+	close(release)
+	time.Sleep(100 * time.Millisecond)
+	assert.Equal(t, 0, len(actualState.ComponentInstanceMap), "Actual state should not be changed by timed out action")
+}
+
+func TestApplyCancelled(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy
+	desired := newTestData(t, makePolicyBuilder())
+
+	// process all actions with a context, which is already cancelled
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		mockRegistry(true, false),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// check that all actions got skipped
+	actualState, result := applier.Apply(ctx, action.ConcurrencyLimits{Global: 50}, 0, nil)
+	assert.Equal(t, action.ApplyResult{Success: 0, Failed: 0, Skipped: 4, Total: 4, Cancelled: true}, *result, "Apply result should be correct")
+
+	// check that actual state didn't get updated
+	assert.Equal(t, 0, len(actualState.ComponentInstanceMap), "Actual state should not be touched by apply()")
+}
+
+func TestApplyFailureBudgetExceeded(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve policy with multiple independent services
+	desired := newTestData(t, makePolicyBuilderWithServices(5))
+	newApplier := func() *EngineApply {
+		return NewEngineApply(
+			desired.policy(),
+			desired.resolution(),
+			actual.NewNoOpActionStateUpdater(actualState),
+			desired.external(),
+			mockRegistry(false, false),
+			diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+			event.NewLog(logrus.DebugLevel, "test-apply"),
+			action.NewApplyResultUpdaterImpl(),
+			action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+		)
+	}
+
+	// without failure budget, every component fails and apply doesn't get aborted
+	_, result := newApplier().Apply(context.Background(), action.ConcurrencyLimits{Global: 1}, 0, nil)
+	assert.Equal(t, action.ApplyResult{Success: 0, Failed: 5, Skipped: 15, Total: 20}, *result, "Apply result should be correct")
+
+	// with failure budget, apply gets aborted after the first failure and no new actions get started
+	budget, err := action.ParseFailureBudget("0")
+	assert.NoError(t, err, "Failure budget should be parsed")
+	_, result = newApplier().Apply(context.Background(), action.ConcurrencyLimits{Global: 1}, 0, budget)
+	assert.Equal(t, uint32(1), result.Failed, "Only one action should fail")
+	assert.Equal(t, uint32(19), result.Success+result.Skipped, "The rest of actions should succeed or be skipped")
+	assert.True(t, result.Aborted, "Apply should be aborted")
+	assert.Contains(t, result.AbortReason, "failure budget exceeded", "Abort reason should be set")
+
+	// percentage-based budget allows some actions to fail
+	budget, err = action.ParseFailureBudget("50%")
+	assert.NoError(t, err, "Failure budget should be parsed")
+	_, result = newApplier().Apply(context.Background(), action.ConcurrencyLimits{Global: 1}, 0, budget)
+	assert.Equal(t, uint32(5), result.Failed, "All components should fail")
+	assert.False(t, result.Aborted, "Apply should not be aborted")
+}
+
+func TestApplyConcurrencyLimits(t *testing.T) {
+	// resolve policy with multiple independent services, running in a single cluster
+	desired := newTestData(t, makePolicyBuilderWithServices(6))
+	cluster := desired.policy().GetObjectsByKind(lang.ClusterObject.Kind)[0].(*lang.Cluster)
+
+	checkMaxConcurrency := func(limits action.ConcurrencyLimits, expectedMax int) {
+		t.Helper()
+		actualState := resolve.NewPolicyResolution()
+		registry, tracker := mockRegistryConcurrency(50 * time.Millisecond)
+		applier := NewEngineApply(
+			desired.policy(),
+			desired.resolution(),
+			actual.NewNoOpActionStateUpdater(actualState),
+			desired.external(),
+			registry,
+			diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+			event.NewLog(logrus.DebugLevel, "test-apply"),
+			action.NewApplyResultUpdaterImpl(),
+			action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+		)
+		_, result := applier.Apply(context.Background(), limits, 0, nil)
+		assert.Equal(t, action.ApplyResult{Success: 24, Failed: 0, Skipped: 0, Total: 24}, *result, "Apply result should be correct")
+
+		// services are independent, so the limit should be reached, but never exceeded
+		assert.Equal(t, expectedMax, tracker.max, "Max number of concurrent plugin calls should be equal to the limit")
+	}
+
+	// no limits other than the global one, which is higher than the number of services
+	checkMaxConcurrency(action.ConcurrencyLimits{Global: 50}, 6)
+
+	// global limit
+	checkMaxConcurrency(action.ConcurrencyLimits{Global: 4}, 4)
+
+	// limit declared in cluster labels
+	cluster.Labels = map[string]string{lang.LabelMaxConcurrentActions: "2"}
+	checkMaxConcurrency(action.ConcurrencyLimits{Global: 50}, 2)
+
+	// limit from config takes precedence over cluster labels, regardless of whether it's higher or lower
+	checkMaxConcurrency(action.ConcurrencyLimits{Global: 50, Cluster: map[string]int{cluster.Name: 3}}, 3)
+	cluster.Labels = map[string]string{lang.LabelMaxConcurrentActions: "5"}
+	checkMaxConcurrency(action.ConcurrencyLimits{Global: 50, Cluster: map[string]int{cluster.Name: 3}}, 3)
+
+	// limit for cluster from config
+	cluster.Labels = nil
+	checkMaxConcurrency(action.ConcurrencyLimits{Global: 50, Cluster: map[string]int{cluster.Name: 2}}, 2)
+
+	// limit for code type
+	checkMaxConcurrency(action.ConcurrencyLimits{Global: 50, CodeType: map[string]int{"helm": 1}}, 1)
+	checkMaxConcurrency(action.ConcurrencyLimits{Global: 50, CodeType: map[string]int{"helm": 3}}, 3)
+}
+
+func TestApplyHoldProtectedDeletions(t *testing.T) {
+	// resolve full policy and apply it, so that actual state gets populated
+	actualState := resolve.NewPolicyResolution()
+	desired := newTestData(t, makePolicyBuilder())
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		mockRegistry(true, false),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	)
+	actualState = applyAndCheck(t, applier, action.ApplyResult{Success: 4, Failed: 0, Skipped: 0})
+
+	// delete everything from the policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	plan := diff.NewPolicyResolutionDiff(empty.resolution(), actualState).ActionPlan
+
+	// nothing is protected by default
+	assert.Empty(t, FindProtectedDeletions(plan, desired.policy(), actualState), "No component instances should be protected")
+
+	// component instances can be protected via labels
+	for _, instance := range actualState.ComponentInstanceMap {
+		instance.CalculatedLabels.Labels[lang.LabelProtected] = "true"
+	}
+	protected := FindProtectedDeletions(plan, empty.policy(), actualState)
+	assert.Equal(t, len(actualState.ComponentInstanceMap), len(protected), "All component instances should be protected via labels")
+	for _, instance := range actualState.ComponentInstanceMap {
+		delete(instance.CalculatedLabels.Labels, lang.LabelProtected)
+	}
+
+	// component instances can be protected via service labels
+	service := desired.policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service)
+	service.Labels = map[string]string{lang.LabelProtected: "true"}
+	protected = FindProtectedDeletions(plan, desired.policy(), actualState)
+	assert.Equal(t, len(actualState.ComponentInstanceMap), len(protected), "All component instances should be protected via service labels")
+
+	// deletion of protected component instances gets held, while the rest of actions get applied
+	applier = NewEngineApply(
+		empty.policy(),
+		empty.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		empty.external(),
+		mockRegistry(true, false),
+		plan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	).HoldDeletions(protected)
+	actualStateUpdated, result := applier.Apply(context.Background(), action.ConcurrencyLimits{Global: 50}, 0, nil)
+	assert.Equal(t, uint32(0), result.Failed, "No actions should fail")
+	assert.True(t, result.Success > 0, "Non-destructive actions should be applied")
+	assert.True(t, result.Skipped >= uint32(len(protected)), "Held deletions should be skipped")
+	assert.Equal(t, len(protected), len(actualStateUpdated.ComponentInstanceMap), "Protected component instances should not be deleted")
+}
+
+func TestApplyDeferChangesByMaintenance(t *testing.T) {
+	now := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+	freezeEnds := now.Add(2 * time.Hour)
+
+	// two independent services, changes to the first one are frozen
+	b := makePolicyBuilderWithServices(2)
+	services := b.Policy().GetObjectsByKind(lang.ServiceObject.Kind)
+	frozen := services[0].(*lang.Service)
+	b.AddMaintenance(
+		&lang.Criteria{RequireAll: []string{"Service.Name == '" + frozen.Name + "'"}},
+		nil,
+		[]*lang.MaintenanceFreeze{{From: now.Add(-time.Hour), To: freezeEnds}},
+	)
+	desired := newTestData(t, b)
+	actualState := resolve.NewPolicyResolution()
+	plan := diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan
+
+	// only code component instance of the frozen service is deferred
+	deferred, until := FindDeferredChanges(plan, desired.policy(), desired.resolution(), actualState, now)
+	if assert.Equal(t, 1, len(deferred), "Changes to one component instance should be deferred") {
+		instance := desired.resolution().ComponentInstanceMap[deferred[0]]
+		assert.Equal(t, frozen.Name, instance.Metadata.Key.ServiceName, "Component instance of the frozen service should be deferred")
+		assert.True(t, instance.IsCode, "Code component instance should be deferred")
+	}
+	assert.Equal(t, freezeEnds, until, "Deferred changes should be allowed when freeze ends")
+
+	// nothing is deferred after freeze ends
+	deferredLater, _ := FindDeferredChanges(plan, desired.policy(), desired.resolution(), actualState, freezeEnds)
+	assert.Empty(t, deferredLater, "No changes should be deferred after freeze ends")
+
+	// actions of the frozen service get skipped, while the rest of actions get applied
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		mockRegistry(true, false),
+		plan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	).DeferChanges(deferred)
+	actualState = applyAndCheck(t, applier, action.ApplyResult{Success: 4, Failed: 0, Skipped: 4})
+	assert.Equal(t, 2, len(actualState.ComponentInstanceMap), "Only component instances of the service, which isn't frozen, should be created")
+	for _, instance := range actualState.ComponentInstanceMap {
+		assert.NotEqual(t, frozen.Name, instance.Metadata.Key.ServiceName, "Component instances of the frozen service should not be created")
+	}
+}
+
 func TestDiffHasUpdatedComponentsAndCheckTimes(t *testing.T) {
 	/*
 		Step 1: actual = empty, desired = test policy, check = dependency update/create times
@@ -109,6 +523,7 @@ func TestDiffHasUpdatedComponentsAndCheckTimes(t *testing.T) {
 		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
 		event.NewLog(logrus.DebugLevel, "test-apply"),
 		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
 	)
 
 	// Check that policy apply finished with expected results
@@ -156,6 +571,7 @@ func TestDiffHasUpdatedComponentsAndCheckTimes(t *testing.T) {
 		diff.NewPolicyResolutionDiff(desiredNext.resolution(), actualState).ActionPlan,
 		event.NewLog(logrus.DebugLevel, "test-apply"),
 		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
 	)
 
 	// Check that policy apply finished with expected results
@@ -191,6 +607,7 @@ func TestDiffHasUpdatedComponentsAndCheckTimes(t *testing.T) {
 		diff.NewPolicyResolutionDiff(desiredNextAfterUpdate.resolution(), actualState).ActionPlan,
 		event.NewLog(logrus.DebugLevel, "test-apply"),
 		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
 	)
 
 	// Check that policy apply finished with expected results
@@ -232,6 +649,7 @@ func TestDeletePolicyObjectsWhileComponentInstancesAreStillRunningFails(t *testi
 		diff.NewPolicyResolutionDiff(generated.resolution(), actualState).ActionPlan,
 		event.NewLog(logrus.DebugLevel, "test-apply"),
 		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
 	)
 
 	// Check that policy apply finished with expected results
@@ -252,6 +670,7 @@ func TestDeletePolicyObjectsWhileComponentInstancesAreStillRunningFails(t *testi
 		diff.NewPolicyResolutionDiff(reset.resolution(), actualState).ActionPlan,
 		event.NewLog(logrus.DebugLevel, "test-apply"),
 		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
 	)
 
 	// detach successful, deletion fails
@@ -259,6 +678,369 @@ func TestDeletePolicyObjectsWhileComponentInstancesAreStillRunningFails(t *testi
 	assert.Equal(t, 2, len(actualState.ComponentInstanceMap), "Actual state should still have component instances after actions failing")
 }
 
+func TestApplyComponentBlueGreenUpdate(t *testing.T) {
+	// make component use blue/green update strategy
+	pBuilder := makePolicyBuilder()
+	service := pBuilder.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service) // nolint: errcheck
+	code := service.Components[0].Code
+	code.UpdateStrategy = lang.UpdateStrategyBlueGreen
+
+	registry, tracker := mockRegistryBlueGreen()
+
+	// create component instance
+	desired := newTestData(t, pBuilder)
+	actualState := resolve.NewPolicyResolution()
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		registry,
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	)
+	actualState = applyAndCheck(t, applier, action.ApplyResult{Success: 4, Failed: 0, Skipped: 0})
+
+	cluster := desired.policy().GetObjectsByKind(lang.ClusterObject.Kind)[0].(*lang.Cluster)    // nolint: errcheck
+	contract := desired.policy().GetObjectsByKind(lang.ContractObject.Kind)[0].(*lang.Contract) // nolint: errcheck
+	key := resolve.NewComponentInstanceKey(cluster, "k8ns", contract, contract.Contexts[0], nil, service, service.Components[0])
+	deployName := key.GetDeployName()
+	nextDeployName := getInstanceInternal(t, key.GetKey(), actualState).GetNextDeployName()
+	assert.Equal(t, deployName, getInstanceInternal(t, key.GetKey(), actualState).GetDeployName(), "Component instance should be created under default deploy name")
+
+	// update code params, new copy becomes ready
+	updateParams := func(value string) *testData {
+		result := newTestData(t, pBuilder)
+		for _, dependency := range result.policy().GetObjectsByKind(lang.DependencyObject.Kind) {
+			dependency.(*lang.Dependency).Labels["param"] = value
+		}
+		return result
+	}
+	desired = updateParams("value2")
+	tracker.reset(true)
+	applier = NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		registry,
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	)
+	actualState = applyAndCheck(t, applier, action.ApplyResult{Success: 2, Failed: 0, Skipped: 0})
+
+	assert.Equal(t, []string{nextDeployName}, tracker.created, "New copy of code should be deployed")
+	assert.Equal(t, []string{deployName}, tracker.destroyed, "Old copy of code should be deleted")
+	assert.Equal(t, nextDeployName, getInstanceInternal(t, key.GetKey(), actualState).GetDeployName(), "Actual state should switch to the new copy of code")
+	assert.Equal(t, "value2", getInstanceInternal(t, key.GetKey(), actualState).CalculatedCodeParams["param"], "Code params should be updated in actual state")
+
+	// update code params, new copy never becomes ready
+	code.ReadinessTimeout = time.Nanosecond
+	desired = updateParams("value3")
+	tracker.reset(false)
+	applier = NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		registry,
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	)
+	actualState = applyAndCheck(t, applier, action.ApplyResult{Success: 0, Failed: 1, Skipped: 1})
+
+	assert.Equal(t, []string{deployName}, tracker.created, "New copy of code should be deployed")
+	assert.Equal(t, []string{deployName}, tracker.destroyed, "New copy of code should be deleted on rollback")
+	assert.Equal(t, nextDeployName, getInstanceInternal(t, key.GetKey(), actualState).GetDeployName(), "Actual state should keep pointing to the old copy of code")
+	assert.Equal(t, "value2", getInstanceInternal(t, key.GetKey(), actualState).CalculatedCodeParams["param"], "Code params should not be updated in actual state")
+}
+
+func TestApplyCheckPauses(t *testing.T) {
+	desired := newTestData(t, makePolicyBuilder())
+	actualState := resolve.NewPolicyResolution()
+
+	// changes to code component instance get paused while actions are being applied
+	codeKey := ""
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		mockRegistry(true, false),
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	).CheckPauses(func(instance *resolve.ComponentInstance) bool {
+		if instance.IsCode {
+			codeKey = instance.GetKey()
+		}
+		return instance.IsCode
+	})
+	actualState = applyAndCheck(t, applier, action.ApplyResult{Success: 0, Failed: 0, Skipped: 4})
+
+	assert.Empty(t, actualState.ComponentInstanceMap, "Component instances should not be created while changes are paused")
+	assert.Equal(t, []string{codeKey}, applier.GetPausedChanges(), "Paused changes should be recorded")
+}
+
+func TestApplyWaitForReadiness(t *testing.T) {
+	// resolve empty policy
+	empty := newTestData(t, builder.NewPolicyBuilder())
+	actualState := empty.resolution()
+
+	// resolve full policy
+	desired := newTestData(t, makePolicyBuilder())
+
+	// component instance becomes ready after a few status checks
+	registry, tracker := mockRegistryReadiness(3)
+	applier := NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		registry,
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	).WaitForReadiness(time.Minute)
+	applier.readinessCheckInterval = time.Millisecond
+	applyAndCheck(t, applier, action.ApplyResult{Success: 4, Failed: 0, Skipped: 0})
+
+	assert.Equal(t, 3, tracker.checks, "Readiness should be checked until component instance becomes ready")
+	assert.Equal(t, map[string]int{action.ReadinessReady: 1}, countByReadiness(applier.GetPlanRecord()), "Readiness should be recorded for create action")
+
+	// component instance never becomes ready
+	actualState = newTestData(t, builder.NewPolicyBuilder()).resolution()
+	registry, _ = mockRegistryReadiness(-1)
+	applier = NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(actualState),
+		desired.external(),
+		registry,
+		diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	).WaitForReadiness(20 * time.Millisecond)
+	applier.readinessCheckInterval = time.Millisecond
+	applyAndCheck(t, applier, action.ApplyResult{Success: 0, Failed: 1, Skipped: 3})
+
+	assert.Equal(t, map[string]int{action.ReadinessTimeout: 1}, countByReadiness(applier.GetPlanRecord()), "Readiness timeout should be recorded for create action")
+	for _, node := range applier.GetPlanRecord().Nodes {
+		for _, actionRecord := range node.Actions {
+			if actionRecord.Readiness == action.ReadinessTimeout {
+				assert.Equal(t, action.ActionStatusFailed, actionRecord.Status, "Action should fail if component instance doesn't become ready")
+				assert.True(t, actionRecord.ReadinessWait >= 20*time.Millisecond, "Readiness wait time should be recorded")
+			}
+		}
+	}
+
+	// component instance, which didn't become ready, stays marked as not ready and keeps holding its dependents on
+	// subsequent runs, until it becomes ready
+	actualState = applier.actualStateUpdater.GetUpdatedActualState()
+	notReadyCnt := 0
+	for _, instance := range actualState.ComponentInstanceMap {
+		if instance.NotReady {
+			notReadyCnt++
+		}
+	}
+	assert.Equal(t, 1, notReadyCnt, "Component instance should be marked as not ready in actual state")
+	for _, readyAfter := range []int{-1, 1} {
+		registry, _ = mockRegistryReadiness(readyAfter)
+		applier = NewEngineApply(
+			desired.policy(),
+			desired.resolution(),
+			actual.NewNoOpActionStateUpdater(actualState),
+			desired.external(),
+			registry,
+			diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+			event.NewLog(logrus.DebugLevel, "test-apply"),
+			action.NewApplyResultUpdaterImpl(),
+			action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+		).WaitForReadiness(20 * time.Millisecond)
+		applier.readinessCheckInterval = time.Millisecond
+		var result *action.ApplyResult
+		actualState, result = applier.Apply(context.Background(), action.ConcurrencyLimits{Global: 1}, 0, nil)
+
+		if readyAfter > 0 {
+			assert.Equal(t, uint32(0), result.Failed, "Dependents should be processed once component instance becomes ready")
+			assert.Equal(t, uint32(0), result.Skipped, "Dependents should be processed once component instance becomes ready")
+			for _, instance := range actualState.ComponentInstanceMap {
+				assert.False(t, instance.NotReady, "Component instance should no longer be marked as not ready")
+			}
+		} else {
+			assert.Equal(t, uint32(1), result.Failed, "Component instance should not become ready")
+			assert.True(t, result.Skipped > 0, "Dependents should be held while component instance is not ready")
+			assert.Equal(t, 1, len(actualState.ComponentInstanceMap), "Dependents should not be created")
+		}
+	}
+}
+
+func TestApplyComponentHooks(t *testing.T) {
+	// add hooks to the component
+	pBuilder := makePolicyBuilder()
+	service := pBuilder.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service) // nolint: errcheck
+	hookCode := &lang.Code{Type: "helm", Params: util.NestedParameterMap{"hook": "{{ .Labels.param }}"}}
+	service.Components[0].Hooks = &lang.Hooks{
+		PreCreate:  hookCode,
+		PostCreate: hookCode,
+	}
+	desired := newTestData(t, pBuilder)
+
+	cluster := desired.policy().GetObjectsByKind(lang.ClusterObject.Kind)[0].(*lang.Cluster)    // nolint: errcheck
+	contract := desired.policy().GetObjectsByKind(lang.ContractObject.Kind)[0].(*lang.Contract) // nolint: errcheck
+	key := resolve.NewComponentInstanceKey(cluster, "k8ns", contract, contract.Contexts[0], nil, service, service.Components[0])
+	deployName := key.GetDeployName()
+
+	registry, tracker := mockRegistryHooks()
+	newApply := func(actualState *resolve.PolicyResolution) *EngineApply {
+		return NewEngineApply(
+			desired.policy(),
+			desired.resolution(),
+			actual.NewNoOpActionStateUpdater(actualState),
+			desired.external(),
+			registry,
+			diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+			event.NewLog(logrus.DebugLevel, "test-apply"),
+			action.NewApplyResultUpdaterImpl(),
+			action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+		)
+	}
+
+	// failed pre-create hook should block creation of component instance
+	tracker.reset(lang.HookPreCreate)
+	actualState := applyAndCheck(t, newApply(resolve.NewPolicyResolution()), action.ApplyResult{Success: 0, Failed: 1, Skipped: 5})
+	assert.Equal(t, []string{deployName + "-pre-create"}, tracker.created, "Component instance should not be created if pre-create hook fails")
+	assert.Equal(t, []string{deployName + "-pre-create"}, tracker.destroyed, "Hook code should be deleted after execution")
+	assert.Empty(t, actualState.ComponentInstanceMap, "Component instance should not be created if pre-create hook fails")
+
+	// failed post-create hook should be recorded as pending
+	tracker.reset(lang.HookPostCreate)
+	actualState = applyAndCheck(t, newApply(resolve.NewPolicyResolution()), action.ApplyResult{Success: 2, Failed: 1, Skipped: 3})
+	assert.Equal(t, []string{deployName + "-pre-create", deployName, deployName + "-post-create"}, tracker.created, "Hooks should be executed around creation of component instance")
+	assert.Equal(t, []string{lang.HookPostCreate}, getInstanceInternal(t, key.GetKey(), actualState).PendingHooks, "Failed post-create hook should be pending")
+
+	// pending post-create hook should be retried
+	tracker.reset("")
+	actualState = applyAndCheck(t, newApply(actualState), action.ApplyResult{Success: 3, Failed: 0, Skipped: 0})
+	assert.Equal(t, []string{deployName + "-post-create"}, tracker.created, "Pending post-create hook should be retried")
+	assert.Empty(t, getInstanceInternal(t, key.GetKey(), actualState).PendingHooks, "Post-create hook should no longer be pending")
+	assert.Equal(t, "value1", tracker.params[deployName+"-post-create"]["hook"], "Hook should be executed with calculated params")
+}
+
+func TestApplyComponentHookAdded(t *testing.T) {
+	pBuilder := makePolicyBuilder()
+	service := pBuilder.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service)    // nolint: errcheck
+	cluster := pBuilder.Policy().GetObjectsByKind(lang.ClusterObject.Kind)[0].(*lang.Cluster)    // nolint: errcheck
+	contract := pBuilder.Policy().GetObjectsByKind(lang.ContractObject.Kind)[0].(*lang.Contract) // nolint: errcheck
+	key := resolve.NewComponentInstanceKey(cluster, "k8ns", contract, contract.Contexts[0], nil, service, service.Components[0])
+	deployName := key.GetDeployName()
+
+	registry, tracker := mockRegistryHooks()
+	newApply := func(desired *testData, actualState *resolve.PolicyResolution) *EngineApply {
+		return NewEngineApply(
+			desired.policy(),
+			desired.resolution(),
+			actual.NewNoOpActionStateUpdater(actualState),
+			desired.external(),
+			registry,
+			diff.NewPolicyResolutionDiff(desired.resolution(), actualState).ActionPlan,
+			event.NewLog(logrus.DebugLevel, "test-apply"),
+			action.NewApplyResultUpdaterImpl(),
+			action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+		)
+	}
+
+	// component instance is running without hooks
+	tracker.reset("")
+	actualState := applyAndCheck(t, newApply(newTestData(t, pBuilder), resolve.NewPolicyResolution()), action.ApplyResult{Success: 4, Failed: 0, Skipped: 0})
+	assert.False(t, getInstanceInternal(t, key.GetKey(), actualState).HasHook(lang.HookPreDelete), "Component instance should not have hooks")
+
+	// pre-delete hook gets added to the running component instance, its params get recorded without redeploying code
+	service.Components[0].Hooks = &lang.Hooks{
+		PreDelete: &lang.Code{Type: "helm", Params: util.NestedParameterMap{"hook": "{{ .Labels.param }}"}},
+	}
+	tracker.reset("")
+	actualState = applyAndCheck(t, newApply(newTestData(t, pBuilder), actualState), action.ApplyResult{Success: 1, Failed: 0, Skipped: 0})
+	assert.Empty(t, tracker.updated, "Code should not be redeployed when only hook params change")
+	assert.Equal(t, "value1", getInstanceInternal(t, key.GetKey(), actualState).CalculatedHookParams[lang.HookPreDelete]["hook"], "Hook params should be recorded in actual state")
+
+	// pre-delete hook gets executed once component instance is no longer needed
+	dependency := pBuilder.Policy().GetObjectsByKind(lang.DependencyObject.Kind)[0].(*lang.Dependency) // nolint: errcheck
+	pBuilder.Policy().RemoveObject(dependency)
+	tracker.reset("")
+	_, result := newApply(newTestData(t, pBuilder), actualState).Apply(context.Background(), action.ConcurrencyLimits{Global: 50}, 0, nil)
+	assert.Equal(t, uint32(0), result.Failed, "Component instance should be deleted without errors")
+	assert.Equal(t, []string{deployName + "-pre-delete"}, tracker.created, "Pre-delete hook should be executed")
+	assert.Equal(t, "value1", tracker.params[deployName+"-pre-delete"]["hook"], "Pre-delete hook should be executed with recorded params")
+}
+
+func TestApplyComponentDrift(t *testing.T) {
+	// create component instances
+	desired := newTestData(t, makePolicyBuilder())
+	actualState := applyAndCheck(t, NewEngineApply(
+		desired.policy(),
+		desired.resolution(),
+		actual.NewNoOpActionStateUpdater(resolve.NewPolicyResolution()),
+		desired.external(),
+		mockRegistry(true, false),
+		diff.NewPolicyResolutionDiff(desired.resolution(), resolve.NewPolicyResolution()).ActionPlan,
+		event.NewLog(logrus.DebugLevel, "test-apply"),
+		action.NewApplyResultUpdaterImpl(),
+		action.NewRetryTrackerImpl(action.RetryConfig{}, 0),
+	), action.ApplyResult{Success: 4, Failed: 0, Skipped: 0})
+
+	var key string
+	for _, instance := range actualState.ComponentInstanceMap {
+		if instance.IsCode {
+			key = instance.GetKey()
+		}
+	}
+	if !assert.NotEmpty(t, key, "Code component instance should be created") {
+		return
+	}
+
+	registry, tracker := mockRegistryDrift()
+	detectDrift := func(repair bool) *resolve.ComponentInstance {
+		t.Helper()
+		actionContext := action.NewContext(context.Background(), desired.policy(), nil, actual.NewNoOpActionStateUpdater(actualState), nil, registry, event.NewLog(logrus.DebugLevel, "test-drift"))
+		assert.NoError(t, component.NewDriftAction(key, repair).Apply(actionContext), "Drift should be detected without errors")
+		return getInstanceInternal(t, key, actualState)
+	}
+
+	// missing code should be recorded as drift and marked for repair
+	tracker.drift = &plugin.Drift{Missing: true, Details: "release not found"}
+	instance := detectDrift(true)
+	assert.Equal(t, "release not found", instance.Drift, "Drift details should be recorded")
+	assert.True(t, instance.DriftMissing, "Missing code should be recorded")
+	assert.True(t, instance.DriftRepair, "Drifted component instance should be marked for repair")
+	assert.False(t, instance.DriftDetectedAt.IsZero(), "Drift detection time should be recorded")
+
+	// changed code should be recorded without repair, keeping the original detection time
+	detectedAt := instance.DriftDetectedAt
+	tracker.drift = &plugin.Drift{Details: "replicas changed"}
+	instance = detectDrift(false)
+	assert.Equal(t, "replicas changed", instance.Drift, "Drift details should be updated")
+	assert.False(t, instance.DriftMissing, "Changed code should not be recorded as missing")
+	assert.False(t, instance.DriftRepair, "Drifted component instance should not be marked for repair")
+	assert.Equal(t, detectedAt, instance.DriftDetectedAt, "Drift detection time should be kept while component instance stays drifted")
+
+	// drift should be reset once deployed code matches actual state again
+	tracker.drift = nil
+	instance = detectDrift(true)
+	assert.False(t, instance.IsDrifted(), "Drift should be reset")
+	assert.False(t, instance.DriftRepair, "Component instance should not be marked for repair")
+	assert.True(t, instance.DriftDetectedAt.IsZero(), "Drift detection time should be reset")
+}
+
 /*
 	Helpers
 */
@@ -316,6 +1098,25 @@ func makePolicyBuilder() *builder.PolicyBuilder {
 	return b
 }
 
+func makePolicyBuilderWithServices(count int) *builder.PolicyBuilder {
+	b := builder.NewPolicyBuilder()
+
+	// add rule to set cluster
+	clusterObj := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelTarget, clusterObj.Name)))
+
+	// create independent services, each with its own contract and dependency
+	for i := 0; i < count; i++ {
+		service := b.AddService()
+		b.AddServiceComponent(service, b.CodeComponent(util.NestedParameterMap{"param": "{{ .Labels.param }}"}, nil))
+		contract := b.AddContract(service, b.CriteriaTrue())
+		dependency := b.AddDependency(b.AddUser(), contract)
+		dependency.Labels["param"] = "value1"
+	}
+
+	return b
+}
+
 func resolvePolicy(t *testing.T, b *builder.PolicyBuilder) *resolve.PolicyResolution {
 	t.Helper()
 	eventLog := event.NewLog(logrus.DebugLevel, "test-resolve")
@@ -336,7 +1137,7 @@ func resolvePolicy(t *testing.T, b *builder.PolicyBuilder) *resolve.PolicyResolu
 
 func applyAndCheck(t *testing.T, apply *EngineApply, expectedResult action.ApplyResult) *resolve.PolicyResolution {
 	t.Helper()
-	actualState, result := apply.Apply(50)
+	actualState, result := apply.Apply(context.Background(), action.ConcurrencyLimits{Global: 50}, 0, nil)
 
 	ok := assert.Equal(t, expectedResult.Success, result.Success, "Number of successfully executed actions")
 	ok = ok && assert.Equal(t, expectedResult.Failed, result.Failed, "Number of failed actions")
@@ -393,3 +1194,300 @@ func mockRegistry(applySuccess, failAsPanic bool) plugin.Registry {
 
 	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes)
 }
+
+func mockRegistrySleep(sleepTime time.Duration) plugin.Registry {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
+	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+		return fake.NewNoOpCodePlugin(sleepTime), nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes)
+}
+
+// concurrencyTracker tracks the max number of concurrent plugin calls
+type concurrencyTracker struct {
+	mutex   sync.Mutex
+	current int
+	max     int
+}
+
+type concurrencyTrackingPlugin struct {
+	plugin.CodePlugin
+	tracker *concurrencyTracker
+}
+
+func (p *concurrencyTrackingPlugin) Create(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	p.tracker.mutex.Lock()
+	p.tracker.current++
+	if p.tracker.current > p.tracker.max {
+		p.tracker.max = p.tracker.current
+	}
+	p.tracker.mutex.Unlock()
+
+	defer func() {
+		p.tracker.mutex.Lock()
+		p.tracker.current--
+		p.tracker.mutex.Unlock()
+	}()
+
+	return p.CodePlugin.Create(ctx, invocation)
+}
+
+func mockRegistryConcurrency(sleepTime time.Duration) (plugin.Registry, *concurrencyTracker) {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+	tracker := &concurrencyTracker{}
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
+	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+		return &concurrencyTrackingPlugin{CodePlugin: fake.NewNoOpCodePlugin(sleepTime), tracker: tracker}, nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes), tracker
+}
+
+// blueGreenTracker tracks deploy names of created and destroyed code, as well as controls readiness of code
+type blueGreenTracker struct {
+	mutex     sync.Mutex
+	ready     bool
+	created   []string
+	destroyed []string
+}
+
+func (tracker *blueGreenTracker) reset(ready bool) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.ready = ready
+	tracker.created = nil
+	tracker.destroyed = nil
+}
+
+type blueGreenTrackingPlugin struct {
+	plugin.CodePlugin
+	tracker *blueGreenTracker
+}
+
+func (p *blueGreenTrackingPlugin) Create(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	p.tracker.created = append(p.tracker.created, invocation.DeployName)
+	return nil
+}
+
+func (p *blueGreenTrackingPlugin) Destroy(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	p.tracker.destroyed = append(p.tracker.destroyed, invocation.DeployName)
+	return nil
+}
+
+func (p *blueGreenTrackingPlugin) Status(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (bool, error) {
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	return p.tracker.ready, nil
+}
+
+// readinessTracker tracks the number of readiness checks, as well as controls after how many checks code becomes ready
+type readinessTracker struct {
+	mutex      sync.Mutex
+	readyAfter int
+	checks     int
+}
+
+type readinessTrackingPlugin struct {
+	plugin.CodePlugin
+	tracker *readinessTracker
+}
+
+func (p *readinessTrackingPlugin) Status(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (bool, error) {
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	p.tracker.checks++
+	return p.tracker.readyAfter > 0 && p.tracker.checks >= p.tracker.readyAfter, nil
+}
+
+// contextIgnoringPlugin is a code plugin, which keeps creating code after context is done
+type contextIgnoringPlugin struct {
+	plugin.CodePlugin
+	release <-chan struct{}
+}
+
+func (p *contextIgnoringPlugin) Create(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	<-p.release
+	return nil
+}
+
+// mockRegistryIgnoringContext returns registry with code, creation of which doesn't complete until a given channel
+// gets closed, regardless of the action timeout
+func mockRegistryIgnoringContext(release <-chan struct{}) plugin.Registry {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
+	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+		return &contextIgnoringPlugin{CodePlugin: fake.NewNoOpCodePlugin(0), release: release}, nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes)
+}
+
+// mockRegistryReadiness returns registry with code, which becomes ready after a given number of readiness checks
+// (or never, if it's not positive)
+func mockRegistryReadiness(readyAfter int) (plugin.Registry, *readinessTracker) {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+	tracker := &readinessTracker{readyAfter: readyAfter}
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
+	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+		return &readinessTrackingPlugin{CodePlugin: fake.NewNoOpCodePlugin(0), tracker: tracker}, nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes), tracker
+}
+
+func countByReadiness(planRecord *action.PlanRecord) map[string]int {
+	result := make(map[string]int)
+	for _, node := range planRecord.Nodes {
+		for _, actionRecord := range node.Actions {
+			if len(actionRecord.Readiness) > 0 {
+				result[actionRecord.Readiness]++
+			}
+		}
+	}
+	return result
+}
+
+// hookTracker tracks deploy names of created and destroyed code, as well as makes code of a given hook fail
+type hookTracker struct {
+	mutex     sync.Mutex
+	failHook  string
+	created   []string
+	updated   []string
+	destroyed []string
+	params    map[string]util.NestedParameterMap
+}
+
+func (tracker *hookTracker) reset(failHook string) {
+	tracker.mutex.Lock()
+	defer tracker.mutex.Unlock()
+	tracker.failHook = failHook
+	tracker.created = nil
+	tracker.updated = nil
+	tracker.destroyed = nil
+	tracker.params = make(map[string]util.NestedParameterMap)
+}
+
+type hookTrackingPlugin struct {
+	plugin.CodePlugin
+	tracker *hookTracker
+}
+
+func (p *hookTrackingPlugin) Create(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	p.tracker.created = append(p.tracker.created, invocation.DeployName)
+	p.tracker.params[invocation.DeployName] = invocation.Params
+	if len(p.tracker.failHook) > 0 && strings.HasSuffix(invocation.DeployName, "-"+p.tracker.failHook) {
+		return fmt.Errorf("hook failed")
+	}
+	return nil
+}
+
+func (p *hookTrackingPlugin) Update(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	p.tracker.updated = append(p.tracker.updated, invocation.DeployName)
+	return nil
+}
+
+func (p *hookTrackingPlugin) Destroy(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	p.tracker.mutex.Lock()
+	defer p.tracker.mutex.Unlock()
+	p.tracker.destroyed = append(p.tracker.destroyed, invocation.DeployName)
+	return nil
+}
+
+func mockRegistryHooks() (plugin.Registry, *hookTracker) {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+	tracker := &hookTracker{}
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
+	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+		return &hookTrackingPlugin{CodePlugin: fake.NewNoOpCodePlugin(0), tracker: tracker}, nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes), tracker
+}
+
+func mockRegistryBlueGreen() (plugin.Registry, *blueGreenTracker) {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+	tracker := &blueGreenTracker{ready: true}
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
+	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+		return &blueGreenTrackingPlugin{CodePlugin: fake.NewNoOpCodePlugin(0), tracker: tracker}, nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes), tracker
+}
+
+// driftTrackingPlugin is a code plugin, which reports a given drift for all deployed code
+type driftTrackingPlugin struct {
+	plugin.CodePlugin
+	tracker *driftTracker
+}
+
+type driftTracker struct {
+	drift *plugin.Drift
+}
+
+func (p *driftTrackingPlugin) Drift(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (*plugin.Drift, error) {
+	return p.tracker.drift, nil
+}
+
+func mockRegistryDrift() (plugin.Registry, *driftTracker) {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+	tracker := &driftTracker{}
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
+	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+		return &driftTrackingPlugin{CodePlugin: fake.NewNoOpCodePlugin(0), tracker: tracker}, nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes), tracker
+}
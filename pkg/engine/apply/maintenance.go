@@ -0,0 +1,164 @@
+package apply
+
+import (
+	"sort"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// FindDeferredChanges returns a sorted list of keys of code component instances, which are going to be changed by a
+// given action plan, but changes to which are not allowed at a given point of time by maintenance windows and freezes.
+// It also returns the earliest time when changes to some of them are expected to be allowed (zero time means that it
+// couldn't be determined, e.g. because maintenance criteria failed to evaluate, and has to be checked again later)
+func FindDeferredChanges(plan *action.Plan, policy *lang.Policy, desiredState *resolve.PolicyResolution, actualState *resolve.PolicyResolution, now time.Time) ([]string, time.Time) {
+	result := []string{}
+	var until time.Time
+	untilKnown := true
+
+	maintenances := policy.GetObjectsByKind(lang.MaintenanceObject.Kind)
+	if len(maintenances) <= 0 {
+		return result, until
+	}
+
+	cache := expression.NewCache()
+	for key, node := range plan.NodeMap {
+		if len(node.Actions) <= 0 {
+			continue
+		}
+
+		// component instance may only be present in actual state (e.g. when it's being deleted)
+		instance := desiredState.ComponentInstanceMap[key]
+		if instance == nil {
+			instance = actualState.ComponentInstanceMap[key]
+		}
+		if instance == nil || !instance.IsCode {
+			continue
+		}
+
+		allowedAt, deferred := getChangesAllowedAt(instance, maintenances, policy, cache, now)
+		if !deferred {
+			continue
+		}
+		result = append(result, key)
+
+		if allowedAt.IsZero() {
+			untilKnown = false
+		} else if until.IsZero() || allowedAt.Before(until) {
+			until = allowedAt
+		}
+	}
+
+	sort.Strings(result)
+	if !untilKnown {
+		until = time.Time{}
+	}
+	return result, until
+}
+
+// getChangesAllowedAt returns true if changes to a given component instance are not allowed at a given point of time,
+// together with the time when they are expected to be allowed. Maintenance, which fails to evaluate, doesn't allow
+// changes (and zero time is returned), so nothing gets changed in the clusters by mistake
+func getChangesAllowedAt(instance *resolve.ComponentInstance, maintenances []lang.Base, policy *lang.Policy, cache *expression.Cache, now time.Time) (time.Time, bool) {
+	params := getContextualDataForMaintenanceExpression(instance, policy)
+
+	allowedAt := now
+	for _, obj := range maintenances {
+		maintenance := obj.(*lang.Maintenance) // nolint: errcheck
+
+		// maintenance defined in a namespace only applies to component instances in that namespace
+		if maintenance.Namespace != runtime.SystemNS && maintenance.Namespace != instance.Metadata.Key.Namespace {
+			continue
+		}
+
+		matches, err := maintenance.Matches(params, cache)
+		if err != nil {
+			return time.Time{}, true
+		}
+		if !matches {
+			continue
+		}
+
+		next, err := maintenance.NextChangeAllowed(now)
+		if err != nil {
+			return time.Time{}, true
+		}
+		if next.After(allowedAt) {
+			allowedAt = next
+		}
+	}
+
+	return allowedAt, allowedAt.After(now)
+}
+
+// This method defines which contextual information will be exposed to the expression engine (for evaluating maintenance criteria)
+// Be careful about what gets exposed through this method. User can refer to structs and their methods from the policy
+func getContextualDataForMaintenanceExpression(instance *resolve.ComponentInstance, policy *lang.Policy) *expression.Parameters {
+	labels := map[string]string{}
+	if instance.CalculatedLabels != nil {
+		labels = instance.CalculatedLabels.Labels
+	}
+
+	return expression.NewParams(
+		labels,
+		map[string]interface{}{
+			"Service": proxyForMaintenance(policy, lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace),
+			"Cluster": proxyForMaintenance(policy, lang.ClusterObject.Kind, instance.Metadata.Key.ClusterName, instance.Metadata.Key.ClusterNameSpace),
+			"Target": struct {
+				Namespace string
+			}{
+				Namespace: instance.Metadata.Key.TargetSuffix,
+			},
+		},
+	)
+}
+
+// proxyForMaintenance returns metadata and labels of a given service or cluster, to be exposed to maintenance criteria
+func proxyForMaintenance(policy *lang.Policy, kind string, name string, namespace string) interface{} {
+	result := struct {
+		lang.Metadata
+		Labels map[string]string
+	}{
+		Metadata: lang.Metadata{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Labels: map[string]string{},
+	}
+
+	obj, err := policy.GetObject(kind, name, namespace)
+	if err != nil || obj == nil {
+		return result
+	}
+	switch o := obj.(type) {
+	case *lang.Service:
+		result.Labels = o.Labels
+	case *lang.Cluster:
+		result.Labels = o.Labels
+	}
+	if result.Labels == nil {
+		result.Labels = map[string]string{}
+	}
+	return result
+}
+
+// DeferChanges makes apply skip all actions for component instances with given keys (e.g. until changes to them are
+// allowed by maintenance windows and freezes, or until enforcement gets resumed). All other actions, which don't
+// depend on deferred ones, still get applied
+func (apply *EngineApply) DeferChanges(keys []string) *EngineApply {
+	apply.deferredChanges = make(map[string]bool)
+	for _, key := range keys {
+		apply.deferredChanges[key] = true
+	}
+	return apply
+}
+
+// isDeferred returns true if a given action changes component instance, changes to which are being deferred
+func (apply *EngineApply) isDeferred(act action.Interface) bool {
+	key, ok := act.DescribeChanges()["key"].(string)
+	return ok && apply.deferredChanges[key]
+}
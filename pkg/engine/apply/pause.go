@@ -0,0 +1,60 @@
+package apply
+
+import (
+	"sort"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+)
+
+// PauseChecker returns true if changes to a given component instance are paused at the moment
+type PauseChecker func(instance *resolve.ComponentInstance) bool
+
+// CheckPauses makes apply check right before starting every action whether changes to its component instance have
+// been paused (e.g. enforcement got paused while actions were being applied). Such actions get skipped, and keys of
+// their component instances can be retrieved via GetPausedChanges once apply is done
+func (apply *EngineApply) CheckPauses(isPaused PauseChecker) *EngineApply {
+	apply.pauseChecker = isPaused
+	apply.pausedChanges = make(map[string]bool)
+	return apply
+}
+
+// GetPausedChanges returns a sorted list of keys of component instances, changes to which have been skipped because
+// they got paused while actions were being applied
+func (apply *EngineApply) GetPausedChanges() []string {
+	apply.pausedMutex.Lock()
+	defer apply.pausedMutex.Unlock()
+
+	result := []string{}
+	for key := range apply.pausedChanges {
+		result = append(result, key)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// isPaused returns true if a given action changes component instance, changes to which are paused at the moment
+func (apply *EngineApply) isPaused(act action.Interface) bool {
+	if apply.pauseChecker == nil {
+		return false
+	}
+
+	key, ok := act.DescribeChanges()["key"].(string)
+	if !ok || len(key) <= 0 {
+		return false
+	}
+
+	// component instance may only be present in actual state (e.g. when it's being deleted)
+	instance := apply.desiredState.ComponentInstanceMap[key]
+	if instance == nil {
+		instance = apply.actualStateUpdater.GetComponentInstance(key)
+	}
+	if instance == nil || !apply.pauseChecker(instance) {
+		return false
+	}
+
+	apply.pausedMutex.Lock()
+	defer apply.pausedMutex.Unlock()
+	apply.pausedChanges[key] = true
+	return true
+}
@@ -0,0 +1,65 @@
+package apply
+
+import (
+	"sort"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
+)
+
+// FindProtectedDeletions returns a sorted list of keys of protected component instances, which are going to be deleted
+// by a given action plan. Component instance is protected if its service is marked as protected in the policy, or
+// if its calculated labels mark it as protected
+func FindProtectedDeletions(plan *action.Plan, policy *lang.Policy, actualState *resolve.PolicyResolution) []string {
+	result := []string{}
+	for _, node := range plan.NodeMap {
+		for _, act := range node.Actions {
+			deleteAction, ok := act.(*component.DeleteAction)
+			if !ok {
+				continue
+			}
+			instance := actualState.ComponentInstanceMap[deleteAction.ComponentKey]
+			if instance != nil && isProtected(instance, policy) {
+				result = append(result, deleteAction.ComponentKey)
+			}
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// isProtected returns true if a given component instance is protected from deletion
+func isProtected(instance *resolve.ComponentInstance, policy *lang.Policy) bool {
+	if instance.CalculatedLabels != nil && lang.IsProtectedByLabels(instance.CalculatedLabels.Labels) {
+		return true
+	}
+	serviceObj, err := policy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
+	if err != nil || serviceObj == nil {
+		return false
+	}
+	return serviceObj.(*lang.Service).IsProtected() // nolint: errcheck
+}
+
+// HoldDeletions makes apply skip deletion of component instances with given keys (e.g. until deletion of protected
+// component instances gets approved). All other actions, which don't depend on held deletions, still get applied
+func (apply *EngineApply) HoldDeletions(keys []string) *EngineApply {
+	apply.heldDeletions = make(map[string]bool)
+	for _, key := range keys {
+		apply.heldDeletions[key] = true
+	}
+	return apply
+}
+
+// isHeld returns true if a given action is a deletion of component instance (or its pre-delete hook), which is being
+// held
+func (apply *EngineApply) isHeld(act action.Interface) bool {
+	switch a := act.(type) {
+	case *component.DeleteAction:
+		return apply.heldDeletions[a.ComponentKey]
+	case *component.HookAction:
+		return a.Hook == lang.HookPreDelete && apply.heldDeletions[a.ComponentKey]
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+package apply
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+)
+
+// defaultReadinessCheckInterval is how often readiness of component instance gets checked after create/update
+const defaultReadinessCheckInterval = 5 * time.Second
+
+// WaitForReadiness makes apply wait (for at most a given timeout) until code component instance becomes ready after
+// it gets created or updated, before unblocking actions of component instances which depend on it. If component
+// instance doesn't become ready in time, the action is considered failed. Until component instance becomes ready, it
+// stays marked as not ready in actual state, so component instances depending on it are held on subsequent runs as
+// well (see component.ReadinessAction). Zero timeout means no waiting
+func (apply *EngineApply) WaitForReadiness(timeout time.Duration) *EngineApply {
+	apply.readinessTimeout = timeout
+	return apply
+}
+
+// waitForReadiness waits until component instance becomes ready after a given action, if it's a create or update of
+// code component instance, or a check of component instance which hasn't become ready before. The outcome and wait
+// time are recorded in the action plan record
+func (apply *EngineApply) waitForReadiness(act action.Interface, context *action.Context) error {
+	var key string
+	switch a := act.(type) {
+	case *component.CreateAction:
+		key = a.ComponentKey
+	case *component.UpdateAction:
+		key = a.ComponentKey
+	case *component.ReadinessAction:
+		key = a.ComponentKey
+	default:
+		return nil
+	}
+
+	instance := apply.actualStateUpdater.GetComponentInstance(key)
+	if instance == nil || !instance.IsCode {
+		return nil
+	}
+
+	// if readiness isn't checked, component instance can't stay marked as not ready
+	if apply.readinessTimeout <= 0 {
+		return apply.setNotReady(key, false)
+	}
+
+	// component instance stays marked as not ready until it becomes ready (even if apply gets interrupted)
+	err := apply.setNotReady(key, true)
+	if err != nil {
+		return err
+	}
+
+	codePlugin, err := apply.getCodePlugin(instance)
+	if err != nil {
+		return fmt.Errorf("unable to check readiness of component instance '%s': %s", key, err)
+	}
+
+	invocation := &plugin.CodePluginInvocationParams{
+		DeployName:   instance.GetDeployName(),
+		Params:       instance.CalculatedCodeParams,
+		PluginParams: map[string]string{plugin.ParamTargetSuffix: instance.Metadata.Key.TargetSuffix},
+		EventLog:     context.EventLog,
+	}
+
+	context.EventLog.NewEntry().Infof("Waiting for component instance to become ready: %s", key)
+
+	start := time.Now()
+	deadline := start.Add(apply.readinessTimeout)
+	for {
+		ready, statusErr := codePlugin.Status(context.Ctx, invocation)
+		if statusErr != nil {
+			context.EventLog.NewEntry().Debugf("Error while checking readiness of component instance '%s': %s", key, statusErr)
+		} else if ready {
+			apply.planRecord.ReadinessChecked(act, action.ReadinessReady, time.Since(start))
+			context.EventLog.NewEntry().Infof("Component instance is ready: %s", key)
+			return apply.setNotReady(key, false)
+		}
+
+		if time.Now().After(deadline) {
+			apply.planRecord.ReadinessChecked(act, action.ReadinessTimeout, time.Since(start))
+			return fmt.Errorf("component instance '%s' didn't become ready in %s", key, apply.readinessTimeout)
+		}
+
+		timer := time.NewTimer(apply.readinessCheckInterval)
+		select {
+		case <-timer.C:
+		case <-context.Ctx.Done():
+			timer.Stop()
+			apply.planRecord.ReadinessChecked(act, action.ReadinessCancelled, time.Since(start))
+			return fmt.Errorf("waiting for component instance '%s' to become ready cancelled: %s", key, context.Ctx.Err())
+		}
+	}
+}
+
+// setNotReady marks component instance in actual state as not ready (or ready), if it's not marked as such already
+func (apply *EngineApply) setNotReady(key string, notReady bool) error {
+	instance := apply.actualStateUpdater.GetComponentInstance(key)
+	if instance == nil || instance.NotReady == notReady {
+		return nil
+	}
+	return apply.actualStateUpdater.UpdateComponentInstance(key, func(obj *resolve.ComponentInstance) {
+		obj.NotReady = notReady
+	})
+}
+
+// getCodePlugin returns code plugin for a given code component instance
+func (apply *EngineApply) getCodePlugin(instance *resolve.ComponentInstance) (plugin.CodePlugin, error) {
+	serviceObj, err := apply.desiredPolicy.GetObject(lang.ServiceObject.Kind, instance.Metadata.Key.ServiceName, instance.Metadata.Key.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	if serviceObj == nil {
+		return nil, fmt.Errorf("service '%s/%s' in not present in policy", instance.Metadata.Key.Namespace, instance.Metadata.Key.ServiceName)
+	}
+	component := serviceObj.(*lang.Service).GetComponentsMap()[instance.Metadata.Key.ComponentName] // nolint: errcheck
+	if component == nil || component.Code == nil {
+		return nil, fmt.Errorf("component '%s' is not a code component", instance.Metadata.Key.ComponentName)
+	}
+
+	clusterObj, err := apply.desiredPolicy.GetObject(lang.ClusterObject.Kind, instance.Metadata.Key.ClusterName, instance.Metadata.Key.ClusterNameSpace)
+	if err != nil {
+		return nil, err
+	}
+	if clusterObj == nil {
+		return nil, fmt.Errorf("cluster '%s/%s' in not present in policy", instance.Metadata.Key.ClusterNameSpace, instance.Metadata.Key.ClusterName)
+	}
+
+	return apply.plugins.ForCodeType(clusterObj.(*lang.Cluster), component.Code.Type)
+}
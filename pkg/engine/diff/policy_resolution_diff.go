@@ -1,9 +1,12 @@
 package diff
 
 import (
+	"reflect"
+
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/util"
 )
 
@@ -98,6 +101,7 @@ func (diff *PolicyResolutionDiff) buildActions(key string) { // nolint: gocyclo
 
 	// See if a component needs to be destructed
 	if len(depKeysPrev) > 0 && len(depKeysNext) <= 0 {
+		diff.addHookAction(node, prevInstance, lang.HookPreDelete)
 		node.AddAction(component.NewDeleteAction(key, prevInstance.CalculatedCodeParams), diff.Prev, true)
 		return // exit right away
 	}
@@ -109,15 +113,36 @@ func (diff *PolicyResolutionDiff) buildActions(key string) { // nolint: gocyclo
 	// See if it's a service or component
 	isCodeComponent := (prevInstance != nil && prevInstance.IsCode) || (nextInstance != nil && nextInstance.IsCode)
 
-	// See if a component needs to be instantiated
-	if len(depKeysPrev) <= 0 && len(depKeysNext) > 0 {
+	// See if a component has drifted (e.g. its code has been deleted or changed manually) and has to be repaired
+	driftRepair := isCodeComponent && len(depKeysPrev) > 0 && len(depKeysNext) > 0 && prevInstance.DriftRepair
+
+	// Keep track of whether code of a component gets created or updated
+	codeChanged := false
+
+	// See if a component needs to be instantiated (or re-created, if its code no longer exists in the cloud)
+	if (len(depKeysPrev) <= 0 && len(depKeysNext) > 0) || (driftRepair && prevInstance.DriftMissing) {
+		codeChanged = true
+		diff.addHookAction(node, nextInstance, lang.HookPreCreate)
 		node.AddAction(component.NewCreateAction(key, nextInstance.CalculatedCodeParams), diff.Prev, true)
+		diff.addHookAction(node, nextInstance, lang.HookPostCreate)
+	} else if len(depKeysPrev) > 0 && len(depKeysNext) > 0 && util.ContainsString(prevInstance.PendingHooks, lang.HookPostCreate) {
+		// post-create hook failed after component had been created, so it has to be retried
+		diff.addHookAction(node, nextInstance, lang.HookPostCreate)
 	}
 
 	// See if a component needs to be updated
-	if isCodeComponent && len(depKeysPrev) > 0 && len(depKeysNext) > 0 {
+	if isCodeComponent && len(depKeysPrev) > 0 && len(depKeysNext) > 0 && !(driftRepair && prevInstance.DriftMissing) {
 		sameParams := prevInstance.CalculatedCodeParams.DeepEqual(nextInstance.CalculatedCodeParams)
-		if !sameParams {
+
+		// changes in data for plugins (e.g. ingress being rejected) have to be propagated to the plugins as well
+		sameDataForPlugins := reflect.DeepEqual(prevInstance.DataForPlugins, nextInstance.DataForPlugins)
+
+		// changes in hook params (e.g. a hook being added) have to be recorded in actual state, so hooks get executed
+		// with the right params later on (e.g. pre-delete hook, which is executed for the instance in actual state)
+		sameHookParams := reflect.DeepEqual(prevInstance.CalculatedHookParams, nextInstance.CalculatedHookParams)
+		if !sameParams || !sameDataForPlugins || driftRepair {
+			codeChanged = true
+			diff.addHookAction(node, nextInstance, lang.HookPreUpdate)
 			node.AddAction(component.NewUpdateAction(key, prevInstance.CalculatedCodeParams, nextInstance.CalculatedCodeParams), diff.Prev, true)
 
 			// indicate that a parent service component instance gets updated as well
@@ -126,9 +151,18 @@ func (diff *PolicyResolutionDiff) buildActions(key string) { // nolint: gocyclo
 			serviceKey := nextInstance.Metadata.Key.GetParentServiceKey().GetKey()
 			serviceNode := diff.ActionPlan.GetActionGraphNode(serviceKey)
 			serviceNode.AddAction(component.NewUpdateAction(serviceKey, util.NestedParameterMap{}, util.NestedParameterMap{}), diff.Prev, true)
+		} else if !sameHookParams {
+			// code itself doesn't change, so only hook params get recorded by the update (no pre-update hook is needed)
+			node.AddAction(component.NewUpdateAction(key, prevInstance.CalculatedCodeParams, nextInstance.CalculatedCodeParams), diff.Prev, true)
 		}
 	}
 
+	// See if a component, which hasn't become ready after it had been created or updated, needs to be checked again
+	// before component instances depending on it get processed (readiness gets checked after create/update anyway)
+	if isCodeComponent && len(depKeysPrev) > 0 && len(depKeysNext) > 0 && prevInstance.NotReady && !codeChanged {
+		node.AddAction(component.NewReadinessAction(key), diff.Prev, true)
+	}
+
 	// See if a dependency needs to be attached to a component
 	for dependencyID, depth := range depKeysNext {
 		if _, found := depKeysPrev[dependencyID]; !found {
@@ -136,3 +170,10 @@ func (diff *PolicyResolutionDiff) buildActions(key string) { // nolint: gocyclo
 		}
 	}
 }
+
+// Adds an action for a given hook of component instance, if the hook is defined
+func (diff *PolicyResolutionDiff) addHookAction(node *action.GraphNode, instance *resolve.ComponentInstance, hook string) {
+	if instance.HasHook(hook) {
+		node.AddAction(component.NewHookAction(instance.GetKey(), hook, instance.CalculatedHookParams[hook]), diff.Prev, true)
+	}
+}
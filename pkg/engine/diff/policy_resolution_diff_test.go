@@ -2,6 +2,7 @@
 package diff
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -11,6 +12,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/lang/builder"
+	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -71,6 +73,31 @@ func TestDiffComponentUpdate(t *testing.T) {
 	verifyDiff(t, diffAgain, 0, 0, 2, 0, 0)
 }
 
+func TestDiffComponentIngressRejected(t *testing.T) {
+	b := makePolicyBuilder()
+
+	// add dependency
+	d1 := b.AddDependency(b.AddUser(), b.Policy().GetObjectsByKind(lang.ContractObject.Kind)[0].(*lang.Contract))
+	d1.Labels["param"] = "value1"
+	resolvedPrev := resolvePolicy(t, b)
+
+	// add rule which rejects ingress traffic
+	rule := b.AddRule(b.CriteriaTrue(), &lang.RuleActions{Ingress: lang.Reject})
+	resolvedNext := resolvePolicy(t, b)
+
+	// component should be updated, so plugins can block ingress traffic
+	diff := NewPolicyResolutionDiff(resolvedNext, resolvedPrev)
+	verifyDiff(t, diff, 0, 0, 2, 0, 0)
+
+	// rule starts allowing ingress traffic
+	rule.Actions.Ingress = "allow"
+	resolvedNextAgain := resolvePolicy(t, b)
+
+	// component should be updated again, so plugins can allow ingress traffic
+	diffAgain := NewPolicyResolutionDiff(resolvedNextAgain, resolvedNext)
+	verifyDiff(t, diffAgain, 0, 0, 2, 0, 0)
+}
+
 func TestDiffComponentDelete(t *testing.T) {
 	b := makePolicyBuilder()
 	resolvedPrev := resolvePolicy(t, b)
@@ -102,6 +129,102 @@ func TestDiffComponentWithServiceSharing(t *testing.T) {
 	verifyDiff(t, diff, 7, 0, 0, 9, 0)
 }
 
+func TestDiffComponentDriftRepair(t *testing.T) {
+	b := makePolicyBuilder()
+
+	// add dependency
+	d1 := b.AddDependency(b.AddUser(), b.Policy().GetObjectsByKind(lang.ContractObject.Kind)[0].(*lang.Contract))
+	d1.Labels["param"] = "value1"
+	resolvedPrev := resolvePolicy(t, b)
+	resolvedNext := resolvePolicy(t, b)
+
+	// find code component instance
+	var instance *resolve.ComponentInstance
+	for _, obj := range resolvedPrev.ComponentInstanceMap {
+		if obj.IsCode {
+			instance = obj
+		}
+	}
+	if !assert.NotNil(t, instance, "Code component instance should exist") {
+		t.FailNow()
+	}
+
+	// drift without repair should not result in any actions
+	instance.Drift = "changed manually"
+	verifyDiff(t, NewPolicyResolutionDiff(resolvedNext, resolvedPrev), 0, 0, 0, 0, 0)
+
+	// changed component instance should be updated (as well as its parent service)
+	instance.DriftRepair = true
+	verifyDiff(t, NewPolicyResolutionDiff(resolvedNext, resolvedPrev), 0, 0, 2, 0, 0)
+
+	// missing component instance should be re-created
+	instance.DriftMissing = true
+	verifyDiff(t, NewPolicyResolutionDiff(resolvedNext, resolvedPrev), 1, 0, 0, 0, 0)
+}
+
+func TestDiffComponentHooks(t *testing.T) {
+	b := makePolicyBuilder()
+	service := b.Policy().GetObjectsByKind(lang.ServiceObject.Kind)[0].(*lang.Service) // nolint: errcheck
+	hookCode := &lang.Code{Type: "helm", Params: util.NestedParameterMap{"hook": "{{ .Labels.param }}"}}
+	service.Components[0].Hooks = &lang.Hooks{
+		PreCreate:  hookCode,
+		PostCreate: hookCode,
+		PreUpdate:  hookCode,
+		PreDelete:  hookCode,
+	}
+	resolvedPrev := resolvePolicy(t, b)
+
+	// add dependency
+	d1 := b.AddDependency(b.AddUser(), b.Policy().GetObjectsByKind(lang.ContractObject.Kind)[0].(*lang.Contract))
+	d1.Labels["param"] = "value1"
+	resolvedNext := resolvePolicy(t, b)
+	instance := getCodeInstance(t, resolvedNext)
+	assert.Equal(t, "value1", instance.CalculatedHookParams[lang.HookPreCreate]["hook"], "Hook params should be calculated")
+
+	// hooks should be executed before and after component gets created
+	diff := NewPolicyResolutionDiff(resolvedNext, resolvedPrev)
+	assert.Equal(t, []string{"hook pre-create", "create", "hook post-create", "attach"}, describeActions(diff.ActionPlan.NodeMap[instance.GetKey()]), "Create hooks should be added around component creation")
+
+	// hook should be executed before component gets updated
+	d1.Labels["param"] = "value2"
+	resolvedNextAgain := resolvePolicy(t, b)
+	diff = NewPolicyResolutionDiff(resolvedNextAgain, resolvedNext)
+	assert.Equal(t, []string{"hook pre-update", "update"}, describeActions(diff.ActionPlan.NodeMap[instance.GetKey()]), "Update hook should be added before component update")
+
+	// failed post-create hook should be retried
+	instance.PendingHooks = []string{lang.HookPostCreate}
+	diff = NewPolicyResolutionDiff(resolvePolicy(t, b), resolvedNext)
+	assert.Equal(t, []string{"hook post-create", "hook pre-update", "update"}, describeActions(diff.ActionPlan.NodeMap[instance.GetKey()]), "Pending post-create hook should be retried")
+
+	// hook should be executed before component gets deleted
+	instance.PendingHooks = nil
+	diff = NewPolicyResolutionDiff(resolvePolicy(t, builder.NewPolicyBuilder()), resolvedNext)
+	assert.Equal(t, []string{"detach", "hook pre-delete", "delete"}, describeActions(diff.ActionPlan.NodeMap[instance.GetKey()]), "Delete hook should be added before component deletion")
+}
+
+func TestDiffScopedActionPlan(t *testing.T) {
+	b := makePolicyBuilderWithServiceSharing()
+	resolvedNext := resolvePolicy(t, b)
+	resolvedEmpty := resolvePolicy(t, builder.NewPolicyBuilder())
+	diff := NewPolicyResolutionDiff(resolvedNext, resolvedEmpty)
+
+	// empty scope should result in the whole action plan
+	verifyPlan(t, diff.ScopedActionPlan(nil), 7, 0, 0, 9, 0)
+	verifyPlan(t, diff.ScopedActionPlan(&Scope{}), 7, 0, 0, 9, 0)
+
+	// scope with a single dependency should only include its own component instances and the shared service
+	dependency := b.Policy().GetObjectsByKind(lang.DependencyObject.Kind)[0].(*lang.Dependency)
+	verifyPlan(t, diff.ScopedActionPlan(&Scope{Dependencies: []string{runtime.KeyForStorable(dependency)}}), 3, 0, 0, 5, 0)
+
+	// scope with a namespace and a cluster should include everything
+	cluster := b.Policy().GetObjectsByKind(lang.ClusterObject.Kind)[0].(*lang.Cluster)
+	verifyPlan(t, diff.ScopedActionPlan(&Scope{Namespaces: []string{dependency.Namespace}, Clusters: []string{cluster.Name}}), 7, 0, 0, 9, 0)
+
+	// scope with unknown namespace or unknown cluster should include nothing
+	verifyPlan(t, diff.ScopedActionPlan(&Scope{Namespaces: []string{"unknown"}}), 0, 0, 0, 0, 0)
+	verifyPlan(t, diff.ScopedActionPlan(&Scope{Namespaces: []string{dependency.Namespace}, Clusters: []string{"unknown"}}), 0, 0, 0, 0, 0)
+}
+
 /*
 	Helpers
 */
@@ -168,7 +291,47 @@ func resolvePolicy(t *testing.T, builder *builder.PolicyBuilder) *resolve.Policy
 	return result
 }
 
+func getCodeInstance(t *testing.T, resolution *resolve.PolicyResolution) *resolve.ComponentInstance {
+	t.Helper()
+	for _, instance := range resolution.ComponentInstanceMap {
+		if instance.IsCode {
+			return instance
+		}
+	}
+	t.Fatal("Code component instance should exist")
+	return nil
+}
+
+func describeActions(node *action.GraphNode) []string {
+	result := []string{}
+	if node == nil {
+		return result
+	}
+	for _, act := range node.Actions {
+		switch a := act.(type) {
+		case *component.HookAction:
+			result = append(result, "hook "+a.Hook)
+		case *component.CreateAction:
+			result = append(result, "create")
+		case *component.UpdateAction:
+			result = append(result, "update")
+		case *component.DeleteAction:
+			result = append(result, "delete")
+		case *component.AttachDependencyAction:
+			result = append(result, "attach")
+		case *component.DetachDependencyAction:
+			result = append(result, "detach")
+		}
+	}
+	return result
+}
+
 func verifyDiff(t *testing.T, diff *PolicyResolutionDiff, componentInstantiate int, componentDestruct int, componentUpdate int, componentAttachDependency int, componentDetachDependency int) {
+	t.Helper()
+	verifyPlan(t, diff.ActionPlan, componentInstantiate, componentDestruct, componentUpdate, componentAttachDependency, componentDetachDependency)
+}
+
+func verifyPlan(t *testing.T, plan *action.Plan, componentInstantiate int, componentDestruct int, componentUpdate int, componentAttachDependency int, componentDetachDependency int) {
 	t.Helper()
 	cnt := struct {
 		create    int
@@ -201,7 +364,7 @@ func verifyDiff(t *testing.T, diff *PolicyResolutionDiff, componentInstantiate i
 		return nil
 	}
 
-	_ = diff.ActionPlan.Apply(action.WrapSequential(fn), action.NewApplyResultUpdaterImpl())
+	_ = plan.Apply(context.Background(), action.WrapSequential(fn), action.NewApplyResultUpdaterImpl(), nil, nil)
 
 	ok := assert.Equal(t, componentInstantiate, cnt.create, "Diff: component instantiations")
 	ok = ok && assert.Equal(t, componentDestruct, cnt.delete, "Diff: component destructions")
@@ -0,0 +1,87 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/util"
+)
+
+// Scope defines a subset of component instances, to which the action plan can be restricted (e.g. to push changes
+// for a single team without touching everything else). Component instance belongs to the scope if it matches all
+// non-empty lists, i.e. it belongs to one of the listed dependencies, one of the listed namespaces and one of the
+// listed clusters
+type Scope struct {
+	// Dependencies is a list of dependency keys
+	Dependencies []string `yaml:",omitempty"`
+
+	// Namespaces is a list of namespaces
+	Namespaces []string `yaml:",omitempty"`
+
+	// Clusters is a list of cluster names
+	Clusters []string `yaml:",omitempty"`
+}
+
+// IsEmpty returns true if scope is not defined, i.e. it includes all component instances
+func (scope *Scope) IsEmpty() bool {
+	return scope == nil || (len(scope.Dependencies) <= 0 && len(scope.Namespaces) <= 0 && len(scope.Clusters) <= 0)
+}
+
+// String returns a human-readable representation of the scope
+func (scope *Scope) String() string {
+	if scope.IsEmpty() {
+		return "all"
+	}
+	parts := []string{}
+	if len(scope.Dependencies) > 0 {
+		parts = append(parts, fmt.Sprintf("dependencies: %s", strings.Join(scope.Dependencies, ", ")))
+	}
+	if len(scope.Namespaces) > 0 {
+		parts = append(parts, fmt.Sprintf("namespaces: %s", strings.Join(scope.Namespaces, ", ")))
+	}
+	if len(scope.Clusters) > 0 {
+		parts = append(parts, fmt.Sprintf("clusters: %s", strings.Join(scope.Clusters, ", ")))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// matches returns true if a given component instance belongs to the scope
+func (scope *Scope) matches(instance *resolve.ComponentInstance) bool {
+	if instance == nil {
+		return false
+	}
+	if len(scope.Dependencies) > 0 && !scope.matchesDependency(instance) {
+		return false
+	}
+	if len(scope.Namespaces) > 0 && !util.ContainsString(scope.Namespaces, instance.Metadata.Key.Namespace) {
+		return false
+	}
+	if len(scope.Clusters) > 0 && !util.ContainsString(scope.Clusters, instance.Metadata.Key.ClusterName) {
+		return false
+	}
+	return true
+}
+
+// matchesDependency returns true if a given component instance belongs to one of the dependencies in the scope
+func (scope *Scope) matchesDependency(instance *resolve.ComponentInstance) bool {
+	for _, dependencyKey := range scope.Dependencies {
+		if _, found := instance.DependencyKeys[dependencyKey]; found {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopedActionPlan returns the action plan restricted to a given scope. It includes actions for component instances
+// which belong to the scope (in either actual or desired state), as well as actions which have to be applied before
+// them. If scope is empty, the whole action plan is returned
+func (diff *PolicyResolutionDiff) ScopedActionPlan(scope *Scope) *action.Plan {
+	if scope.IsEmpty() {
+		return diff.ActionPlan
+	}
+	return diff.ActionPlan.Filter(func(key string) bool {
+		return scope.matches(diff.Next.ComponentInstanceMap[key]) || scope.matches(diff.Prev.ComponentInstanceMap[key])
+	})
+}
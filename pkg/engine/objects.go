@@ -1,6 +1,7 @@
 package engine
 
 import (
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/runtime"
@@ -15,6 +16,9 @@ var (
 		component.AttachDependencyActionObject,
 		component.DetachDependencyActionObject,
 		component.EndpointsActionObject,
+		component.DriftActionObject,
+		component.HookActionObject,
+		component.ReadinessActionObject,
 	}
 
 	// Objects is the list of informational objects for all objects in the engine
@@ -22,6 +26,9 @@ var (
 		PolicyDataObject,
 		RevisionObject,
 		DesiredStateObject,
+		RevisionPlanObject,
 		resolve.ComponentInstanceObject,
+		action.ComponentRetryStateObject,
+		EnforcerPauseObject,
 	}, ActionObjects)
 )
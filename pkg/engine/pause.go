@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// EnforcerPauseObject is Info for EnforcerPause
+var EnforcerPauseObject = &runtime.Info{
+	Kind:        "enforcer-pause",
+	Storable:    true,
+	Versioned:   false,
+	Constructor: func() runtime.Object { return &EnforcerPause{} },
+}
+
+// EnforcerPause pauses desired state enforcement, either globally or for component instances in a given namespace or
+// cluster. It's persisted in the store, so enforcement stays paused across server restarts until it gets resumed
+type EnforcerPause struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	// Namespace restricts the pause to component instances in a given namespace, if set
+	Namespace string `yaml:",omitempty"`
+
+	// Cluster restricts the pause to component instances in a cluster with a given name, if set
+	Cluster string `yaml:",omitempty"`
+
+	// Reason is a human-readable reason of the pause
+	Reason string
+
+	// PausedBy is a name of the user who paused enforcement
+	PausedBy string
+
+	// PausedAt is a time when enforcement was paused
+	PausedAt time.Time
+}
+
+// NewEnforcerPause creates a new EnforcerPause for a given namespace or cluster (or a global one, if both are empty)
+func NewEnforcerPause(namespace string, cluster string, reason string, pausedBy string) *EnforcerPause {
+	return &EnforcerPause{
+		TypeKind:  EnforcerPauseObject.GetTypeKind(),
+		Namespace: namespace,
+		Cluster:   cluster,
+		Reason:    reason,
+		PausedBy:  pausedBy,
+		PausedAt:  time.Now(),
+	}
+}
+
+// EnforcerPauseName returns name of the EnforcerPause for a given namespace or cluster (or of the global one, if both
+// are empty). There can only be one pause with a given name
+func EnforcerPauseName(namespace string, cluster string) string {
+	if len(namespace) > 0 {
+		return "namespace#" + namespace
+	}
+	if len(cluster) > 0 {
+		return "cluster#" + cluster
+	}
+	return "global"
+}
+
+// GetName returns name of the EnforcerPause
+func (pause *EnforcerPause) GetName() string {
+	return EnforcerPauseName(pause.Namespace, pause.Cluster)
+}
+
+// GetNamespace returns namespace of the EnforcerPause
+func (pause *EnforcerPause) GetNamespace() string {
+	return runtime.SystemNS
+}
+
+// IsGlobal returns true if the pause applies to all component instances, i.e. enforcement is completely stopped
+func (pause *EnforcerPause) IsGlobal() bool {
+	return len(pause.Namespace) <= 0 && len(pause.Cluster) <= 0
+}
+
+// Matches returns true if changes to a given component instance are paused
+func (pause *EnforcerPause) Matches(instance *resolve.ComponentInstance) bool {
+	if len(pause.Namespace) > 0 && pause.Namespace != instance.Metadata.Key.Namespace {
+		return false
+	}
+	if len(pause.Cluster) > 0 && pause.Cluster != instance.Metadata.Key.ClusterName {
+		return false
+	}
+	return true
+}
+
+// String returns a human-readable scope of the pause
+func (pause *EnforcerPause) String() string {
+	if len(pause.Namespace) > 0 {
+		return fmt.Sprintf("namespace '%s'", pause.Namespace)
+	}
+	if len(pause.Cluster) > 0 {
+		return fmt.Sprintf("cluster '%s'", pause.Cluster)
+	}
+	return "all namespaces and clusters"
+}
@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnforcerPauseMatches(t *testing.T) {
+	instance := &resolve.ComponentInstance{
+		Metadata: &resolve.ComponentInstanceMetadata{
+			Key: &resolve.ComponentInstanceKey{Namespace: "main", ClusterName: "cluster-us"},
+		},
+	}
+
+	// global pause matches every component instance
+	pause := NewEnforcerPause("", "", "reason", "alice")
+	assert.True(t, pause.IsGlobal(), "Pause without namespace and cluster should be global")
+	assert.Equal(t, "global", pause.GetName(), "Global pause should have a fixed name")
+	assert.True(t, pause.Matches(instance), "Global pause should match every component instance")
+
+	// pauses for a namespace or a cluster only match component instances in them
+	for _, test := range []struct {
+		pause   *EnforcerPause
+		name    string
+		matches bool
+	}{
+		{NewEnforcerPause("main", "", "reason", "alice"), "namespace#main", true},
+		{NewEnforcerPause("other", "", "reason", "alice"), "namespace#other", false},
+		{NewEnforcerPause("", "cluster-us", "reason", "alice"), "cluster#cluster-us", true},
+		{NewEnforcerPause("", "cluster-eu", "reason", "alice"), "cluster#cluster-eu", false},
+	} {
+		assert.False(t, test.pause.IsGlobal(), "Pause for %s should not be global", test.pause)
+		assert.Equal(t, test.name, test.pause.GetName(), "Pause for %s should have correct name", test.pause)
+		assert.Equal(t, test.matches, test.pause.Matches(instance), "Pause for %s should match component instance correctly", test.pause)
+	}
+}
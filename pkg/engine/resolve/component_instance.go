@@ -24,6 +24,9 @@ type ComponentInstanceMetadata struct {
 	Key *ComponentInstanceKey
 }
 
+// deployNameNextSuffix is added to the default deploy name of the second copy of code during blue/green update
+const deployNameNextSuffix = "-g"
+
 // AllowIngres is an special key, which is used in DataForPlugins to indicate whether ingress traffic should be allowed for a given component instance
 const AllowIngres = "allow_ingress"
 
@@ -67,6 +70,9 @@ type ComponentInstance struct {
 	// CalculatedCodeParams is a set of calculated code parameters for the component (non-conflicting over all uses of this component)
 	CalculatedCodeParams util.NestedParameterMap
 
+	// CalculatedHookParams is a set of calculated code parameters for every hook defined for the component (hook -> params)
+	CalculatedHookParams map[string]util.NestedParameterMap `yaml:",omitempty"`
+
 	// DataForPlugins is an additional data recorded for use in plugins
 	DataForPlugins map[string]string
 
@@ -92,6 +98,31 @@ type ComponentInstance struct {
 
 	// Endpoints represents all URLs that could be used to access deployed service
 	Endpoints map[string]string
+
+	// DeployName is a name, under which code of the component instance is currently deployed, if it differs from the
+	// default one (i.e. after blue/green update)
+	DeployName string `yaml:",omitempty"`
+
+	// Drift is a description of how deployed code differs from this component instance (e.g. it has been deleted or
+	// changed manually), as reported by the drift detector. Empty if no drift has been detected
+	Drift string `yaml:",omitempty"`
+
+	// DriftMissing is true if deployed code no longer exists in the cloud
+	DriftMissing bool `yaml:",omitempty"`
+
+	// DriftDetectedAt is when drift has been detected for the first time
+	DriftDetectedAt time.Time
+
+	// DriftRepair is true if drifted component instance has to be re-created or updated during the next enforcement
+	DriftRepair bool `yaml:",omitempty"`
+
+	// NotReady is true if code of the component instance hasn't become ready since it had been created or updated, so
+	// component instances depending on it are held until it does
+	NotReady bool `yaml:",omitempty"`
+
+	// PendingHooks is a list of hooks, which have to be executed for already created component instance, but haven't
+	// succeeded yet (e.g. post-create hook, which failed after component instance had been created)
+	PendingHooks []string `yaml:",omitempty"`
 }
 
 // Creates a new component instance
@@ -116,9 +147,34 @@ func (instance *ComponentInstance) GetKey() string {
 
 // GetDeployName returns a string that could be used as name for deployment inside the cluster
 func (instance *ComponentInstance) GetDeployName() string {
+	if len(instance.DeployName) > 0 {
+		return instance.DeployName
+	}
 	return instance.Metadata.Key.GetDeployName()
 }
 
+// GetNextDeployName returns a deploy name for the second copy of code, which gets deployed during blue/green update.
+// It alternates between the default deploy name and the default deploy name with a suffix
+func (instance *ComponentInstance) GetNextDeployName() string {
+	deployName := instance.Metadata.Key.GetDeployName()
+	if instance.GetDeployName() == deployName {
+		return deployName + deployNameNextSuffix
+	}
+	return deployName
+}
+
+// HasHook returns true if a given hook is defined for the component instance
+func (instance *ComponentInstance) HasHook(hook string) bool {
+	_, ok := instance.CalculatedHookParams[hook]
+	return ok
+}
+
+// GetHookDeployName returns a deploy name for code of a given hook. It's derived from the default deploy name, so it
+// doesn't change after blue/green updates
+func (instance *ComponentInstance) GetHookDeployName(hook string) string {
+	return instance.Metadata.Key.GetDeployName() + "-" + hook
+}
+
 // GetNamespace returns an object namespace. It's a system namespace for all component instances
 func (instance *ComponentInstance) GetNamespace() string {
 	return runtime.SystemNS
@@ -129,6 +185,19 @@ func (instance *ComponentInstance) GetName() string {
 	return instance.GetKey()
 }
 
+// IsDrifted returns true if drift has been detected for the component instance
+func (instance *ComponentInstance) IsDrifted() bool {
+	return len(instance.Drift) > 0
+}
+
+// ResetDrift clears drift information for the component instance (e.g. once it has been re-created or updated)
+func (instance *ComponentInstance) ResetDrift() {
+	instance.Drift = ""
+	instance.DriftMissing = false
+	instance.DriftDetectedAt = time.Time{}
+	instance.DriftRepair = false
+}
+
 // GetRunningTime returns the total lifetime of a component instance (since it was launched till now)
 func (instance *ComponentInstance) GetRunningTime() time.Duration {
 	return time.Since(instance.CreatedAt)
@@ -160,6 +229,27 @@ func (instance *ComponentInstance) addCodeParams(codeParams util.NestedParameter
 	return nil
 }
 
+func (instance *ComponentInstance) addHookParams(hook string, hookParams util.NestedParameterMap) error {
+	if instance.CalculatedHookParams == nil {
+		instance.CalculatedHookParams = make(map[string]util.NestedParameterMap)
+	}
+	if existing, ok := instance.CalculatedHookParams[hook]; !ok {
+		// Record hook parameters
+		instance.CalculatedHookParams[hook] = hookParams
+	} else if !existing.DeepEqual(hookParams) {
+		// Same component instance, different hook parameters
+		return errors.NewErrorWithDetails(
+			fmt.Sprintf("conflicting %s hook parameters for component instance: %s", hook, instance.GetKey()),
+			errors.Details{
+				"hook_params_existing": existing,
+				"hook_params_new":      hookParams,
+				"diff":                 existing.Diff(hookParams),
+			},
+		)
+	}
+	return nil
+}
+
 func (instance *ComponentInstance) addDiscoveryParams(discoveryParams util.NestedParameterMap) error {
 	if len(instance.CalculatedDiscovery) == 0 {
 		// Record discovery parameters
@@ -229,6 +319,15 @@ func (instance *ComponentInstance) appendData(ops *ComponentInstance) {
 		return
 	}
 
+	// Combine hook params
+	for hook, hookParams := range ops.CalculatedHookParams {
+		err = instance.addHookParams(hook, hookParams)
+		if err != nil {
+			instance.Error = err
+			return
+		}
+	}
+
 	// Outgoing graph edges (instance: key -> true) as we are traversing the graph
 	for keyDst := range ops.EdgesOut {
 		instance.addEdgeOut(keyDst)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/lang/builder"
+	"github.com/Aptomi/aptomi/pkg/plugin"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,6 +54,30 @@ func TestComponentKeyUnsafe(t *testing.T) {
 	}
 }
 
+func TestComponentKeyDeployName(t *testing.T) {
+	for _, key := range []*ComponentInstanceKey{makeKey(false), makeKey(true), makeKeyUnsafe()} {
+		assert.True(t, plugin.IsDeployName(key.GetDeployName()), "Deploy name should be recognized by plugins: %s", key.GetDeployName())
+	}
+	assert.False(t, plugin.IsDeployName("my-release"), "Arbitrary name should not be recognized as a deploy name")
+
+	// deploy name of the second copy of code should alternate during blue/green updates
+	instance := newComponentInstance(makeKey(false))
+	deployName := instance.GetDeployName()
+	nextDeployName := instance.GetNextDeployName()
+	assert.NotEqual(t, deployName, nextDeployName, "Second copy of code should have a different deploy name")
+	assert.True(t, plugin.IsDeployName(nextDeployName), "Deploy name of the second copy of code should be recognized by plugins: %s", nextDeployName)
+
+	instance.DeployName = nextDeployName
+	assert.Equal(t, nextDeployName, instance.GetDeployName(), "Current deploy name should be used after blue/green update")
+	assert.Equal(t, deployName, instance.GetNextDeployName(), "Next blue/green update should switch back to the default deploy name")
+
+	// deploy name of hook code should not depend on blue/green updates
+	for _, hook := range []string{lang.HookPreCreate, lang.HookPostCreate, lang.HookPreUpdate, lang.HookPreDelete} {
+		assert.Equal(t, deployName+"-"+hook, instance.GetHookDeployName(hook), "Hook deploy name should be derived from the default deploy name")
+		assert.True(t, plugin.IsDeployName(instance.GetHookDeployName(hook)), "Deploy name of hook code should be recognized by plugins: %s", instance.GetHookDeployName(hook))
+	}
+}
+
 func makeKey(root bool) *ComponentInstanceKey {
 	b := builder.NewPolicyBuilder()
 	service := b.AddService()
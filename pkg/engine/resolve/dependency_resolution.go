@@ -9,12 +9,16 @@ type DependencyResolution struct {
 
 	// ComponentInstanceKey holds the reference to component instance, to which dependency got resolved
 	ComponentInstanceKey string
+
+	// Error holds the resolution error, if dependency could not be resolved
+	Error string
 }
 
 // Creates a new dependency resolution
-func newDependencyResolution(resolved bool, key string) *DependencyResolution {
+func newDependencyResolution(resolved bool, key string, err string) *DependencyResolution {
 	return &DependencyResolution{
 		Resolved:             resolved,
 		ComponentInstanceKey: key,
+		Error:                err,
 	}
 }
@@ -0,0 +1,254 @@
+package resolve
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// DependencyTrace is a structured explanation of how a dependency got resolved. It contains a step for every
+// contract processed while traversing the policy graph, starting with the contract of the dependency itself
+type DependencyTrace struct {
+	// Dependency is a key of the dependency being explained
+	Dependency string
+
+	// Resolved is true if dependency has been successfully resolved
+	Resolved bool
+
+	// Error is the resolution error, if dependency could not be resolved
+	Error string `yaml:",omitempty"`
+
+	// Steps is a list of resolution steps in the order they were processed
+	Steps []*TraceStep
+}
+
+// TraceStep explains resolution of a single contract
+type TraceStep struct {
+	// Depth is a depth in the policy graph, with contract of the dependency being on depth 0
+	Depth int
+
+	// Contract is a contract being resolved
+	Contract string
+
+	// Context is a context which has been matched within the contract
+	Context string `yaml:",omitempty"`
+
+	// RejectedContexts is a list of contexts which were tried before the matched one, but didn't match
+	RejectedContexts []*TraceRejectedContext `yaml:",omitempty"`
+
+	// Service is a service which implements the matched context
+	Service string `yaml:",omitempty"`
+
+	// Labels is a list of label sets, recorded every time labels got changed
+	Labels []*TraceLabels `yaml:",omitempty"`
+
+	// Rules is a list of rules which were evaluated
+	Rules []*TraceRule `yaml:",omitempty"`
+
+	// AllocationKeys is a list of resolved allocation keys of the matched context
+	AllocationKeys []string `yaml:",omitempty"`
+
+	// Cluster is a cluster which has been picked for the service
+	Cluster string `yaml:",omitempty"`
+
+	// TargetSuffix is a target suffix (e.g. k8s namespace) which has been picked for the service
+	TargetSuffix string `yaml:",omitempty"`
+
+	// Error is the error which happened on this step, if any
+	Error string `yaml:",omitempty"`
+}
+
+// TraceRejectedContext explains why a context didn't match
+type TraceRejectedContext struct {
+	// Name is a name of the context
+	Name string
+
+	// FailedClause is a criteria clause which evaluated to false (or a reason why context has been excluded by
+	// traffic split)
+	FailedClause string
+}
+
+// TraceRule explains evaluation of a single rule
+type TraceRule struct {
+	// Rule is a key of the rule
+	Rule string
+
+	// Matched is true if rule criteria evaluated to true and rule actions got applied
+	Matched bool
+
+	// RejectDependency is true if the rule rejected the dependency
+	RejectDependency bool `yaml:",omitempty"`
+
+	// RejectIngress is true if the rule rejected ingress traffic
+	RejectIngress bool `yaml:",omitempty"`
+
+	// ChangedLabels is true if the rule changed labels
+	ChangedLabels bool `yaml:",omitempty"`
+}
+
+// TraceLabels is a snapshot of labels taken at a certain stage of resolution
+type TraceLabels struct {
+	// Stage describes what caused labels to change (e.g. "initial", "contract", "context", "rule <name>")
+	Stage string
+
+	// Labels is a copy of labels
+	Labels map[string]string
+}
+
+// ExplainDependency resolves a single dependency and returns a structured trace, explaining every decision made
+// during its resolution. Quotas are checked the same way as in ResolveAllDependencies, i.e. taking into account usage
+// by all dependencies which go before the given one. It doesn't affect PolicyResolution calculated by ResolveAllDependencies
+func (resolver *PolicyResolver) ExplainDependency(d *lang.Dependency) *DependencyTrace {
+	trace := &DependencyTrace{
+		Dependency: runtime.KeyForStorable(d),
+		Steps:      []*TraceStep{},
+	}
+
+	node, err := resolver.resolveDependency(d, trace)
+	if err == nil {
+		err = resolver.checkQuotas(node)
+	}
+	if err != nil {
+		trace.Error = err.Error()
+	} else {
+		trace.Resolved = true
+	}
+
+	return trace
+}
+
+// Checks whether a resolved dependency fits into quotas. Dependencies which go before the given one get resolved and
+// their usage gets recorded first, so the result is the same as in ResolveAllDependencies
+func (resolver *PolicyResolver) checkQuotas(node *resolutionNode) error {
+	quotas := resolver.newQuotaUsage()
+	if len(quotas.getQuotas(node.dependency)) == 0 {
+		return nil
+	}
+
+	dependencies := resolver.policy.GetObjectsByKind(lang.DependencyObject.Kind)
+	sort.Sort(dependencySorter(dependencies))
+	key := runtime.KeyForStorable(node.dependency)
+	for _, obj := range dependencies {
+		d := obj.(*lang.Dependency) // nolint: errcheck
+		if runtime.KeyForStorable(d) == key || !dependencyLess(d, node.dependency) {
+			break
+		}
+
+		prevNode, err := resolver.resolveDependency(d, nil)
+		if err == nil {
+			// dependencies which don't fit into quotas are not counted, same as in ResolveAllDependencies
+			_ = quotas.checkAndRecord(prevNode)
+		}
+	}
+
+	return quotas.checkAndRecord(node)
+}
+
+/*
+	Trace - record resolution decisions, if the node is being traced
+*/
+
+func (node *resolutionNode) traceStart() {
+	if node.trace == nil {
+		return
+	}
+	node.traceStep = &TraceStep{
+		Depth:    node.depth,
+		Contract: node.contractName,
+	}
+	node.trace.Steps = append(node.trace.Steps, node.traceStep)
+	node.traceLabels(node.labels, "initial")
+}
+
+func (node *resolutionNode) traceLabels(labelSet *lang.LabelSet, stage string) {
+	if node.trace == nil {
+		return
+	}
+	labels := make(map[string]string, len(labelSet.Labels))
+	for k, v := range labelSet.Labels {
+		labels[k] = v
+	}
+	node.traceStep.Labels = append(node.traceStep.Labels, &TraceLabels{Stage: stage, Labels: labels})
+}
+
+func (node *resolutionNode) traceContextRejected(context *lang.Context) {
+	if node.trace == nil || context.Criteria == nil {
+		return
+	}
+	failedClause, err := context.Criteria.FailedClause(node.getContextualDataForContextExpression(), node.resolver.expressionCache)
+	if err != nil {
+		failedClause = err.Error()
+	}
+	node.traceStep.RejectedContexts = append(node.traceStep.RejectedContexts, &TraceRejectedContext{
+		Name:         context.Name,
+		FailedClause: failedClause,
+	})
+}
+
+func (node *resolutionNode) traceContextOutOfTraffic(context *lang.Context, bucket int) {
+	if node.trace == nil {
+		return
+	}
+	node.traceStep.RejectedContexts = append(node.traceStep.RejectedContexts, &TraceRejectedContext{
+		Name:         context.Name,
+		FailedClause: fmt.Sprintf("traffic split: bucket %d is not within %d%%", bucket, context.Traffic.Percent),
+	})
+}
+
+func (node *resolutionNode) traceContextMatched() {
+	if node.trace == nil {
+		return
+	}
+	node.traceStep.Contract = runtime.KeyForStorable(node.contract)
+	node.traceStep.Context = node.context.Name
+}
+
+func (node *resolutionNode) traceServiceMatched() {
+	if node.trace == nil {
+		return
+	}
+	node.traceStep.Service = runtime.KeyForStorable(node.service)
+}
+
+func (node *resolutionNode) traceAllocationKeys() {
+	if node.trace == nil {
+		return
+	}
+	node.traceStep.AllocationKeys = node.allocationKeysResolved
+}
+
+func (node *resolutionNode) traceRule(rule *lang.Rule, matched bool, result *lang.RuleActionResult) {
+	if node.trace == nil {
+		return
+	}
+	traceRule := &TraceRule{
+		Rule:    runtime.KeyForStorable(rule),
+		Matched: matched,
+	}
+	if matched {
+		traceRule.RejectDependency = string(rule.Actions.Dependency) == lang.Reject
+		traceRule.RejectIngress = string(rule.Actions.Ingress) == lang.Reject
+		traceRule.ChangedLabels = result.ChangedLabelsOnLastApply
+	}
+	node.traceStep.Rules = append(node.traceStep.Rules, traceRule)
+	if traceRule.ChangedLabels {
+		node.traceLabels(result.Labels, fmt.Sprintf("rule %s", rule.Name))
+	}
+}
+
+func (node *resolutionNode) traceCluster(cluster *lang.Cluster, targetSuffix string) {
+	if node.trace == nil {
+		return
+	}
+	node.traceStep.Cluster = runtime.KeyForStorable(cluster)
+	node.traceStep.TargetSuffix = targetSuffix
+}
+
+func (node *resolutionNode) traceError(err error) {
+	if node.trace == nil || node.traceStep == nil {
+		return
+	}
+	node.traceStep.Error = err.Error()
+}
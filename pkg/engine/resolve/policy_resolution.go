@@ -15,6 +15,9 @@ import (
 type PolicyResolution struct {
 	// Resolved component instances: componentKey -> componentInstance
 	ComponentInstanceMap map[string]*ComponentInstance
+
+	// Errors for dependencies which could not be resolved: dependencyKey -> error
+	DependencyErrorMap map[string]string `yaml:",omitempty"`
 }
 
 // NewPolicyResolution creates new empty PolicyResolution, given a flag indicating whether it's a
@@ -22,6 +25,7 @@ type PolicyResolution struct {
 func NewPolicyResolution() *PolicyResolution {
 	return &PolicyResolution{
 		ComponentInstanceMap: make(map[string]*ComponentInstance),
+		DependencyErrorMap:   make(map[string]string),
 	}
 }
 
@@ -48,6 +52,11 @@ func (resolution *PolicyResolution) RecordCodeParams(cik *ComponentInstanceKey,
 	return instance.addCodeParams(codeParams)
 }
 
+// RecordHookParams stores calculated params of a given hook for component instance
+func (resolution *PolicyResolution) RecordHookParams(cik *ComponentInstanceKey, hook string, hookParams util.NestedParameterMap) error {
+	return resolution.GetComponentInstanceEntry(cik).addHookParams(hook, hookParams)
+}
+
 // RecordDiscoveryParams stores calculated discovery params for component instance
 func (resolution *PolicyResolution) RecordDiscoveryParams(cik *ComponentInstanceKey, discoveryParams util.NestedParameterMap) error {
 	return resolution.GetComponentInstanceEntry(cik).addDiscoveryParams(discoveryParams)
@@ -58,6 +67,14 @@ func (resolution *PolicyResolution) RecordLabels(cik *ComponentInstanceKey, labe
 	resolution.GetComponentInstanceEntry(cik).addLabels(labels)
 }
 
+// RecordDependencyError stores an error for a dependency, which could not be resolved
+func (resolution *PolicyResolution) RecordDependencyError(dependency *lang.Dependency, err error) {
+	if resolution.DependencyErrorMap == nil {
+		resolution.DependencyErrorMap = make(map[string]string)
+	}
+	resolution.DependencyErrorMap[runtime.KeyForStorable(dependency)] = err.Error()
+}
+
 // StoreEdge stores incoming/outgoing graph edges for component instance for observability and reporting
 func (resolution *PolicyResolution) StoreEdge(src *ComponentInstanceKey, dst *ComponentInstanceKey) {
 	// Arrival key can be empty at the very top of the recursive function in engine, so let's check for that
@@ -78,6 +95,12 @@ func (resolution *PolicyResolution) AppendData(ops *PolicyResolution) {
 			resolution.ComponentInstanceMap[key].appendData(instance)
 		}
 	}
+	for key, err := range ops.DependencyErrorMap {
+		if resolution.DependencyErrorMap == nil {
+			resolution.DependencyErrorMap = make(map[string]string)
+		}
+		resolution.DependencyErrorMap[key] = err
+	}
 }
 
 // GetDependencyResolution returns resolution status for a particular dependency
@@ -100,7 +123,15 @@ func (resolution *PolicyResolution) GetDependencyResolution(dependency *lang.Dep
 		}
 	}
 
-	return newDependencyResolution(dError == nil && len(dComponentKey) > 0, dComponentKey)
+	// see if dependency resolution failed
+	var dErrorStr string
+	if resolutionErr, failed := resolution.DependencyErrorMap[dKey]; failed {
+		dErrorStr = resolutionErr
+	} else if dError != nil {
+		dErrorStr = dError.Error()
+	}
+
+	return newDependencyResolution(dError == nil && len(dComponentKey) > 0, dComponentKey, dErrorStr)
 }
 
 // Validate checks that the state is valid, meaning that all objects references are valid and all components are valid
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	sysruntime "runtime"
 	"runtime/debug"
+	"sort"
 	"sync"
 
 	"github.com/Aptomi/aptomi/pkg/event"
@@ -11,6 +12,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/lang/expression"
 	"github.com/Aptomi/aptomi/pkg/lang/template"
+	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
 )
 
@@ -32,6 +34,10 @@ type PolicyResolver struct {
 	// External data
 	externalData *external.Data
 
+	// Deploy names of component instances, which are deployed under names other than the default ones (e.g. after
+	// blue/green update), as recorded in actual state
+	deployNames map[string]string
+
 	/*
 		Cache
 	*/
@@ -74,6 +80,26 @@ func NewPolicyResolver(policy *lang.Policy, externalData *external.Data, eventLo
 	}
 }
 
+// WithDeployNames makes resolver take into account deploy names of component instances recorded in a given actual
+// state (e.g. after blue/green update), so other components discover the copy of code which is currently running
+func (resolver *PolicyResolver) WithDeployNames(actualState *PolicyResolution) *PolicyResolver {
+	resolver.deployNames = make(map[string]string)
+	for key, instance := range actualState.ComponentInstanceMap {
+		if len(instance.DeployName) > 0 {
+			resolver.deployNames[key] = instance.DeployName
+		}
+	}
+	return resolver
+}
+
+// getDeployName returns the name, under which code of a given component instance is currently deployed
+func (resolver *PolicyResolver) getDeployName(cik *ComponentInstanceKey) string {
+	if deployName, ok := resolver.deployNames[cik.GetKey()]; ok {
+		return deployName
+	}
+	return cik.GetDeployName()
+}
+
 // ResolveAllDependencies takes policy as input and calculates PolicyResolution (desired state) as output.
 //
 // The method resolves all recorded claims for consuming contracts ("instantiate <contract> with <labels>"), calculating
@@ -87,22 +113,38 @@ func (resolver *PolicyResolver) ResolveAllDependencies() *PolicyResolution {
 	var wg sync.WaitGroup
 	dependencies := resolver.policy.GetObjectsByKind(lang.DependencyObject.Kind)
 
+	// Sort dependencies, so they get combined in a deterministic order (older dependencies go first when quotas are enforced)
+	sort.Sort(dependencySorter(dependencies))
+
 	// Resolve every declared dependency
-	for _, d := range dependencies {
+	nodes := make([]*resolutionNode, len(dependencies))
+	resolveErrs := make([]error, len(dependencies))
+	for idx, d := range dependencies {
 		// Start go routine for resolving a given dependency
 		wg.Add(1)
 		semaphore <- 1
-		go func(d *lang.Dependency) {
+		go func(idx int, d *lang.Dependency) {
 			defer wg.Done()
-			node, resolveErr := resolver.resolveDependency(d)
-			resolver.combineData(node, resolveErr)
+			nodes[idx], resolveErrs[idx] = resolver.resolveDependency(d, nil)
 			<-semaphore
-		}(d.(*lang.Dependency))
+		}(idx, d.(*lang.Dependency))
 	}
 
 	// Wait for all go routines to end
 	wg.Wait()
 
+	// Combine resolved dependencies, making sure they fit into quotas
+	quotas := resolver.newQuotaUsage()
+	for idx := range dependencies {
+		if resolveErrs[idx] == nil {
+			resolveErrs[idx] = quotas.checkAndRecord(nodes[idx])
+			if resolveErrs[idx] != nil {
+				nodes[idx].eventLog.NewEntry().Error(resolveErrs[idx])
+			}
+		}
+		resolver.combineData(nodes[idx], resolveErrs[idx])
+	}
+
 	// Once all components are resolved, print information about them into event log
 	for _, instance := range resolver.resolution.ComponentInstanceMap {
 		if instance.Metadata.Key.IsComponent() {
@@ -113,8 +155,9 @@ func (resolver *PolicyResolver) ResolveAllDependencies() *PolicyResolution {
 	return resolver.resolution
 }
 
-// Resolves a single dependency and returns an error if it cannot be resolved
-func (resolver *PolicyResolver) resolveDependency(d *lang.Dependency) (node *resolutionNode, resolveErr error) {
+// Resolves a single dependency and returns an error if it cannot be resolved. If trace is not nil, resolution
+// decisions will be recorded into it
+func (resolver *PolicyResolver) resolveDependency(d *lang.Dependency, trace *DependencyTrace) (node *resolutionNode, resolveErr error) {
 	// make sure we are converting panics into errors
 	defer func() {
 		if err := recover(); err != nil {
@@ -125,6 +168,7 @@ func (resolver *PolicyResolver) resolveDependency(d *lang.Dependency) (node *res
 
 	// create new resolution node
 	node = resolver.newResolutionNode()
+	node.trace = trace
 
 	// populate resolution node with data (e.g. construct initial set of labels)
 	resolver.initResolutionNode(node, d)
@@ -153,9 +197,35 @@ func (resolver *PolicyResolver) combineData(node *resolutionNode, resolutionErr
 	if resolutionErr == nil {
 		// aggregate component instance data
 		resolver.resolution.AppendData(node.resolution)
+	} else if node != nil && node.dependency != nil {
+		// record resolution error, so it can be reported in dependency status
+		resolver.resolution.RecordDependencyError(node.dependency, resolutionErr)
 	}
 }
 
+type dependencySorter []lang.Base
+
+func (ds dependencySorter) Len() int {
+	return len(ds)
+}
+
+func (ds dependencySorter) Swap(i, j int) {
+	ds[i], ds[j] = ds[j], ds[i]
+}
+
+func (ds dependencySorter) Less(i, j int) bool {
+	return dependencyLess(ds[i].(*lang.Dependency), ds[j].(*lang.Dependency))
+}
+
+// Returns true if dependency di goes before dependency dj when resolved dependencies get combined (i.e. older
+// dependencies go first)
+func dependencyLess(di *lang.Dependency, dj *lang.Dependency) bool {
+	if !di.CreatedAt.Equal(dj.CreatedAt) {
+		return di.CreatedAt.Before(dj.CreatedAt)
+	}
+	return runtime.KeyForStorable(di) < runtime.KeyForStorable(dj)
+}
+
 // Evaluate evaluates and resolves a single dependency ("<user> needs <service> with <labels>") and calculates component allocations
 // Returns error only if there is an issue with the given dependency and it cannot be resolved
 func (resolver *PolicyResolver) resolveNode(node *resolutionNode) (resolveErr error) {
@@ -171,6 +241,11 @@ func (resolver *PolicyResolver) resolveNode(node *resolutionNode) (resolveErr er
 
 			// Log that service or component instance cannot be resolved
 			node.logCannotResolveInstance()
+
+			// Record error in the trace
+			if !recursiveError {
+				node.traceError(resolveErr)
+			}
 		}
 	}()
 
@@ -180,6 +255,7 @@ func (resolver *PolicyResolver) resolveNode(node *resolutionNode) (resolveErr er
 	// Indicate that we are starting to resolve dependency
 	node.objectResolved(node.dependency)
 	node.logStartResolvingDependency()
+	node.traceStart()
 
 	// Locate the user
 	err = node.checkUserExists()
@@ -193,13 +269,14 @@ func (resolver *PolicyResolver) resolveNode(node *resolutionNode) (resolveErr er
 	node.objectResolved(node.contract)
 
 	// Process service and transform labels
-	node.transformLabels(node.labels, node.contract.ChangeLabels)
+	node.transformLabels(node.labels, node.contract.ChangeLabels, "contract")
 
 	// Match the context
 	node.context, err = node.getMatchedContext(resolver.policy)
 	if err != nil {
 		return err
 	}
+	node.traceContextMatched()
 
 	// Check that service, which current context is implemented with, exists
 	node.service, err = node.getMatchedService(resolver.policy)
@@ -207,15 +284,17 @@ func (resolver *PolicyResolver) resolveNode(node *resolutionNode) (resolveErr er
 		return err
 	}
 	node.objectResolved(node.service)
+	node.traceServiceMatched()
 
 	// Process context and transform labels
-	node.transformLabels(node.labels, node.context.ChangeLabels)
+	node.transformLabels(node.labels, node.context.ChangeLabels, "context")
 
 	// Resolve allocation keys for the context
 	node.allocationKeysResolved, err = node.resolveAllocationKeys(resolver.policy)
 	if err != nil {
 		return err
 	}
+	node.traceAllocationKeys()
 
 	// Process global rules before processing service key and dependent component keys
 	ruleResult, err := node.processRules()
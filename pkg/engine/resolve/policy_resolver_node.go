@@ -67,6 +67,11 @@ type resolutionNode struct {
 
 	// path that we traveled so far (to detect cycles)
 	path []string
+
+	// structured trace of the dependency resolution (shared between all nodes in the tree) and the current step in it.
+	// trace is nil, unless dependency is being explained
+	trace     *DependencyTrace
+	traceStep *TraceStep
 }
 
 // Creates a new empty resolution node
@@ -102,10 +107,16 @@ func (resolver *PolicyResolver) initResolutionNode(node *resolutionNode, depende
 	node.contractName = dependency.Contract
 
 	// create a starting set of labels, combining user labels and dependency labels
-	node.labels = lang.NewLabelSet(dependency.Labels)
+	node.labels = getInitialLabels(dependency, user)
+}
+
+// Returns a starting set of labels for a given dependency, combining user labels and dependency labels
+func getInitialLabels(dependency *lang.Dependency, user *lang.User) *lang.LabelSet {
+	result := lang.NewLabelSet(dependency.Labels)
 	if user != nil {
-		node.labels.AddLabels(user.Labels)
+		result.AddLabels(user.Labels)
 	}
+	return result
 }
 
 // Creates a new resolution node (as we are processing dependency on another service)
@@ -137,6 +148,9 @@ func (node *resolutionNode) createChildNode() *resolutionNode {
 
 		// copy path
 		path: util.CopySliceOfStrings(node.path),
+
+		// continue the same trace
+		trace: node.trace,
 	}
 }
 
@@ -181,10 +195,28 @@ func (node *resolutionNode) getMatchedContext(policy *lang.Policy) (*lang.Contex
 			return nil, node.errorWhenTestingContext(context, err)
 		}
 		node.logTestedContextCriteria(context, matched)
-		if matched {
-			contextMatched = context
-			break
+		if !matched {
+			node.traceContextRejected(context)
+			continue
 		}
+
+		// Check if consumer falls into the fraction of traffic routed to the context
+		if context.Traffic != nil {
+			bucket, err := context.ResolveTrafficBucket(node.getContextualDataForContextAllocationTemplate(), runtime.KeyForStorable(node.dependency), node.resolver.templateCache)
+			if err != nil {
+				// Propagate error up
+				return nil, node.errorWhenTestingContext(context, err)
+			}
+			inTraffic := bucket < context.Traffic.Percent
+			node.logTestedContextTraffic(context, bucket, inTraffic)
+			if !inTraffic {
+				node.traceContextOutOfTraffic(context, bucket)
+				continue
+			}
+		}
+
+		contextMatched = context
+		break
 	}
 
 	if contextMatched == nil {
@@ -275,6 +307,10 @@ func (node *resolutionNode) createComponentKey(component *lang.ServiceComponent)
 		}
 	}
 
+	if component == nil {
+		node.traceCluster(cluster, target.Suffix)
+	}
+
 	return NewComponentInstanceKey(
 		cluster,
 		target.Suffix,
@@ -286,10 +322,11 @@ func (node *resolutionNode) createComponentKey(component *lang.ServiceComponent)
 	), nil
 }
 
-func (node *resolutionNode) transformLabels(labels *lang.LabelSet, operations lang.LabelOperations) {
+func (node *resolutionNode) transformLabels(labels *lang.LabelSet, operations lang.LabelOperations, stage string) {
 	changedLabels := labels.ApplyTransform(operations)
 	if changedLabels {
 		node.logLabels(labels, "after transform")
+		node.traceLabels(labels, stage)
 	}
 }
 
@@ -308,6 +345,9 @@ func (node *resolutionNode) processRulesWithinNamespace(policyNamespace *lang.Po
 		node.logTestedRuleMatch(rule, matched)
 		if matched {
 			rule.ApplyActions(result)
+		}
+		node.traceRule(rule, matched, result)
+		if matched {
 
 			// if a dependency has been rejected, handle it right away and return that we cannot resolve it
 			if result.RejectDependency {
@@ -341,7 +381,9 @@ func (node *resolutionNode) processRules() (*lang.RuleActionResult, error) {
 }
 
 func (node *resolutionNode) calculateAndStoreCodeParams() error {
-	componentCodeParams, err := util.ProcessParameterTree(node.component.Code.Params, node.getContextualDataForCodeDiscoveryTemplate(), node.resolver.templateCache, util.ModeEvaluate)
+	// code params don't depend on which copy of code is running, otherwise every blue/green update would cause another
+	// one, so own component instance is always announced under the default deploy name
+	componentCodeParams, err := util.ProcessParameterTree(node.component.Code.Params, node.getContextualDataForCodeDiscoveryTemplate(node.componentKey.GetDeployName()), node.resolver.templateCache, util.ModeEvaluate)
 	if err != nil {
 		return node.errorWhenProcessingCodeParams(err)
 	}
@@ -351,11 +393,26 @@ func (node *resolutionNode) calculateAndStoreCodeParams() error {
 		return node.errorWhenProcessingCodeParams(err)
 	}
 
+	// hooks get the same contextual data as the code itself
+	for hook, code := range node.component.Hooks.GetAll() {
+		hookParams, hookErr := util.ProcessParameterTree(code.Params, node.getContextualDataForCodeDiscoveryTemplate(node.componentKey.GetDeployName()), node.resolver.templateCache, util.ModeEvaluate)
+		if hookErr != nil {
+			return node.errorWhenProcessingHookParams(hook, hookErr)
+		}
+
+		hookErr = node.resolution.RecordHookParams(node.componentKey, hook, hookParams)
+		if hookErr != nil {
+			return node.errorWhenProcessingHookParams(hook, hookErr)
+		}
+	}
+
 	return nil
 }
 
 func (node *resolutionNode) calculateAndStoreDiscoveryParams() error {
-	componentDiscoveryParams, err := util.ProcessParameterTree(node.component.Discovery, node.getContextualDataForCodeDiscoveryTemplate(), node.resolver.templateCache, util.ModeEvaluate)
+	// discovery params are used by other components, so they have to point to the copy of code which is currently running
+	deployName := node.resolver.getDeployName(node.componentKey)
+	componentDiscoveryParams, err := util.ProcessParameterTree(node.component.Discovery, node.getContextualDataForCodeDiscoveryTemplate(deployName), node.resolver.templateCache, util.ModeEvaluate)
 	if err != nil {
 		return node.errorWhenProcessingDiscoveryParams(err)
 	}
@@ -366,7 +423,7 @@ func (node *resolutionNode) calculateAndStoreDiscoveryParams() error {
 	}
 
 	// Populate discovery tree (allow this component to announce its discovery properties in the discovery tree)
-	node.discoveryTreeNode.GetNestedMap(node.component.Name)["instance"] = util.EscapeName(node.componentKey.GetDeployName())
+	node.discoveryTreeNode.GetNestedMap(node.component.Name)["instance"] = util.EscapeName(deployName)
 	for k, v := range componentDiscoveryParams {
 		node.discoveryTreeNode.GetNestedMap(node.component.Name)[k] = v
 	}
@@ -46,6 +46,35 @@ func (node *resolutionNode) getContextualDataForRuleExpression() *expression.Par
 	)
 }
 
+/*
+	Data exposed to quotas defined in policy
+*/
+
+// This method defines which contextual information will be exposed to the expression engine (for evaluating quota criteria)
+// Be careful about what gets exposed through this method. User can refer to structs and their methods from the policy
+func (node *resolutionNode) getContextualDataForQuotaExpression() *expression.Parameters {
+	return expression.NewParams(
+		getInitialLabels(node.dependency, node.user).Labels,
+		map[string]interface{}{},
+	)
+}
+
+// This method defines which contextual information will be exposed to the template engine (for evaluating quota groups)
+// Be careful about what gets exposed through this method. User can refer to structs and their methods from the policy
+func (node *resolutionNode) getContextualDataForQuotaTemplate() *template.Parameters {
+	return template.NewParams(
+		struct {
+			User       interface{}
+			Dependency interface{}
+			Labels     interface{}
+		}{
+			User:       node.proxyUser(node.user),
+			Dependency: node.proxyDependency(node.dependency),
+			Labels:     getInitialLabels(node.dependency, node.user).Labels,
+		},
+	)
+}
+
 /*
 	Data exposed to templates defined in policy
 */
@@ -67,8 +96,9 @@ func (node *resolutionNode) getContextualDataForContextAllocationTemplate() *tem
 }
 
 // This method defines which contextual information will be exposed to the template engine (for evaluating all templates - discovery, code params, etc)
-// Be careful about what gets exposed through this method. User can refer to structs and their methods from the policy
-func (node *resolutionNode) getContextualDataForCodeDiscoveryTemplate() *template.Parameters {
+// Be careful about what gets exposed through this method. User can refer to structs and their methods from the policy.
+// Own component instance is announced under a given deploy name
+func (node *resolutionNode) getContextualDataForCodeDiscoveryTemplate(deployName string) *template.Parameters {
 	return template.NewParams(
 		struct {
 			User      interface{}
@@ -78,7 +108,7 @@ func (node *resolutionNode) getContextualDataForCodeDiscoveryTemplate() *templat
 		}{
 			User:      node.proxyUser(node.user),
 			Labels:    node.labels.Labels,
-			Discovery: node.proxyDiscovery(node.discoveryTreeNode, node.componentKey),
+			Discovery: node.proxyDiscovery(node.discoveryTreeNode, node.componentKey, deployName),
 			Target:    node.proxyTarget(node.componentKey),
 		},
 	)
@@ -135,11 +165,16 @@ func (node *resolutionNode) proxyTarget(cik *ComponentInstanceKey) interface{} {
 }
 
 // How discovery tree is visible from the policy language
-func (node *resolutionNode) proxyDiscovery(discoveryTree util.NestedParameterMap, cik *ComponentInstanceKey) interface{} {
+func (node *resolutionNode) proxyDiscovery(discoveryTree util.NestedParameterMap, cik *ComponentInstanceKey, deployName string) interface{} {
 	result := discoveryTree.MakeCopy()
 
-	// special case to announce own component instance
-	result["Instance"] = util.EscapeName(cik.GetDeployName())
+	// special case to announce own component instance (in the discovery tree as well, if it's already there)
+	result["Instance"] = util.EscapeName(deployName)
+	if own, ok := result[node.component.Name].(util.NestedParameterMap); ok && len(own) > 0 {
+		ownCopy := own.MakeCopy()
+		ownCopy["instance"] = util.EscapeName(deployName)
+		result[node.component.Name] = ownCopy
+	}
 
 	// special case to announce own component ID
 	result["InstanceId"] = util.HashFnv(cik.GetKey())
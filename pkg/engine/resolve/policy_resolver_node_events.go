@@ -63,6 +63,10 @@ func (node *resolutionNode) errorWhenProcessingCodeParams(cause error) error {
 	return fmt.Errorf("error when processing code params for service '%s', contract '%s', context '%s', component '%s': %s", node.service.Name, node.contract.Name, node.context.Name, node.component.Name, printCauseDetailsOnDebug(cause, node.eventLog))
 }
 
+func (node *resolutionNode) errorWhenProcessingHookParams(hook string, cause error) error {
+	return fmt.Errorf("error when processing %s hook params for service '%s', contract '%s', context '%s', component '%s': %s", hook, node.service.Name, node.contract.Name, node.context.Name, node.component.Name, printCauseDetailsOnDebug(cause, node.eventLog))
+}
+
 func (node *resolutionNode) errorWhenProcessingDiscoveryParams(cause error) error {
 	return fmt.Errorf("error when processing discovery params for service '%s', contract '%s', context '%s', component '%s': %s", node.service.Name, node.contract.Name, node.context.Name, node.component.Name, printCauseDetailsOnDebug(cause, node.eventLog))
 }
@@ -123,6 +127,10 @@ func (node *resolutionNode) logTestedContextCriteria(context *lang.Context, matc
 	node.eventLog.NewEntry().Debugf("Trying context '%s' within contract '%s'. Matched = %t", context.Name, node.contract.Name, matched)
 }
 
+func (node *resolutionNode) logTestedContextTraffic(context *lang.Context, bucket int, inTraffic bool) {
+	node.eventLog.NewEntry().Infof("Traffic split for context '%s' within contract '%s': bucket %d, %d%% of traffic routed to context. Matched = %t", context.Name, node.contract.Name, bucket, context.Traffic.Percent, inTraffic)
+}
+
 func (node *resolutionNode) logRulesProcessingResult(policyNamespace *lang.PolicyNamespace, result *lang.RuleActionResult) {
 	node.eventLog.NewEntry().Debugf("Rules processed within namespace '%s' for context '%s' within contract '%s'", policyNamespace.Name, node.context.Name, node.contract.Name)
 }
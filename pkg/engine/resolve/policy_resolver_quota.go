@@ -0,0 +1,195 @@
+package resolve
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// quotaUsage keeps track of quota usage, while resolved dependencies are being combined into policy resolution
+type quotaUsage struct {
+	// pointer to the policy resolver
+	resolver *PolicyResolver
+
+	// usage by quota group: quota key + group name -> usage
+	groups map[string]*quotaGroupUsage
+}
+
+// quotaGroupUsage is a usage of a single quota by a single group of dependencies
+type quotaGroupUsage struct {
+	dependencies              int
+	instances                 map[string]bool
+	clusterComponentInstances map[string]map[string]bool
+}
+
+// dependencyFootprint is a set of instances, which a resolved dependency results in
+type dependencyFootprint struct {
+	instances                 map[string]bool
+	clusterComponentInstances map[string]map[string]bool
+}
+
+// Creates a new tracker of quota usage
+func (resolver *PolicyResolver) newQuotaUsage() *quotaUsage {
+	return &quotaUsage{
+		resolver: resolver,
+		groups:   make(map[string]*quotaGroupUsage),
+	}
+}
+
+// Checks whether a resolved dependency fits into all quotas, which apply to it. If it fits, then its usage gets
+// recorded. Otherwise an error is returned, with nothing being recorded
+func (usage *quotaUsage) checkAndRecord(node *resolutionNode) error {
+	quotas := usage.getQuotas(node.dependency)
+	if len(quotas) == 0 {
+		return nil
+	}
+
+	footprint := getDependencyFootprint(node.dependency, node.resolution)
+	params := node.getContextualDataForQuotaExpression()
+	templateParams := node.getContextualDataForQuotaTemplate()
+
+	// check all quotas first, so usage doesn't get recorded partially
+	affected := []*quotaGroupUsage{}
+	for _, quota := range quotas {
+		matches, err := quota.Matches(params, usage.resolver.expressionCache)
+		if err != nil {
+			return fmt.Errorf("error while evaluating criteria of quota '%s': %s", runtime.KeyForStorable(quota), err)
+		}
+		if !matches {
+			continue
+		}
+
+		group, err := quota.ResolveGroup(templateParams, usage.resolver.templateCache)
+		if err != nil {
+			return fmt.Errorf("error while resolving group of quota '%s': %s", runtime.KeyForStorable(quota), err)
+		}
+
+		groupKey := runtime.KeyForStorable(quota) + "#" + group
+		groupUsage, ok := usage.groups[groupKey]
+		if !ok {
+			groupUsage = &quotaGroupUsage{
+				instances:                 make(map[string]bool),
+				clusterComponentInstances: make(map[string]map[string]bool),
+			}
+			usage.groups[groupKey] = groupUsage
+		}
+
+		err = groupUsage.check(quota.Limits, footprint)
+		if err != nil {
+			if len(group) > 0 {
+				return fmt.Errorf("quota '%s' exceeded for '%s': %s", runtime.KeyForStorable(quota), group, err)
+			}
+			return fmt.Errorf("quota '%s' exceeded: %s", runtime.KeyForStorable(quota), err)
+		}
+		affected = append(affected, groupUsage)
+	}
+
+	// dependency fits into all quotas, record its usage
+	for _, groupUsage := range affected {
+		groupUsage.record(footprint)
+	}
+
+	return nil
+}
+
+// Returns all quotas which apply to a given dependency (i.e. quotas in the same namespace and global quotas), sorted
+// by their keys
+func (usage *quotaUsage) getQuotas(dependency *lang.Dependency) []*lang.Quota {
+	result := []*lang.Quota{}
+	for _, ns := range []string{dependency.Namespace, runtime.SystemNS} {
+		policyNS, ok := usage.resolver.policy.Namespace[ns]
+		if !ok {
+			continue
+		}
+		for _, quota := range policyNS.Quotas {
+			result = append(result, quota)
+		}
+		if ns == runtime.SystemNS {
+			break
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return runtime.KeyForStorable(result[i]) < runtime.KeyForStorable(result[j])
+	})
+	return result
+}
+
+// Checks whether adding a given footprint will exceed any of the limits
+func (groupUsage *quotaGroupUsage) check(limits *lang.QuotaLimits, footprint *dependencyFootprint) error {
+	if limits.Dependencies > 0 && groupUsage.dependencies+1 > limits.Dependencies {
+		return fmt.Errorf("limit of %d dependencies reached", limits.Dependencies)
+	}
+
+	if limits.Instances > 0 && countUnion(groupUsage.instances, footprint.instances) > limits.Instances {
+		return fmt.Errorf("limit of %d instances reached", limits.Instances)
+	}
+
+	if limits.ClusterComponentInstances > 0 {
+		for cluster, instances := range footprint.clusterComponentInstances {
+			if countUnion(groupUsage.clusterComponentInstances[cluster], instances) > limits.ClusterComponentInstances {
+				return fmt.Errorf("limit of %d component instances reached in cluster '%s'", limits.ClusterComponentInstances, cluster)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Records a given footprint
+func (groupUsage *quotaGroupUsage) record(footprint *dependencyFootprint) {
+	groupUsage.dependencies++
+	for key := range footprint.instances {
+		groupUsage.instances[key] = true
+	}
+	for cluster, instances := range footprint.clusterComponentInstances {
+		if _, ok := groupUsage.clusterComponentInstances[cluster]; !ok {
+			groupUsage.clusterComponentInstances[cluster] = make(map[string]bool)
+		}
+		for key := range instances {
+			groupUsage.clusterComponentInstances[cluster][key] = true
+		}
+	}
+}
+
+// Returns a set of instances, which a given dependency has been resolved into
+func getDependencyFootprint(dependency *lang.Dependency, resolution *PolicyResolution) *dependencyFootprint {
+	dKey := runtime.KeyForStorable(dependency)
+	result := &dependencyFootprint{
+		instances:                 make(map[string]bool),
+		clusterComponentInstances: make(map[string]map[string]bool),
+	}
+	for key, instance := range resolution.ComponentInstanceMap {
+		depth, found := instance.DependencyKeys[dKey]
+		if !found {
+			continue
+		}
+
+		// service instance, which dependency got resolved into
+		if depth == 0 && instance.Metadata.Key.IsService() {
+			result.instances[key] = true
+		}
+
+		// component instances, grouped by cluster
+		if instance.Metadata.Key.IsComponent() {
+			cluster := runtime.KeyFromParts(instance.Metadata.Key.ClusterNameSpace, lang.ClusterObject.Kind, instance.Metadata.Key.ClusterName)
+			if _, ok := result.clusterComponentInstances[cluster]; !ok {
+				result.clusterComponentInstances[cluster] = make(map[string]bool)
+			}
+			result.clusterComponentInstances[cluster][key] = true
+		}
+	}
+	return result
+}
+
+// Returns the number of elements in a union of two sets
+func countUnion(existing map[string]bool, added map[string]bool) int {
+	result := len(existing)
+	for key := range added {
+		if !existing[key] {
+			result++
+		}
+	}
+	return result
+}
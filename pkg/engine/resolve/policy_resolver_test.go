@@ -4,10 +4,12 @@ package resolve
 import (
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/lang/builder"
+	"github.com/Aptomi/aptomi/pkg/runtime"
 	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -476,9 +478,234 @@ func TestPolicyResolverAllocationKeys(t *testing.T) {
 	}
 }
 
-/*
-	Helpers
-*/
+func TestPolicyResolverExplainDependency(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with two contexts within a contract
+	service := b.AddService()
+	b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContractMultipleContexts(service,
+		b.Criteria("label1 == 'value1'", "true", "false"),
+		b.Criteria("label2 == 'value2'", "true", "false"),
+	)
+
+	// add rule to set cluster and rule which doesn't match
+	cluster := b.AddCluster()
+	ruleTarget := b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelTarget, cluster.Name)))
+	ruleNotMatched := b.AddRule(b.Criteria("label3 == 'value3'", "true", "false"), b.RuleActions(lang.NewLabelOperationsSetSingleLabel("label4", "value4")))
+
+	// add dependency (should be resolved to the second context)
+	d1 := b.AddDependency(b.AddUser(), contract)
+	d1.Labels["label2"] = "value2"
+
+	// add dependency which can't be resolved
+	d2 := b.AddDependency(b.AddUser(), contract)
+
+	resolver := NewPolicyResolver(b.Policy(), b.External(), event.NewLog(logrus.DebugLevel, "test-resolve"))
+
+	// check trace of the resolved dependency
+	trace := resolver.ExplainDependency(d1)
+	assert.True(t, trace.Resolved, "Dependency should be resolved")
+	if !assert.Equal(t, 1, len(trace.Steps), "Trace should contain one step") {
+		t.FailNow()
+	}
+	step := trace.Steps[0]
+	assert.Equal(t, contract.Contexts[1].Name, step.Context, "Second context should be matched")
+	if assert.Equal(t, 1, len(step.RejectedContexts), "First context should be rejected") {
+		assert.Equal(t, contract.Contexts[0].Name, step.RejectedContexts[0].Name, "First context should be rejected")
+		assert.Contains(t, step.RejectedContexts[0].FailedClause, "label1 == 'value1'", "Failed clause should be reported")
+	}
+	assert.Equal(t, runtime.KeyForStorable(service), step.Service, "Service should be reported")
+	assert.Equal(t, runtime.KeyForStorable(cluster), step.Cluster, "Cluster should be reported")
+	assert.Equal(t, "k8ns", step.TargetSuffix, "Target suffix should be reported")
+
+	rules := make(map[string]bool)
+	for _, rule := range step.Rules {
+		rules[rule.Rule] = rule.Matched
+	}
+	assert.True(t, rules[runtime.KeyForStorable(ruleTarget)], "Rule setting target should be matched")
+	assert.False(t, rules[runtime.KeyForStorable(ruleNotMatched)], "Rule should not be matched")
+	assert.Equal(t, cluster.Name, step.Labels[len(step.Labels)-1].Labels[lang.LabelTarget], "Label change by rule should be recorded")
+
+	// check trace of the dependency which can't be resolved
+	trace = resolver.ExplainDependency(d2)
+	assert.False(t, trace.Resolved, "Dependency should not be resolved")
+	assert.NotEmpty(t, trace.Error, "Resolution error should be reported")
+	if assert.Equal(t, 1, len(trace.Steps), "Trace should contain one step") {
+		assert.Equal(t, 2, len(trace.Steps[0].RejectedContexts), "Both contexts should be rejected")
+		assert.NotEmpty(t, trace.Steps[0].Error, "Step error should be reported")
+	}
+}
+
+func TestPolicyResolverQuota(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with a code component, with a separate instance for every value of 'key' label
+	service := b.AddService()
+	b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+	contract := b.AddContract(service, b.CriteriaTrue())
+	contract.Contexts[0].Allocation.Keys = b.AllocationKeys("{{ .Labels.key }}")
+
+	// add rule to set cluster
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelTarget, cluster.Name)))
+
+	// add quotas: 2 dependencies per user, 1 instance for 'infra' team, 1 component instance per cluster for 'ops' team
+	b.AddQuota(nil, "{{ .User.Name }}", &lang.QuotaLimits{Dependencies: 2})
+	b.SwitchNamespace(runtime.SystemNS)
+	b.AddQuota(b.Criteria("team == 'infra'", "true", "false"), "", &lang.QuotaLimits{Instances: 1})
+	b.AddQuota(b.Criteria("team == 'ops'", "true", "false"), "", &lang.QuotaLimits{ClusterComponentInstances: 1})
+	b.SwitchNamespace("main")
+
+	// add dependencies, the older ones should be preferred
+	createdAt := time.Now()
+	addDependency := func(user *lang.User, key string) *lang.Dependency {
+		d := b.AddDependency(user, contract)
+		d.Labels["key"] = key
+		d.CreatedAt = createdAt
+		createdAt = createdAt.Add(time.Second)
+		return d
+	}
+
+	user := b.AddUser()
+	d1 := addDependency(user, "a")
+	d2 := addDependency(user, "b")
+	d3 := addDependency(user, "c")
+	d4 := addDependency(b.AddUser(), "d")
+
+	userInfra := b.AddUser()
+	userInfra.Labels["team"] = "infra"
+	d5 := addDependency(userInfra, "e")
+	d6 := addDependency(b.AddUser(), "e")
+	d6.Labels["team"] = "infra"
+	d7 := addDependency(b.AddUser(), "f")
+	d7.Labels["team"] = "infra"
+
+	userOps := b.AddUser()
+	userOps.Labels["team"] = "ops"
+	d8 := addDependency(userOps, "g")
+	d9 := addDependency(userOps, "h")
+
+	// policy resolution should reject dependencies which exceed quotas
+	resolution := resolvePolicy(t, b, []verifyDependency{
+		{d: d1, resolved: true},
+		{d: d2, resolved: true},
+		{d: d3, resolved: false, logMessage: "limit of 2 dependencies reached"},
+		{d: d4, resolved: true},
+		{d: d5, resolved: true},
+		{d: d6, resolved: true},
+		{d: d7, resolved: false, logMessage: "limit of 1 instances reached"},
+		{d: d8, resolved: true},
+		{d: d9, resolved: false, logMessage: "limit of 1 component instances reached"},
+	})
+
+	// quota errors should be recorded in dependency resolution
+	assert.Contains(t, resolution.GetDependencyResolution(d3).Error, user.Name, "Quota error should mention the group")
+	assert.Empty(t, resolution.GetDependencyResolution(d1).Error, "Resolved dependency should have no error")
+
+	// explanation should go through the same quota accounting
+	resolver := NewPolicyResolver(b.Policy(), b.External(), event.NewLog(logrus.DebugLevel, "test-resolve"))
+	for _, d := range []*lang.Dependency{d2, d6, d8} {
+		assert.True(t, resolver.ExplainDependency(d).Resolved, "Dependency which fits into quotas should be explained as resolved")
+	}
+	trace := resolver.ExplainDependency(d3)
+	assert.False(t, trace.Resolved, "Dependency which exceeds quota should be explained as not resolved")
+	assert.Contains(t, trace.Error, "limit of 2 dependencies reached", "Quota error should be reported in trace")
+	trace = resolver.ExplainDependency(d9)
+	assert.False(t, trace.Resolved, "Dependency which exceeds quota should be explained as not resolved")
+	assert.Contains(t, trace.Error, "limit of 1 component instances reached", "Quota error should be reported in trace")
+}
+
+func TestPolicyResolverTrafficSplit(t *testing.T) {
+	for _, percent := range []int{0, 10, 50, 100} {
+		b := builder.NewPolicyBuilder()
+
+		// create a service with canary and stable contexts within a contract
+		service := b.AddService()
+		b.AddServiceComponent(service, b.CodeComponent(nil, nil))
+		contract := b.AddContractMultipleContexts(service, b.CriteriaTrue(), b.CriteriaTrue())
+		canary := contract.Contexts[0]
+		canary.Traffic = &lang.TrafficSplit{Percent: percent}
+
+		// add rule to set cluster
+		cluster := b.AddCluster()
+		b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelTarget, cluster.Name)))
+
+		// add dependencies
+		expected := []verifyDependency{}
+		for i := 0; i < 50; i++ {
+			d := b.AddDependency(b.AddUser(), contract)
+			expected = append(expected, verifyDependency{d: d, resolved: true})
+		}
+
+		// policy resolution should be completed successfully
+		resolution := resolvePolicy(t, b, expected)
+
+		// every dependency should land on canary context only if its bucket is within the percentage
+		canaryCnt := 0
+		for _, check := range expected {
+			instance := resolution.ComponentInstanceMap[resolution.GetDependencyResolution(check.d).ComponentInstanceKey]
+			inCanary := int(util.HashFnv(runtime.KeyForStorable(check.d))%100) < percent
+			if inCanary {
+				canaryCnt++
+				assert.Equal(t, canary.Name, instance.Metadata.Key.ContextName, "Dependency should land on canary context")
+			} else {
+				assert.NotEqual(t, canary.Name, instance.Metadata.Key.ContextName, "Dependency should land on stable context")
+			}
+		}
+
+		switch percent {
+		case 0:
+			assert.Equal(t, 0, canaryCnt, "No dependencies should land on canary context")
+		case 100:
+			assert.Equal(t, len(expected), canaryCnt, "All dependencies should land on canary context")
+		default:
+			assert.True(t, canaryCnt > 0 && canaryCnt < len(expected), "Some dependencies should land on canary context")
+		}
+	}
+}
+
+func TestPolicyResolverDeployNames(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+
+	// create a service with 2 components, second of which consumes discovery params of the first one
+	service := b.AddService()
+	component1 := b.CodeComponent(
+		util.NestedParameterMap{"name": "{{ .Discovery.Instance }}"},
+		util.NestedParameterMap{"url": "component1-{{ .Discovery.Instance }}"},
+	)
+	component2 := b.CodeComponent(
+		util.NestedParameterMap{"address": fmt.Sprintf("{{ .Discovery.%s.url }}", component1.Name)},
+		nil,
+	)
+	b.AddServiceComponent(service, component1)
+	b.AddServiceComponent(service, component2)
+
+	contract := b.AddContract(service, b.CriteriaTrue())
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelTarget, cluster.Name)))
+	dependency := b.AddDependency(b.AddUser(), contract)
+
+	resolution := resolvePolicy(t, b, []verifyDependency{
+		{d: dependency, resolved: true},
+	})
+	instance1 := getInstanceByParams(t, cluster, "k8ns", contract, contract.Contexts[0], nil, service, component1, resolution)
+	instance2 := getInstanceByParams(t, cluster, "k8ns", contract, contract.Contexts[0], nil, service, component2, resolution)
+	defaultDeployName := instance1.GetDeployName()
+	assert.Equal(t, "component1-"+util.EscapeName(defaultDeployName), instance2.CalculatedCodeParams["address"], "Consumer should discover the default deploy name")
+
+	// once the second copy of code is running after blue/green update, consumers discover it
+	instance1.DeployName = instance1.GetNextDeployName()
+	resolutionSwapped := NewPolicyResolver(b.Policy(), b.External(), event.NewLog(logrus.WarnLevel, "test-resolve")).WithDeployNames(resolution).ResolveAllDependencies()
+	instance1Swapped := getInstanceByParams(t, cluster, "k8ns", contract, contract.Contexts[0], nil, service, component1, resolutionSwapped)
+	instance2Swapped := getInstanceByParams(t, cluster, "k8ns", contract, contract.Contexts[0], nil, service, component2, resolutionSwapped)
+	assert.Equal(t, "component1-"+util.EscapeName(instance1.DeployName), instance1Swapped.CalculatedDiscovery["url"], "Discovery params should point to the running copy of code")
+	assert.Equal(t, instance1Swapped.CalculatedDiscovery["url"], instance2Swapped.CalculatedCodeParams["address"], "Consumer should discover the running copy of code")
+
+	// code params of the component itself don't change, otherwise it would be updated again
+	assert.Equal(t, instance1.CalculatedCodeParams, instance1Swapped.CalculatedCodeParams, "Own code params should not depend on the running copy of code")
+	assert.Equal(t, util.EscapeName(defaultDeployName), instance1Swapped.CalculatedCodeParams["name"], "Own code params should refer to the default deploy name")
+}
 
 type verifyDependency struct {
 	d          *lang.Dependency
@@ -486,6 +713,10 @@ type verifyDependency struct {
 	logMessage string
 }
 
+/*
+	Helpers
+*/
+
 func resolvePolicy(t *testing.T, builder *builder.PolicyBuilder, expected []verifyDependency) *PolicyResolution {
 	t.Helper()
 	eventLog := event.NewLog(logrus.DebugLevel, "test-resolve")
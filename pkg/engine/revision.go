@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/diff"
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 )
@@ -26,6 +27,12 @@ const (
 	RevisionStatusInProgress = "inprogress"
 	// RevisionStatusCompleted represents Revision status with apply finished
 	RevisionStatusCompleted = "completed"
+	// RevisionStatusAwaitingApproval represents Revision status when apply finished, but deletion of protected
+	// component instances is waiting for user approval
+	RevisionStatusAwaitingApproval = "awaiting-approval"
+	// RevisionStatusDeferred represents Revision status when apply finished, but changes to some of component
+	// instances are deferred until they are allowed by maintenance windows and freezes (or enforcement gets resumed)
+	RevisionStatusDeferred = "deferred"
 	// RevisionStatusError represents Revision status when a critical error happened (we should rarely see those)
 	RevisionStatusError = "error"
 )
@@ -42,9 +49,38 @@ type Revision struct {
 	CreatedAt      time.Time
 	RecalculateAll bool
 
+	// FailureBudget overrides enforcer's failure budget for this revision (e.g. '10' or '20%'), if set
+	FailureBudget string `yaml:",omitempty"`
+
+	// Scope restricts the action plan of this revision to a subset of component instances, if set. It only applies
+	// to the first run of the revision, after which scope gets reset and the rest of actions get applied as well
+	Scope *diff.Scope `yaml:",omitempty"`
+
 	Result    *action.ApplyResult
 	AppliedAt time.Time
 
+	// PendingApproval is a list of keys of protected component instances, deletion of which is waiting for approval
+	PendingApproval []string `yaml:",omitempty"`
+
+	// Approved is a list of keys of protected component instances, deletion of which has been approved. Deletion of
+	// any other protected component instances still needs to be approved
+	Approved []string `yaml:",omitempty"`
+
+	// ApprovedBy is a name of the user who approved deletion of protected component instances
+	ApprovedBy string `yaml:",omitempty"`
+	ApprovedAt time.Time
+
+	// Deferred is a list of keys of component instances, changes to which are deferred until they are allowed by
+	// maintenance windows and freezes
+	Deferred []string `yaml:",omitempty"`
+
+	// DeferredUntil is the earliest time when some of the deferred changes are expected to be allowed
+	DeferredUntil time.Time
+
+	// Paused is a list of keys of component instances, changes to which are held while enforcement is paused for
+	// their namespace or cluster
+	Paused []string `yaml:",omitempty"`
+
 	// TODO: do not store apply log in revision
 	ApplyLog []*event.APIEvent
 }
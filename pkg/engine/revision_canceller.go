@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"context"
+	"sync"
+
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// RevisionCanceller keeps track of the revision, which is currently being applied, and allows to cancel it
+type RevisionCanceller struct {
+	mutex  sync.Mutex
+	gen    runtime.Generation
+	cancel context.CancelFunc
+}
+
+// NewRevisionCanceller creates a new RevisionCanceller
+func NewRevisionCanceller() *RevisionCanceller {
+	return &RevisionCanceller{}
+}
+
+// Start should be called when revision with a given generation starts being applied. It returns context, which gets
+// cancelled when Cancel is called, as well as a function which must be called once revision has been applied
+func (canceller *RevisionCanceller) Start(gen runtime.Generation) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	canceller.mutex.Lock()
+	defer canceller.mutex.Unlock()
+	canceller.gen = gen
+	canceller.cancel = cancel
+
+	return ctx, func() {
+		canceller.mutex.Lock()
+		defer canceller.mutex.Unlock()
+		canceller.gen = 0
+		canceller.cancel = nil
+		cancel()
+	}
+}
+
+// WhileIdle calls a given function, unless some revision is currently being applied, in which case it returns
+// generation of that revision and false. No revision can start being applied until the function returns, so it's safe
+// to make changes in the cloud, which would otherwise interfere with the revision (e.g. deleting orphaned code)
+func (canceller *RevisionCanceller) WhileIdle(fn func()) (runtime.Generation, bool) {
+	canceller.mutex.Lock()
+	defer canceller.mutex.Unlock()
+	if canceller.cancel != nil {
+		return canceller.gen, false
+	}
+	fn()
+	return 0, true
+}
+
+// Cancel cancels the revision, which is currently being applied, and returns its generation. If there is no such
+// revision, it returns false
+func (canceller *RevisionCanceller) Cancel() (runtime.Generation, bool) {
+	canceller.mutex.Lock()
+	defer canceller.mutex.Unlock()
+	if canceller.cancel == nil {
+		return 0, false
+	}
+	canceller.cancel()
+	return canceller.gen, true
+}
@@ -0,0 +1,49 @@
+package engine
+
+import (
+	"fmt"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// RevisionPlanObject is an informational data structure with Kind and Constructor for RevisionPlan
+var RevisionPlanObject = &runtime.Info{
+	Kind:        "revision-plan",
+	Storable:    true,
+	Versioned:   false,
+	Constructor: func() runtime.Object { return &RevisionPlan{} },
+}
+
+// RevisionPlan represents the action plan, which has been applied by specific revision, together with the outcome of
+// every action
+type RevisionPlan struct {
+	runtime.TypeKind `yaml:",inline"`
+
+	RevisionGen runtime.Generation
+	Plan        *action.PlanRecord
+}
+
+// NewRevisionPlan creates new RevisionPlan instance from revision and plan record
+func NewRevisionPlan(revision *Revision, plan *action.PlanRecord) *RevisionPlan {
+	return &RevisionPlan{
+		TypeKind:    RevisionPlanObject.GetTypeKind(),
+		RevisionGen: revision.GetGeneration(),
+		Plan:        plan,
+	}
+}
+
+// GetName returns name of the RevisionPlan
+func (rp *RevisionPlan) GetName() string {
+	return GetRevisionPlanName(rp.RevisionGen)
+}
+
+// GetNamespace returns namespace of the RevisionPlan
+func (rp *RevisionPlan) GetNamespace() string {
+	return runtime.SystemNS
+}
+
+// GetRevisionPlanName returns name of the RevisionPlan for specific Revision generation
+func GetRevisionPlanName(revisionGen runtime.Generation) string {
+	return fmt.Sprintf("revision-%s-plan", revisionGen)
+}
@@ -168,6 +168,38 @@ func (builder *PolicyBuilder) AddRule(criteria *lang.Criteria, actions *lang.Rul
 	return result
 }
 
+// AddQuota creates a new quota and adds it to the policy
+func (builder *PolicyBuilder) AddQuota(criteria *lang.Criteria, groupBy string, limits *lang.QuotaLimits) *lang.Quota {
+	result := &lang.Quota{
+		TypeKind: lang.QuotaObject.GetTypeKind(),
+		Metadata: lang.Metadata{
+			Namespace: builder.namespace,
+			Name:      util.RandomID(builder.random, idLength),
+		},
+		Criteria: criteria,
+		GroupBy:  groupBy,
+		Limits:   limits,
+	}
+	builder.addObject(builder.domainAdminView, result)
+	return result
+}
+
+// AddMaintenance creates a new maintenance and adds it to the policy
+func (builder *PolicyBuilder) AddMaintenance(criteria *lang.Criteria, windows []*lang.MaintenanceWindow, freezes []*lang.MaintenanceFreeze) *lang.Maintenance {
+	result := &lang.Maintenance{
+		TypeKind: lang.MaintenanceObject.GetTypeKind(),
+		Metadata: lang.Metadata{
+			Namespace: builder.namespace,
+			Name:      util.RandomID(builder.random, idLength),
+		},
+		Criteria: criteria,
+		Windows:  windows,
+		Freezes:  freezes,
+	}
+	builder.addObject(builder.domainAdminView, result)
+	return result
+}
+
 // AddCluster creates a new cluster and adds it to the policy
 func (builder *PolicyBuilder) AddCluster() *lang.Cluster {
 	result := &lang.Cluster{
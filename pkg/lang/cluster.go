@@ -2,6 +2,7 @@ package lang
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/Aptomi/aptomi/pkg/runtime"
 	"gopkg.in/yaml.v2"
@@ -16,6 +17,10 @@ var ClusterObject = &runtime.Info{
 	Constructor: func() runtime.Object { return &Cluster{} },
 }
 
+// LabelMaxConcurrentActions is a special cluster label, which limits the number of actions applied concurrently
+// in the cluster (e.g. for small clusters, which can't handle many parallel deployments)
+const LabelMaxConcurrentActions = "maxConcurrentActions"
+
 // Cluster defines an individual cluster where containers get deployed.
 // Various cloud providers are supported via setting a cluster type (k8s, Amazon ECS, GKE, etc).
 type Cluster struct {
@@ -47,6 +52,20 @@ func (cluster *Cluster) ParseConfigInto(obj interface{}) error {
 	return nil
 }
 
+// GetMaxConcurrentActions returns the max number of actions applied concurrently in the cluster, as declared in
+// cluster labels. Zero means that the limit is not set
+func (cluster *Cluster) GetMaxConcurrentActions() (int, error) {
+	value, ok := cluster.Labels[LabelMaxConcurrentActions]
+	if !ok {
+		return 0, nil
+	}
+	result, err := strconv.Atoi(value)
+	if err != nil || result < 0 {
+		return 0, fmt.Errorf("label '%s' should be a non-negative number, got '%s'", LabelMaxConcurrentActions, value)
+	}
+	return result, nil
+}
+
 // MakeCopy makes a shallow copy of the Cluster struct
 func (cluster *Cluster) MakeCopy() *Cluster {
 	return &Cluster{
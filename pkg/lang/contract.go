@@ -4,6 +4,7 @@ import (
 	"github.com/Aptomi/aptomi/pkg/lang/expression"
 	"github.com/Aptomi/aptomi/pkg/lang/template"
 	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
 )
 
 // ContractObject is an informational data structure with Kind and Constructor for Contract
@@ -48,10 +49,27 @@ type Context struct {
 	// the context gets matched
 	ChangeLabels LabelOperations `yaml:"change-labels,omitempty" validate:"labelOperations"`
 
+	// Traffic defines which fraction of consumers matching the criteria will land on this context. It's an optional
+	// field, so if it's nil then all consumers matching the criteria will land on this context
+	Traffic *TrafficSplit `yaml:",omitempty" validate:"omitempty"`
+
 	// Allocation defines how the context will get allocated (which service to allocate and which unique key to use)
 	Allocation *Allocation `validate:"required"`
 }
 
+// TrafficSplit defines a fraction of consumers, which will land on a context. It allows to roll out a new version
+// of a service gradually (e.g. to 10% of dependencies, then to 50%, then to all of them). Every consumer gets
+// assigned to a stable bucket from 0 to 99 by hashing the value of HashBy, and lands on a context only if its bucket
+// is less than Percent. So increasing Percent never moves consumers, which already landed on a context, away from it
+type TrafficSplit struct {
+	// Percent is a percentage of consumers, which will land on a context
+	Percent int `validate:"min=0,max=100"`
+
+	// HashBy is a text template, which defines what consumers get hashed by (e.g. '{{ .User.Name }}'). It's an
+	// optional field, so if it's empty then consumers will get hashed by dependency key
+	HashBy string `yaml:"hash-by,omitempty" validate:"omitempty,template"`
+}
+
 // Allocation determines which service should be allocated for by the given context
 // and which additional keys should be added to component instance key
 type Allocation struct {
@@ -93,3 +111,20 @@ func (context *Context) ResolveKeys(params *template.Parameters, cache *template
 	}
 	return result, nil
 }
+
+// ResolveTrafficBucket resolves a stable bucket from 0 to 99 for a consumer, which is then compared with the
+// percentage of traffic routed to the context. If HashBy is empty, the default value gets hashed
+func (context *Context) ResolveTrafficBucket(params *template.Parameters, defaultValue string, cache *template.Cache) (int, error) {
+	value := defaultValue
+	if len(context.Traffic.HashBy) > 0 {
+		if cache == nil {
+			cache = template.NewCache()
+		}
+		var err error
+		value, err = cache.Evaluate(context.Traffic.HashBy, params)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return int(util.HashFnv(value) % 100), nil
+}
@@ -287,3 +287,28 @@ func TestServiceContextKeyResolution(t *testing.T) {
 	evalKeys(t, context, paramFailure, true, nil, nil)
 	evalKeys(t, context, paramFailure, true, nil, cache)
 }
+
+func TestCriteriaFailedClause(t *testing.T) {
+	criteria := &Criteria{
+		RequireAll:  []string{"prod == 'yes'"},
+		RequireAny:  []string{"priority > 100", "team == 'infra'"},
+		RequireNone: []string{"dev == 'yes'"},
+	}
+
+	checks := []struct {
+		labels   map[string]string
+		expected string
+	}{
+		{map[string]string{"prod": "yes", "dev": "no", "priority": "200", "team": "web"}, ""},
+		{map[string]string{"prod": "no", "dev": "no", "priority": "200", "team": "web"}, "require-all: 'prod == 'yes'' is false"},
+		{map[string]string{"prod": "yes", "dev": "yes", "priority": "200", "team": "web"}, "require-none: 'dev == 'yes'' is true"},
+		{map[string]string{"prod": "yes", "dev": "no", "priority": "10", "team": "web"}, "require-any: none of [priority > 100 team == 'infra'] is true"},
+	}
+
+	cache := expression.NewCache()
+	for _, check := range checks {
+		failedClause, err := criteria.FailedClause(expression.NewParams(check.labels, nil), cache)
+		assert.NoError(t, err, "Criteria should be evaluated without errors: %+v", check.labels)
+		assert.Equal(t, check.expected, failedClause, "Failed clause should be reported correctly: %+v", check.labels)
+	}
+}
@@ -1,6 +1,8 @@
 package lang
 
 import (
+	"fmt"
+
 	"github.com/Aptomi/aptomi/pkg/lang/expression"
 )
 
@@ -29,15 +31,25 @@ type Criteria struct {
 
 // Returns whether criteria evaluates to "true", given a set of parameters for its expressions and a cache
 func (criteria *Criteria) allows(params *expression.Parameters, cache *expression.Cache) (bool, error) {
+	failedClause, err := criteria.FailedClause(params, cache)
+	if err != nil {
+		return false, err
+	}
+	return len(failedClause) == 0, nil
+}
+
+// FailedClause returns a human-readable description of the clause which makes criteria evaluate to "false" (e.g.
+// "require-all: 'prod == true' is false"). It returns an empty string if criteria evaluates to "true"
+func (criteria *Criteria) FailedClause(params *expression.Parameters, cache *expression.Cache) (string, error) {
 	// Make sure all "require-all" criteria evaluate to true
 	for _, exprShouldBeTrue := range criteria.RequireAll {
 		result, err := criteria.evaluateBool(exprShouldBeTrue, params, cache)
 		if err != nil {
 			// propagate expression error up, if happened
-			return false, err
+			return "", err
 		}
 		if !result {
-			return false, nil
+			return fmt.Sprintf("require-all: '%s' is false", exprShouldBeTrue), nil
 		}
 	}
 
@@ -46,10 +58,10 @@ func (criteria *Criteria) allows(params *expression.Parameters, cache *expressio
 		result, err := criteria.evaluateBool(exprShouldBeFalse, params, cache)
 		if err != nil {
 			// propagate expression error up, if happened
-			return false, err
+			return "", err
 		}
 		if result {
-			return false, nil
+			return fmt.Sprintf("require-none: '%s' is true", exprShouldBeFalse), nil
 		}
 	}
 
@@ -59,19 +71,19 @@ func (criteria *Criteria) allows(params *expression.Parameters, cache *expressio
 			result, err := criteria.evaluateBool(exprShouldBeTrue, params, cache)
 			if err != nil {
 				// propagate expression error up, if happened
-				return false, err
+				return "", err
 			}
 			if result {
-				return true, nil
+				return "", nil
 			}
 		}
 
 		// If no criteria got evaluated to true, return false
-		return false, nil
+		return fmt.Sprintf("require-any: none of %s is true", criteria.RequireAny), nil
 	}
 
 	// Everything is fine and "require-any" is empty, let's return true
-	return true, nil
+	return "", nil
 }
 
 // Evaluates bool expression, given a set of parameters and a cache. If cache is nil, it will still be evaluated
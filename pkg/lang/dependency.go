@@ -1,6 +1,8 @@
 package lang
 
 import (
+	"time"
+
 	"github.com/Aptomi/aptomi/pkg/runtime"
 )
 
@@ -30,4 +32,58 @@ type Dependency struct {
 
 	// Labels which are provided by the user.
 	Labels map[string]string `yaml:"labels,omitempty" validate:"omitempty,labels"`
+
+	// Expiration is an optional expiration of the dependency. Once dependency expires, it gets removed from the
+	// policy by Aptomi server, which in turn results in destruction of all corresponding instances. It's useful for
+	// short-lived environments (e.g. for running tests), which users tend to forget to delete
+	Expiration *DependencyExpiration `yaml:"expiration,omitempty"`
+
+	// CreatedAt is a time when dependency was added to the policy. It gets set by Aptomi server and used to
+	// calculate expiration time for dependencies which expire after a given duration
+	CreatedAt time.Time `yaml:"created-at,omitempty"`
+}
+
+// DependencyExpiration defines when dependency expires. It can either be an absolute point of time, or a duration
+// since dependency creation. If both are specified, absolute point of time takes precedence
+type DependencyExpiration struct {
+	// At is an absolute point of time when dependency expires
+	At time.Time `yaml:"at,omitempty"`
+
+	// After is a duration since dependency creation, after which dependency expires (e.g. '4h')
+	After time.Duration `yaml:"after,omitempty"`
+}
+
+// GetExpirationTime returns a point of time when dependency expires. It returns false if dependency never expires
+// (i.e. it has no expiration defined, or it expires after a given duration, but its creation time is unknown)
+func (dependency *Dependency) GetExpirationTime() (time.Time, bool) {
+	if dependency.Expiration == nil {
+		return time.Time{}, false
+	}
+	if !dependency.Expiration.At.IsZero() {
+		return dependency.Expiration.At, true
+	}
+	if dependency.Expiration.After > 0 && !dependency.CreatedAt.IsZero() {
+		return dependency.CreatedAt.Add(dependency.Expiration.After), true
+	}
+	return time.Time{}, false
+}
+
+// IsExpired returns true if dependency has expired by a given point of time
+func (dependency *Dependency) IsExpired(now time.Time) bool {
+	expiresAt, ok := dependency.GetExpirationTime()
+	return ok && !now.Before(expiresAt)
+}
+
+// ExtendExpiration extends expiration of the dependency by a given duration, starting from its current expiration
+// time or from a given point of time if dependency has already expired. It returns the new expiration time
+func (dependency *Dependency) ExtendExpiration(now time.Time, duration time.Duration) time.Time {
+	expiresAt, ok := dependency.GetExpirationTime()
+	if !ok || expiresAt.Before(now) {
+		expiresAt = now
+	}
+	if dependency.Expiration == nil {
+		dependency.Expiration = &DependencyExpiration{}
+	}
+	dependency.Expiration.At = expiresAt.Add(duration)
+	return dependency.Expiration.At
 }
@@ -0,0 +1,43 @@
+package lang
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyExpiration(t *testing.T) {
+	now := time.Date(2018, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// no expiration
+	dependency := makeDependency("contract")
+	_, ok := dependency.GetExpirationTime()
+	assert.False(t, ok, "Dependency without expiration should never expire")
+	assert.False(t, dependency.IsExpired(now), "Dependency without expiration should never expire")
+
+	// expiration after a given duration, but creation time is unknown
+	dependency.Expiration = &DependencyExpiration{After: time.Hour}
+	_, ok = dependency.GetExpirationTime()
+	assert.False(t, ok, "Dependency with unknown creation time should never expire")
+
+	// expiration after a given duration since creation
+	dependency.CreatedAt = now.Add(-2 * time.Hour)
+	expiresAt, ok := dependency.GetExpirationTime()
+	assert.True(t, ok, "Dependency should expire")
+	assert.Equal(t, now.Add(-time.Hour), expiresAt, "Dependency should expire after a given duration since creation")
+	assert.True(t, dependency.IsExpired(now), "Dependency should be expired")
+
+	// absolute expiration time takes precedence
+	dependency.Expiration.At = now.Add(time.Hour)
+	expiresAt, _ = dependency.GetExpirationTime()
+	assert.Equal(t, now.Add(time.Hour), expiresAt, "Absolute expiration time should take precedence")
+	assert.False(t, dependency.IsExpired(now), "Dependency should not be expired")
+	assert.True(t, dependency.IsExpired(now.Add(time.Hour)), "Dependency should be expired")
+
+	// extension starts from the current expiration time
+	assert.Equal(t, now.Add(3*time.Hour), dependency.ExtendExpiration(now, 2*time.Hour), "Expiration should be extended from the current expiration time")
+
+	// extension of an expired dependency starts from now
+	assert.Equal(t, now.Add(7*time.Hour), dependency.ExtendExpiration(now.Add(5*time.Hour), 2*time.Hour), "Expiration of expired dependency should be extended from now")
+}
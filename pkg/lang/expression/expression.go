@@ -15,20 +15,11 @@ type Expression struct {
 
 // NewExpression compiles an expression and returns the result in Expression struct
 // Parameter expressionStr must follow syntax defined by https://github.com/Knetic/govaluate
+// and may call any of the built-in functions (see FunctionNames)
 func NewExpression(expressionStr string) (*Expression, error) {
-	functions := map[string]govaluate.ExpressionFunction{
-		"in": func(args ...interface{}) (interface{}, error) {
-			if len(args) == 0 {
-				return nil, fmt.Errorf("can't evaluate in() function when zero arguments supplied")
-			}
-			v := args[0]
-			for i := 1; i < len(args); i++ {
-				if v == args[i] {
-					return true, nil
-				}
-			}
-			return false, nil
-		},
+	err := checkFunctions(expressionStr)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compile expression '%s': %s", expressionStr, err)
 	}
 
 	expressionCompiled, err := govaluate.NewEvaluableExpressionWithFunctions(expressionStr, functions)
@@ -127,3 +127,70 @@ func TestExpressions(t *testing.T) {
 		evaluateWithCache(t, test.expression, params, test.result, cache)
 	}
 }
+
+func TestExpressionFunctions(t *testing.T) {
+	params := NewParams(
+		map[string]string{
+			"name":     "web-frontend",
+			"teams":    "dev,qa, ops",
+			"version":  "1.4.2",
+			"replicas": "3",
+			"ratio":    "2.5",
+		},
+		nil,
+	)
+
+	tests := []struct {
+		expression string
+		result     int
+	}{
+		// regex matching
+		{"matches(name, '^web-.*$')", ResTrue},
+		{"matches(name, '^api')", ResFalse},
+		{"matches(name, '[')", ResEvalError},
+
+		// prefix & suffix
+		{"hasPrefix(name, 'web')", ResTrue},
+		{"hasPrefix(name, 'api')", ResFalse},
+		{"hasSuffix(name, 'end')", ResTrue},
+		{"hasSuffix(name, 'web')", ResFalse},
+		{"hasPrefix(name)", ResEvalError},
+
+		// comma-separated lists
+		{"contains(teams, 'dev')", ResTrue},
+		{"contains(teams, 'ops')", ResTrue},
+		{"contains(teams, 'prod')", ResFalse},
+
+		// semantic versions
+		{"semver(version, '>= 1.2, < 2.0')", ResTrue},
+		{"semver(version, '^2')", ResFalse},
+		{"semver(name, '^2')", ResEvalError},
+		{"semver(version, 'not-a-constraint')", ResEvalError},
+		{"semverCompare(version, '1.5.0') < 0", ResTrue},
+		{"semverCompare(version, '1.4.2') == 0", ResTrue},
+		{"semverCompare(version, '1.4.0') > 0", ResTrue},
+
+		// numbers
+		{"number(ratio) > 2", ResTrue},
+		{"number(ratio) > 3", ResFalse},
+		{"number(replicas) == 3", ResTrue},
+		{"number(name) > 0", ResEvalError},
+
+		// unknown functions
+		{"unknownFunction(name)", ResCompileError},
+		{"hasPrefix(name, 'web') && unknownFunction(name)", ResCompileError},
+		{"'unknownFunction(x)' == name", ResFalse},
+		{"'it\\'s unknownFunction(x)' == name", ResFalse},
+		{"'it\\'s (' == name && hasPrefix(name, 'web')", ResFalse},
+		{"\"say \\\"hi\\\" (\" == name || hasPrefix(name, 'web')", ResTrue},
+
+		// textual operators
+		{"name IN ('web-frontend', 'api')", ResTrue},
+		{"name IN ('api', 'db')", ResFalse},
+		{"hasPrefix(name, 'web') && name IN ('web-frontend')", ResTrue},
+	}
+
+	for _, test := range tests {
+		evaluate(t, test.expression, params, test.result)
+	}
+}
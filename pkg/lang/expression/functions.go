@@ -0,0 +1,224 @@
+package expression
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Masterminds/semver"
+	"github.com/ralekseenkov/govaluate"
+)
+
+// functions is a library of built-in functions, which can be used in expressions:
+//
+//	in(value, v1, v2, ...)       - returns true if value is equal to one of v1, v2, ...
+//	matches(str, regex)          - returns true if str matches regular expression regex
+//	hasPrefix(str, prefix)       - returns true if str starts with prefix
+//	hasSuffix(str, suffix)       - returns true if str ends with suffix
+//	contains(list, value)        - returns true if comma-separated list contains value (e.g. contains(teams, 'dev'))
+//	semver(version, constraint)  - returns true if semantic version satisfies constraint (e.g. semver(version, '>= 1.2, < 2.0'))
+//	semverCompare(v1, v2)        - compares semantic versions and returns -1, 0 or 1
+//	number(str)                  - parses str as a number (e.g. number(replicas) > 2)
+//
+// All string arguments also accept numbers and bools, as labels which look like them get converted automatically.
+var functions = map[string]govaluate.ExpressionFunction{
+	"in":            funcIn,
+	"matches":       funcMatches,
+	"hasPrefix":     funcHasPrefix,
+	"hasSuffix":     funcHasSuffix,
+	"contains":      funcContains,
+	"semver":        funcSemver,
+	"semverCompare": funcSemverCompare,
+	"number":        funcNumber,
+}
+
+// regexCache is a thread-safe cache of compiled regular expressions used in matches() function
+var regexCache sync.Map
+
+// functionCallRegex matches a function name followed by an opening parenthesis
+var functionCallRegex = regexp.MustCompile(`([A-Za-z_][A-Za-z0-9_.]*)\s*\($`)
+
+// FunctionNames returns sorted list of names of all built-in functions, which can be used in expressions
+func FunctionNames() []string {
+	result := make([]string, 0, len(functions))
+	for name := range functions {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// checkFunctions verifies that expression only calls known functions. It gets called before compilation, so
+// that policy gets a clear error message instead of a generic syntax error when a function is unknown
+func checkFunctions(expressionStr string) error {
+	var quote rune
+	escaped := false
+	for idx, ch := range expressionStr {
+		// skip string literals, which may contain escaped characters (e.g. 'it\'s')
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == quote:
+				quote = 0
+			}
+			continue
+		}
+		if ch == '\'' || ch == '"' {
+			quote = ch
+			continue
+		}
+
+		if ch != '(' {
+			continue
+		}
+
+		match := functionCallRegex.FindStringSubmatch(expressionStr[:idx+1])
+		if match == nil {
+			continue
+		}
+
+		// calls of struct methods (e.g. Service.Method()) are handled by govaluate itself
+		name := match[1]
+		if strings.Contains(name, ".") {
+			continue
+		}
+
+		// textual operator followed by a list of values (e.g. team IN ('dev', 'prod')) is not a function call
+		if name == "in" || name == "IN" {
+			continue
+		}
+
+		if _, exist := functions[name]; !exist {
+			return fmt.Errorf("unknown function '%s', supported functions are: %s", name, strings.Join(FunctionNames(), ", "))
+		}
+	}
+	return nil
+}
+
+func checkArgs(name string, args []interface{}, count int) error {
+	if len(args) != count {
+		return fmt.Errorf("function %s() expects %d argument(s), but %d supplied", name, count, len(args))
+	}
+	return nil
+}
+
+func toString(arg interface{}) string {
+	if str, ok := arg.(string); ok {
+		return str
+	}
+	return fmt.Sprint(arg)
+}
+
+func funcIn(args ...interface{}) (interface{}, error) {
+	if len(args) == 0 {
+		return nil, fmt.Errorf("can't evaluate in() function when zero arguments supplied")
+	}
+	v := args[0]
+	for i := 1; i < len(args); i++ {
+		if v == args[i] {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func funcMatches(args ...interface{}) (interface{}, error) {
+	if err := checkArgs("matches", args, 2); err != nil {
+		return nil, err
+	}
+
+	pattern := toString(args[1])
+	var re *regexp.Regexp
+	if cached, ok := regexCache.Load(pattern); ok {
+		re = cached.(*regexp.Regexp) // nolint: errcheck
+	} else {
+		var err error
+		re, err = regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression '%s' in matches(): %s", pattern, err)
+		}
+		regexCache.Store(pattern, re)
+	}
+
+	return re.MatchString(toString(args[0])), nil
+}
+
+func funcHasPrefix(args ...interface{}) (interface{}, error) {
+	if err := checkArgs("hasPrefix", args, 2); err != nil {
+		return nil, err
+	}
+	return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+}
+
+func funcHasSuffix(args ...interface{}) (interface{}, error) {
+	if err := checkArgs("hasSuffix", args, 2); err != nil {
+		return nil, err
+	}
+	return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+}
+
+func funcContains(args ...interface{}) (interface{}, error) {
+	if err := checkArgs("contains", args, 2); err != nil {
+		return nil, err
+	}
+	value := toString(args[1])
+	for _, item := range strings.Split(toString(args[0]), ",") {
+		if strings.TrimSpace(item) == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func funcSemver(args ...interface{}) (interface{}, error) {
+	if err := checkArgs("semver", args, 2); err != nil {
+		return nil, err
+	}
+	version, err := semver.NewVersion(toString(args[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid version '%s' in semver(): %s", toString(args[0]), err)
+	}
+	constraint, err := semver.NewConstraint(toString(args[1]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid constraint '%s' in semver(): %s", toString(args[1]), err)
+	}
+	return constraint.Check(version), nil
+}
+
+func funcSemverCompare(args ...interface{}) (interface{}, error) {
+	if err := checkArgs("semverCompare", args, 2); err != nil {
+		return nil, err
+	}
+	v1, err := semver.NewVersion(toString(args[0]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid version '%s' in semverCompare(): %s", toString(args[0]), err)
+	}
+	v2, err := semver.NewVersion(toString(args[1]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid version '%s' in semverCompare(): %s", toString(args[1]), err)
+	}
+	return float64(v1.Compare(v2)), nil
+}
+
+func funcNumber(args ...interface{}) (interface{}, error) {
+	if err := checkArgs("number", args, 1); err != nil {
+		return nil, err
+	}
+	switch value := args[0].(type) {
+	case float64:
+		return value, nil
+	case int:
+		return float64(value), nil
+	}
+	result, err := strconv.ParseFloat(strings.TrimSpace(toString(args[0])), 64)
+	if err != nil {
+		return nil, fmt.Errorf("can't convert '%s' to a number in number(): %s", toString(args[0]), err)
+	}
+	return result, nil
+}
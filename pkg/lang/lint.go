@@ -0,0 +1,458 @@
+package lang
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+)
+
+// Names of the checks performed by PolicyLinter
+const (
+	// LintUnreachableContext is reported for contract contexts which can never be matched, because one of the earlier contexts has no criteria
+	LintUnreachableContext = "unreachable-context"
+
+	// LintUnusedService is reported for services not referenced by any contract
+	LintUnusedService = "unused-service"
+
+	// LintUnusedContract is reported for contracts not consumed by any dependency or service component
+	LintUnusedContract = "unused-contract"
+
+	// LintShadowedRule is reported for rules which have no effect, because a rule with higher weight and identical criteria overrides all of their actions
+	LintShadowedRule = "shadowed-rule"
+
+	// LintUnusedCluster is reported for clusters never targeted by any dependency, contract, context or rule
+	LintUnusedCluster = "unused-cluster"
+
+	// LintUnknownLabel is reported for templates referencing labels which are never set by any dependency, contract, context or rule
+	LintUnknownLabel = "unknown-label"
+)
+
+// templateLabelRegex matches references to labels in text templates (e.g. {{ .Labels.name }}), but not to user labels (e.g. {{ .User.Labels.name }})
+var templateLabelRegex = regexp.MustCompile(`(?:^|[^A-Za-z0-9_])\.Labels\.([A-Za-z0-9_-]+)`)
+
+// LintIssue is a single problem found in the policy by PolicyLinter
+type LintIssue struct {
+	// Check is the name of the check which reported the issue
+	Check string
+
+	// Kind, Namespace and Name identify the policy object the issue belongs to
+	Kind      string
+	Namespace string
+	Name      string
+
+	// Message is a human-readable description of the issue
+	Message string
+}
+
+// GetDefaultColumns returns default set of columns to be displayed
+func (issue *LintIssue) GetDefaultColumns() []string {
+	return []string{"Check", "Object", "Message"}
+}
+
+// AsColumns returns LintIssue representation as columns
+func (issue *LintIssue) AsColumns() map[string]string {
+	return map[string]string{
+		"Check":   issue.Check,
+		"Object":  fmt.Sprintf("%s %s/%s", issue.Kind, issue.Namespace, issue.Name),
+		"Message": issue.Message,
+	}
+}
+
+// PolicyLinter performs static analysis of the policy and reports constructs which are structurally correct (i.e.
+// pass PolicyValidator), but most likely are mistakes: unreachable contexts, unused objects, shadowed rules, etc.
+// It doesn't require users, secrets or any state, so it can be used offline on a set of policy files.
+type PolicyLinter struct {
+	policy *Policy
+	issues []*LintIssue
+}
+
+// NewPolicyLinter creates a new PolicyLinter
+func NewPolicyLinter(policy *Policy) *PolicyLinter {
+	return &PolicyLinter{
+		policy: policy,
+	}
+}
+
+// Lint runs all checks and returns the list of found issues, sorted by check, object and message
+func (linter *PolicyLinter) Lint() []*LintIssue {
+	linter.issues = []*LintIssue{}
+
+	linter.checkUnreachableContexts()
+	linter.checkUnusedServices()
+	linter.checkUnusedContracts()
+	linter.checkShadowedRules()
+	linter.checkUnusedClusters()
+	linter.checkUnknownLabels()
+
+	sort.SliceStable(linter.issues, func(i, j int) bool {
+		a, b := linter.issues[i], linter.issues[j]
+		if a.Check != b.Check {
+			return a.Check < b.Check
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.Message < b.Message
+	})
+
+	return linter.issues
+}
+
+func (linter *PolicyLinter) addIssue(check string, obj Base, format string, args ...interface{}) {
+	linter.issues = append(linter.issues, &LintIssue{
+		Check:     check,
+		Kind:      obj.GetKind(),
+		Namespace: obj.GetNamespace(),
+		Name:      obj.GetName(),
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
+
+// sortedNamespaces returns all policy namespaces sorted by name
+func (linter *PolicyLinter) sortedNamespaces() []*PolicyNamespace {
+	result := []*PolicyNamespace{}
+	for _, name := range util.GetSortedStringKeys(linter.policy.Namespace) {
+		result = append(result, linter.policy.Namespace[name])
+	}
+	return result
+}
+
+// parseLocator splits object locator in form [namespace/]name into namespace and name
+func parseLocator(locator string, currentNs string) (string, string) {
+	parts := strings.SplitN(locator, "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return currentNs, locator
+}
+
+// lintKey returns key of the object. Unlike runtime.KeyFromParts it doesn't panic on empty namespace, as policy files
+// being linted are not validated
+func lintKey(namespace string, kind string, name string) string {
+	return strings.Join([]string{namespace, kind, name}, runtime.KeySeparator)
+}
+
+// isEmptyCriteria returns true if criteria always evaluates to true
+func isEmptyCriteria(criteria *Criteria) bool {
+	return criteria == nil || (len(criteria.RequireAll) == 0 && len(criteria.RequireAny) == 0 && len(criteria.RequireNone) == 0)
+}
+
+func (linter *PolicyLinter) checkUnreachableContexts() {
+	for _, policyNS := range linter.sortedNamespaces() {
+		for _, contractName := range util.GetSortedStringKeys(policyNS.Contracts) {
+			contract := policyNS.Contracts[contractName]
+			for idx, context := range contract.Contexts {
+				if context == nil || !isEmptyCriteria(context.Criteria) {
+					continue
+				}
+				for _, unreachable := range contract.Contexts[idx+1:] {
+					if unreachable == nil {
+						continue
+					}
+					linter.addIssue(LintUnreachableContext, contract, "context '%s' can never be matched, because earlier context '%s' has no criteria", unreachable.Name, context.Name)
+				}
+				break
+			}
+		}
+	}
+}
+
+func (linter *PolicyLinter) checkUnusedServices() {
+	referenced := make(map[string]bool)
+	for _, policyNS := range linter.policy.Namespace {
+		for _, contract := range policyNS.Contracts {
+			for _, context := range contract.Contexts {
+				if context == nil || context.Allocation == nil {
+					continue
+				}
+				ns, name := parseLocator(context.Allocation.Service, contract.Namespace)
+				referenced[lintKey(ns, ServiceObject.Kind, name)] = true
+			}
+		}
+	}
+
+	for _, policyNS := range linter.sortedNamespaces() {
+		for _, serviceName := range util.GetSortedStringKeys(policyNS.Services) {
+			service := policyNS.Services[serviceName]
+			if !referenced[lintKey(service.GetNamespace(), service.GetKind(), service.GetName())] {
+				linter.addIssue(LintUnusedService, service, "service is not referenced by any contract")
+			}
+		}
+	}
+}
+
+func (linter *PolicyLinter) checkUnusedContracts() {
+	consumed := make(map[string]bool)
+	for _, policyNS := range linter.policy.Namespace {
+		for _, dependency := range policyNS.Dependencies {
+			ns, name := parseLocator(dependency.Contract, dependency.Namespace)
+			consumed[lintKey(ns, ContractObject.Kind, name)] = true
+		}
+		for _, service := range policyNS.Services {
+			for _, component := range service.Components {
+				if component == nil || len(component.Contract) == 0 {
+					continue
+				}
+				ns, name := parseLocator(component.Contract, service.Namespace)
+				consumed[lintKey(ns, ContractObject.Kind, name)] = true
+			}
+		}
+	}
+
+	for _, policyNS := range linter.sortedNamespaces() {
+		for _, contractName := range util.GetSortedStringKeys(policyNS.Contracts) {
+			contract := policyNS.Contracts[contractName]
+			if !consumed[lintKey(contract.GetNamespace(), contract.GetKind(), contract.GetName())] {
+				linter.addIssue(LintUnusedContract, contract, "contract has no dependencies and is not used by any service component")
+			}
+		}
+	}
+}
+
+func (linter *PolicyLinter) checkShadowedRules() {
+	for _, policyNS := range linter.sortedNamespaces() {
+		rules := GetRulesSortedByWeight(policyNS.Rules)
+		for idx, rule := range rules {
+			// rules get applied in order of their weight and processing stops once a dependency gets rejected, so
+			// rule never gets applied if a rule with lower weight and identical criteria rejects dependency
+			if lower := findRejectingRule(rules[:idx], rule); lower != nil {
+				linter.addIssue(LintShadowedRule, rule, "rule has no effect, as rule '%s' with lower weight %d and identical criteria rejects dependency before it", lower.Name, lower.Weight)
+				continue
+			}
+
+			for _, higher := range rules[idx+1:] {
+				if higher.Weight == rule.Weight || !sameCriteria(rule.Criteria, higher.Criteria) {
+					continue
+				}
+				if overridesActions(higher.Actions, rule.Actions) {
+					linter.addIssue(LintShadowedRule, rule, "rule has no effect, all of its actions are overridden by rule '%s' with higher weight %d and identical criteria", higher.Name, higher.Weight)
+					break
+				}
+			}
+		}
+	}
+}
+
+// findRejectingRule returns a rule with lower weight and the same criteria as the specified rule, which rejects dependency
+func findRejectingRule(lower []*Rule, rule *Rule) *Rule {
+	for _, lowerRule := range lower {
+		if lowerRule.Weight == rule.Weight || !sameCriteria(lowerRule.Criteria, rule.Criteria) {
+			continue
+		}
+		if lowerRule.Actions != nil && string(lowerRule.Actions.Dependency) == Reject {
+			return lowerRule
+		}
+	}
+	return nil
+}
+
+// sameCriteria returns true if two criteria consist of the same expressions (order of expressions doesn't matter)
+func sameCriteria(a *Criteria, b *Criteria) bool {
+	if isEmptyCriteria(a) || isEmptyCriteria(b) {
+		return isEmptyCriteria(a) && isEmptyCriteria(b)
+	}
+	sorted := func(list []string) []string {
+		result := append([]string{}, list...)
+		sort.Strings(result)
+		return result
+	}
+	return reflect.DeepEqual(sorted(a.RequireAll), sorted(b.RequireAll)) &&
+		reflect.DeepEqual(sorted(a.RequireAny), sorted(b.RequireAny)) &&
+		reflect.DeepEqual(sorted(a.RequireNone), sorted(b.RequireNone))
+}
+
+// overridesActions returns true if every action of the rule gets overridden by the actions of a rule applied later
+func overridesActions(later *RuleActions, actions *RuleActions) bool {
+	if later == nil || actions == nil {
+		return false
+	}
+	// rejected dependency stops processing of the rules, so it can't be overridden by a rule applied later
+	if string(actions.Dependency) == Reject {
+		return false
+	}
+	if len(actions.Dependency) > 0 && len(later.Dependency) == 0 {
+		return false
+	}
+	if len(actions.Ingress) > 0 && len(later.Ingress) == 0 {
+		return false
+	}
+	for _, op := range labelOpsKeys {
+		for label := range actions.ChangeLabels[op] {
+			_, laterSets := later.ChangeLabels["set"][label]
+			_, laterRemoves := later.ChangeLabels["remove"][label]
+			if !laterSets && !laterRemoves {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// targetedClusters returns keys of all clusters referenced via 'target' label by label operations and dependencies
+func (linter *PolicyLinter) targetedClusters() map[string]bool {
+	result := make(map[string]bool)
+	addTarget := func(targetLabel string, currentNs string) {
+		if len(targetLabel) == 0 {
+			return
+		}
+		target := NewTarget(targetLabel)
+		if len(target.ClusterNamespace) > 0 {
+			result[lintKey(target.ClusterNamespace, ClusterObject.Kind, target.ClusterName)] = true
+			return
+		}
+		// cluster is looked up in the current namespace first and then in the system namespace
+		result[lintKey(currentNs, ClusterObject.Kind, target.ClusterName)] = true
+		result[lintKey(runtime.SystemNS, ClusterObject.Kind, target.ClusterName)] = true
+	}
+
+	for _, policyNS := range linter.policy.Namespace {
+		for _, dependency := range policyNS.Dependencies {
+			addTarget(dependency.Labels[LabelTarget], dependency.Namespace)
+		}
+		for _, contract := range policyNS.Contracts {
+			addTarget(contract.ChangeLabels["set"][LabelTarget], contract.Namespace)
+			for _, context := range contract.Contexts {
+				if context != nil {
+					addTarget(context.ChangeLabels["set"][LabelTarget], contract.Namespace)
+				}
+			}
+		}
+		for _, rule := range policyNS.Rules {
+			if rule.Actions == nil {
+				continue
+			}
+			// global rules get applied to dependencies in all namespaces, so any namespace may be the current one
+			if rule.Namespace == runtime.SystemNS {
+				for ns := range linter.policy.Namespace {
+					addTarget(rule.Actions.ChangeLabels["set"][LabelTarget], ns)
+				}
+			} else {
+				addTarget(rule.Actions.ChangeLabels["set"][LabelTarget], rule.Namespace)
+			}
+		}
+	}
+
+	return result
+}
+
+func (linter *PolicyLinter) checkUnusedClusters() {
+	targeted := linter.targetedClusters()
+	for _, policyNS := range linter.sortedNamespaces() {
+		for _, clusterName := range util.GetSortedStringKeys(policyNS.Clusters) {
+			cluster := policyNS.Clusters[clusterName]
+			if !targeted[lintKey(cluster.GetNamespace(), cluster.GetKind(), cluster.GetName())] {
+				linter.addIssue(LintUnusedCluster, cluster, "cluster is never targeted by any dependency, contract, context or rule")
+			}
+		}
+	}
+}
+
+// labelsSet returns names of all labels which are set by dependencies, contracts, contexts and rules
+func (linter *PolicyLinter) labelsSet() map[string]bool {
+	result := make(map[string]bool)
+	addOps := func(ops LabelOperations) {
+		for label := range ops["set"] {
+			result[label] = true
+		}
+	}
+
+	for _, policyNS := range linter.policy.Namespace {
+		for _, dependency := range policyNS.Dependencies {
+			for label := range dependency.Labels {
+				result[label] = true
+			}
+		}
+		for _, contract := range policyNS.Contracts {
+			addOps(contract.ChangeLabels)
+			for _, context := range contract.Contexts {
+				if context != nil {
+					addOps(context.ChangeLabels)
+				}
+			}
+		}
+		for _, rule := range policyNS.Rules {
+			if rule.Actions != nil {
+				addOps(rule.Actions.ChangeLabels)
+			}
+		}
+	}
+
+	return result
+}
+
+// templateLabels returns names of all labels referenced in a given text template
+func templateLabels(templateStr string) []string {
+	result := []string{}
+	for _, match := range templateLabelRegex.FindAllStringSubmatch(templateStr, -1) {
+		result = append(result, match[1])
+	}
+	return result
+}
+
+// nestedMapTemplates returns all text templates from a given nested map of parameters
+func nestedMapTemplates(params util.NestedParameterMap) []string {
+	result := []string{}
+	for _, key := range util.GetSortedStringKeys(params) {
+		switch value := params[key].(type) {
+		case string:
+			result = append(result, value)
+		case util.NestedParameterMap:
+			result = append(result, nestedMapTemplates(value)...)
+		case map[string]interface{}:
+			result = append(result, nestedMapTemplates(value)...)
+		}
+	}
+	return result
+}
+
+func (linter *PolicyLinter) checkUnknownLabels() {
+	known := linter.labelsSet()
+	check := func(obj Base, where string, templates []string) {
+		reported := make(map[string]bool)
+		for _, templateStr := range templates {
+			for _, label := range templateLabels(templateStr) {
+				if known[label] || reported[label] {
+					continue
+				}
+				reported[label] = true
+				linter.addIssue(LintUnknownLabel, obj, "%s references label '%s', which is never set by any dependency, contract, context or rule (it may only come from user labels)", where, label)
+			}
+		}
+	}
+
+	for _, policyNS := range linter.sortedNamespaces() {
+		for _, serviceName := range util.GetSortedStringKeys(policyNS.Services) {
+			service := policyNS.Services[serviceName]
+			for _, component := range service.Components {
+				if component == nil {
+					continue
+				}
+				if component.Code != nil {
+					check(service, fmt.Sprintf("code params of component '%s'", component.Name), nestedMapTemplates(component.Code.Params))
+				}
+				hooks := component.Hooks.GetAll()
+				for _, hook := range util.GetSortedStringKeys(hooks) {
+					check(service, fmt.Sprintf("%s hook params of component '%s'", hook, component.Name), nestedMapTemplates(hooks[hook].Params))
+				}
+				check(service, fmt.Sprintf("discovery of component '%s'", component.Name), nestedMapTemplates(component.Discovery))
+			}
+		}
+		for _, contractName := range util.GetSortedStringKeys(policyNS.Contracts) {
+			contract := policyNS.Contracts[contractName]
+			for _, context := range contract.Contexts {
+				if context == nil || context.Allocation == nil {
+					continue
+				}
+				check(contract, fmt.Sprintf("allocation keys of context '%s'", context.Name), context.Allocation.Keys)
+			}
+		}
+	}
+}
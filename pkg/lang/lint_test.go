@@ -0,0 +1,131 @@
+package lang
+
+import (
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyLinter(t *testing.T) {
+	policy := NewPolicy()
+
+	// service referenced by contract, with code params referencing known, unknown and user labels
+	service := makeService("service", Nil)
+	service.Components = []*ServiceComponent{
+		{
+			Name: "component",
+			Code: &Code{
+				Type: "helm",
+				Params: util.NestedParameterMap{
+					"name": "{{ .Labels.name }}",
+					"nested": util.NestedParameterMap{
+						"value": "{{ .Labels.missing }}",
+						"team":  "{{ .User.Labels.team }}",
+					},
+				},
+			},
+		},
+	}
+
+	// contract with a second context, which can never be matched
+	contract := makeContract("contract", 0, service.Name)
+	contract.Contexts = append(contract.Contexts, &Context{
+		Name:     "unreachable",
+		Criteria: &Criteria{RequireAll: []string{"true"}},
+		Allocation: &Allocation{
+			Service: service.Name,
+		},
+	})
+
+	// rules with identical criteria, where the first one gets fully overridden by the second one
+	ruleShadowed := makeRule(10, "false", 0, LabelTarget)
+	ruleShadowed.Name = "shadowed"
+	ruleShadowed.Actions.ChangeLabels = NewLabelOperationsSetSingleLabel(LabelTarget, "cluster.ns")
+	ruleOverriding := makeRule(20, "false", 0, LabelTarget)
+	ruleOverriding.Name = "overriding"
+	ruleOverriding.Actions.ChangeLabels = NewLabelOperationsSetSingleLabel(LabelTarget, "cluster.ns")
+
+	// rule with the same criteria, but different actions
+	ruleReject := makeRule(30, "false", 1, "reject")
+	ruleReject.Name = "reject"
+
+	clusterUnused := makeCluster("kubernetes", runtime.SystemNS)
+	clusterUnused.Name = "unused"
+
+	for _, obj := range []Base{
+		service,
+		makeService("unused", Nil),
+		contract,
+		makeContract("unused", Nil, ""),
+		makeDependency(contract.Name),
+		ruleShadowed,
+		ruleOverriding,
+		ruleReject,
+		makeCluster("kubernetes", runtime.SystemNS),
+		clusterUnused,
+	} {
+		assert.NoError(t, policy.AddObject(obj), "Object should be added to the policy")
+	}
+
+	issues := NewPolicyLinter(policy).Lint()
+
+	type issueKey struct {
+		check string
+		kind  string
+		name  string
+	}
+	found := []issueKey{}
+	for _, issue := range issues {
+		found = append(found, issueKey{issue.Check, issue.Kind, issue.Name})
+	}
+
+	assert.Equal(t, []issueKey{
+		{LintShadowedRule, RuleObject.Kind, "shadowed"},
+		{LintUnknownLabel, ServiceObject.Kind, "service"},
+		{LintUnreachableContext, ContractObject.Kind, "contract"},
+		{LintUnusedCluster, ClusterObject.Kind, "unused"},
+		{LintUnusedContract, ContractObject.Kind, "unused"},
+		{LintUnusedService, ServiceObject.Kind, "unused"},
+	}, found, "Linter should report expected issues")
+
+	assert.Contains(t, issues[1].Message, "'missing'", "Unknown label issue should mention label name")
+	assert.Contains(t, issues[2].Message, "'unreachable'", "Unreachable context issue should mention context name")
+}
+
+func TestPolicyLinterEmpty(t *testing.T) {
+	assert.Empty(t, NewPolicyLinter(NewPolicy()).Lint(), "Linter should report no issues for an empty policy")
+}
+
+func TestPolicyLinterRejectingRule(t *testing.T) {
+	policy := NewPolicy()
+
+	// rule, which rejects dependency, with identical criteria and lower weight than the other rules
+	ruleReject := makeRule(10, "false", 1, Reject)
+	ruleReject.Name = "reject"
+
+	// rules, which never get applied, as processing stops once dependency gets rejected
+	ruleLabels := makeRule(20, "false", 0, LabelTarget)
+	ruleLabels.Name = "labels"
+	ruleRejectAgain := makeRule(30, "false", 1, Reject)
+	ruleRejectAgain.Name = "reject-again"
+
+	// rule with different criteria, which still gets applied
+	ruleOther := makeRule(40, "true", 0, LabelTarget)
+	ruleOther.Name = "other"
+
+	for _, obj := range []Base{ruleReject, ruleLabels, ruleRejectAgain, ruleOther} {
+		assert.NoError(t, policy.AddObject(obj), "Object should be added to the policy")
+	}
+
+	found := []string{}
+	for _, issue := range NewPolicyLinter(policy).Lint() {
+		if issue.Check == LintShadowedRule {
+			found = append(found, issue.Name)
+			assert.Contains(t, issue.Message, "'reject'", "Shadowed rule issue should mention rule rejecting dependency")
+		}
+	}
+
+	assert.Equal(t, []string{"labels", "reject-again"}, found, "Linter should report rules applied after dependency gets rejected")
+}
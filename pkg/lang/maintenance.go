@@ -0,0 +1,156 @@
+package lang
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// MaintenanceObject is an informational data structure with Kind and Constructor for Maintenance
+var MaintenanceObject = &runtime.Info{
+	Kind:        "maintenance",
+	Storable:    true,
+	Versioned:   true,
+	Deletable:   true,
+	Constructor: func() runtime.Object { return &Maintenance{} },
+}
+
+// Maintenance restricts when changes can be made to code component instances running in the clusters. Changes to
+// component instances matching its criteria are only made while one of its windows is open (if windows are defined)
+// and never during its freezes. Changes, which aren't allowed at the moment, get deferred until they are allowed.
+//
+// Maintenance defined in a namespace applies to component instances of services in that namespace only. Maintenance
+// defined in 'system' namespace applies to component instances in all namespaces
+type Maintenance struct {
+	runtime.TypeKind `yaml:",inline"`
+	Metadata         `validate:"required"`
+
+	// Criteria - if it gets evaluated to true for a component instance, then changes to the component instance are
+	// subject to this maintenance. Criteria are evaluated against calculated labels of the component instance, as well
+	// as 'Cluster', 'Service' and 'Target' objects. It's an optional field, so if it's nil then maintenance applies
+	// to all component instances
+	Criteria *Criteria `yaml:",omitempty" validate:"omitempty"`
+
+	// Windows is a list of maintenance windows. If it's not empty, changes are only allowed while at least one of
+	// the windows is open
+	Windows []*MaintenanceWindow `yaml:"windows,omitempty" validate:"dive"`
+
+	// Freezes is a list of change freezes. Changes are not allowed during any of the freezes
+	Freezes []*MaintenanceFreeze `yaml:"freezes,omitempty" validate:"dive"`
+}
+
+// MaintenanceWindow is a recurring period of time, when changes are allowed
+type MaintenanceWindow struct {
+	// Schedule is a cron-like schedule of when the window opens, consisting of 5 fields: minute, hour, day of month,
+	// month and day of week (e.g. '0 22 * * 6' means every Saturday at 22:00)
+	Schedule string `yaml:"schedule" validate:"schedule"`
+
+	// Duration is how long the window stays open (e.g. '4h')
+	Duration time.Duration `yaml:"duration" validate:"gt=0"`
+
+	// Timezone is a name of the timezone from IANA Time Zone database (e.g. 'America/Los_Angeles'), in which
+	// the schedule is defined. It's an optional field, so if it's empty then UTC is used
+	Timezone string `yaml:"timezone,omitempty" validate:"omitempty,timezone"`
+}
+
+// MaintenanceFreeze is an absolute period of time, when changes are not allowed
+type MaintenanceFreeze struct {
+	// From is when freeze starts
+	From time.Time `yaml:"from"`
+
+	// To is when freeze ends
+	To time.Time `yaml:"to"`
+
+	// Reason is a human-readable reason of the freeze (e.g. 'end of the year sales')
+	Reason string `yaml:"reason,omitempty"`
+}
+
+// maxMaintenanceSteps limits the number of steps taken, while looking for the next point of time when changes are
+// allowed (in case windows and freezes never allow any changes)
+const maxMaintenanceSteps = 1000
+
+// Matches returns true if changes to a component instance with given parameters are subject to maintenance
+func (maintenance *Maintenance) Matches(params *expression.Parameters, cache *expression.Cache) (bool, error) {
+	if maintenance.Criteria == nil {
+		return true, nil
+	}
+	return maintenance.Criteria.allows(params, cache)
+}
+
+// IsChangeAllowed returns true if changes are allowed at a given point of time
+func (maintenance *Maintenance) IsChangeAllowed(t time.Time) (bool, error) {
+	next, err := maintenance.NextChangeAllowed(t)
+	if err != nil {
+		return false, err
+	}
+	return !next.After(t), nil
+}
+
+// NextChangeAllowed returns the first point of time (at or after a given one), when changes are allowed, i.e. one of
+// the windows is open and there are no freezes in effect
+func (maintenance *Maintenance) NextChangeAllowed(t time.Time) (time.Time, error) {
+	for i := 0; i < maxMaintenanceSteps; i++ {
+		changed := false
+
+		// skip over freezes
+		for _, freeze := range maintenance.Freezes {
+			if freeze.isActive(t) {
+				t = freeze.To
+				changed = true
+			}
+		}
+
+		// wait until one of the windows opens
+		if len(maintenance.Windows) > 0 {
+			var opensAt time.Time
+			for _, window := range maintenance.Windows {
+				windowOpensAt, err := window.nextOpen(t)
+				if err != nil {
+					return time.Time{}, err
+				}
+				if !windowOpensAt.IsZero() && (opensAt.IsZero() || windowOpensAt.Before(opensAt)) {
+					opensAt = windowOpensAt
+				}
+			}
+			if opensAt.IsZero() {
+				return time.Time{}, fmt.Errorf("maintenance '%s/%s' never opens its windows", maintenance.Namespace, maintenance.Name)
+			}
+			if opensAt.After(t) {
+				t = opensAt
+				changed = true
+			}
+		}
+
+		if !changed {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("maintenance '%s/%s' doesn't allow changes in the foreseeable future", maintenance.Namespace, maintenance.Name)
+}
+
+// isActive returns true if freeze is in effect at a given point of time
+func (freeze *MaintenanceFreeze) isActive(t time.Time) bool {
+	return !t.Before(freeze.From) && t.Before(freeze.To)
+}
+
+// nextOpen returns a given point of time if the window is open at that time. Otherwise it returns the next point of
+// time when the window opens (or zero time, if it never opens)
+func (window *MaintenanceWindow) nextOpen(t time.Time) (time.Time, error) {
+	sched, err := parseSchedule(window.Schedule)
+	if err != nil {
+		return time.Time{}, err
+	}
+	loc, err := time.LoadLocation(window.Timezone)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	// window is open if it has opened within its duration before a given time
+	openedAt := sched.next(t.Add(-window.Duration).Add(time.Nanosecond), loc)
+	if !openedAt.IsZero() && !openedAt.After(t) {
+		return t, nil
+	}
+	return sched.next(t, loc), nil
+}
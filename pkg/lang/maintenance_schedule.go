@@ -0,0 +1,154 @@
+package lang
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// schedule is a parsed cron-like schedule. Every field is a bit set of allowed values
+type schedule struct {
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+
+	// day of month and day of week are combined with OR if both of them are restricted (same as in cron)
+	dayOfMonthAny bool
+	dayOfWeekAny  bool
+}
+
+// scheduleField describes the allowed range of values for a field of the schedule
+type scheduleField struct {
+	name string
+	min  int
+	max  int
+}
+
+var scheduleFields = []scheduleField{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// maxScheduleYears limits how far in the future the next point of time matching a schedule is searched for
+const maxScheduleYears = 5
+
+// parseSchedule parses cron-like schedule, consisting of 5 fields: minute, hour, day of month, month and day of week.
+// Every field is a comma-separated list of values, ranges ('1-5') and steps ('*/15', '0-30/10'), or '*' for any value
+func parseSchedule(spec string) (*schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(scheduleFields) {
+		return nil, fmt.Errorf("schedule '%s' should have %d fields (minute, hour, day of month, month, day of week), got %d", spec, len(scheduleFields), len(fields))
+	}
+
+	values := make([]uint64, len(fields))
+	for i, field := range fields {
+		value, err := parseScheduleField(field, scheduleFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("schedule '%s' is invalid: %s", spec, err)
+		}
+		values[i] = value
+	}
+
+	// Sunday can be either 0 or 7
+	if values[4]&(1<<7) != 0 {
+		values[4] |= 1
+	}
+
+	return &schedule{
+		minute:        values[0],
+		hour:          values[1],
+		dayOfMonth:    values[2],
+		month:         values[3],
+		dayOfWeek:     values[4],
+		dayOfMonthAny: fields[2] == "*",
+		dayOfWeekAny:  fields[4] == "*",
+	}, nil
+}
+
+func parseScheduleField(field string, desc scheduleField) (uint64, error) {
+	var result uint64
+	for _, part := range strings.Split(field, ",") {
+		step := 1
+		if idx := strings.Index(part, "/"); idx >= 0 {
+			var err error
+			step, err = strconv.Atoi(part[idx+1:])
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step in %s '%s'", desc.name, part)
+			}
+			part = part[:idx]
+		}
+
+		from, to := desc.min, desc.max
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			from, err = strconv.Atoi(bounds[0])
+			if err != nil {
+				return 0, fmt.Errorf("invalid %s '%s'", desc.name, part)
+			}
+			to = from
+			if len(bounds) > 1 {
+				to, err = strconv.Atoi(bounds[1])
+				if err != nil {
+					return 0, fmt.Errorf("invalid %s '%s'", desc.name, part)
+				}
+			} else if step > 1 {
+				to = desc.max
+			}
+		}
+		if from < desc.min || to > desc.max || from > to {
+			return 0, fmt.Errorf("%s '%s' is out of range [%d, %d]", desc.name, part, desc.min, desc.max)
+		}
+
+		for value := from; value <= to; value += step {
+			result |= 1 << uint(value)
+		}
+	}
+	return result, nil
+}
+
+// next returns the first point of time (at or after a given one, rounded up to a minute), which matches the schedule
+// in a given location. It returns zero time if there is no such point of time in the foreseeable future
+func (sched *schedule) next(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	if t.Second() > 0 || t.Nanosecond() > 0 {
+		t = t.Truncate(time.Minute).Add(time.Minute)
+	}
+
+	yearLimit := t.Year() + maxScheduleYears
+	for t.Year() <= yearLimit {
+		if sched.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !sched.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if sched.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if sched.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (sched *schedule) matchesDay(t time.Time) bool {
+	dayOfMonth := sched.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := sched.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if sched.dayOfMonthAny || sched.dayOfWeekAny {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
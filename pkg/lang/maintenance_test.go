@@ -0,0 +1,96 @@
+package lang
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceSchedule(t *testing.T) {
+	// 2018-01-01 is Monday
+	now := time.Date(2018, 1, 1, 12, 30, 15, 0, time.UTC)
+
+	tests := []struct {
+		spec string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2018, 1, 1, 12, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2018, 1, 1, 12, 45, 0, 0, time.UTC)},
+		{"0 22 * * 6", time.Date(2018, 1, 6, 22, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2018, 1, 7, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2018, 1, 1, 13, 0, 0, 0, time.UTC)},
+		{"0 0 1 3 *", time.Date(2018, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 * *", time.Date(2018, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, 2, 29, 0, 0, 0, 0, time.UTC)},
+
+		// day of month and day of week are combined with OR if both are restricted
+		{"0 0 15 * 3", time.Date(2018, 1, 3, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		sched, err := parseSchedule(test.spec)
+		if !assert.NoError(t, err, "Schedule '%s' should be valid", test.spec) {
+			continue
+		}
+		assert.Equal(t, test.next, sched.next(now, time.UTC), "Next time for schedule '%s'", test.spec)
+	}
+
+	// never matching schedule
+	sched, err := parseSchedule("0 0 31 2 *")
+	assert.NoError(t, err, "Schedule should be valid")
+	assert.True(t, sched.next(now, time.UTC).IsZero(), "Schedule should never match")
+
+	// invalid schedules
+	for _, spec := range []string{"", "* * * *", "* * * * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *", "* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *"} {
+		_, err := parseSchedule(spec)
+		assert.Error(t, err, "Schedule '%s' should be invalid", spec)
+	}
+}
+
+func TestMaintenanceChangeAllowed(t *testing.T) {
+	// every Saturday 22:00-02:00 in Los Angeles (06:00-10:00 UTC on Sunday in winter)
+	maintenance := &Maintenance{
+		Windows: []*MaintenanceWindow{{
+			Schedule: "0 22 * * 6",
+			Duration: 4 * time.Hour,
+			Timezone: "America/Los_Angeles",
+		}},
+	}
+	windowOpens := time.Date(2018, 1, 7, 6, 0, 0, 0, time.UTC)
+
+	checkChangeAllowed(t, maintenance, windowOpens.Add(-time.Minute), windowOpens)
+	checkChangeAllowed(t, maintenance, windowOpens, windowOpens)
+	checkChangeAllowed(t, maintenance, windowOpens.Add(3*time.Hour), windowOpens.Add(3*time.Hour))
+	checkChangeAllowed(t, maintenance, windowOpens.Add(4*time.Hour), windowOpens.Add(7*24*time.Hour))
+
+	// freeze covering the next window moves changes to the window after it
+	maintenance.Freezes = []*MaintenanceFreeze{{
+		From: time.Date(2018, 1, 5, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2018, 1, 8, 0, 0, 0, 0, time.UTC),
+	}}
+	checkChangeAllowed(t, maintenance, windowOpens.Add(-time.Minute), windowOpens.Add(7*24*time.Hour))
+
+	// freeze only
+	maintenance.Windows = nil
+	checkChangeAllowed(t, maintenance, windowOpens, time.Date(2018, 1, 8, 0, 0, 0, 0, time.UTC))
+	checkChangeAllowed(t, maintenance, time.Date(2018, 1, 8, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 8, 0, 0, 0, 0, time.UTC))
+	checkChangeAllowed(t, maintenance, time.Date(2018, 1, 4, 0, 0, 0, 0, time.UTC), time.Date(2018, 1, 4, 0, 0, 0, 0, time.UTC))
+
+	// window which never opens
+	maintenance.Windows = []*MaintenanceWindow{{Schedule: "0 0 31 2 *", Duration: time.Hour}}
+	_, err := maintenance.NextChangeAllowed(windowOpens)
+	assert.Error(t, err, "Maintenance with window which never opens should not allow changes")
+}
+
+func checkChangeAllowed(t *testing.T, maintenance *Maintenance, now time.Time, expected time.Time) {
+	t.Helper()
+	next, err := maintenance.NextChangeAllowed(now)
+	if !assert.NoError(t, err, "Next allowed change time should be calculated") {
+		return
+	}
+	assert.True(t, expected.Equal(next), "Changes at %s should be allowed at %s, got %s", now, expected, next)
+
+	allowed, err := maintenance.IsChangeAllowed(now)
+	assert.NoError(t, err, "Change should be checked")
+	assert.Equal(t, expected.Equal(now), allowed, "Changes at %s should be allowed: %t", now, expected.Equal(now))
+}
@@ -1,6 +1,10 @@
 package lang
 
-import "github.com/Aptomi/aptomi/pkg/runtime"
+import (
+	"sort"
+
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
 
 var (
 	// PolicyObjects is the list of informational data for all policy objects
@@ -11,6 +15,9 @@ var (
 		ClusterObject,
 		RuleObject,
 		ACLRuleObject,
+		ACLCustomRoleObject,
+		QuotaObject,
+		MaintenanceObject,
 	}
 
 	policyObjectsMap = make(map[runtime.Kind]bool)
@@ -22,6 +29,16 @@ func init() {
 	}
 }
 
+// returns the sorted list of kinds of all policy objects
+func policyObjectKinds() []string {
+	result := []string{}
+	for _, obj := range PolicyObjects {
+		result = append(result, obj.Kind)
+	}
+	sort.Strings(result)
+	return result
+}
+
 // IsPolicyObject returns true if provided object is part of the policy objects list
 func IsPolicyObject(obj runtime.Object) bool {
 	return policyObjectsMap[obj.GetKind()]
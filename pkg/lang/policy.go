@@ -48,14 +48,24 @@ func (policy *Policy) getCachedACLResolver() *ACLResolver {
 	if policy.aclResolver == nil {
 		systemNamespace := policy.Namespace[runtime.SystemNS]
 		if systemNamespace != nil {
-			policy.aclResolver = NewACLResolver(systemNamespace.ACLRules)
+			policy.aclResolver = NewACLResolver(systemNamespace.ACLRules, systemNamespace.ACLRoles)
 		} else {
-			policy.aclResolver = NewACLResolver(make(map[string]*ACLRule))
+			policy.aclResolver = NewACLResolver(make(map[string]*ACLRule), make(map[string]*ACLCustomRole))
 		}
 	}
 	return policy.aclResolver
 }
 
+// hasCustomRole returns true if a custom ACL role with a given ID is defined in the policy
+func (policy *Policy) hasCustomRole(roleID string) bool {
+	systemNamespace := policy.Namespace[runtime.SystemNS]
+	if systemNamespace == nil {
+		return false
+	}
+	_, ok := systemNamespace.ACLRoles[roleID]
+	return ok
+}
+
 // View returns a policy view object, which allows to make all policy operations on behalf of a certain user
 // Policy view object will enforce all ACLs, allowing the user to only perform actions which he is allowed to perform
 // All ACL rules should be loaded and added to the policy before this method gets called
@@ -73,8 +83,8 @@ func (policy *Policy) AddObject(obj Base) error {
 	}
 	err := policyNamespace.addObject(obj)
 
-	// if we just added ACLRule, we need to invalidate cached aclResolver
-	if obj.GetKind() == ACLRuleObject.Kind {
+	// if we just added ACLRule or ACLCustomRole, we need to invalidate cached aclResolver
+	if obj.GetKind() == ACLRuleObject.Kind || obj.GetKind() == ACLCustomRoleObject.Kind {
 		policy.invalidateCachedACLResolver()
 	}
 
@@ -88,7 +98,14 @@ func (policy *Policy) RemoveObject(obj Base) bool {
 		return false
 	}
 
-	return policyNamespace.removeObject(obj)
+	removed := policyNamespace.removeObject(obj)
+
+	// if we just removed ACLRule or ACLCustomRole, we need to invalidate cached aclResolver
+	if removed && (obj.GetKind() == ACLRuleObject.Kind || obj.GetKind() == ACLCustomRoleObject.Kind) {
+		policy.invalidateCachedACLResolver()
+	}
+
+	return removed
 }
 
 // GetObjectsByKind returns all objects in a policy with a given kind, across all namespaces
@@ -10,13 +10,16 @@ import (
 // PolicyNamespace describes a specific namespace within Aptomi policy.
 // All policy objects get placed in the appropriate maps and structs within PolicyNamespace.
 type PolicyNamespace struct {
-	Name         string                 `validate:"identifier"`
-	Services     map[string]*Service    `validate:"dive"`
-	Contracts    map[string]*Contract   `validate:"dive"`
-	Clusters     map[string]*Cluster    `validate:"dive"`
-	Rules        map[string]*Rule       `validate:"dive"`
-	ACLRules     map[string]*ACLRule    `validate:"dive"`
-	Dependencies map[string]*Dependency `validate:"dive"`
+	Name         string                    `validate:"identifier"`
+	Services     map[string]*Service       `validate:"dive"`
+	Contracts    map[string]*Contract      `validate:"dive"`
+	Clusters     map[string]*Cluster       `validate:"dive"`
+	Rules        map[string]*Rule          `validate:"dive"`
+	ACLRules     map[string]*ACLRule       `validate:"dive"`
+	ACLRoles     map[string]*ACLCustomRole `validate:"dive"`
+	Dependencies map[string]*Dependency    `validate:"dive"`
+	Quotas       map[string]*Quota         `validate:"dive"`
+	Maintenances map[string]*Maintenance   `validate:"dive"`
 }
 
 // NewPolicyNamespace creates a new PolicyNamespace
@@ -28,7 +31,10 @@ func NewPolicyNamespace(name string) *PolicyNamespace {
 		Clusters:     make(map[string]*Cluster),
 		Rules:        make(map[string]*Rule),
 		ACLRules:     make(map[string]*ACLRule),
+		ACLRoles:     make(map[string]*ACLCustomRole),
 		Dependencies: make(map[string]*Dependency),
+		Quotas:       make(map[string]*Quota),
+		Maintenances: make(map[string]*Maintenance),
 	}
 }
 
@@ -63,8 +69,14 @@ func (policyNamespace *PolicyNamespace) addObject(obj Base) error {
 		policyNamespace.Rules[obj.GetName()] = obj.(*Rule) // nolint: errcheck
 	case ACLRuleObject.Kind:
 		policyNamespace.ACLRules[obj.GetName()] = obj.(*ACLRule) // nolint: errcheck
+	case ACLCustomRoleObject.Kind:
+		policyNamespace.ACLRoles[obj.GetName()] = obj.(*ACLCustomRole) // nolint: errcheck
 	case DependencyObject.Kind:
 		policyNamespace.Dependencies[obj.GetName()] = obj.(*Dependency) // nolint: errcheck
+	case QuotaObject.Kind:
+		policyNamespace.Quotas[obj.GetName()] = obj.(*Quota) // nolint: errcheck
+	case MaintenanceObject.Kind:
+		policyNamespace.Maintenances[obj.GetName()] = obj.(*Maintenance) // nolint: errcheck
 	default:
 		return fmt.Errorf("not supported by PolicyNamespace.addObject(): unknown kind %s", kind)
 	}
@@ -98,11 +110,26 @@ func (policyNamespace *PolicyNamespace) removeObject(obj Base) bool {
 			delete(policyNamespace.ACLRules, obj.GetName())
 			return true
 		}
+	case ACLCustomRoleObject.Kind:
+		if _, exist := policyNamespace.ACLRoles[obj.GetName()]; exist {
+			delete(policyNamespace.ACLRoles, obj.GetName())
+			return true
+		}
 	case DependencyObject.Kind:
 		if _, exist := policyNamespace.Dependencies[obj.GetName()]; exist {
 			delete(policyNamespace.Dependencies, obj.GetName())
 			return true
 		}
+	case QuotaObject.Kind:
+		if _, exist := policyNamespace.Quotas[obj.GetName()]; exist {
+			delete(policyNamespace.Quotas, obj.GetName())
+			return true
+		}
+	case MaintenanceObject.Kind:
+		if _, exist := policyNamespace.Maintenances[obj.GetName()]; exist {
+			delete(policyNamespace.Maintenances, obj.GetName())
+			return true
+		}
 	}
 
 	return false
@@ -131,10 +158,22 @@ func (policyNamespace *PolicyNamespace) getObjectsByKind(kind string) []Base {
 		for _, rule := range policyNamespace.ACLRules {
 			result = append(result, rule)
 		}
+	case ACLCustomRoleObject.Kind:
+		for _, role := range policyNamespace.ACLRoles {
+			result = append(result, role)
+		}
 	case DependencyObject.Kind:
 		for _, dependency := range policyNamespace.Dependencies {
 			result = append(result, dependency)
 		}
+	case QuotaObject.Kind:
+		for _, quota := range policyNamespace.Quotas {
+			result = append(result, quota)
+		}
+	case MaintenanceObject.Kind:
+		for _, maintenance := range policyNamespace.Maintenances {
+			result = append(result, maintenance)
+		}
 	default:
 		panic(fmt.Sprintf("not supported by PolicyNamespace.getObjectsByKind(): unknown kind %s", kind))
 	}
@@ -165,10 +204,22 @@ func (policyNamespace *PolicyNamespace) getObject(kind string, name string) (run
 		if result, ok = policyNamespace.ACLRules[name]; !ok {
 			return nil, nil
 		}
+	case ACLCustomRoleObject.Kind:
+		if result, ok = policyNamespace.ACLRoles[name]; !ok {
+			return nil, nil
+		}
 	case DependencyObject.Kind:
 		if result, ok = policyNamespace.Dependencies[name]; !ok {
 			return nil, nil
 		}
+	case QuotaObject.Kind:
+		if result, ok = policyNamespace.Quotas[name]; !ok {
+			return nil, nil
+		}
+	case MaintenanceObject.Kind:
+		if result, ok = policyNamespace.Maintenances[name]; !ok {
+			return nil, nil
+		}
 	default:
 		return nil, fmt.Errorf("not supported by PolicyNamespace.getObject(): unknown kind %s, %s", kind, name)
 	}
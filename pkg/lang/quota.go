@@ -0,0 +1,73 @@
+package lang
+
+import (
+	"github.com/Aptomi/aptomi/pkg/lang/expression"
+	"github.com/Aptomi/aptomi/pkg/lang/template"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// QuotaObject is an informational data structure with Kind and Constructor for Quota
+var QuotaObject = &runtime.Info{
+	Kind:        "quota",
+	Storable:    true,
+	Versioned:   true,
+	Deletable:   true,
+	Constructor: func() runtime.Object { return &Quota{} },
+}
+
+// Quota limits the number of dependencies and instances, which users can create. It applies to all dependencies
+// matching its criteria, with usage being counted separately for every group of dependencies (e.g. per user or
+// per team).
+//
+// Quota defined in a namespace applies to dependencies in that namespace only. Quota defined in 'system' namespace
+// applies to dependencies in all namespaces. Dependencies, which would exceed quota, don't get resolved
+type Quota struct {
+	runtime.TypeKind `yaml:",inline"`
+	Metadata         `validate:"required"`
+
+	// Criteria - if it gets evaluated to true for a dependency, then the dependency is subject to this quota.
+	// It's an optional field, so if it's nil then quota applies to all dependencies
+	Criteria *Criteria `yaml:",omitempty" validate:"omitempty"`
+
+	// GroupBy is a text template, which defines how dependencies are grouped when quota usage gets calculated (e.g.
+	// '{{ .User.Name }}' for a quota per user or '{{ .Labels.team }}' for a quota per team). It's an optional field,
+	// so if it's empty then all dependencies subject to this quota are counted together
+	GroupBy string `yaml:"group-by,omitempty" validate:"omitempty,template"`
+
+	// Limits define limits within each group of dependencies
+	Limits *QuotaLimits `validate:"required"`
+}
+
+// QuotaLimits is a set of limits, which can be enforced by a quota. All fields in this structure are optional. If a
+// field is zero, then the corresponding limit will not be enforced
+type QuotaLimits struct {
+	// Dependencies is a maximum number of dependencies
+	Dependencies int `yaml:"dependencies,omitempty" validate:"min=0"`
+
+	// Instances is a maximum number of distinct service instances, which dependencies can be resolved into (i.e.
+	// multiple dependencies resolved into the same service instance are counted only once)
+	Instances int `yaml:"instances,omitempty" validate:"min=0"`
+
+	// ClusterComponentInstances is a maximum number of distinct component instances, which dependencies can result
+	// in within a single cluster
+	ClusterComponentInstances int `yaml:"cluster-component-instances,omitempty" validate:"min=0"`
+}
+
+// Matches returns true if a dependency with given parameters is subject to quota
+func (quota *Quota) Matches(params *expression.Parameters, cache *expression.Cache) (bool, error) {
+	if quota.Criteria == nil {
+		return true, nil
+	}
+	return quota.Criteria.allows(params, cache)
+}
+
+// ResolveGroup returns a name of the group, which a dependency with given parameters belongs to
+func (quota *Quota) ResolveGroup(params *template.Parameters, cache *template.Cache) (string, error) {
+	if len(quota.GroupBy) == 0 {
+		return "", nil
+	}
+	if cache == nil {
+		cache = template.NewCache()
+	}
+	return cache.Evaluate(quota.GroupBy, params)
+}
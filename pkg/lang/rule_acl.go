@@ -78,23 +78,27 @@ func GetACLRulesSortedByWeight(rules map[string]*ACLRule) []*ACLRule {
 // Service consumer can only consume services within a given set of namespaces. Service consumption is treated as capability
 // to instantiate services in a given namespace.
 // Nobody cannot do anything except viewing the policy.
+// In addition to built-in roles, domain admins can define custom roles in the policy (see ACLCustomRole).
 type ACLRole struct {
 	ID         string
 	Name       string
 	Privileges *Privileges
+
+	// Custom is true if role is not built-in, but defined in the policy
+	Custom bool `yaml:",omitempty"`
 }
 
 // Privileges defines a set of privileges for a particular role in Aptomi
 type Privileges struct {
 	// AllNamespaces, when set to true, indicated that user privileges apply to all namespaces. Otherwise it applies
 	// to a set of given namespaces
-	AllNamespaces bool
+	AllNamespaces bool `yaml:"all-namespaces,omitempty"`
 
 	// NamespaceObjects specifies whether or not this role can view/manage a certain object kind within a non-system namespace
-	NamespaceObjects map[string]*Privilege
+	NamespaceObjects map[string]*Privilege `yaml:"namespace-objects,omitempty" validate:"omitempty,privilegeKinds"`
 
 	// GlobalObjects specifies whether or not this role can view/manage a certain object kind within a system namespace
-	GlobalObjects map[string]*Privilege
+	GlobalObjects map[string]*Privilege `yaml:"global-objects,omitempty" validate:"omitempty,privilegeKinds"`
 }
 
 // Returns privileges for a given object
@@ -114,10 +118,10 @@ func (privileges *Privileges) getObjectPrivileges(obj Base) *Privilege {
 // Privilege is a unit of privilege for any single given object
 type Privilege struct {
 	// View indicates whether or not a user can view an object (R)
-	View bool
+	View bool `yaml:",omitempty"`
 
 	// Manage indicates whether or not a user can manage an object, i.e. perform operations (CUD)
-	Manage bool
+	Manage bool `yaml:",omitempty"`
 }
 
 // Full access privilege
@@ -141,15 +145,20 @@ var DomainAdmin = &ACLRole{
 	Privileges: &Privileges{
 		AllNamespaces: true,
 		NamespaceObjects: map[string]*Privilege{
-			ServiceObject.Kind:    fullAccess,
-			ContractObject.Kind:   fullAccess,
-			DependencyObject.Kind: fullAccess,
-			RuleObject.Kind:       fullAccess,
+			ServiceObject.Kind:     fullAccess,
+			ContractObject.Kind:    fullAccess,
+			DependencyObject.Kind:  fullAccess,
+			RuleObject.Kind:        fullAccess,
+			QuotaObject.Kind:       fullAccess,
+			MaintenanceObject.Kind: fullAccess,
 		},
 		GlobalObjects: map[string]*Privilege{
-			ClusterObject.Kind: fullAccess,
-			RuleObject.Kind:    fullAccess,
-			ACLRuleObject.Kind: fullAccess,
+			ClusterObject.Kind:       fullAccess,
+			RuleObject.Kind:          fullAccess,
+			ACLRuleObject.Kind:       fullAccess,
+			QuotaObject.Kind:         fullAccess,
+			MaintenanceObject.Kind:   fullAccess,
+			ACLCustomRoleObject.Kind: fullAccess,
 		},
 	},
 }
@@ -160,15 +169,20 @@ var NamespaceAdmin = &ACLRole{
 	Name: "Namespace Admin",
 	Privileges: &Privileges{
 		NamespaceObjects: map[string]*Privilege{
-			ServiceObject.Kind:    fullAccess,
-			ContractObject.Kind:   fullAccess,
-			DependencyObject.Kind: fullAccess,
-			RuleObject.Kind:       fullAccess,
+			ServiceObject.Kind:     fullAccess,
+			ContractObject.Kind:    fullAccess,
+			DependencyObject.Kind:  fullAccess,
+			RuleObject.Kind:        fullAccess,
+			QuotaObject.Kind:       viewAccess,
+			MaintenanceObject.Kind: viewAccess,
 		},
 		GlobalObjects: map[string]*Privilege{
-			ClusterObject.Kind: viewAccess,
-			RuleObject.Kind:    viewAccess,
-			ACLRuleObject.Kind: viewAccess,
+			ClusterObject.Kind:       viewAccess,
+			RuleObject.Kind:          viewAccess,
+			ACLRuleObject.Kind:       viewAccess,
+			QuotaObject.Kind:         viewAccess,
+			MaintenanceObject.Kind:   viewAccess,
+			ACLCustomRoleObject.Kind: viewAccess,
 		},
 	},
 }
@@ -179,15 +193,20 @@ var ServiceConsumer = &ACLRole{
 	Name: "Service Consumer",
 	Privileges: &Privileges{
 		NamespaceObjects: map[string]*Privilege{
-			ServiceObject.Kind:    viewAccess,
-			ContractObject.Kind:   viewAccess,
-			DependencyObject.Kind: fullAccess,
-			RuleObject.Kind:       viewAccess,
+			ServiceObject.Kind:     viewAccess,
+			ContractObject.Kind:    viewAccess,
+			DependencyObject.Kind:  fullAccess,
+			RuleObject.Kind:        viewAccess,
+			QuotaObject.Kind:       viewAccess,
+			MaintenanceObject.Kind: viewAccess,
 		},
 		GlobalObjects: map[string]*Privilege{
-			ClusterObject.Kind: viewAccess,
-			RuleObject.Kind:    viewAccess,
-			ACLRuleObject.Kind: viewAccess,
+			ClusterObject.Kind:       viewAccess,
+			RuleObject.Kind:          viewAccess,
+			ACLRuleObject.Kind:       viewAccess,
+			QuotaObject.Kind:         viewAccess,
+			MaintenanceObject.Kind:   viewAccess,
+			ACLCustomRoleObject.Kind: viewAccess,
 		},
 	},
 }
@@ -198,15 +217,20 @@ var nobody = &ACLRole{
 	Name: "Nobody",
 	Privileges: &Privileges{
 		NamespaceObjects: map[string]*Privilege{
-			ServiceObject.Kind:    viewAccess,
-			ContractObject.Kind:   viewAccess,
-			DependencyObject.Kind: viewAccess,
-			RuleObject.Kind:       viewAccess,
+			ServiceObject.Kind:     viewAccess,
+			ContractObject.Kind:    viewAccess,
+			DependencyObject.Kind:  viewAccess,
+			RuleObject.Kind:        viewAccess,
+			QuotaObject.Kind:       viewAccess,
+			MaintenanceObject.Kind: viewAccess,
 		},
 		GlobalObjects: map[string]*Privilege{
-			ClusterObject.Kind: viewAccess,
-			RuleObject.Kind:    viewAccess,
-			ACLRuleObject.Kind: viewAccess,
+			ClusterObject.Kind:       viewAccess,
+			RuleObject.Kind:          viewAccess,
+			ACLRuleObject.Kind:       viewAccess,
+			QuotaObject.Kind:         viewAccess,
+			MaintenanceObject.Kind:   viewAccess,
+			ACLCustomRoleObject.Kind: viewAccess,
 		},
 	},
 }
@@ -10,10 +10,10 @@ type ACLRuleActions struct {
 	AddRole map[string]string `yaml:"add-role,omitempty" validate:"omitempty,addRoleNS"`
 }
 
-// ApplyActions applies rule actions and updates result
-func (rule *ACLRule) ApplyActions(roleMap map[string]map[string]bool) {
+// ApplyActions applies rule actions and updates result, given the map of all known roles (Role ID -> Role)
+func (rule *ACLRule) ApplyActions(roleMap map[string]map[string]bool, roles map[string]*ACLRole) {
 	for roleID, namespaceList := range rule.Actions.AddRole {
-		role := ACLRolesMap[roleID]
+		role := roles[roleID]
 		if role == nil {
 			// skip non-existing roles
 			continue
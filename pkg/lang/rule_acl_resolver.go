@@ -2,6 +2,7 @@ package lang
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/Aptomi/aptomi/pkg/lang/expression"
@@ -11,19 +12,48 @@ import (
 // objects they access
 type ACLResolver struct {
 	aclRules     []*ACLRule
+	roles        []*ACLRole
+	rolesMap     map[string]*ACLRole
 	cache        *expression.Cache
 	roleMapCache sync.Map
 }
 
-// NewACLResolver creates a new ACLResolver
-func NewACLResolver(aclRules map[string]*ACLRule) *ACLResolver {
+// NewACLResolver creates a new ACLResolver, given a set of ACL rules and a set of custom roles defined in the policy
+func NewACLResolver(aclRules map[string]*ACLRule, customRoles map[string]*ACLCustomRole) *ACLResolver {
+	// built-in roles go first, followed by custom roles sorted by their IDs
+	roles := append([]*ACLRole{}, ACLRolesOrderedList...)
+	rolesMap := make(map[string]*ACLRole)
+	for id, role := range ACLRolesMap {
+		rolesMap[id] = role
+	}
+	custom := []*ACLRole{}
+	for _, customRole := range customRoles {
+		role := customRole.GetACLRole()
+		if _, exists := rolesMap[role.ID]; exists {
+			// custom roles can't override built-in roles
+			continue
+		}
+		custom = append(custom, role)
+		rolesMap[role.ID] = role
+	}
+	sort.Slice(custom, func(i, j int) bool {
+		return custom[i].ID < custom[j].ID
+	})
+
 	return &ACLResolver{
 		aclRules:     GetACLRulesSortedByWeight(aclRules),
+		roles:        append(roles, custom...),
+		rolesMap:     rolesMap,
 		cache:        expression.NewCache(),
 		roleMapCache: sync.Map{},
 	}
 }
 
+// GetRoles returns the list of all roles known to the resolver (built-in roles first, followed by custom roles)
+func (resolver *ACLResolver) GetRoles() []*ACLRole {
+	return resolver.roles
+}
+
 // GetUserPrivileges is a main method which determines privileges that a given user has for a given object
 func (resolver *ACLResolver) GetUserPrivileges(user *User, obj Base) (*Privilege, error) {
 	roleMap, err := resolver.GetUserRoleMap(user)
@@ -31,15 +61,20 @@ func (resolver *ACLResolver) GetUserPrivileges(user *User, obj Base) (*Privilege
 		return nil, err
 	}
 
-	// figure out which role's privileges apply
-	for _, role := range ACLRolesOrderedList {
+	// user gets a union of privileges of all roles, which apply to the object's namespace. everyone can do at
+	// least what "nobody" can do
+	result := &Privilege{}
+	*result = *nobody.Privileges.getObjectPrivileges(obj)
+	for _, role := range resolver.roles {
 		namespaceSpan := roleMap[role.ID]
 		if namespaceSpan[namespaceAll] || namespaceSpan[obj.GetNamespace()] {
-			return role.Privileges.getObjectPrivileges(obj), nil
+			privilege := role.Privileges.getObjectPrivileges(obj)
+			result.View = result.View || privilege.View
+			result.Manage = result.Manage || privilege.Manage
 		}
 	}
 
-	return nobody.Privileges.getObjectPrivileges(obj), nil
+	return result, nil
 }
 
 // GetUserRoleMap returns the map role ID -> to which namespaces this role applies, for a given user.
@@ -47,6 +82,7 @@ func (resolver *ACLResolver) GetUserPrivileges(user *User, obj Base) (*Privilege
 // - domain admin (i.e. for all namespaces within Aptomi domain)
 // - namespace admin for a set of given namespaces
 // - service consumer for a set of given namespaces
+// - any of the custom roles for a set of given namespaces
 func (resolver *ACLResolver) GetUserRoleMap(user *User) (map[string]map[string]bool, error) {
 	roleMapCached, ok := resolver.roleMapCache.Load(user.Name)
 	if ok {
@@ -67,7 +103,7 @@ func (resolver *ACLResolver) GetUserRoleMap(user *User) (map[string]map[string]b
 				return nil, fmt.Errorf("unable to resolve role for user '%s': %s", user.Name, err)
 			}
 			if matched {
-				rule.ApplyActions(roleMap)
+				rule.ApplyActions(roleMap, resolver.rolesMap)
 			}
 		}
 	}
@@ -32,12 +32,16 @@ func (privileges testCaseObjPrivileges) print(t *testing.T, testCase aclTestCase
 	t.Logf("Object '%s' in namespace '%s', accessed by user '%s'", privileges.obj.GetKind(), privileges.obj.GetNamespace(), testCase.user.Name)
 }
 
-func runACLTests(testCases []aclTestCase, rules []*ACLRule, t *testing.T) {
+func runACLTests(testCases []aclTestCase, rules []*ACLRule, roles []*ACLCustomRole, t *testing.T) {
 	aclRules := make(map[string]*ACLRule)
 	for _, rule := range rules {
 		aclRules[rule.GetName()] = rule
 	}
-	resolver := NewACLResolver(aclRules)
+	customRoles := make(map[string]*ACLCustomRole)
+	for _, role := range roles {
+		customRoles[role.GetName()] = role
+	}
+	resolver := NewACLResolver(aclRules, customRoles)
 	for _, tc := range testCases {
 		roleMap, err := resolver.GetUserRoleMap(tc.user)
 		if !assert.NoError(t, err, "User role map should be retrieved successfully") {
@@ -163,7 +167,7 @@ func TestAclResolver(t *testing.T) {
 		},
 	}
 
-	runACLTests(testCases, rules, t)
+	runACLTests(testCases, rules, nil, t)
 }
 
 func TestAclResolverAdminUser(t *testing.T) {
@@ -176,5 +180,125 @@ func TestAclResolverAdminUser(t *testing.T) {
 			expected:  true,
 		},
 	}
-	runACLTests(testCases, rules, t)
+	runACLTests(testCases, rules, nil, t)
+}
+
+func TestAclResolverCustomRoles(t *testing.T) {
+	var roles = []*ACLCustomRole{
+		// can manage dependencies and only view everything else
+		{
+			TypeKind: ACLCustomRoleObject.GetTypeKind(),
+			Metadata: Metadata{
+				Namespace: runtime.SystemNS,
+				Name:      "dependency-operator",
+			},
+			Privileges: &Privileges{
+				NamespaceObjects: map[string]*Privilege{
+					DependencyObject.Kind: fullAccess,
+				},
+			},
+		},
+		// can manage contracts
+		{
+			TypeKind: ACLCustomRoleObject.GetTypeKind(),
+			Metadata: Metadata{
+				Namespace: runtime.SystemNS,
+				Name:      "contract-editor",
+			},
+			Title: "Contract Editor",
+			Privileges: &Privileges{
+				NamespaceObjects: map[string]*Privilege{
+					ContractObject.Kind: fullAccess,
+				},
+			},
+		},
+		// attempt to redefine built-in role, should be ignored
+		{
+			TypeKind: ACLCustomRoleObject.GetTypeKind(),
+			Metadata: Metadata{
+				Namespace: runtime.SystemNS,
+				Name:      nobody.ID,
+			},
+			Privileges: &Privileges{
+				AllNamespaces: true,
+				NamespaceObjects: map[string]*Privilege{
+					ServiceObject.Kind: fullAccess,
+				},
+			},
+		},
+	}
+
+	var rules = []*ACLRule{
+		{
+			TypeKind: ACLRuleObject.GetTypeKind(),
+			Metadata: Metadata{
+				Namespace: runtime.SystemNS,
+				Name:      "is_operator",
+			},
+			Weight:   100,
+			Criteria: &Criteria{RequireAll: []string{"is_operator"}},
+			Actions: &ACLRuleActions{
+				AddRole: map[string]string{"dependency-operator": "main"},
+			},
+		},
+		{
+			TypeKind: ACLRuleObject.GetTypeKind(),
+			Metadata: Metadata{
+				Namespace: runtime.SystemNS,
+				Name:      "is_consumer_and_editor",
+			},
+			Weight:   200,
+			Criteria: &Criteria{RequireAll: []string{"is_consumer_and_editor"}},
+			Actions: &ACLRuleActions{
+				AddRole: map[string]string{ServiceConsumer.ID: "main", "contract-editor": "main"},
+			},
+		},
+	}
+
+	testCases := []aclTestCase{
+		{
+			user:      &User{Name: "1", Labels: map[string]string{"is_operator": "true"}},
+			role:      roles[0].GetACLRole(),
+			namespace: "main",
+			expected:  true,
+			objectPrivileges: []testCaseObjPrivileges{
+				{obj: &Dependency{TypeKind: DependencyObject.GetTypeKind(), Metadata: Metadata{Namespace: "main"}}, expected: fullAccess},
+				{obj: &Dependency{TypeKind: DependencyObject.GetTypeKind(), Metadata: Metadata{Namespace: "somens"}}, expected: viewAccess},
+				{obj: &Service{TypeKind: ServiceObject.GetTypeKind(), Metadata: Metadata{Namespace: "main"}}, expected: viewAccess},
+				{obj: &Cluster{TypeKind: ClusterObject.GetTypeKind(), Metadata: Metadata{Namespace: runtime.SystemNS}}, expected: viewAccess},
+			},
+		},
+		{
+			user:      &User{Name: "2", Labels: map[string]string{"is_consumer_and_editor": "true"}},
+			role:      roles[1].GetACLRole(),
+			namespace: "main",
+			expected:  true,
+			objectPrivileges: []testCaseObjPrivileges{
+				{obj: &Contract{TypeKind: ContractObject.GetTypeKind(), Metadata: Metadata{Namespace: "main"}}, expected: fullAccess},
+				{obj: &Dependency{TypeKind: DependencyObject.GetTypeKind(), Metadata: Metadata{Namespace: "main"}}, expected: fullAccess},
+				{obj: &Service{TypeKind: ServiceObject.GetTypeKind(), Metadata: Metadata{Namespace: "main"}}, expected: viewAccess},
+				{obj: &Contract{TypeKind: ContractObject.GetTypeKind(), Metadata: Metadata{Namespace: "somens"}}, expected: viewAccess},
+			},
+		},
+		{
+			user:      &User{Name: "3", Labels: map[string]string{"name": "value"}},
+			role:      nobody,
+			namespace: "main",
+			expected:  false,
+			objectPrivileges: []testCaseObjPrivileges{
+				{obj: &Service{TypeKind: ServiceObject.GetTypeKind(), Metadata: Metadata{Namespace: "main"}}, expected: viewAccess},
+			},
+		},
+	}
+
+	runACLTests(testCases, rules, roles, t)
+
+	// custom roles should be reported after built-in ones, sorted by ID
+	resolver := NewACLResolver(map[string]*ACLRule{}, map[string]*ACLCustomRole{roles[0].Name: roles[0], roles[1].Name: roles[1], roles[2].Name: roles[2]})
+	roleIDs := []string{}
+	for _, role := range resolver.GetRoles() {
+		roleIDs = append(roleIDs, role.ID)
+	}
+	assert.Equal(t, []string{DomainAdmin.ID, NamespaceAdmin.ID, ServiceConsumer.ID, nobody.ID, "contract-editor", "dependency-operator"}, roleIDs, "Roles should be reported correctly")
+	assert.Equal(t, "Contract Editor", resolver.GetRoles()[4].Name, "Custom role title should be used as its name")
 }
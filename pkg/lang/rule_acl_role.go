@@ -0,0 +1,43 @@
+package lang
+
+import (
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// ACLCustomRoleObject is an informational data structure with Kind and Constructor for ACLCustomRole
+var ACLCustomRoleObject = &runtime.Info{
+	Kind:        "aclcustomrole",
+	Storable:    true,
+	Versioned:   true,
+	Deletable:   true,
+	Constructor: func() runtime.Object { return &ACLCustomRole{} },
+}
+
+// ACLCustomRole is a user-defined ACL role with its own set of privileges (e.g. "dependency operator", who can
+// manage dependencies and view services, or "auditor", who can view everything). Custom roles should be defined by
+// Aptomi domain admins in 'system' namespace. Once defined, they can be assigned to users via ACL rules the same way
+// as built-in roles, using role name as role ID
+type ACLCustomRole struct {
+	runtime.TypeKind `yaml:",inline"`
+	Metadata         `validate:"required"`
+
+	// Title is a human-readable name of the role. It's an optional field, so if it's empty then role name will be used
+	Title string `yaml:",omitempty"`
+
+	// Privileges defines which objects this role can view and manage
+	Privileges *Privileges `validate:"required"`
+}
+
+// GetACLRole returns ACL role, which corresponds to the custom role
+func (role *ACLCustomRole) GetACLRole() *ACLRole {
+	name := role.Title
+	if len(name) == 0 {
+		name = role.Name
+	}
+	return &ACLRole{
+		ID:         role.Name,
+		Name:       name,
+		Privileges: role.Privileges,
+		Custom:     true,
+	}
+}
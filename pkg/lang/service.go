@@ -2,7 +2,9 @@ package lang
 
 import (
 	"fmt"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/Aptomi/aptomi/pkg/lang/expression"
 	"github.com/Aptomi/aptomi/pkg/runtime"
@@ -18,6 +20,16 @@ var ServiceObject = &runtime.Info{
 	Constructor: func() runtime.Object { return &Service{} },
 }
 
+// LabelProtected is a special label, which marks services and component instances as protected. Protected component
+// instances can only be deleted after deletion has been approved by a user
+const LabelProtected = "protected"
+
+// IsProtectedByLabels returns true if a given set of labels marks an object as protected
+func IsProtectedByLabels(labels map[string]string) bool {
+	protected, err := strconv.ParseBool(labels[LabelProtected])
+	return protected && err == nil
+}
+
 // Service defines individual service in Aptomi. The idea is that services get defined by different teams. Those
 // teams define service-specific consumption rules of how others can consume their services.
 //
@@ -43,6 +55,12 @@ type Service struct {
 	componentsMap     map[string]*ServiceComponent
 }
 
+// IsProtected returns true if service is marked as protected (i.e. its component instances can only be deleted after
+// deletion has been approved by a user)
+func (service *Service) IsProtected() bool {
+	return IsProtectedByLabels(service.Labels)
+}
+
 // ServiceComponent defines component within a service
 type ServiceComponent struct {
 	// Name is a user-defined component name
@@ -67,6 +85,66 @@ type ServiceComponent struct {
 	// Dependencies is cross-component dependencies within a service. Component may need other components within that
 	// service to run, before it gets instantiated
 	Dependencies []string `yaml:"dependencies,omitempty" validate:"dive,identifier"`
+
+	// Hooks, if not empty, define code which gets executed at certain points of lifecycle of code component instances
+	// (e.g. running DB migrations before update, or taking a backup before deletion)
+	Hooks *Hooks `yaml:"hooks,omitempty" validate:"omitempty"`
+}
+
+// Hooks define code which gets executed at certain points of lifecycle of code component instances. Every hook is
+// executed via the same plugin registry as regular code (e.g. k8sraw Job manifest or Helm chart): it gets deployed
+// under its own deploy name, considered successful once it becomes ready (e.g. Job gets completed), and then
+// deleted. If hook fails, the corresponding action on component instance doesn't get executed
+type Hooks struct {
+	// PreCreate is executed before component instance gets created
+	PreCreate *Code `yaml:"preCreate,omitempty" validate:"omitempty"`
+
+	// PostCreate is executed after component instance gets created
+	PostCreate *Code `yaml:"postCreate,omitempty" validate:"omitempty"`
+
+	// PreUpdate is executed before component instance gets updated
+	PreUpdate *Code `yaml:"preUpdate,omitempty" validate:"omitempty"`
+
+	// PreDelete is executed before component instance gets deleted
+	PreDelete *Code `yaml:"preDelete,omitempty" validate:"omitempty"`
+}
+
+const (
+	// HookPreCreate is a hook, which is executed before component instance gets created
+	HookPreCreate = "pre-create"
+
+	// HookPostCreate is a hook, which is executed after component instance gets created
+	HookPostCreate = "post-create"
+
+	// HookPreUpdate is a hook, which is executed before component instance gets updated
+	HookPreUpdate = "pre-update"
+
+	// HookPreDelete is a hook, which is executed before component instance gets deleted
+	HookPreDelete = "pre-delete"
+)
+
+// GetAll returns a map of all defined hooks (hook -> code)
+func (hooks *Hooks) GetAll() map[string]*Code {
+	result := make(map[string]*Code)
+	if hooks == nil {
+		return result
+	}
+	for hook, code := range map[string]*Code{
+		HookPreCreate:  hooks.PreCreate,
+		HookPostCreate: hooks.PostCreate,
+		HookPreUpdate:  hooks.PreUpdate,
+		HookPreDelete:  hooks.PreDelete,
+	} {
+		if code != nil {
+			result[hook] = code
+		}
+	}
+	return result
+}
+
+// Get returns code for a given hook, or nil if hook is not defined
+func (hooks *Hooks) Get(hook string) *Code {
+	return hooks.GetAll()[hook]
 }
 
 // Code with type and parameters, used to instantiate/update/delete component instances
@@ -79,6 +157,32 @@ type Code struct {
 	// and can refer to arbitrary labels, as well as discovery parameters exposed by other components (within the
 	// current service) and discovery parameters exposed by services the current service depends on
 	Params util.NestedParameterMap `validate:"omitempty,templateNestedMap"`
+
+	// UpdateStrategy defines how component instances get updated when their code parameters change. It's either
+	// 'in-place' (default, code gets updated in place) or 'blue-green' (a second copy of code gets deployed under a
+	// different deploy name, and the old copy gets deleted only after the new one becomes ready). Blue/green update
+	// requires names of all deployed objects to be derived from the deploy name (e.g. Helm release name), so both
+	// copies can co-exist
+	UpdateStrategy string `yaml:"updateStrategy,omitempty" validate:"omitempty,updateStrategy"`
+
+	// ReadinessTimeout is how long to wait for the new copy of code to become ready during blue/green update, before
+	// rolling the update back (e.g. '10m'). For hooks, it's how long to wait for hook code to become ready, before
+	// considering the hook failed. If it's not specified, default timeout is used
+	ReadinessTimeout time.Duration `yaml:"readinessTimeout,omitempty"`
+}
+
+const (
+	// UpdateStrategyInPlace is an update strategy, when code gets updated in place
+	UpdateStrategyInPlace = "in-place"
+
+	// UpdateStrategyBlueGreen is an update strategy, when a second copy of code gets deployed and the old copy gets
+	// deleted only after the new one becomes ready
+	UpdateStrategyBlueGreen = "blue-green"
+)
+
+// IsBlueGreen returns true if component instances with this code have to be updated using blue/green strategy
+func (code *Code) IsBlueGreen() bool {
+	return code.UpdateStrategy == UpdateStrategyBlueGreen
 }
 
 // Matches checks if component criteria is satisfied
@@ -0,0 +1,176 @@
+package template
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	t "text/template"
+
+	"github.com/Masterminds/sprig"
+	"gopkg.in/yaml.v2"
+)
+
+// Library of functions available in text templates in addition to "default". It consists of deterministic Sprig
+// functions (http://masterminds.github.io/sprig/), so the value being processed always goes last and they can be used
+// in pipelines, e.g. {{ .Labels.name | trimPrefix "app-" | lower }}
+var libraryFuncMap = newLibraryFuncMap()
+
+// Sprig functions which are available in text templates. Only functions which always return the same result for the
+// same arguments are allowed, as templates get evaluated on every policy resolution and any difference in the result
+// leads to an update of the component instance. It means that date (now, date, ago, ...), random (randAlpha, shuffle,
+// uuidv4, ...), crypto (genPrivateKey, genCA, ...) and map iteration order dependent (keys, values) functions are
+// not available, as well as env and expandenv, which would expose environment of Aptomi server
+var sprigFuncs = []string{
+	// strings
+	"abbrev", "abbrevboth", "trunc", "trim", "upper", "lower", "title", "untitle", "substr", "repeat", "trimall",
+	"trimAll", "trimSuffix", "trimPrefix", "nospace", "initials", "swapcase", "snakecase", "camelcase", "wrap",
+	"wrapWith", "contains", "hasPrefix", "hasSuffix", "quote", "squote", "cat", "indent", "nindent", "replace",
+	"plural", "toString",
+
+	// conversion & math
+	"atoi", "int64", "int", "float64", "add1", "add", "sub", "div", "mod", "mul", "biggest", "max", "min", "ceil",
+	"floor", "round",
+
+	// lists
+	"split", "splitList", "splitn", "toStrings", "until", "untilStep", "sortAlpha", "tuple", "list", "append", "push",
+	"prepend", "first", "rest", "last", "initial", "reverse", "uniq", "without", "has", "slice",
+
+	// dictionaries
+	"dict", "set", "unset", "hasKey", "pluck", "pick", "omit", "merge",
+
+	// defaults & flow control
+	"empty", "coalesce", "compact", "ternary", "fail", "toPrettyJson",
+
+	// reflection
+	"typeOf", "typeIs", "typeIsLike", "kindOf", "kindIs",
+
+	// paths
+	"base", "dir", "clean", "ext", "isAbs",
+
+	// encoding & hashing
+	"b64enc", "b32enc", "b32dec", "sha1sum", "sha256sum",
+
+	// semantic versions
+	"semver", "semverCompare",
+
+	// regular expressions
+	"regexMatch", "regexFindAll", "regexFind", "regexReplaceAll", "regexReplaceAllLiteral", "regexSplit",
+}
+
+// Functions which are either not part of Sprig, but are commonly used in Helm charts, or replace Sprig ones to
+// report an error instead of silently returning an unexpected value
+var extraFuncMap = t.FuncMap{
+	"join":     funcJoin,
+	"b64dec":   funcBase64Decode,
+	"required": funcRequired,
+	"toYaml":   funcToYaml,
+	"toJson":   funcToJSON,
+}
+
+func newLibraryFuncMap() t.FuncMap {
+	all := sprig.TxtFuncMap()
+	result := t.FuncMap{}
+	for _, name := range sprigFuncs {
+		if f, exist := all[name]; exist {
+			result[name] = f
+		}
+	}
+	for name, f := range extraFuncMap {
+		result[name] = f
+	}
+	return result
+}
+
+func toString(value interface{}) string {
+	if str, ok := value.(string); ok {
+		return str
+	}
+	if value == nil {
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+// funcJoin replaces Sprig's join, which silently joins a string representation of any value which is not a list
+func funcJoin(sep string, list interface{}) (string, error) {
+	if list == nil {
+		return "", nil
+	}
+
+	v := reflect.ValueOf(list)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		parts := make([]string, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			parts[i] = toString(v.Index(i).Interface())
+		}
+		return strings.Join(parts, sep), nil
+	case reflect.String:
+		return v.String(), nil
+	}
+
+	return "", fmt.Errorf("join: expected a list, but got %T", list)
+}
+
+// funcBase64Decode replaces Sprig's b64dec, which returns an error message as a result if value can't be decoded
+func funcBase64Decode(str string) (string, error) {
+	result, err := base64.StdEncoding.DecodeString(str)
+	if err != nil {
+		return "", fmt.Errorf("b64dec: unable to decode '%s': %s", str, err)
+	}
+	return string(result), nil
+}
+
+func funcRequired(message string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, fmt.Errorf("required value is missing: %s", message)
+	}
+	if str, ok := value.(string); ok && len(str) == 0 {
+		return nil, fmt.Errorf("required value is empty: %s", message)
+	}
+	return value, nil
+}
+
+func funcToYaml(value interface{}) (string, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("toYaml: %s", err)
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// funcToJSON replaces Sprig's toJson, which silently returns an empty string for maps produced by YAML parser
+func funcToJSON(value interface{}) (string, error) {
+	data, err := json.Marshal(jsonCompatible(value))
+	if err != nil {
+		return "", fmt.Errorf("toJson: %s", err)
+	}
+	return string(data), nil
+}
+
+// jsonCompatible converts maps with interface{} keys (produced by YAML parser) into maps with string keys, so they can be serialized into JSON
+func jsonCompatible(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Map:
+		result := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			result[toString(key.Interface())] = jsonCompatible(v.MapIndex(key).Interface())
+		}
+		return result
+	case reflect.Slice, reflect.Array:
+		result := make([]interface{}, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			result[i] = jsonCompatible(v.Index(i).Interface())
+		}
+		return result
+	}
+
+	return value
+}
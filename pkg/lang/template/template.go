@@ -56,9 +56,9 @@ var textFuncMap = t.FuncMap{
 }
 
 // NewTemplate compiles a text template and returns the result in Template struct
-// Parameter templateStr must follow syntax defined by text/template
+// Parameter templateStr must follow syntax defined by text/template and may call any function from the library
 func NewTemplate(templateStr string) (*Template, error) {
-	templateCompiled, err := t.New("").Funcs(textFuncMap).Parse(templateStr)
+	templateCompiled, err := t.New("").Funcs(libraryFuncMap).Funcs(textFuncMap).Parse(templateStr)
 	if err != nil {
 		return nil, fmt.Errorf("unable to compile template '%s': %s", templateStr, err)
 	}
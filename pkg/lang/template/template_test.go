@@ -3,6 +3,7 @@ package template
 import (
 	"testing"
 
+	"github.com/Masterminds/sprig"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -94,3 +95,111 @@ func TestTemplateEvaluation(t *testing.T) {
 	}
 
 }
+
+func TestTemplateFunctions(t *testing.T) {
+	params := NewParams(struct {
+		Labels    interface{}
+		Discovery interface{}
+	}{
+		map[string]string{
+			"name":  " App-Frontend ",
+			"teams": "dev,qa",
+			"b64":   "aGVsbG8=",
+		},
+		map[string]interface{}{
+			"db": map[string]interface{}{
+				"host": "mysql",
+				"port": 3306,
+			},
+			"list": []string{"a", "b", "c"},
+		},
+	})
+
+	tests := []struct {
+		template       string
+		result         int
+		expectedString string
+	}{
+		// strings
+		{"{{ .Labels.name | trim | lower }}", ResSuccess, "app-frontend"},
+		{"{{ .Labels.name | trim | upper }}", ResSuccess, "APP-FRONTEND"},
+		{"{{ .Labels.name | trim | trimPrefix \"App-\" }}", ResSuccess, "Frontend"},
+		{"{{ .Labels.name | trim | trimSuffix \"-Frontend\" }}", ResSuccess, "App"},
+		{"{{ .Labels.name | trimAll \" \" | replace \"-\" \"_\" }}", ResSuccess, "App_Frontend"},
+		{"{{ .Labels.name | trim | trunc 3 }}", ResSuccess, "App"},
+		{"{{ .Labels.teams | quote }}", ResSuccess, "\"dev,qa\""},
+		{"{{ if .Labels.teams | contains \"qa\" }}yes{{ else }}no{{ end }}", ResSuccess, "yes"},
+		{"{{ if .Labels.teams | hasPrefix \"qa\" }}yes{{ else }}no{{ end }}", ResSuccess, "no"},
+
+		// lists
+		{"{{ .Labels.teams | splitList \",\" | join \"+\" }}", ResSuccess, "dev+qa"},
+		{"{{ .Discovery.list | join \"-\" }}", ResSuccess, "a-b-c"},
+		{"{{ .Discovery.db | join \"-\" }}", ResEvalError, ""},
+
+		// encoding & hashing
+		{"{{ \"hello\" | b64enc }}", ResSuccess, "aGVsbG8="},
+		{"{{ .Labels.b64 | b64dec }}", ResSuccess, "hello"},
+		{"{{ \"%%%\" | b64dec }}", ResEvalError, ""},
+		{"{{ \"hello\" | sha256sum | trunc 10 }}", ResSuccess, "2cf24dba5f"},
+
+		// required
+		{"{{ .Labels.teams | required \"teams label must be set\" }}", ResSuccess, "dev,qa"},
+		{"{{ .Labels.missing | required \"missing label must be set\" }}", ResEvalError, ""},
+
+		// serialization
+		{"{{ .Discovery.db | toYaml }}", ResSuccess, "host: mysql\nport: 3306"},
+		{"{{ .Discovery.db | toYaml | indent 2 }}", ResSuccess, "  host: mysql\n  port: 3306"},
+		{"{{ .Discovery.db | toJson }}", ResSuccess, "{\"host\":\"mysql\",\"port\":3306}"},
+		{"{{ .Discovery.list | toJson }}", ResSuccess, "[\"a\",\"b\",\"c\"]"},
+
+		// unsafe functions are not available
+		{"{{ env \"HOME\" }}", ResCompileError, ""},
+		{"{{ expandenv \"$HOME\" }}", ResCompileError, ""},
+
+		// wrong arguments
+		{"{{ trunc \"a\" \"b\" }}", ResEvalError, ""},
+		{"{{ unknownFunction .Labels.name }}", ResCompileError, ""},
+	}
+
+	for _, test := range tests {
+		evaluate(t, test.template, test.result, test.expectedString, params)
+	}
+}
+
+func TestTemplateFunctionsDeterministic(t *testing.T) {
+	// Sprig functions, which may return different results for the same arguments or expose environment of Aptomi
+	// server, so they must not be available in templates
+	excluded := []string{
+		"now", "date", "date_in_zone", "dateInZone", "date_modify", "dateModify", "htmlDate", "htmlDateInZone", "ago", "toDate",
+		"randAlphaNum", "randAlpha", "randAscii", "randNumeric", "shuffle", "uuidv4",
+		"genPrivateKey", "derivePassword", "buildCustomCert", "genCA", "genSelfSignedCert", "genSignedCert",
+		"keys", "values", "env", "expandenv", "hello",
+	}
+
+	for _, name := range excluded {
+		assert.NotContains(t, libraryFuncMap, name, "Function should not be available in templates: %s", name)
+		_, err := NewTemplate("{{ " + name + " }}")
+		assert.Error(t, err, "Template calling function should not compile: %s", name)
+	}
+
+	// every Sprig function has to be either explicitly allowed or excluded, so new functions don't become available
+	// in templates without checking that they are deterministic
+	allowed := make(map[string]bool)
+	for _, name := range sprigFuncs {
+		allowed[name] = true
+	}
+	for name := range sprig.TxtFuncMap() {
+		_, extra := extraFuncMap[name]
+		isExcluded := false
+		for _, excludedName := range excluded {
+			isExcluded = isExcluded || excludedName == name
+		}
+		assert.True(t, extra || allowed[name] || isExcluded || name == "default", "Sprig function should be either allowed or excluded in templates: %s", name)
+	}
+
+	// all available functions are either allowed Sprig functions or our own ones
+	for name := range libraryFuncMap {
+		_, extra := extraFuncMap[name]
+		assert.True(t, extra || allowed[name], "Function should not be available in templates: %s", name)
+	}
+}
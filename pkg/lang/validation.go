@@ -6,6 +6,7 @@ import (
 	"reflect"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/Aptomi/aptomi/pkg/lang/expression"
 	"github.com/Aptomi/aptomi/pkg/lang/template"
@@ -24,6 +25,7 @@ var (
 	codeTypes       = []string{"helm", "raw"}
 	labelOpsKeys    = []string{"set", "remove"}
 	allowReject     = []string{"allow", "reject"}
+	updateStrategy  = []string{UpdateStrategyInPlace, UpdateStrategyBlueGreen}
 )
 
 // Custom type for context key, so we don't have to use 'string' directly
@@ -65,6 +67,7 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 	result.RegisterValidationCtx("identifier", validateIdentifier)               // nolint: errcheck
 	result.RegisterValidationCtx("clustertype", validateClusterType)             // nolint: errcheck
 	result.RegisterValidationCtx("codetype", validateCodeType)                   // nolint: errcheck
+	result.RegisterValidationCtx("updateStrategy", validateUpdateStrategy)       // nolint: errcheck
 	result.RegisterValidationCtx("expression", validateExpression)               // nolint: errcheck
 	result.RegisterValidationCtx("template", validateTemplate)                   // nolint: errcheck
 	result.RegisterValidationCtx("templateNestedMap", validateTemplateNestedMap) // nolint: errcheck
@@ -72,11 +75,18 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 	result.RegisterValidationCtx("labelOperations", validateLabelOperations)     // nolint: errcheck
 	result.RegisterValidationCtx("allowReject", validateAllowRejectAction)       // nolint: errcheck
 	result.RegisterValidationCtx("addRoleNS", validateACLRoleActionMap)          // nolint: errcheck
+	result.RegisterValidationCtx("privilegeKinds", validatePrivilegeKinds)       // nolint: errcheck
+	result.RegisterValidationCtx("schedule", validateSchedule)                   // nolint: errcheck
+	result.RegisterValidationCtx("timezone", validateTimezone)                   // nolint: errcheck
 
 	// validators with context containing policy
 	result.RegisterStructValidation(validateRule, Rule{})
 	result.RegisterStructValidation(validateACLRule, ACLRule{})
+	result.RegisterStructValidation(validateACLCustomRole, ACLCustomRole{})
 	result.RegisterStructValidation(validateCluster, Cluster{})
+	result.RegisterStructValidation(validateQuota, Quota{})
+	result.RegisterStructValidation(validateMaintenance, Maintenance{})
+	result.RegisterStructValidation(validateMaintenanceFreeze, MaintenanceFreeze{})
 	result.RegisterStructValidationCtx(validateService, Service{})
 	result.RegisterStructValidationCtx(validateDependency, Dependency{})
 	result.RegisterStructValidationCtx(validateContract, Contract{})
@@ -107,6 +117,10 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 			tag:         "codetype",
 			translation: fmt.Sprintf("'{0}' is not valid, must be in %s", codeTypes),
 		},
+		{
+			tag:         "updateStrategy",
+			translation: fmt.Sprintf("'{0}' is not valid, must be in %s", updateStrategy),
+		},
 		{
 			tag:         "allowReject",
 			translation: fmt.Sprintf("'{0}' is not valid, must be in %s", allowReject),
@@ -119,6 +133,14 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 			tag:         "identifier",
 			translation: fmt.Sprintf("'{0}' is not a valid identifier"),
 		},
+		{
+			tag:         "schedule",
+			translation: fmt.Sprintf("'{0}' is not a valid schedule (must have 5 fields: minute, hour, day of month, month, day of week)"),
+		},
+		{
+			tag:         "timezone",
+			translation: fmt.Sprintf("'{0}' is not a valid timezone"),
+		},
 		{
 			tag:         "expression",
 			translation: fmt.Sprintf("'{0}' is not a valid expression"),
@@ -141,7 +163,15 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 		},
 		{
 			tag:         "addRoleNS",
-			translation: fmt.Sprintf("is not a valid role assignment map (key must be in %s, namespace list must be comma-separated identifiers/wildcards)", util.GetSortedStringKeys(ACLRolesMap)),
+			translation: fmt.Sprintf("is not a valid role assignment map (key must be in %s or a custom role, namespace list must be comma-separated identifiers/wildcards)", util.GetSortedStringKeys(ACLRolesMap)),
+		},
+		{
+			tag:         "privilegeKinds",
+			translation: fmt.Sprintf("is not a valid privilege map (keys must be in %s)", policyObjectKinds()),
+		},
+		{
+			tag:         "aclRoleBuiltin",
+			translation: fmt.Sprintf("'{0}' is a built-in role and can't be redefined"),
 		},
 		{
 			tag:         "exists",
@@ -151,6 +181,10 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 			tag:         "codeContractSingle",
 			translation: fmt.Sprintf("component '{0}' should either be code or contract"),
 		},
+		{
+			tag:         "hooksCode",
+			translation: fmt.Sprintf("component '{0}' can only have hooks if it's code"),
+		},
 		{
 			tag:         "unique",
 			translation: fmt.Sprintf("'{0}' is not unique"),
@@ -167,6 +201,22 @@ func NewPolicyValidator(policy *Policy) *PolicyValidator {
 			tag:         "aclRuleActions",
 			translation: fmt.Sprintf("is a required field (role assignment map must be specified)"),
 		},
+		{
+			tag:         "quotaLimits",
+			translation: fmt.Sprintf("is a required field (at least one limit must be specified)"),
+		},
+		{
+			tag:         "maintenanceRules",
+			translation: fmt.Sprintf("is a required field (at least one window or freeze must be specified)"),
+		},
+		{
+			tag:         "freezeRange",
+			translation: fmt.Sprintf("is not valid (must be after the start of the freeze)"),
+		},
+		{
+			tag:         "expiration",
+			translation: fmt.Sprintf("is not valid (either absolute time or positive duration must be specified)"),
+		},
 	}
 	for _, t := range translations {
 		err = result.RegisterTranslation(t.tag, trans, registrationFunc(t.tag, t.translation), translateFunc)
@@ -252,6 +302,26 @@ func validateCodeType(ctx context.Context, fl validator.FieldLevel) bool {
 	return validateInStringArray(ctx, codeTypes, fl)
 }
 
+// checks if a given string is a valid code update strategy
+func validateUpdateStrategy(ctx context.Context, fl validator.FieldLevel) bool {
+	return validateInStringArray(ctx, updateStrategy, fl)
+}
+
+// checks if a given string is a valid cron-like schedule
+func validateSchedule(ctx context.Context, fl validator.FieldLevel) bool {
+	_, err := parseSchedule(fl.Field().String())
+	if err != nil {
+		attachErrorToContext(ctx, fl, err.Error())
+	}
+	return err == nil
+}
+
+// checks if a given string is a valid timezone name
+func validateTimezone(ctx context.Context, fl validator.FieldLevel) bool {
+	_, err := time.LoadLocation(fl.Field().String())
+	return err == nil
+}
+
 // checks if a given string is valid identifier
 func validateIdentifier(ctx context.Context, fl validator.FieldLevel) bool {
 	return isIdentifier(fl.Field().String())
@@ -304,10 +374,11 @@ func validateLabelOperations(ctx context.Context, fl validator.FieldLevel) bool
 
 // checks if a given map is a valid map of setting ACL Role actions
 func validateACLRoleActionMap(ctx context.Context, fl validator.FieldLevel) bool {
+	policy := ctx.Value(policyKey).(*Policy)                 // nolint: errcheck
 	addRoleMap := fl.Field().Interface().(map[string]string) // nolint: errcheck
 	for roleID, namespaceList := range addRoleMap {
-		role := ACLRolesMap[roleID]
-		if role == nil {
+		// role should be either built-in or custom
+		if ACLRolesMap[roleID] == nil && !policy.hasCustomRole(roleID) {
 			return false
 		}
 
@@ -322,6 +393,17 @@ func validateACLRoleActionMap(ctx context.Context, fl validator.FieldLevel) bool
 	return true
 }
 
+// checks if a given map of privileges refers to known policy object kinds only
+func validatePrivilegeKinds(ctx context.Context, fl validator.FieldLevel) bool {
+	privileges := fl.Field().Interface().(map[string]*Privilege) // nolint: errcheck
+	for kind, privilege := range privileges {
+		if !policyObjectsMap[kind] || privilege == nil {
+			return false
+		}
+	}
+	return true
+}
+
 // checks if a given map[string]string is a valid map of labels
 func validateLabels(ctx context.Context, fl validator.FieldLevel) bool {
 	names := fl.Field().MapKeys()
@@ -352,6 +434,12 @@ func validateService(ctx context.Context, sl validator.StructLevel) {
 			return
 		}
 
+		// hooks can only be defined for code
+		if component.Hooks != nil && component.Code == nil {
+			sl.ReportError(component.Name, fmt.Sprintf("Component[%s].Hooks", component.Name), "", "hooksCode", "")
+			return
+		}
+
 		// if contract is set, it should point to an existing contract
 		if len(component.Contract) > 0 {
 			obj, err := policy.GetObject(ContractObject.Kind, component.Contract, service.Namespace)
@@ -400,6 +488,12 @@ func validateDependency(ctx context.Context, sl validator.StructLevel) {
 		sl.ReportError(dependency.Contract, fmt.Sprintf("Contract[%s/%s]", dependency.Namespace, dependency.Contract), "", "exists", "")
 		return
 	}
+
+	// expiration should define either absolute time or a positive duration
+	if dependency.Expiration != nil && dependency.Expiration.At.IsZero() && dependency.Expiration.After <= 0 {
+		sl.ReportError(dependency.Expiration, "Expiration", "", "expiration", "")
+		return
+	}
 }
 
 // checks if contract is valid
@@ -436,6 +530,43 @@ func validateRule(sl validator.StructLevel) {
 	}
 }
 
+// checks if quota is valid
+func validateQuota(sl validator.StructLevel) {
+	quota := sl.Current().Addr().Interface().(*Quota) // nolint: errcheck
+
+	// quota should have at least one of the limits set
+	hasLimits := false
+	hasLimits = hasLimits || (quota.Limits != nil && quota.Limits.Dependencies > 0)
+	hasLimits = hasLimits || (quota.Limits != nil && quota.Limits.Instances > 0)
+	hasLimits = hasLimits || (quota.Limits != nil && quota.Limits.ClusterComponentInstances > 0)
+	if !hasLimits {
+		sl.ReportError(quota.Limits, "Limits", "", "quotaLimits", "")
+		return
+	}
+}
+
+// checks if maintenance is valid
+func validateMaintenance(sl validator.StructLevel) {
+	maintenance := sl.Current().Addr().Interface().(*Maintenance) // nolint: errcheck
+
+	// maintenance should have at least one window or freeze
+	if len(maintenance.Windows) <= 0 && len(maintenance.Freezes) <= 0 {
+		sl.ReportError(maintenance.Windows, "Windows", "", "maintenanceRules", "")
+		return
+	}
+}
+
+// checks if maintenance freeze is valid
+func validateMaintenanceFreeze(sl validator.StructLevel) {
+	freeze := sl.Current().Addr().Interface().(*MaintenanceFreeze) // nolint: errcheck
+
+	// freeze should end after it starts
+	if !freeze.To.After(freeze.From) {
+		sl.ReportError(freeze.To, "To", "", "freezeRange", "")
+		return
+	}
+}
+
 // checks if ACL rule is valid
 func validateACLRule(sl validator.StructLevel) {
 	rule := sl.Current().Addr().Interface().(*ACLRule) // nolint: errcheck
@@ -449,6 +580,21 @@ func validateACLRule(sl validator.StructLevel) {
 	}
 }
 
+// checks if custom ACL role is valid
+func validateACLCustomRole(sl validator.StructLevel) {
+	role := sl.Current().Addr().Interface().(*ACLCustomRole) // nolint: errcheck
+
+	// custom roles can only be defined in system namespace
+	if role.Namespace != runtime.SystemNS {
+		sl.ReportError(role.Namespace, "Namespace", "", "systemNS", "")
+	}
+
+	// custom roles can't redefine built-in roles
+	if _, builtin := ACLRolesMap[role.Name]; builtin {
+		sl.ReportError(role.Name, "Name", "", "aclRoleBuiltin", "")
+	}
+}
+
 // checks if cluster is valid
 func validateCluster(sl validator.StructLevel) {
 	cluster := sl.Current().Addr().Interface().(*Cluster) // nolint: errcheck
@@ -3,6 +3,7 @@ package lang
 import (
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/Aptomi/aptomi/pkg/lang/yaml"
 	"github.com/Aptomi/aptomi/pkg/runtime"
@@ -47,6 +48,8 @@ func TestPolicyValidationService(t *testing.T) {
 		makeServiceComponents(2, contract.Name, Nil, 0),
 		makeServiceComponents(3, "", 0, 1),
 		makeServiceComponents(4, "", 1, 1),
+		makeServiceComponents(2, "", 2, 1),
+		withHooks(makeServiceComponents(2, "", 1, 1), makeHooks("raw")),
 	}
 	for _, components := range componentTestsPass {
 		service := makeService("service", Empty)
@@ -60,10 +63,13 @@ func TestPolicyValidationService(t *testing.T) {
 		makeServiceComponents(1, "", Nil, 0),
 		makeServiceComponents(1, "", Invalid, 0),
 		makeServiceComponents(1, "", Invalid-1, 0),
+		makeServiceComponents(1, "", Invalid-2, 0),
 		makeServiceComponents(1, contract.Name, Nil, Invalid),
 		duplicateNames(makeServiceComponents(10, "", 1, 1)),
 		dependenciesInvalid(makeServiceComponents(10, "", 1, 1)),
 		dependenciesCycle(makeServiceComponents(10, "", 1, 1)),
+		withHooks(makeServiceComponents(1, contract.Name, Nil, 0), makeHooks("raw")),
+		withHooks(makeServiceComponents(1, "", 1, 1), makeHooks("unknown")),
 	}
 	for _, components := range componentTestsFail {
 		service := makeService("service", Empty)
@@ -101,6 +107,21 @@ func TestPolicyValidationContract(t *testing.T) {
 		makeService("service", Empty),
 		invalidAllocationKeys(makeContract("test1", 0, "service")),
 	})
+
+	// Check traffic split
+	runValidationTests(t, ResSuccess, false, []Base{
+		makeService("service", Empty),
+		withTrafficSplit(makeContract("test1", 0, "service"), 0, ""),
+		withTrafficSplit(makeContract("test2", 0, "service"), 100, "{{ .User.Name }}"),
+	})
+	runValidationTests(t, ResFailure, false, []Base{
+		makeService("service", Empty),
+		withTrafficSplit(makeContract("test1", 0, "service"), 101, ""),
+	})
+	runValidationTests(t, ResFailure, false, []Base{
+		makeService("service", Empty),
+		withTrafficSplit(makeContract("test1", 0, "service"), 10, "{{{ invalid"),
+	})
 }
 
 func TestPolicyValidationDependency(t *testing.T) {
@@ -113,6 +134,60 @@ func TestPolicyValidationDependency(t *testing.T) {
 		makeContract("contract", 0, ""),
 		makeDependency("contract-unknown"),
 	})
+
+	// Dependency expiration should define either absolute time or positive duration
+	expiresAt := makeDependency("contract")
+	expiresAt.Expiration = &DependencyExpiration{At: time.Now()}
+	runValidationTests(t, ResSuccess, false, []Base{
+		makeContract("contract", 0, ""),
+		expiresAt,
+	})
+	expiresAfter := makeDependency("contract")
+	expiresAfter.Expiration = &DependencyExpiration{After: time.Hour}
+	runValidationTests(t, ResSuccess, false, []Base{
+		makeContract("contract", 0, ""),
+		expiresAfter,
+	})
+	expiresInvalid := makeDependency("contract")
+	expiresInvalid.Expiration = &DependencyExpiration{After: -time.Hour}
+	runValidationTests(t, ResFailure, false, []Base{
+		makeContract("contract", 0, ""),
+		expiresInvalid,
+	})
+}
+
+func TestPolicyValidationQuota(t *testing.T) {
+	// Quota should have at least one limit and a valid group template
+	runValidationTests(t, ResSuccess, true, []Base{
+		makeQuota("{{ .User.Name }}", &QuotaLimits{Dependencies: 1}),
+		makeQuota("", &QuotaLimits{Instances: 5, ClusterComponentInstances: 10}),
+	})
+	runValidationTests(t, ResFailure, true, []Base{
+		makeQuota("", nil),
+		makeQuota("", &QuotaLimits{}),
+		makeQuota("", &QuotaLimits{Dependencies: -1}),
+		makeQuota("{{ .User.Name ", &QuotaLimits{Dependencies: 1}),
+	})
+}
+
+func TestPolicyValidationMaintenance(t *testing.T) {
+	from := time.Date(2018, 12, 20, 0, 0, 0, 0, time.UTC)
+
+	// Maintenance should have at least one valid window or freeze
+	runValidationTests(t, ResSuccess, true, []Base{
+		makeMaintenance([]*MaintenanceWindow{{Schedule: "0 22 * * 6", Duration: 4 * time.Hour}}, nil),
+		makeMaintenance([]*MaintenanceWindow{{Schedule: "*/30 1-5 1,15 * 0-7", Duration: time.Hour, Timezone: "America/Los_Angeles"}}, nil),
+		makeMaintenance(nil, []*MaintenanceFreeze{{From: from, To: from.Add(time.Hour), Reason: "holidays"}}),
+	})
+	runValidationTests(t, ResFailure, true, []Base{
+		makeMaintenance(nil, nil),
+		makeMaintenance([]*MaintenanceWindow{{Schedule: "0 22 * *", Duration: 4 * time.Hour}}, nil),
+		makeMaintenance([]*MaintenanceWindow{{Schedule: "0 25 * * *", Duration: 4 * time.Hour}}, nil),
+		makeMaintenance([]*MaintenanceWindow{{Schedule: "0 22 * * 6"}}, nil),
+		makeMaintenance([]*MaintenanceWindow{{Schedule: "0 22 * * 6", Duration: 4 * time.Hour, Timezone: "Mars/Olympus_Mons"}}, nil),
+		makeMaintenance(nil, []*MaintenanceFreeze{{From: from, To: from}}),
+		makeMaintenance(nil, []*MaintenanceFreeze{{From: from, To: from.Add(-time.Hour)}}),
+	})
 }
 
 func TestPolicyValidationRule(t *testing.T) {
@@ -121,10 +196,12 @@ func TestPolicyValidationRule(t *testing.T) {
 		makeRule(1, "true", 0, "labelName"),
 		makeRule(20, "", 1, Reject),
 		makeRule(100, "specialname + specialvalue == 'b'", 2, Reject),
+		makeRule(100, "matches(specialname, '^a.*') && contains(teams, 'dev')", 2, Reject),
 	})
 	runValidationTests(t, ResFailure, true, []Base{
 		makeRule(-1, "true", 0, "labelName"),                               // negative weight
 		makeRule(100, "specialname + '123')(((", 0, "labelName"),           // bad expression
+		makeRule(100, "unknownFunction(specialname)", 0, "labelName"),      // unknown function
 		makeRule(100, "true", Empty, ""),                                   // no actions specified
 		makeRule(100, "true", Nil, ""),                                     // actions = nil
 		makeRule(100, "specialname + specialvalue == 'b'", 2, "notreject"), // action is not (allow, reject)
@@ -154,6 +231,31 @@ func TestPolicyValidationCluster(t *testing.T) {
 	})
 }
 
+func TestPolicyValidationACLCustomRole(t *testing.T) {
+	// Custom roles should be defined in system namespace, should not redefine built-in roles and refer to known kinds
+	runValidationTests(t, ResSuccess, true, []Base{
+		makeACLCustomRole(runtime.SystemNS, "dependency-operator", DependencyObject.Kind),
+		makeACLCustomRole(runtime.SystemNS, "auditor", ServiceObject.Kind),
+	})
+	runValidationTests(t, ResFailure, true, []Base{
+		makeACLCustomRole("main", "dependency-operator", DependencyObject.Kind),
+		makeACLCustomRole(runtime.SystemNS, DomainAdmin.ID, DependencyObject.Kind),
+		makeACLCustomRole(runtime.SystemNS, "dependency-operator", "unknown"),
+	})
+
+	// ACL rules can refer to custom roles
+	rule := makeACLRule(0)
+	rule.Actions.AddRole = map[string]string{"dependency-operator": "main"}
+	runValidationTests(t, ResSuccess, false, []Base{
+		makeACLCustomRole(runtime.SystemNS, "dependency-operator", DependencyObject.Kind),
+		rule,
+	})
+	runValidationTests(t, ResFailure, false, []Base{
+		makeACLCustomRole(runtime.SystemNS, "auditor", DependencyObject.Kind),
+		rule,
+	})
+}
+
 func runValidationTests(t *testing.T, result int, every bool, objects []Base) {
 	t.Helper()
 
@@ -302,6 +404,28 @@ func invalidAllocationKeys(contract *Contract) *Contract {
 	return contract
 }
 
+func withTrafficSplit(contract *Contract, percent int, hashBy string) *Contract {
+	for _, context := range contract.Contexts {
+		context.Traffic = &TrafficSplit{Percent: percent, HashBy: hashBy}
+	}
+	return contract
+}
+
+func makeACLCustomRole(namespace string, name string, kind string) *ACLCustomRole {
+	return &ACLCustomRole{
+		TypeKind: ACLCustomRoleObject.GetTypeKind(),
+		Metadata: Metadata{
+			Namespace: namespace,
+			Name:      name,
+		},
+		Privileges: &Privileges{
+			NamespaceObjects: map[string]*Privilege{
+				kind: fullAccess,
+			},
+		},
+	}
+}
+
 func makeCluster(clusterType, ns string) *Cluster {
 	return &Cluster{
 		TypeKind: ClusterObject.GetTypeKind(),
@@ -337,6 +461,30 @@ func makeService(name string, labelNum int) *Service {
 	return service
 }
 
+func makeQuota(groupBy string, limits *QuotaLimits) *Quota {
+	return &Quota{
+		TypeKind: QuotaObject.GetTypeKind(),
+		Metadata: Metadata{
+			Namespace: "main",
+			Name:      "quota",
+		},
+		GroupBy: groupBy,
+		Limits:  limits,
+	}
+}
+
+func makeMaintenance(windows []*MaintenanceWindow, freezes []*MaintenanceFreeze) *Maintenance {
+	return &Maintenance{
+		TypeKind: MaintenanceObject.GetTypeKind(),
+		Metadata: Metadata{
+			Namespace: "main",
+			Name:      "maintenance",
+		},
+		Windows: windows,
+		Freezes: freezes,
+	}
+}
+
 func makeDependency(contract string) *Dependency {
 	dependency := &Dependency{
 		TypeKind: DependencyObject.GetTypeKind(),
@@ -370,6 +518,12 @@ func makeServiceComponents(count int, contract string, codeNum int, discoveryNum
 				Type:   "helm",
 				Params: util.NestedParameterMap{"a": "aValue", "nested": util.NestedParameterMap{"c": "d"}},
 			}
+		case 2:
+			component.Code = &Code{
+				Type:           "helm",
+				Params:         util.NestedParameterMap{"a": "aValue"},
+				UpdateStrategy: UpdateStrategyBlueGreen,
+			}
 		case Empty:
 			// no code defined, empty
 			component.Code = &Code{}
@@ -387,6 +541,13 @@ func makeServiceComponents(count int, contract string, codeNum int, discoveryNum
 				Type:   "helm",
 				Params: util.NestedParameterMap{"a": "aValue", "nested": util.NestedParameterMap{"c": "{{ broken___$$%@ }}"}},
 			}
+		case Invalid - 2:
+			// invalid update strategy
+			component.Code = &Code{
+				Type:           "helm",
+				Params:         util.NestedParameterMap{"a": "aValue"},
+				UpdateStrategy: "rolling",
+			}
 		}
 
 		switch discoveryNum {
@@ -426,6 +587,26 @@ func dependenciesInvalid(components []*ServiceComponent) []*ServiceComponent {
 	return components
 }
 
+func makeHooks(codeType string) *Hooks {
+	return &Hooks{
+		PreUpdate: &Code{
+			Type:   codeType,
+			Params: util.NestedParameterMap{"manifest": "migrate"},
+		},
+		PreDelete: &Code{
+			Type:   codeType,
+			Params: util.NestedParameterMap{"manifest": "backup"},
+		},
+	}
+}
+
+func withHooks(components []*ServiceComponent, hooks *Hooks) []*ServiceComponent {
+	for _, component := range components {
+		component.Hooks = hooks
+	}
+	return components
+}
+
 func dependenciesCycle(components []*ServiceComponent) []*ServiceComponent {
 	for _, component := range components {
 		component.Dependencies = []string{component.Name}
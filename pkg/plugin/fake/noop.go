@@ -1,8 +1,10 @@
 package fake
 
 import (
+	"context"
 	"time"
 
+	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/plugin"
 )
 
@@ -35,32 +37,55 @@ func (plugin *noOpPlugin) Cleanup() error {
 	return nil
 }
 
-func (plugin *noOpPlugin) Create(invocation *plugin.CodePluginInvocationParams) error {
-	time.Sleep(plugin.sleepTime)
-	return nil
+func (plugin *noOpPlugin) Create(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	return plugin.sleep(ctx)
 }
 
-func (plugin *noOpPlugin) Update(invocation *plugin.CodePluginInvocationParams) error {
-	time.Sleep(plugin.sleepTime)
-	return nil
+func (plugin *noOpPlugin) Update(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	return plugin.sleep(ctx)
 }
 
-func (plugin *noOpPlugin) Destroy(invocation *plugin.CodePluginInvocationParams) error {
-	time.Sleep(plugin.sleepTime)
-	return nil
+func (plugin *noOpPlugin) Destroy(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	return plugin.sleep(ctx)
 }
 
-func (plugin *noOpPlugin) Endpoints(invocation *plugin.CodePluginInvocationParams) (map[string]string, error) {
-	time.Sleep(plugin.sleepTime)
+func (plugin *noOpPlugin) Endpoints(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (map[string]string, error) {
+	err := plugin.sleep(ctx)
+	if err != nil {
+		return nil, err
+	}
 	return map[string]string{
 		"http": "endpoint_fake",
 	}, nil
 }
 
-func (plugin *noOpPlugin) Resources(invocation *plugin.CodePluginInvocationParams) (plugin.Resources, error) {
+func (plugin *noOpPlugin) Resources(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (plugin.Resources, error) {
 	return nil, nil
 }
 
-func (plugin *noOpPlugin) Status(invocation *plugin.CodePluginInvocationParams) (bool, error) {
+func (plugin *noOpPlugin) Status(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (bool, error) {
 	return true, nil
 }
+
+func (plugin *noOpPlugin) Drift(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (*plugin.Drift, error) {
+	return nil, nil
+}
+
+func (plugin *noOpPlugin) ListManaged(ctx context.Context, eventLog *event.Log) ([]*plugin.ManagedCode, error) {
+	return nil, nil
+}
+
+// sleep sleeps a given time amount, returning an error if the context gets cancelled before that
+func (plugin *noOpPlugin) sleep(ctx context.Context) error {
+	if plugin.sleepTime <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(plugin.sleepTime)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -1,8 +1,10 @@
 package helm
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/event"
@@ -69,17 +71,22 @@ func (p *Plugin) Cleanup() error {
 	return nil
 }
 
+// getTimeout returns timeout (in seconds) for Tiller operations, so they don't run beyond the deadline of the action
+func (p *Plugin) getTimeout(ctx context.Context) int64 {
+	return int64(plugin.GetTimeout(ctx, p.config.Timeout) / time.Second)
+}
+
 // Create implements creation of a new component instance in the cloud by deploying a Helm chart
-func (p *Plugin) Create(invocation *plugin.CodePluginInvocationParams) error {
-	return p.createOrUpdate(invocation, true)
+func (p *Plugin) Create(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	return p.createOrUpdate(ctx, invocation, true)
 }
 
 // Update implements update of an existing component instance in the cloud by updating parameters of a helm chart
-func (p *Plugin) Update(invocation *plugin.CodePluginInvocationParams) error {
-	return p.createOrUpdate(invocation, false)
+func (p *Plugin) Update(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
+	return p.createOrUpdate(ctx, invocation, false)
 }
 
-func (p *Plugin) createOrUpdate(invocation *plugin.CodePluginInvocationParams, create bool) error {
+func (p *Plugin) createOrUpdate(ctx context.Context, invocation *plugin.CodePluginInvocationParams, create bool) error {
 	err := p.init(invocation.EventLog)
 	if err != nil {
 		return err
@@ -121,10 +128,19 @@ func (p *Plugin) createOrUpdate(invocation *plugin.CodePluginInvocationParams, c
 		return err
 	}
 
+	// don't start installing or updating the release, if the action has already been cancelled or timed out
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
 	currRelease, err := helmClient.ReleaseContent(releaseName)
 	if err != nil && !strings.Contains(err.Error(), "not found") {
 		return fmt.Errorf("error while looking for Helm release %s: %s", releaseName, err)
 	}
+	if currRelease != nil && isReleaseDeleted(currRelease.Release) {
+		// release has been deleted without purging it (e.g. manually), so it has to be installed again
+		currRelease = nil
+	}
 
 	cluster := p.cluster
 	if create {
@@ -138,16 +154,19 @@ func (p *Plugin) createOrUpdate(invocation *plugin.CodePluginInvocationParams, c
 			// Print installation line on info level
 			invocation.EventLog.NewEntry().Infof("Installing Helm release '%s', chart '%s', cluster: '%s'", releaseName, chartName, cluster.Name)
 
-			_, err = helmClient.InstallRelease(
+			newRelease, installErr := helmClient.InstallRelease(
 				chartPath,
 				namespace,
 				helm.ReleaseName(releaseName),
 				helm.ValueOverrides(helmParams),
 				helm.InstallReuseName(true),
-				helm.InstallTimeout(int64(p.config.Timeout)),
+				helm.InstallTimeout(p.getTimeout(ctx)),
 			)
+			if installErr != nil {
+				return installErr
+			}
 
-			return err
+			return p.kube.EnsureIngressPolicyForManifest(namespace, invocation.DeployName, newRelease.Release.Manifest, k8s.IsIngressAllowed(invocation), invocation.EventLog)
 		}
 	}
 
@@ -169,7 +188,7 @@ func (p *Plugin) createOrUpdate(invocation *plugin.CodePluginInvocationParams, c
 		releaseName,
 		chartPath,
 		helm.UpdateValueOverrides(helmParams),
-		helm.UpgradeTimeout(int64(p.config.Timeout)),
+		helm.UpgradeTimeout(p.getTimeout(ctx)),
 	)
 	if err != nil {
 		return err
@@ -198,11 +217,11 @@ func (p *Plugin) createOrUpdate(invocation *plugin.CodePluginInvocationParams, c
 	// Print update line on info level
 	invocation.EventLog.NewEntry().Infof("Updated Helm release '%s', chart '%s', cluster '%s'", releaseName, chartName, cluster.Name)
 
-	return err
+	return p.kube.EnsureIngressPolicyForManifest(namespace, invocation.DeployName, newRelease.Release.Manifest, k8s.IsIngressAllowed(invocation), invocation.EventLog)
 }
 
 // Destroy implements destruction of an existing component instance in the cloud by running "helm delete" on the corresponding helm chart
-func (p *Plugin) Destroy(invocation *plugin.CodePluginInvocationParams) error {
+func (p *Plugin) Destroy(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
 	err := p.init(invocation.EventLog)
 	if err != nil {
 		return err
@@ -215,18 +234,33 @@ func (p *Plugin) Destroy(invocation *plugin.CodePluginInvocationParams) error {
 		return err
 	}
 
+	// don't start deleting the release, if the action has already been cancelled or timed out
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
 	invocation.EventLog.NewEntry().Infof("Deleting Helm release '%s'", releaseName)
 
 	_, err = helmClient.DeleteRelease(
 		releaseName,
 		helm.DeletePurge(true),
-		helm.DeleteTimeout(int64(p.config.Timeout)),
+		helm.DeleteTimeout(p.getTimeout(ctx)),
 	)
-	return err
+	if err != nil {
+		return err
+	}
+
+	// remove network policies, which may have been created if ingress traffic was rejected
+	namespace := invocation.PluginParams[plugin.ParamTargetSuffix]
+	if len(namespace) > 0 {
+		return p.kube.DeleteIngressPolicy(namespace, invocation.DeployName, invocation.EventLog)
+	}
+
+	return nil
 }
 
 // Endpoints returns map from port type to url for all services of the current chart
-func (p *Plugin) Endpoints(invocation *plugin.CodePluginInvocationParams) (map[string]string, error) {
+func (p *Plugin) Endpoints(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (map[string]string, error) {
 	err := p.init(invocation.EventLog)
 	if err != nil {
 		return nil, err
@@ -253,7 +287,7 @@ func (p *Plugin) Endpoints(invocation *plugin.CodePluginInvocationParams) (map[s
 }
 
 // Resources returns list of all resources (like services, config maps, etc.) deployed into the cluster by specified component instance
-func (p *Plugin) Resources(invocation *plugin.CodePluginInvocationParams) (plugin.Resources, error) {
+func (p *Plugin) Resources(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (plugin.Resources, error) {
 	err := p.init(invocation.EventLog)
 	if err != nil {
 		return nil, err
@@ -280,7 +314,7 @@ func (p *Plugin) Resources(invocation *plugin.CodePluginInvocationParams) (plugi
 }
 
 // Status returns readiness of all resources (like services, config maps, etc.) deployed into the cluster by specified component instance
-func (p *Plugin) Status(invocation *plugin.CodePluginInvocationParams) (bool, error) {
+func (p *Plugin) Status(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (bool, error) {
 	err := p.init(invocation.EventLog)
 	if err != nil {
 		return false, err
@@ -305,3 +339,96 @@ func (p *Plugin) Status(invocation *plugin.CodePluginInvocationParams) (bool, er
 
 	return p.kube.ReadinessStatusForManifest(namespace, invocation.DeployName, currRelease.Release.Manifest, invocation.EventLog)
 }
+
+// Drift checks whether Helm release of the component instance still exists, runs the expected chart with the expected
+// parameters and all of its objects are present in the cluster
+func (p *Plugin) Drift(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (*plugin.Drift, error) {
+	err := p.init(invocation.EventLog)
+	if err != nil {
+		return nil, err
+	}
+
+	helmClient, err := p.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := invocation.PluginParams[plugin.ParamTargetSuffix]
+	if len(namespace) <= 0 {
+		return nil, fmt.Errorf("namespace is a mandatory parameter")
+	}
+
+	releaseName := getReleaseName(invocation.DeployName)
+
+	currRelease, err := helmClient.ReleaseContent(releaseName)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			return &plugin.Drift{Missing: true, Details: fmt.Sprintf("Helm release '%s' not found", releaseName)}, nil
+		}
+		return nil, fmt.Errorf("error while looking for Helm release %s: %s", releaseName, err)
+	}
+	if isReleaseDeleted(currRelease.Release) {
+		return &plugin.Drift{Missing: true, Details: fmt.Sprintf("Helm release '%s' has been deleted", releaseName)}, nil
+	}
+
+	_, chartName, chartVersion, err := getHelmReleaseInfo(invocation.Params)
+	if err != nil {
+		return nil, err
+	}
+	chart := currRelease.Release.GetChart().GetMetadata()
+	if chart.GetName() != chartName || (len(chartVersion) > 0 && chart.GetVersion() != chartVersion) {
+		return &plugin.Drift{Details: fmt.Sprintf("Helm release '%s' runs chart '%s' version '%s', expected chart '%s' version '%s'", releaseName, chart.GetName(), chart.GetVersion(), chartName, chartVersion)}, nil
+	}
+
+	sameValues, err := isSameValues(currRelease.Release.GetConfig().GetRaw(), invocation.Params)
+	if err != nil {
+		return nil, fmt.Errorf("error while comparing values of Helm release %s: %s", releaseName, err)
+	}
+	if !sameValues {
+		return &plugin.Drift{Details: fmt.Sprintf("values of Helm release '%s' don't match component instance parameters", releaseName)}, nil
+	}
+
+	missing, _, err := p.kube.MissingObjectsForManifest(namespace, invocation.DeployName, currRelease.Release.Manifest, invocation.EventLog)
+	if err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		return &plugin.Drift{Details: fmt.Sprintf("objects of Helm release '%s' not found: %s", releaseName, strings.Join(missing, ", "))}, nil
+	}
+
+	return nil, nil
+}
+
+// ListManaged returns all Helm releases in the cluster, which have been deployed by Aptomi (i.e. named after component
+// instances), including the ones which have been deleted without purging
+func (p *Plugin) ListManaged(ctx context.Context, eventLog *event.Log) ([]*plugin.ManagedCode, error) {
+	err := p.init(eventLog)
+	if err != nil {
+		return nil, err
+	}
+
+	helmClient, err := p.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	releases, err := listReleases(helmClient)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing Helm releases: %s", err)
+	}
+
+	result := []*plugin.ManagedCode{}
+	for _, rel := range releases {
+		if !plugin.IsDeployName(rel.GetName()) {
+			continue
+		}
+		chart := rel.GetChart().GetMetadata()
+		result = append(result, &plugin.ManagedCode{
+			DeployName:   rel.GetName(),
+			TargetSuffix: rel.GetNamespace(),
+			Description:  fmt.Sprintf("Helm release '%s', chart '%s' version '%s', status '%s'", rel.GetName(), chart.GetName(), chart.GetVersion(), rel.GetInfo().GetStatus().GetCode()),
+		})
+	}
+
+	return result, nil
+}
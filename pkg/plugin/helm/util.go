@@ -3,9 +3,12 @@ package helm
 import (
 	"fmt"
 	"io/ioutil"
+	"reflect"
 
 	"github.com/Aptomi/aptomi/pkg/util"
+	"gopkg.in/yaml.v2"
 	"k8s.io/helm/pkg/helm"
+	"k8s.io/helm/pkg/proto/hapi/release"
 	"k8s.io/helm/pkg/repo"
 )
 
@@ -42,6 +45,109 @@ func getReleaseName(deployName string) string {
 	return deployName
 }
 
+// listReleases returns the latest version of every Helm release in all namespaces, in any status
+func listReleases(helmClient *helm.Client) ([]*release.Release, error) {
+	statuses := []release.Status_Code{
+		release.Status_UNKNOWN,
+		release.Status_DEPLOYED,
+		release.Status_DELETED,
+		release.Status_DELETING,
+		release.Status_FAILED,
+		release.Status_PENDING_INSTALL,
+		release.Status_PENDING_UPGRADE,
+		release.Status_PENDING_ROLLBACK,
+	}
+
+	result := []*release.Release{}
+	seen := make(map[string]bool)
+	offset := ""
+	for {
+		resp, err := helmClient.ListReleases(
+			helm.ReleaseListStatuses(statuses),
+			helm.ReleaseListOffset(offset),
+			helm.ReleaseListLimit(256),
+		)
+		if err != nil {
+			return nil, err
+		}
+		for _, rel := range resp.GetReleases() {
+			if seen[rel.GetName()] {
+				continue
+			}
+			seen[rel.GetName()] = true
+			result = append(result, rel)
+		}
+
+		offset = resp.GetNext()
+		if len(offset) == 0 {
+			break
+		}
+	}
+
+	return result, nil
+}
+
+func isReleaseDeleted(rel *release.Release) bool {
+	return rel.GetInfo().GetStatus().GetCode() == release.Status_DELETED
+}
+
+// isSameValues returns true if values of the deployed release (in yaml) match given parameters. Both get converted
+// into the same form, so representation differences (e.g. int vs float, nil vs empty map) don't get reported as drift
+func isSameValues(raw string, params util.NestedParameterMap) (bool, error) {
+	var releaseValues interface{}
+	err := yaml.Unmarshal([]byte(raw), &releaseValues)
+	if err != nil {
+		return false, err
+	}
+
+	// convert parameters into the same form by going through yaml
+	data, err := yaml.Marshal(params)
+	if err != nil {
+		return false, err
+	}
+	var paramsValues interface{}
+	err = yaml.Unmarshal(data, &paramsValues)
+	if err != nil {
+		return false, err
+	}
+
+	return reflect.DeepEqual(normalizeValues(releaseValues), normalizeValues(paramsValues)), nil
+}
+
+// normalizeValues converts values parsed from yaml into a canonical form, where all numbers are float64, all map keys
+// are strings and empty maps and lists are nil
+func normalizeValues(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[interface{}]interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			result[fmt.Sprint(key)] = normalizeValues(item)
+		}
+		return result
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		result := make([]interface{}, len(v))
+		for idx, item := range v {
+			result[idx] = normalizeValues(item)
+		}
+		return result
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
+
 func (p *Plugin) fetchChart(repository, name, version string) (string, error) {
 	chartURL, err := repo.FindChartInRepoURL(
 		repository, name, version,
@@ -0,0 +1,56 @@
+package helm
+
+import (
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSameValues(t *testing.T) {
+	tests := []struct {
+		raw    string
+		params util.NestedParameterMap
+		result bool
+	}{
+		// same values
+		{"a: 1\nb: text\nenabled: true\n", util.NestedParameterMap{"a": 1, "b": "text", "enabled": true}, true},
+		{"a:\n  b:\n  - x\n  - z\n", util.NestedParameterMap{"a": util.NestedParameterMap{"b": []interface{}{"x", "z"}}}, true},
+
+		// numbers of different types
+		{"replicas: 3\n", util.NestedParameterMap{"replicas": float64(3)}, true},
+		{"replicas: 3.0\n", util.NestedParameterMap{"replicas": 3}, true},
+		{"size: 1000000\n", util.NestedParameterMap{"size": float64(1000000)}, true},
+		{"size: 1e+06\n", util.NestedParameterMap{"size": 1000000}, true},
+		{"ratio: 2.5\n", util.NestedParameterMap{"ratio": 2.5}, true},
+		{"a:\n  replicas: 3\n", util.NestedParameterMap{"a": util.NestedParameterMap{"replicas": float64(3)}}, true},
+		{"list:\n- 1\n- 2\n", util.NestedParameterMap{"list": []interface{}{float64(1), float64(2)}}, true},
+
+		// empty values
+		{"", nil, true},
+		{"", util.NestedParameterMap{}, true},
+		{"{}\n", nil, true},
+		{"a: {}\n", util.NestedParameterMap{"a": nil}, true},
+		{"a: null\n", util.NestedParameterMap{"a": util.NestedParameterMap{}}, true},
+		{"a: []\n", util.NestedParameterMap{"a": []interface{}{}}, true},
+
+		// different values
+		{"replicas: 3\n", util.NestedParameterMap{"replicas": 4}, false},
+		{"ratio: 2.5\n", util.NestedParameterMap{"ratio": 2}, false},
+		{"a: \"1\"\n", util.NestedParameterMap{"a": 1}, false},
+		{"a: 1\n", util.NestedParameterMap{"a": 1, "b": 2}, false},
+		{"a:\n  b: 1\n", util.NestedParameterMap{"a": util.NestedParameterMap{"b": 2}}, false},
+		{"list:\n- 1\n- 2\n", util.NestedParameterMap{"list": []interface{}{2, 1}}, false},
+		{"", util.NestedParameterMap{"a": 1}, false},
+	}
+
+	for _, test := range tests {
+		same, err := isSameValues(test.raw, test.params)
+		if assert.NoError(t, err, "Values should be compared: %s", test.raw) {
+			assert.Equal(t, test.result, same, "Values comparison result for %q and %v", test.raw, test.params)
+		}
+	}
+
+	_, err := isSameValues("a: [", util.NestedParameterMap{})
+	assert.Error(t, err, "Invalid values of the deployed release should be reported")
+}
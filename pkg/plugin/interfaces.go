@@ -1,6 +1,10 @@
 package plugin
 
 import (
+	"context"
+	"regexp"
+	"time"
+
 	"github.com/Aptomi/aptomi/pkg/config"
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/lang"
@@ -11,6 +15,7 @@ import (
 type Registry interface {
 	ForCluster(cluster *lang.Cluster) (ClusterPlugin, error)
 	ForCodeType(cluster *lang.Cluster, codeType string) (CodePlugin, error)
+	CodeTypes(cluster *lang.Cluster) []string
 }
 
 // RegistryFactory returns plugins registry on demand
@@ -34,20 +39,82 @@ type ClusterPluginConstructor func(cluster *lang.Cluster, cfg config.Plugins) (C
 
 // CodePlugin is a definition of deployment plugin which takes care of creating, updating and destroying
 // component instances in the cloud. It's created for specific cluster and enforcement cycle or API call.
+// Provided context gets cancelled when the caller is no longer interested in the result (e.g. action timed out or
+// revision has been cancelled), so plugins should stop as soon as possible
 type CodePlugin interface {
 	Base
 
-	Create(*CodePluginInvocationParams) error
-	Update(*CodePluginInvocationParams) error
-	Destroy(*CodePluginInvocationParams) error
-	Endpoints(*CodePluginInvocationParams) (map[string]string, error)
-	Resources(*CodePluginInvocationParams) (Resources, error)
-	Status(*CodePluginInvocationParams) (bool, error)
+	Create(context.Context, *CodePluginInvocationParams) error
+	Update(context.Context, *CodePluginInvocationParams) error
+	Destroy(context.Context, *CodePluginInvocationParams) error
+	Endpoints(context.Context, *CodePluginInvocationParams) (map[string]string, error)
+	Resources(context.Context, *CodePluginInvocationParams) (Resources, error)
+	Status(context.Context, *CodePluginInvocationParams) (bool, error)
+	Drift(context.Context, *CodePluginInvocationParams) (*Drift, error)
+	ListManaged(context.Context, *event.Log) ([]*ManagedCode, error)
+}
+
+// Drift describes the difference between a component instance recorded in the actual state and what is really
+// deployed into the cloud (e.g. it was removed or changed manually). Nil drift means that nothing has drifted
+type Drift struct {
+	// Missing is true if deployed code (e.g. Helm release or k8s objects) no longer exists in the cloud
+	Missing bool
+
+	// Details is a human-readable description of the drift
+	Details string
+}
+
+// ManagedCode represents code deployed into the cloud by the code plugin (e.g. Helm release), which is returned
+// regardless of whether corresponding component instance is recorded in the actual state or not
+type ManagedCode struct {
+	// DeployName is a name, under which the code has been deployed
+	DeployName string
+
+	// TargetSuffix is where the code resides (see ParamTargetSuffix), empty if it's unknown
+	TargetSuffix string
+
+	// Params are code parameters, which have to be passed to Destroy in order to delete the code
+	Params util.NestedParameterMap
+
+	// Description is a human-readable description of the deployed code
+	Description string
+}
+
+// deployNameRegex matches names generated for component instances by ComponentInstanceKey.GetDeployName, as well as
+// names of the second copy of code deployed during blue/green update (see ComponentInstance.GetNextDeployName) and
+// names of code deployed for hooks (see ComponentInstance.GetHookDeployName)
+var deployNameRegex = regexp.MustCompile("^a-[0-9a-v]{13}(-g|-pre-create|-post-create|-pre-update|-pre-delete)?$")
+
+// IsDeployName returns true if a given name looks like a deploy name of a component instance, which means that code
+// with such name has been deployed by Aptomi
+func IsDeployName(name string) bool {
+	return deployNameRegex.MatchString(name)
+}
+
+// GetTimeout returns how long a plugin operation may take, so it doesn't run beyond the deadline of a given context
+// (e.g. when action has a timeout). If context has no deadline, a given default timeout is returned
+func GetTimeout(ctx context.Context, defaultTimeout time.Duration) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return defaultTimeout
+	}
+	remaining := time.Until(deadline)
+	if remaining < time.Second {
+		// operation has to be given at least some time, it will be interrupted anyway once context is done
+		remaining = time.Second
+	}
+	if defaultTimeout > 0 && defaultTimeout < remaining {
+		return defaultTimeout
+	}
+	return remaining
 }
 
 // ParamTargetSuffix it's a plugin-specific parameter, which is additionally specifies where the code should reside (in case of k8s and Helm, it's a string consisting of k8s namespace)
 const ParamTargetSuffix = "target-suffix"
 
+// ParamAllowIngress it's a plugin-specific parameter, which specifies whether ingress traffic should be allowed for the code ("true" or "false", allowed if not set)
+const ParamAllowIngress = "allow-ingress"
+
 // CodePluginInvocationParams is a struct that will be passed into CodePlugin when invoking its methods
 type CodePluginInvocationParams struct {
 	DeployName   string
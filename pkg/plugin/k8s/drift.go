@@ -0,0 +1,33 @@
+package k8s
+
+import (
+	"strings"
+
+	"github.com/Aptomi/aptomi/pkg/event"
+	"k8s.io/apimachinery/pkg/api/errors"
+)
+
+// MissingObjectsForManifest returns the list of objects (as 'kind/name') from specified manifest, which don't exist
+// in the cluster, as well as the total number of objects in the manifest
+func (p *Plugin) MissingObjectsForManifest(namespace, deployName, targetManifest string, eventLog *event.Log) ([]string, int, error) {
+	helmKube := p.NewHelmKube(deployName, eventLog)
+
+	infos, err := helmKube.BuildUnstructured(namespace, strings.NewReader(targetManifest))
+	if err != nil {
+		return nil, 0, err
+	}
+
+	missing := []string{}
+	for _, info := range infos {
+		getErr := info.Get()
+		if getErr != nil {
+			if errors.IsNotFound(getErr) {
+				missing = append(missing, info.Mapping.GroupVersionKind.Kind+"/"+info.Name)
+				continue
+			}
+			return nil, 0, getErr
+		}
+	}
+
+	return missing, len(infos), nil
+}
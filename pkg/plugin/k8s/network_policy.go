@@ -0,0 +1,235 @@
+package k8s
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	networking "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/kubernetes/pkg/kubectl/resource"
+)
+
+const (
+	// networkPolicyDeployNameLabel is a label put on all network policies generated by Aptomi, it stores deploy name of the component instance
+	networkPolicyDeployNameLabel = "aptomi.io/deploy-name"
+)
+
+// IsIngressAllowed returns false if ingress traffic was explicitly rejected for the code by the policy and true otherwise
+func IsIngressAllowed(invocation *plugin.CodePluginInvocationParams) bool {
+	value, exist := invocation.PluginParams[plugin.ParamAllowIngress]
+	if !exist || len(value) == 0 {
+		return true
+	}
+
+	allow, err := strconv.ParseBool(value)
+	if err != nil {
+		return true
+	}
+
+	return allow
+}
+
+// EnsureIngressPolicyForManifest creates or updates network policies which block all incoming traffic to the pods
+// of the specified manifest if ingress isn't allowed, or removes them if ingress is allowed. One network policy gets
+// created for every distinct set of pod labels found in the manifest.
+func (p *Plugin) EnsureIngressPolicyForManifest(namespace, deployName, targetManifest string, allowIngress bool, eventLog *event.Log) error {
+	kubeClient, err := p.NewClient()
+	if err != nil {
+		return err
+	}
+
+	if allowIngress {
+		return p.deleteNetworkPolicies(kubeClient, namespace, deployName, nil, eventLog)
+	}
+
+	helmKube := p.NewHelmKube(deployName, eventLog)
+
+	infos, err := helmKube.BuildUnstructured(namespace, strings.NewReader(targetManifest))
+	if err != nil {
+		return err
+	}
+
+	return p.ensureNetworkPolicies(kubeClient, namespace, deployName, getPodSelectors(deployName, infos), eventLog)
+}
+
+// DeleteIngressPolicy removes all network policies created for the specified deploy name
+func (p *Plugin) DeleteIngressPolicy(namespace, deployName string, eventLog *event.Log) error {
+	kubeClient, err := p.NewClient()
+	if err != nil {
+		return err
+	}
+
+	return p.deleteNetworkPolicies(kubeClient, namespace, deployName, nil, eventLog)
+}
+
+// ensureNetworkPolicies makes sure that there is a network policy for every pod selector from the specified map
+// (network policy name -> pod labels) and that all other network policies created for the deploy name are removed
+func (p *Plugin) ensureNetworkPolicies(client kubernetes.Interface, namespace, deployName string, selectors map[string]map[string]string, eventLog *event.Log) error {
+	if len(selectors) == 0 {
+		eventLog.NewEntry().Warningf("Ingress is rejected for '%s', but no pods found in its manifest to apply network policy to", deployName)
+	}
+
+	for name, podLabels := range selectors {
+		err := p.ensureNetworkPolicy(client, namespace, deployName, name, podLabels, eventLog)
+		if err != nil {
+			return err
+		}
+	}
+
+	// remove network policies which are no longer needed (e.g. pod labels changed)
+	return p.deleteNetworkPolicies(client, namespace, deployName, selectors, eventLog)
+}
+
+func (p *Plugin) ensureNetworkPolicy(client kubernetes.Interface, namespace, deployName, name string, podLabels map[string]string, eventLog *event.Log) error {
+	spec := networking.NetworkPolicySpec{
+		PodSelector: meta.LabelSelector{
+			MatchLabels: podLabels,
+		},
+		// no ingress rules specified means that all incoming traffic is blocked
+		PolicyTypes: []networking.PolicyType{networking.PolicyTypeIngress},
+	}
+
+	policies := client.NetworkingV1().NetworkPolicies(namespace)
+
+	policy, err := policies.Get(name, meta.GetOptions{})
+	if err != nil {
+		if errors.IsNotFound(err) {
+			eventLog.NewEntry().Infof("Rejecting ingress traffic for '%s' by creating network policy '%s' in namespace '%s', cluster '%s'", deployName, name, namespace, p.Cluster.Name)
+
+			policy = &networking.NetworkPolicy{
+				ObjectMeta: meta.ObjectMeta{
+					Name: name,
+					Labels: map[string]string{
+						networkPolicyDeployNameLabel: deployName,
+					},
+				},
+				Spec: spec,
+			}
+
+			_, err = policies.Create(policy)
+		}
+
+		return err
+	}
+
+	policy.Spec = spec
+	_, err = policies.Update(policy)
+
+	return err
+}
+
+func (p *Plugin) deleteNetworkPolicies(client kubernetes.Interface, namespace, deployName string, keep map[string]map[string]string, eventLog *event.Log) error {
+	policies := client.NetworkingV1().NetworkPolicies(namespace)
+
+	list, err := policies.List(meta.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", networkPolicyDeployNameLabel, deployName),
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range list.Items {
+		if _, exist := keep[policy.Name]; exist {
+			continue
+		}
+
+		eventLog.NewEntry().Infof("Allowing ingress traffic for '%s' by deleting network policy '%s' in namespace '%s', cluster '%s'", deployName, policy.Name, namespace, p.Cluster.Name)
+
+		err = policies.Delete(policy.Name, &meta.DeleteOptions{})
+		if err != nil && !errors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getPodSelectors returns a map from network policy name to pod labels for every distinct set of pod labels found
+// in the specified objects
+func getPodSelectors(deployName string, infos []*resource.Info) map[string]map[string]string {
+	selectors := make(map[string]map[string]string)
+	for _, info := range infos {
+		obj, ok := info.Object.(*unstructured.Unstructured)
+		if !ok {
+			continue
+		}
+
+		podLabels := getPodLabels(info.Mapping.GroupVersionKind.Kind, obj.Object)
+		if len(podLabels) == 0 {
+			continue
+		}
+
+		selectors[getNetworkPolicyName(deployName, podLabels)] = podLabels
+	}
+
+	return selectors
+}
+
+// getNetworkPolicyName returns stable name of the network policy for the specified deploy name and set of pod labels
+func getNetworkPolicyName(deployName string, podLabels map[string]string) string {
+	keys := make([]string, 0, len(podLabels))
+	for key := range podLabels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, key+"="+podLabels[key])
+	}
+
+	return fmt.Sprintf("%s-deny-ingress-%s", deployName, hashString(strings.Join(parts, ",")))
+}
+
+// getPodLabels returns labels of the pods which will be created by the specified object
+func getPodLabels(kind string, obj map[string]interface{}) map[string]string {
+	var path []string
+	switch kind {
+	case "Pod":
+		path = []string{"metadata", "labels"}
+	case "Deployment", "StatefulSet", "DaemonSet", "ReplicaSet", "ReplicationController", "Job":
+		path = []string{"spec", "template", "metadata", "labels"}
+	default:
+		return nil
+	}
+
+	var value interface{} = obj
+	for _, field := range path {
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		value = m[field]
+	}
+
+	labelsMap, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	result := make(map[string]string)
+	for key, labelValue := range labelsMap {
+		if str, ok := labelValue.(string); ok {
+			result[key] = str
+		}
+	}
+
+	return result
+}
+
+func hashString(str string) string {
+	h := fnv.New32a()
+	_, err := h.Write([]byte(str))
+	if err != nil {
+		panic(err)
+	}
+	return fmt.Sprintf("%08x", h.Sum32())
+}
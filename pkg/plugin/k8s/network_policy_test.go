@@ -0,0 +1,223 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	networking "k8s.io/api/networking/v1"
+	apimeta "k8s.io/apimachinery/pkg/api/meta"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	"k8s.io/kubernetes/pkg/kubectl/resource"
+)
+
+func TestIsIngressAllowed(t *testing.T) {
+	tests := []struct {
+		params map[string]string
+		result bool
+	}{
+		{nil, true},
+		{map[string]string{plugin.ParamAllowIngress: ""}, true},
+		{map[string]string{plugin.ParamAllowIngress: "true"}, true},
+		{map[string]string{plugin.ParamAllowIngress: "false"}, false},
+		{map[string]string{plugin.ParamAllowIngress: "invalid"}, true},
+	}
+
+	for _, test := range tests {
+		invocation := &plugin.CodePluginInvocationParams{PluginParams: test.params}
+		assert.Equal(t, test.result, IsIngressAllowed(invocation), "Ingress allowed for plugin params: %v", test.params)
+	}
+}
+
+func TestGetPodLabels(t *testing.T) {
+	labels := map[string]interface{}{"app": "web", "tier": "frontend", "replicas": 3}
+	tests := []struct {
+		kind   string
+		obj    map[string]interface{}
+		result map[string]string
+	}{
+		{
+			"Pod",
+			map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}},
+			map[string]string{"app": "web", "tier": "frontend"},
+		},
+		{
+			"Deployment",
+			map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}}}},
+			map[string]string{"app": "web", "tier": "frontend"},
+		},
+		{
+			"StatefulSet",
+			map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}}}},
+			map[string]string{"app": "web", "tier": "frontend"},
+		},
+		{
+			// labels of the deployment itself are not labels of its pods
+			"Deployment",
+			map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}},
+			nil,
+		},
+		{
+			"Deployment",
+			map[string]interface{}{"spec": "invalid"},
+			nil,
+		},
+		{
+			"Service",
+			map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}},
+			nil,
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.result, getPodLabels(test.kind, test.obj), "Pod labels for %s: %v", test.kind, test.obj)
+	}
+}
+
+func TestGetNetworkPolicyName(t *testing.T) {
+	podLabels := map[string]string{"app": "web", "tier": "frontend"}
+	name := getNetworkPolicyName("a-0123456789abc", podLabels)
+
+	// name is stable and doesn't depend on the order of labels
+	for i := 0; i < 10; i++ {
+		assert.Equal(t, name, getNetworkPolicyName("a-0123456789abc", map[string]string{"tier": "frontend", "app": "web"}), "Network policy name should be stable")
+	}
+
+	// name depends on deploy name and pod labels
+	assert.NotEqual(t, name, getNetworkPolicyName("a-0123456789abd", podLabels), "Network policy name should depend on deploy name")
+	assert.NotEqual(t, name, getNetworkPolicyName("a-0123456789abc", map[string]string{"app": "web"}), "Network policy name should depend on pod labels")
+	assert.NotEqual(t, name, getNetworkPolicyName("a-0123456789abc", map[string]string{"app": "web", "tier": "backend"}), "Network policy name should depend on pod labels")
+
+	// name should be a valid object name even for the longest deploy name and a lot of labels
+	longLabels := make(map[string]string)
+	for i := 0; i < 100; i++ {
+		longLabels[fmt.Sprintf("label-%d", i)] = fmt.Sprintf("value-%d", i)
+	}
+	dnsLabelRegex := regexp.MustCompile("^[a-z0-9]([-a-z0-9]*[a-z0-9])?$")
+	for _, deployName := range []string{"a-0123456789abc", "a-0123456789abc-g", "a-0123456789abc-post-create"} {
+		assert.True(t, plugin.IsDeployName(deployName), "Deploy name should be valid: %s", deployName)
+		for _, labels := range []map[string]string{podLabels, longLabels} {
+			name := getNetworkPolicyName(deployName, labels)
+			assert.True(t, len(name) <= 63, "Network policy name should not be longer than 63 characters: %s", name)
+			assert.Regexp(t, dnsLabelRegex, name, "Network policy name should be a valid DNS label")
+		}
+	}
+}
+
+func TestHashString(t *testing.T) {
+	assert.Equal(t, hashString("app=web"), hashString("app=web"), "Hash should be stable")
+	assert.NotEqual(t, hashString("app=web"), hashString("app=db"), "Hash should be different for different strings")
+	for _, str := range []string{"", "app=web", string(make([]byte, 10000))} {
+		assert.Len(t, hashString(str), 8, "Hash should have fixed length")
+	}
+}
+
+func TestGetPodSelectors(t *testing.T) {
+	newInfo := func(kind string, obj map[string]interface{}) *resource.Info {
+		return &resource.Info{
+			Mapping: &apimeta.RESTMapping{GroupVersionKind: schema.GroupVersionKind{Kind: kind}},
+			Object:  &unstructured.Unstructured{Object: obj},
+		}
+	}
+	podTemplate := func(labels map[string]interface{}) map[string]interface{} {
+		return map[string]interface{}{"spec": map[string]interface{}{"template": map[string]interface{}{"metadata": map[string]interface{}{"labels": labels}}}}
+	}
+
+	infos := []*resource.Info{
+		newInfo("Deployment", podTemplate(map[string]interface{}{"app": "web"})),
+		newInfo("StatefulSet", podTemplate(map[string]interface{}{"app": "db"})),
+		// same pod labels as the deployment, so only one network policy is needed for them
+		newInfo("Pod", map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "web"}}}),
+		newInfo("Service", map[string]interface{}{"metadata": map[string]interface{}{"labels": map[string]interface{}{"app": "svc"}}}),
+		newInfo("Deployment", podTemplate(map[string]interface{}{})),
+	}
+
+	assert.Equal(t, map[string]map[string]string{
+		getNetworkPolicyName("a-0123456789abc", map[string]string{"app": "web"}): {"app": "web"},
+		getNetworkPolicyName("a-0123456789abc", map[string]string{"app": "db"}):  {"app": "db"},
+	}, getPodSelectors("a-0123456789abc", infos), "Network policy should be created for every distinct set of pod labels")
+
+	assert.Empty(t, getPodSelectors("a-0123456789abc", nil), "No network policies should be created for empty manifest")
+}
+
+func TestEnsureNetworkPolicies(t *testing.T) {
+	p := &Plugin{Cluster: &lang.Cluster{Metadata: lang.Metadata{Name: "cluster-us"}}}
+	eventLog := event.NewLog(logrus.DebugLevel, "test-network-policy")
+
+	// network policy of another deploy name, which should never be touched
+	otherLabels := map[string]string{"app": "other"}
+	otherName := getNetworkPolicyName("a-0123456789abd", otherLabels)
+	client := fake.NewSimpleClientset(&networking.NetworkPolicy{
+		ObjectMeta: meta.ObjectMeta{
+			Name:      otherName,
+			Namespace: "k8ns",
+			Labels:    map[string]string{networkPolicyDeployNameLabel: "a-0123456789abd"},
+		},
+		Spec: networking.NetworkPolicySpec{PodSelector: meta.LabelSelector{MatchLabels: otherLabels}},
+	})
+
+	webLabels := map[string]string{"app": "web"}
+	dbLabels := map[string]string{"app": "db"}
+	webName := getNetworkPolicyName("a-0123456789abc", webLabels)
+	dbName := getNetworkPolicyName("a-0123456789abc", dbLabels)
+
+	// network policies get created for all pod selectors
+	err := p.ensureNetworkPolicies(client, "k8ns", "a-0123456789abc", map[string]map[string]string{webName: webLabels, dbName: dbLabels}, eventLog)
+	assert.NoError(t, err, "Network policies should be created")
+	assertNetworkPolicies(t, client, map[string]map[string]string{webName: webLabels, dbName: dbLabels, otherName: otherLabels})
+
+	for _, name := range []string{webName, dbName} {
+		policy, getErr := client.NetworkingV1().NetworkPolicies("k8ns").Get(name, meta.GetOptions{})
+		if assert.NoError(t, getErr, "Network policy should exist") {
+			assert.Equal(t, "a-0123456789abc", policy.Labels[networkPolicyDeployNameLabel], "Network policy should be labeled with deploy name")
+			assert.Equal(t, []networking.PolicyType{networking.PolicyTypeIngress}, policy.Spec.PolicyTypes, "Network policy should block ingress")
+			assert.Empty(t, policy.Spec.Ingress, "Network policy should not allow any ingress traffic")
+		}
+	}
+
+	// calling it again with the same pod selectors doesn't change anything
+	err = p.ensureNetworkPolicies(client, "k8ns", "a-0123456789abc", map[string]map[string]string{webName: webLabels, dbName: dbLabels}, eventLog)
+	assert.NoError(t, err, "Network policies should be updated")
+	assertNetworkPolicies(t, client, map[string]map[string]string{webName: webLabels, dbName: dbLabels, otherName: otherLabels})
+
+	// network policy gets removed once pod labels disappear from the manifest and new one gets created for new labels
+	cacheLabels := map[string]string{"app": "cache"}
+	cacheName := getNetworkPolicyName("a-0123456789abc", cacheLabels)
+	err = p.ensureNetworkPolicies(client, "k8ns", "a-0123456789abc", map[string]map[string]string{webName: webLabels, cacheName: cacheLabels}, eventLog)
+	assert.NoError(t, err, "Network policies should be updated")
+	assertNetworkPolicies(t, client, map[string]map[string]string{webName: webLabels, cacheName: cacheLabels, otherName: otherLabels})
+
+	// all network policies of the deploy name get removed once ingress is allowed or code is deleted
+	err = p.deleteNetworkPolicies(client, "k8ns", "a-0123456789abc", nil, eventLog)
+	assert.NoError(t, err, "Network policies should be deleted")
+	assertNetworkPolicies(t, client, map[string]map[string]string{otherName: otherLabels})
+
+	// deleting network policies which don't exist is fine
+	err = p.deleteNetworkPolicies(client, "k8ns", "a-0123456789abc", nil, eventLog)
+	assert.NoError(t, err, "Network policies should be deleted")
+	assertNetworkPolicies(t, client, map[string]map[string]string{otherName: otherLabels})
+}
+
+func assertNetworkPolicies(t *testing.T, client kubernetes.Interface, expected map[string]map[string]string) {
+	t.Helper()
+
+	list, err := client.NetworkingV1().NetworkPolicies("k8ns").List(meta.ListOptions{})
+	if !assert.NoError(t, err, "Network policies should be listed") {
+		return
+	}
+
+	actual := make(map[string]map[string]string)
+	for _, policy := range list.Items {
+		actual[policy.Name] = policy.Spec.PodSelector.MatchLabels
+	}
+	assert.Equal(t, expected, actual, "Network policies should match pod selectors")
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/util"
+	batch "k8s.io/api/batch/v1"
 	"k8s.io/api/core/v1"
 	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
@@ -41,7 +42,7 @@ func (p *Plugin) ReadinessStatusForManifest(namespace, deployName, targetManifes
 
 		var statusErr error
 
-		// todo some objects are missing in this check like DaemonSet, Job, ReplicationController, etc.
+		// todo some objects are missing in this check like DaemonSet, ReplicationController, etc.
 		switch kind := info.Mapping.GroupVersionKind.Kind; kind {
 		case "Service": // nolint: goconst
 			svc, getErr := kubeClient.CoreV1().Services(namespace).Get(info.Name, meta.GetOptions{})
@@ -55,6 +56,12 @@ func (p *Plugin) ReadinessStatusForManifest(namespace, deployName, targetManifes
 				return false, getErr
 			}
 			ready = isPersistentVolumeClaimReady(pvc)
+		case "Job":
+			job, getErr := kubeClient.BatchV1().Jobs(namespace).Get(info.Name, meta.GetOptions{})
+			if getErr != nil {
+				return false, getErr
+			}
+			ready = isJobReady(job)
 		case "Deployment":
 			//deployment, getErr := kubeClient.AppsV1beta1().Deployments(p.Namespace).Get(info.Name, meta.GetOptions{})
 			//if getErr != nil {
@@ -100,6 +107,15 @@ func isPersistentVolumeClaimReady(pvc *v1.PersistentVolumeClaim) bool {
 	return pvc.Status.Phase == v1.ClaimBound
 }
 
+// job is considered ready once it's completed (e.g. when it's used as a hook)
+func isJobReady(job *batch.Job) bool {
+	completions := int32(1)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+	return job.Status.Succeeded >= completions
+}
+
 func isReadyUsingStatusViewer(internalClientSet kubernetes.Interface, groupKind schema.GroupKind, namespace, name string) (bool, error) {
 	statusViewer, err := kubectl.StatusViewerFor(groupKind, internalClientSet)
 	if err != nil {
@@ -1,13 +1,17 @@
 package k8sraw
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/event"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/plugin"
 	"github.com/Aptomi/aptomi/pkg/plugin/k8s"
+	"github.com/Aptomi/aptomi/pkg/util"
 	"github.com/Aptomi/aptomi/pkg/util/sync"
 )
 
@@ -34,6 +38,14 @@ func New(clusterPlugin plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlu
 	}, nil
 }
 
+// defaultTimeout is how long k8s objects are given to be created/updated, unless action has a shorter deadline
+const defaultTimeout = 42 * time.Second
+
+// getTimeout returns timeout (in seconds) for creating/updating k8s objects, so it doesn't run beyond the deadline of the action
+func getTimeout(ctx context.Context) int64 {
+	return int64(plugin.GetTimeout(ctx, defaultTimeout) / time.Second)
+}
+
 func (p *Plugin) init() error {
 	return p.once.Do(func() error {
 		err := p.kube.Init()
@@ -61,7 +73,7 @@ func (p *Plugin) Cleanup() error {
 }
 
 // Create implements creation of a new component instance in the cloud by deploying raw k8s objects
-func (p *Plugin) Create(invocation *plugin.CodePluginInvocationParams) error {
+func (p *Plugin) Create(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
 	err := p.init()
 	if err != nil {
 		return err
@@ -84,16 +96,26 @@ func (p *Plugin) Create(invocation *plugin.CodePluginInvocationParams) error {
 
 	client := p.kube.NewHelmKube(invocation.DeployName, invocation.EventLog)
 
-	err = client.Create(namespace, strings.NewReader(targetManifest), 42, false)
+	// don't start creating objects, if the action has already been cancelled or timed out
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	err = client.Create(namespace, strings.NewReader(targetManifest), getTimeout(ctx), false)
 	if err != nil {
 		return err
 	}
 
-	return p.storeManifest(kubeClient, invocation.DeployName, targetManifest)
+	err = p.kube.EnsureIngressPolicyForManifest(namespace, invocation.DeployName, targetManifest, k8s.IsIngressAllowed(invocation), invocation.EventLog)
+	if err != nil {
+		return err
+	}
+
+	return p.storeManifest(kubeClient, invocation.DeployName, namespace, targetManifest)
 }
 
 // Update implements update of an existing component instance in the cloud by updating raw k8s objects
-func (p *Plugin) Update(invocation *plugin.CodePluginInvocationParams) error {
+func (p *Plugin) Update(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
 	err := p.init()
 	if err != nil {
 		return err
@@ -121,16 +143,26 @@ func (p *Plugin) Update(invocation *plugin.CodePluginInvocationParams) error {
 
 	client := p.kube.NewHelmKube(invocation.DeployName, invocation.EventLog)
 
-	err = client.Update(namespace, strings.NewReader(currentManifest), strings.NewReader(targetManifest), false, false, 42, false)
+	// don't start updating objects, if the action has already been cancelled or timed out
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	err = client.Update(namespace, strings.NewReader(currentManifest), strings.NewReader(targetManifest), false, false, getTimeout(ctx), false)
+	if err != nil {
+		return err
+	}
+
+	err = p.kube.EnsureIngressPolicyForManifest(namespace, invocation.DeployName, targetManifest, k8s.IsIngressAllowed(invocation), invocation.EventLog)
 	if err != nil {
 		return err
 	}
 
-	return p.storeManifest(kubeClient, invocation.DeployName, targetManifest)
+	return p.storeManifest(kubeClient, invocation.DeployName, namespace, targetManifest)
 }
 
 // Destroy implements destruction of an existing component instance in the cloud by deleting raw k8s objects
-func (p *Plugin) Destroy(invocation *plugin.CodePluginInvocationParams) error {
+func (p *Plugin) Destroy(ctx context.Context, invocation *plugin.CodePluginInvocationParams) error {
 	err := p.init()
 	if err != nil {
 		return err
@@ -153,16 +185,26 @@ func (p *Plugin) Destroy(invocation *plugin.CodePluginInvocationParams) error {
 
 	client := p.kube.NewHelmKube(invocation.DeployName, invocation.EventLog)
 
+	// don't start deleting objects, if the action has already been cancelled or timed out
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
 	err = client.Delete(namespace, strings.NewReader(deleteManifest))
 	if err != nil {
 		return err
 	}
 
+	err = p.kube.DeleteIngressPolicy(namespace, invocation.DeployName, invocation.EventLog)
+	if err != nil {
+		return err
+	}
+
 	return p.deleteManifest(kubeClient, invocation.DeployName)
 }
 
 // Endpoints returns map from port type to url for all services of the deployed raw k8s objects
-func (p *Plugin) Endpoints(invocation *plugin.CodePluginInvocationParams) (map[string]string, error) {
+func (p *Plugin) Endpoints(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (map[string]string, error) {
 	err := p.init()
 	if err != nil {
 		return nil, err
@@ -182,7 +224,7 @@ func (p *Plugin) Endpoints(invocation *plugin.CodePluginInvocationParams) (map[s
 }
 
 // Resources returns list of all resources (like services, config maps, etc.) deployed into the cluster by specified component instance
-func (p *Plugin) Resources(invocation *plugin.CodePluginInvocationParams) (plugin.Resources, error) {
+func (p *Plugin) Resources(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (plugin.Resources, error) {
 	err := p.init()
 	if err != nil {
 		return nil, err
@@ -202,7 +244,7 @@ func (p *Plugin) Resources(invocation *plugin.CodePluginInvocationParams) (plugi
 }
 
 // Status returns readiness of all resources (like services, config maps, etc.) deployed into the cluster by specified component instance
-func (p *Plugin) Status(invocation *plugin.CodePluginInvocationParams) (bool, error) {
+func (p *Plugin) Status(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (bool, error) {
 	err := p.init()
 	if err != nil {
 		return false, err
@@ -220,3 +262,83 @@ func (p *Plugin) Status(invocation *plugin.CodePluginInvocationParams) (bool, er
 
 	return p.kube.ReadinessStatusForManifest(namespace, invocation.DeployName, targetManifest, invocation.EventLog)
 }
+
+// Drift checks whether raw k8s objects of the component instance still exist in the cluster and were deployed from
+// the expected manifest
+func (p *Plugin) Drift(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (*plugin.Drift, error) {
+	err := p.init()
+	if err != nil {
+		return nil, err
+	}
+
+	kubeClient, err := p.kube.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := invocation.PluginParams[plugin.ParamTargetSuffix]
+	if len(namespace) <= 0 {
+		return nil, fmt.Errorf("namespace is a mandatory parameter")
+	}
+
+	targetManifest, ok := invocation.Params["manifest"].(string)
+	if !ok {
+		return nil, fmt.Errorf("manifest is a mandatory parameter")
+	}
+
+	missing, total, err := p.kube.MissingObjectsForManifest(namespace, invocation.DeployName, targetManifest, invocation.EventLog)
+	if err != nil {
+		return nil, err
+	}
+	if total > 0 && len(missing) == total {
+		return &plugin.Drift{Missing: true, Details: fmt.Sprintf("k8s objects not found: %s", strings.Join(missing, ", "))}, nil
+	}
+	if len(missing) > 0 {
+		return &plugin.Drift{Details: fmt.Sprintf("some of k8s objects not found: %s", strings.Join(missing, ", "))}, nil
+	}
+
+	currentManifest, err := p.loadManifest(kubeClient, invocation.DeployName)
+	if err != nil {
+		return nil, err
+	}
+	if currentManifest != targetManifest {
+		return &plugin.Drift{Details: "deployed manifest doesn't match component instance parameters"}, nil
+	}
+
+	return nil, nil
+}
+
+// ListManaged returns all component instances, for which raw k8s objects have been deployed into the cluster (i.e.
+// their manifests are stored in the data namespace)
+func (p *Plugin) ListManaged(ctx context.Context, eventLog *event.Log) ([]*plugin.ManagedCode, error) {
+	err := p.init()
+	if err != nil {
+		return nil, err
+	}
+
+	kubeClient, err := p.kube.NewClient()
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, err := p.listManifests(kubeClient)
+	if err != nil {
+		return nil, fmt.Errorf("error while listing manifests in namespace %s: %s", p.dataNamespace, err)
+	}
+
+	result := []*plugin.ManagedCode{}
+	for deployName, data := range manifests {
+		if !plugin.IsDeployName(deployName) {
+			continue
+		}
+		// namespace could be unknown for manifests stored by older versions, such code will have to be deleted manually
+		result = append(result, &plugin.ManagedCode{
+			DeployName:   deployName,
+			TargetSuffix: data["namespace"],
+			Params:       util.NestedParameterMap{"manifest": data["manifest"]},
+			Description:  fmt.Sprintf("k8s objects with manifest stored in configmap %s/%s", p.dataNamespace, p.getManifestConfigMapName(deployName)),
+		})
+	}
+
+	return result, nil
+}
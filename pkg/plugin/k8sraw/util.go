@@ -18,7 +18,7 @@ func (p *Plugin) getManifestConfigMapName(deployName string) string {
 	return strings.ToLower(configMapNameReplacer.Replace(fmt.Sprintf("aptomi-raw-%s-%s", p.cluster.Name, deployName)))
 }
 
-func (p *Plugin) storeManifest(client kubernetes.Interface, deployName, manifest string) error {
+func (p *Plugin) storeManifest(client kubernetes.Interface, deployName, namespace, manifest string) error {
 	name := p.getManifestConfigMapName(deployName)
 
 	cm, err := client.CoreV1().ConfigMaps(p.dataNamespace).Get(name, meta.GetOptions{})
@@ -29,7 +29,8 @@ func (p *Plugin) storeManifest(client kubernetes.Interface, deployName, manifest
 					Name: name,
 				},
 				Data: map[string]string{
-					"manifest": manifest,
+					"manifest":  manifest,
+					"namespace": namespace,
 				},
 			}
 
@@ -40,7 +41,8 @@ func (p *Plugin) storeManifest(client kubernetes.Interface, deployName, manifest
 	}
 
 	cm.Data = map[string]string{
-		"manifest": manifest,
+		"manifest":  manifest,
+		"namespace": namespace,
 	}
 
 	_, err = client.CoreV1().ConfigMaps(p.dataNamespace).Update(cm)
@@ -67,6 +69,26 @@ func (p *Plugin) loadManifest(client kubernetes.Interface, deployName string) (s
 	return manifest, nil
 }
 
+// listManifests returns all manifests stored for the cluster, as a map from deploy name to the config map data
+func (p *Plugin) listManifests(client kubernetes.Interface) (map[string]map[string]string, error) {
+	prefix := p.getManifestConfigMapName("")
+
+	cmList, err := client.CoreV1().ConfigMaps(p.dataNamespace).List(meta.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]map[string]string)
+	for _, cm := range cmList.Items {
+		if !strings.HasPrefix(cm.Name, prefix) {
+			continue
+		}
+		result[strings.TrimPrefix(cm.Name, prefix)] = cm.Data
+	}
+
+	return result, nil
+}
+
 func (p *Plugin) deleteManifest(client kubernetes.Interface, deployName string) error {
 	name := p.getManifestConfigMapName(deployName)
 
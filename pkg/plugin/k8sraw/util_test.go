@@ -0,0 +1,54 @@
+package k8sraw
+
+import (
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/stretchr/testify/assert"
+	api "k8s.io/api/core/v1"
+	meta "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestListManifests(t *testing.T) {
+	p := &Plugin{
+		cluster:       &lang.Cluster{Metadata: lang.Metadata{Name: "cluster-us"}},
+		dataNamespace: "aptomi",
+	}
+	other := &Plugin{
+		cluster:       &lang.Cluster{Metadata: lang.Metadata{Name: "cluster-eu"}},
+		dataNamespace: "aptomi",
+	}
+
+	client := fake.NewSimpleClientset(
+		// config map, which doesn't belong to the plugin
+		&api.ConfigMap{
+			ObjectMeta: meta.ObjectMeta{Name: "unrelated", Namespace: "aptomi"},
+			Data:       map[string]string{"key": "value"},
+		},
+		// manifest stored in another namespace
+		&api.ConfigMap{
+			ObjectMeta: meta.ObjectMeta{Name: p.getManifestConfigMapName("a-0000000000003"), Namespace: "default"},
+			Data:       map[string]string{"manifest": "manifest-3", "namespace": "k8ns"},
+		},
+	)
+	assert.NoError(t, p.storeManifest(client, "a-0000000000001", "k8ns", "manifest-1"), "Manifest should be stored")
+	assert.NoError(t, p.storeManifest(client, "a-0000000000002-pre-create", "k8ns", "manifest-2"), "Manifest should be stored")
+	assert.NoError(t, other.storeManifest(client, "a-0000000000004", "k8ns", "manifest-4"), "Manifest should be stored")
+
+	manifests, err := p.listManifests(client)
+	if !assert.NoError(t, err, "Manifests should be listed") {
+		return
+	}
+	assert.Equal(t, map[string]map[string]string{
+		"a-0000000000001":            {"manifest": "manifest-1", "namespace": "k8ns"},
+		"a-0000000000002-pre-create": {"manifest": "manifest-2", "namespace": "k8ns"},
+	}, manifests, "Only manifests stored by the plugin for its cluster should be listed")
+
+	// deleted manifest is no longer listed
+	assert.NoError(t, p.deleteManifest(client, "a-0000000000001"), "Manifest should be deleted")
+	manifests, err = p.listManifests(client)
+	assert.NoError(t, err, "Manifests should be listed")
+	assert.NotContains(t, manifests, "a-0000000000001", "Deleted manifest should not be listed")
+	assert.Contains(t, manifests, "a-0000000000002-pre-create", "Manifest should be listed")
+}
@@ -2,6 +2,7 @@ package plugin
 
 import (
 	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/Aptomi/aptomi/pkg/config"
@@ -88,3 +89,12 @@ func (registry *defaultRegistry) ForCodeType(cluster *lang.Cluster, codeType str
 
 	return codePlugin, nil
 }
+
+func (registry *defaultRegistry) CodeTypes(cluster *lang.Cluster) []string {
+	result := []string{}
+	for codeType := range registry.codeTypes[cluster.Type] {
+		result = append(result, codeType)
+	}
+	sort.Strings(result)
+	return result
+}
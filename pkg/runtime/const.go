@@ -12,4 +12,7 @@ const (
 
 	// EmptyName is the default empty name for runtime objects
 	EmptyName = ""
+
+	// SystemUser is a name of the user, on behalf of whom Aptomi makes changes to the policy
+	SystemUser = "aptomi"
 )
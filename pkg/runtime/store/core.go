@@ -14,6 +14,8 @@ type Core interface {
 	Policy
 	Revision
 	ActualState
+	RetryState
+	EnforcerPause
 }
 
 // Policy represents database operations for Policy object
@@ -23,12 +25,15 @@ type Policy interface {
 	InitPolicy() error
 	UpdatePolicy(updated []lang.Base, performedBy string) (changed bool, data *engine.PolicyData, err error)
 	DeleteFromPolicy(deleted []lang.Base, performedBy string) (changed bool, data *engine.PolicyData, err error)
+	RestorePolicy(restored []lang.Base, deleted []lang.Base, performedBy string) (changed bool, data *engine.PolicyData, err error)
 }
 
 // Revision represents database operations for Revision object
 type Revision interface {
 	NewRevision(policyGen runtime.Generation, desiredState *resolve.PolicyResolution, recalculateAll bool) (*engine.Revision, error)
 	GetDesiredState(*engine.Revision) (*resolve.PolicyResolution, error)
+	SaveRevisionPlan(revision *engine.Revision, plan *action.PlanRecord) error
+	GetRevisionPlan(gen runtime.Generation) (*engine.RevisionPlan, error)
 	GetRevision(gen runtime.Generation) (*engine.Revision, error)
 	UpdateRevision(revision *engine.Revision) error
 	NewRevisionResultUpdater(revision *engine.Revision) action.ApplyResultUpdater
@@ -42,3 +47,17 @@ type ActualState interface {
 	GetActualState() (*resolve.PolicyResolution, error)
 	NewActualStateUpdater(*resolve.PolicyResolution) actual.StateUpdater
 }
+
+// RetryState represents database operations for retry states of component instances, which failed to be applied
+type RetryState interface {
+	GetComponentRetryStates() (map[string]*action.ComponentRetryState, error)
+	ResetComponentRetryStates(componentKeys ...string) (int, error)
+	NewRetryTracker(config action.RetryConfig, policyGen runtime.Generation) (action.RetryTracker, error)
+}
+
+// EnforcerPause represents database operations for pauses of desired state enforcement
+type EnforcerPause interface {
+	GetEnforcerPauses() ([]*engine.EnforcerPause, error)
+	SaveEnforcerPause(pause *engine.EnforcerPause) error
+	DeleteEnforcerPause(name string) (bool, error)
+}
@@ -0,0 +1,57 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+)
+
+// GetEnforcerPauses returns all pauses of desired state enforcement, sorted by name
+func (ds *defaultStore) GetEnforcerPauses() ([]*engine.EnforcerPause, error) {
+	objs, err := ds.store.List(runtime.KeyFromParts(runtime.SystemNS, engine.EnforcerPauseObject.Kind, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error while getting enforcer pauses: %s", err)
+	}
+
+	result := []*engine.EnforcerPause{}
+	for _, obj := range objs {
+		if pause, ok := obj.(*engine.EnforcerPause); ok {
+			result = append(result, pause)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetName() < result[j].GetName()
+	})
+
+	return result, nil
+}
+
+// SaveEnforcerPause saves a given pause of desired state enforcement, replacing the existing one with the same name
+func (ds *defaultStore) SaveEnforcerPause(pause *engine.EnforcerPause) error {
+	_, err := ds.store.Save(pause)
+	if err != nil {
+		return fmt.Errorf("error while saving enforcer pause '%s': %s", pause.GetName(), err)
+	}
+	return nil
+}
+
+// DeleteEnforcerPause deletes pause of desired state enforcement with a given name. It returns false if there was no
+// such pause
+func (ds *defaultStore) DeleteEnforcerPause(name string) (bool, error) {
+	key := runtime.KeyFromParts(runtime.SystemNS, engine.EnforcerPauseObject.Kind, name)
+	obj, err := ds.store.Get(key)
+	if err != nil {
+		return false, fmt.Errorf("error while getting enforcer pause '%s': %s", name, err)
+	}
+	if obj == nil {
+		return false, nil
+	}
+
+	err = ds.store.Delete(key)
+	if err != nil {
+		return false, fmt.Errorf("error while deleting enforcer pause '%s': %s", name, err)
+	}
+	return true, nil
+}
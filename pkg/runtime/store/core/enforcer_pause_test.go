@@ -0,0 +1,46 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnforcerPauses(t *testing.T) {
+	ds, cleanup := newTestStore(t)
+	defer cleanup()
+
+	pauses, err := ds.GetEnforcerPauses()
+	assert.NoError(t, err, "Enforcer pauses should be loaded")
+	assert.Empty(t, pauses, "There should be no enforcer pauses initially")
+
+	// pauses get saved and returned sorted by name, while saving a pause with the same name replaces it
+	assert.NoError(t, ds.SaveEnforcerPause(engine.NewEnforcerPause("main", "", "reason", "alice")), "Enforcer pause should be saved")
+	assert.NoError(t, ds.SaveEnforcerPause(engine.NewEnforcerPause("", "cluster-us", "reason", "alice")), "Enforcer pause should be saved")
+	assert.NoError(t, ds.SaveEnforcerPause(engine.NewEnforcerPause("main", "", "another reason", "bob")), "Enforcer pause should be saved")
+
+	pauses, err = ds.GetEnforcerPauses()
+	assert.NoError(t, err, "Enforcer pauses should be loaded")
+	if assert.Len(t, pauses, 2, "Pause with the same name should be replaced") {
+		assert.Equal(t, "cluster#cluster-us", pauses[0].GetName(), "Enforcer pauses should be sorted by name")
+		assert.Equal(t, "namespace#main", pauses[1].GetName(), "Enforcer pauses should be sorted by name")
+		assert.Equal(t, "bob", pauses[1].PausedBy, "Pause should be replaced by the latest one")
+		assert.Equal(t, "another reason", pauses[1].Reason, "Pause should be replaced by the latest one")
+	}
+
+	// only existing pauses get deleted
+	deleted, err := ds.DeleteEnforcerPause(engine.EnforcerPauseName("main", ""))
+	assert.NoError(t, err, "Enforcer pause should be deleted")
+	assert.True(t, deleted, "Existing enforcer pause should be deleted")
+
+	deleted, err = ds.DeleteEnforcerPause(engine.EnforcerPauseName("", ""))
+	assert.NoError(t, err, "Deleting non-existing enforcer pause should not fail")
+	assert.False(t, deleted, "Non-existing enforcer pause should not be deleted")
+
+	pauses, err = ds.GetEnforcerPauses()
+	assert.NoError(t, err, "Enforcer pauses should be loaded")
+	if assert.Len(t, pauses, 1, "Only one enforcer pause should be left") {
+		assert.Equal(t, "cluster#cluster-us", pauses[0].GetName(), "Pause for cluster should be left")
+	}
+}
@@ -99,12 +99,7 @@ func (ds *defaultStore) UpdatePolicy(updatedObjects []lang.Base, performedBy str
 	}
 
 	if changed {
-		// update metadata before saving policy data (to capture who and when edited the policy)
-		policyData.Metadata.UpdatedAt = time.Now()
-		policyData.Metadata.UpdatedBy = performedBy
-
-		// save policy data
-		_, err = ds.store.Save(policyData)
+		err = ds.savePolicyData(policyData, performedBy)
 		if err != nil {
 			return false, nil, err
 		}
@@ -121,7 +116,7 @@ func (ds *defaultStore) InitPolicy() error {
 		Metadata: engine.PolicyDataMetadata{
 			Generation: runtime.FirstGen,
 			UpdatedAt:  time.Now(),
-			UpdatedBy:  "aptomi",
+			UpdatedBy:  runtime.SystemUser,
 		},
 		Objects: make(map[string]map[string]map[string]runtime.Generation),
 	}
@@ -148,27 +143,70 @@ func (ds *defaultStore) DeleteFromPolicy(deleted []lang.Base, performedBy string
 		return false, nil, err
 	}
 
+	policyChanged, err := ds.removeFromPolicyData(policyData, deleted)
+	if err != nil {
+		return false, nil, err
+	}
+
+	if policyChanged {
+		err = ds.savePolicyData(policyData, performedBy)
+		if err != nil {
+			return false, nil, err
+		}
+	}
+
+	return policyChanged, policyData, nil
+}
+
+// RestorePolicy makes policy refer to previously saved generations of provided objects (restored) and deletes provided
+// objects from policy (deleted). All changes are made within a single policy generation. If restored object has been
+// modified (e.g. its creation time got reset), it will be saved as a new generation
+func (ds *defaultStore) RestorePolicy(restored []lang.Base, deleted []lang.Base, performedBy string) (bool, *engine.PolicyData, error) {
+	// we should process only a single policy update request at once
+	ds.policyChangeLock.Lock()
+	defer ds.policyChangeLock.Unlock()
+
+	policyData, err := ds.GetPolicyData(runtime.LastGen)
+	if err != nil {
+		return false, nil, err
+	}
+	if policyData == nil {
+		panic(fmt.Sprintf("cannot retrieve last policy from the store, policyData is nil"))
+	}
+
 	policyChanged := false
-	for _, obj := range deleted {
-		if policyData.Remove(obj) {
-			policyChanged = true
+	for _, obj := range restored {
+		// object must exist in the store with the given generation
+		existingObj, errStore := ds.store.GetGen(runtime.KeyForStorable(obj), obj.GetGeneration())
+		if errStore != nil {
+			return false, nil, errStore
+		}
+		if existingObj == nil {
+			return false, nil, fmt.Errorf("can't restore object which doesn't exist in the store: %s (gen %d)", runtime.KeyForStorable(obj), obj.GetGeneration())
+		}
+		if obj.IsDeleted() {
+			return false, nil, fmt.Errorf("objects with deleted=true can't be restored: %s", runtime.KeyForStorable(obj))
 		}
 
-		if !obj.IsDeleted() {
-			obj.SetDeleted(true)
-			_, err = ds.store.Save(obj)
-			if err != nil {
-				return false, nil, fmt.Errorf("error while setting deleted=true for %s: %s", runtime.KeyForStorable(obj), err)
-			}
+		_, err = ds.store.Save(obj)
+		if err != nil {
+			return false, nil, err
+		}
+
+		if policyData.Objects[obj.GetNamespace()][obj.GetKind()][obj.GetName()] != obj.GetGeneration() {
+			policyData.Add(obj)
+			policyChanged = true
 		}
 	}
 
-	if policyChanged {
-		policyData.Metadata.UpdatedAt = time.Now()
-		policyData.Metadata.UpdatedBy = performedBy
+	deletedChanged, err := ds.removeFromPolicyData(policyData, deleted)
+	if err != nil {
+		return false, nil, err
+	}
+	policyChanged = policyChanged || deletedChanged
 
-		// save policy data
-		_, err = ds.store.Save(policyData)
+	if policyChanged {
+		err = ds.savePolicyData(policyData, performedBy)
 		if err != nil {
 			return false, nil, err
 		}
@@ -176,3 +214,31 @@ func (ds *defaultStore) DeleteFromPolicy(deleted []lang.Base, performedBy string
 
 	return policyChanged, policyData, nil
 }
+
+// removeFromPolicyData removes provided objects from policy data and marks them as deleted in the store
+func (ds *defaultStore) removeFromPolicyData(policyData *engine.PolicyData, deleted []lang.Base) (bool, error) {
+	changed := false
+	for _, obj := range deleted {
+		if policyData.Remove(obj) {
+			changed = true
+		}
+
+		if !obj.IsDeleted() {
+			obj.SetDeleted(true)
+			_, err := ds.store.Save(obj)
+			if err != nil {
+				return false, fmt.Errorf("error while setting deleted=true for %s: %s", runtime.KeyForStorable(obj), err)
+			}
+		}
+	}
+	return changed, nil
+}
+
+// savePolicyData saves policy data with updated metadata (to capture who and when edited the policy)
+func (ds *defaultStore) savePolicyData(policyData *engine.PolicyData, performedBy string) error {
+	policyData.Metadata.UpdatedAt = time.Now()
+	policyData.Metadata.UpdatedBy = performedBy
+
+	_, err := ds.store.Save(policyData)
+	return err
+}
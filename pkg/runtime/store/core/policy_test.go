@@ -0,0 +1,118 @@
+package core
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/runtime/store"
+	"github.com/Aptomi/aptomi/pkg/runtime/store/generic/bolt"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRestorePolicy(t *testing.T) {
+	ds, cleanup := newTestStore(t)
+	defer cleanup()
+
+	// create policy with a service and a dependency
+	service := makeService("service", 0)
+	dependency := &lang.Dependency{
+		TypeKind:  lang.DependencyObject.GetTypeKind(),
+		Metadata:  lang.Metadata{Namespace: "main", Name: "dependency"},
+		User:      "alice",
+		Contract:  "contract",
+		CreatedAt: time.Now().Add(-time.Hour).UTC(),
+	}
+	changed, targetPolicyData, err := ds.UpdatePolicy([]lang.Base{service, dependency}, "alice")
+	assert.NoError(t, err, "Policy should be updated")
+	assert.True(t, changed, "Policy should be changed")
+
+	// change the service and delete the dependency
+	serviceUpdated := makeService("service", 0)
+	serviceUpdated.Labels = map[string]string{"changed": "true"}
+	_, _, err = ds.UpdatePolicy([]lang.Base{serviceUpdated}, "alice")
+	assert.NoError(t, err, "Policy should be updated")
+	_, policyData, err := ds.DeleteFromPolicy([]lang.Base{dependency}, "alice")
+	assert.NoError(t, err, "Dependency should be deleted from policy")
+	assert.Equal(t, runtime.Generation(2), policyData.Objects["main"][lang.ServiceObject.Kind]["service"], "Service should have its second generation in policy")
+	assert.NotContains(t, policyData.Objects["main"][lang.DependencyObject.Kind], "dependency", "Dependency should be deleted from policy")
+
+	// restore the original service and dependency with reset creation time
+	targetPolicy, _, err := ds.GetPolicy(targetPolicyData.GetGeneration())
+	if !assert.NoError(t, err, "Target policy should be loaded") {
+		return
+	}
+	serviceRestored, _ := targetPolicy.GetObject(lang.ServiceObject.Kind, "service", "main")
+	dependencyRestoredObj, _ := targetPolicy.GetObject(lang.DependencyObject.Kind, "dependency", "main")
+	dependencyRestored := dependencyRestoredObj.(*lang.Dependency)
+	dependencyRestored.CreatedAt = time.Now().UTC()
+
+	changed, policyData, err = ds.RestorePolicy([]lang.Base{serviceRestored.(lang.Base), dependencyRestored}, nil, "bob")
+	assert.NoError(t, err, "Policy should be restored")
+	assert.True(t, changed, "Policy should be changed")
+	assert.Equal(t, "bob", policyData.Metadata.UpdatedBy, "Policy should be updated by bob")
+	assert.Equal(t, runtime.Generation(1), policyData.Objects["main"][lang.ServiceObject.Kind]["service"], "Service should be restored to its first generation")
+	assert.Equal(t, runtime.Generation(3), policyData.Objects["main"][lang.DependencyObject.Kind]["dependency"], "Modified dependency should be saved as a new generation")
+
+	policy, _, err := ds.GetPolicy(runtime.LastGen)
+	if assert.NoError(t, err, "Restored policy should be loaded") {
+		obj, _ := policy.GetObject(lang.DependencyObject.Kind, "dependency", "main")
+		if assert.NotNil(t, obj, "Dependency should be present in restored policy") {
+			assert.False(t, obj.(*lang.Dependency).Deleted, "Restored dependency should not be marked as deleted")
+			assert.WithinDuration(t, dependencyRestored.CreatedAt, obj.(*lang.Dependency).CreatedAt, time.Second, "Restored dependency should have updated creation time")
+		}
+	}
+
+	// restoring the same objects again doesn't change the policy, while deleting the service does
+	changed, _, err = ds.RestorePolicy([]lang.Base{serviceRestored.(lang.Base), dependencyRestored}, nil, "bob")
+	assert.NoError(t, err, "Policy should be restored")
+	assert.False(t, changed, "Policy should not be changed")
+
+	changed, policyData, err = ds.RestorePolicy(nil, []lang.Base{serviceRestored.(lang.Base)}, "bob")
+	assert.NoError(t, err, "Service should be deleted")
+	assert.True(t, changed, "Policy should be changed")
+	assert.NotContains(t, policyData.Objects["main"][lang.ServiceObject.Kind], "service", "Service should be deleted from policy")
+
+	// objects, which don't exist in the store, can't be restored
+	_, _, err = ds.RestorePolicy([]lang.Base{makeService("missing", 5)}, nil, "bob")
+	assert.Error(t, err, "Object which doesn't exist in the store should not be restored")
+}
+
+func makeService(name string, gen runtime.Generation) *lang.Service {
+	return &lang.Service{
+		TypeKind: lang.ServiceObject.GetTypeKind(),
+		Metadata: lang.Metadata{Namespace: "main", Name: name, Generation: gen},
+	}
+}
+
+func newTestStore(t *testing.T) (*defaultStore, func()) {
+	t.Helper()
+
+	file, err := ioutil.TempFile("", "aptomi-store-test-")
+	if err != nil {
+		t.Fatalf("unable to create temp file: %s", err)
+	}
+	assert.NoError(t, file.Close(), "Temp file should be closed")
+
+	registry := runtime.NewRegistry().Append(store.Objects...)
+	b := bolt.NewGenericStore(registry)
+	err = b.Open(config.DB{Connection: file.Name()})
+	if err != nil {
+		t.Fatalf("unable to open store: %s", err)
+	}
+
+	ds := NewStore(b).(*defaultStore)
+	err = ds.InitPolicy()
+	if err != nil {
+		t.Fatalf("unable to init policy: %s", err)
+	}
+
+	return ds, func() {
+		assert.NoError(t, b.Close(), "Store should be closed")
+		assert.NoError(t, os.Remove(file.Name()), "Temp file should be removed")
+	}
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	"fmt"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/runtime/store"
+)
+
+// GetComponentRetryStates returns retry states for all component instances, which failed to be applied
+func (ds *defaultStore) GetComponentRetryStates() (map[string]*action.ComponentRetryState, error) {
+	objs, err := ds.store.List(runtime.KeyFromParts(runtime.SystemNS, action.ComponentRetryStateObject.Kind, ""))
+	if err != nil {
+		return nil, fmt.Errorf("error while getting all component retry states: %s", err)
+	}
+
+	result := make(map[string]*action.ComponentRetryState)
+	for _, obj := range objs {
+		if state, ok := obj.(*action.ComponentRetryState); ok {
+			result[state.ComponentKey] = state
+		}
+	}
+
+	return result, nil
+}
+
+// ResetComponentRetryStates deletes retry states for given component instances (or for all component instances,
+// if no keys are given) and returns the number of deleted retry states
+func (ds *defaultStore) ResetComponentRetryStates(componentKeys ...string) (int, error) {
+	states, err := ds.GetComponentRetryStates()
+	if err != nil {
+		return 0, err
+	}
+
+	if len(componentKeys) == 0 {
+		for key := range states {
+			componentKeys = append(componentKeys, key)
+		}
+	}
+
+	deleted := 0
+	for _, key := range componentKeys {
+		if _, ok := states[key]; !ok {
+			continue
+		}
+		err = ds.store.Delete(storableKeyForRetryState(key))
+		if err != nil {
+			return deleted, fmt.Errorf("error while deleting retry state for component instance '%s': %s", key, err)
+		}
+		deleted++
+	}
+
+	return deleted, nil
+}
+
+// NewRetryTracker creates a new implementation of RetryTracker, which persists retry states in the store
+func (ds *defaultStore) NewRetryTracker(config action.RetryConfig, policyGen runtime.Generation) (action.RetryTracker, error) {
+	states, err := ds.GetComponentRetryStates()
+	if err != nil {
+		return nil, err
+	}
+
+	tracker := action.NewRetryTrackerImpl(config, policyGen)
+	tracker.States = states
+
+	return &retryTracker{
+		RetryTrackerImpl: tracker,
+		store:            ds.store,
+	}, nil
+}
+
+// retryTracker is a thread-safe implementation of RetryTracker, which saves retry state on every change
+type retryTracker struct {
+	*action.RetryTrackerImpl
+	store store.Generic
+}
+
+// RecordSuccess resets retry state for a given component instance and deletes it from the store (regardless of
+// policy generation it was recorded for)
+func (tracker *retryTracker) RecordSuccess(componentKey string) {
+	if !tracker.HasState(componentKey) {
+		return
+	}
+
+	tracker.RetryTrackerImpl.RecordSuccess(componentKey)
+	err := tracker.store.Delete(storableKeyForRetryState(componentKey))
+	if err != nil {
+		panic(fmt.Sprintf("error while deleting retry state for component instance '%s': %s", componentKey, err))
+	}
+}
+
+// RecordFailure records a failed attempt for a given component instance and saves retry state to the store
+func (tracker *retryTracker) RecordFailure(componentKey string, err error) {
+	tracker.RetryTrackerImpl.RecordFailure(componentKey, err)
+	_, saveErr := tracker.store.Save(tracker.GetState(componentKey))
+	if saveErr != nil {
+		panic(fmt.Sprintf("error while saving retry state for component instance '%s': %s", componentKey, saveErr))
+	}
+}
+
+func storableKeyForRetryState(componentKey string) string {
+	return runtime.KeyFromParts(runtime.SystemNS, action.ComponentRetryStateObject.Kind, componentKey)
+}
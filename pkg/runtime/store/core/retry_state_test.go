@@ -0,0 +1,43 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryTrackerRecordSuccess(t *testing.T) {
+	ds, cleanup := newTestStore(t)
+	defer cleanup()
+
+	config := action.RetryConfig{Backoff: time.Minute, MaxBackoff: time.Hour, MaxAttempts: 5}
+
+	// failures get recorded for policy gen 1
+	tracker, err := ds.NewRetryTracker(config, runtime.Generation(1))
+	if !assert.NoError(t, err, "Retry tracker should be created") {
+		return
+	}
+	tracker.RecordFailure("component-a", fmt.Errorf("failure"))
+	tracker.RecordFailure("component-b", fmt.Errorf("failure"))
+
+	states, err := ds.GetComponentRetryStates()
+	assert.NoError(t, err, "Retry states should be loaded")
+	assert.Len(t, states, 2, "Retry states should be saved for both failed component instances")
+
+	// success for policy gen 2 deletes retry state recorded for the previous policy gen
+	tracker, err = ds.NewRetryTracker(config, runtime.Generation(2))
+	if !assert.NoError(t, err, "Retry tracker should be created") {
+		return
+	}
+	tracker.RecordSuccess("component-a")
+	tracker.RecordSuccess("component-unknown")
+
+	states, err = ds.GetComponentRetryStates()
+	assert.NoError(t, err, "Retry states should be loaded")
+	assert.NotContains(t, states, "component-a", "Retry state from previous policy gen should be deleted on success")
+	assert.Contains(t, states, "component-b", "Retry state of another component instance should be kept")
+}
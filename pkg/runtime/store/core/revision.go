@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/runtime"
 )
@@ -125,7 +126,7 @@ func (ds *defaultStore) GetFirstUnprocessedRevision() (*engine.Revision, error)
 		revision := revisionObj.(*engine.Revision) // nolint: errcheck
 
 		// if this revision has been processed, we don't need to consider it
-		if revision.Status == engine.RevisionStatusCompleted || revision.Status == engine.RevisionStatusError {
+		if revision.Status == engine.RevisionStatusCompleted || revision.Status == engine.RevisionStatusAwaitingApproval || revision.Status == engine.RevisionStatusDeferred || revision.Status == engine.RevisionStatusError {
 			continue
 		}
 
@@ -150,3 +151,30 @@ func (ds *defaultStore) GetDesiredState(revision *engine.Revision) (*resolve.Pol
 
 	return &desiredState.Resolution, nil
 }
+
+// SaveRevisionPlan saves the action plan record, associated with the revision
+func (ds *defaultStore) SaveRevisionPlan(revision *engine.Revision, plan *action.PlanRecord) error {
+	_, err := ds.store.Save(engine.NewRevisionPlan(revision, plan))
+	if err != nil {
+		return fmt.Errorf("error while saving plan for revision %d: %s", revision.GetGeneration(), err)
+	}
+	return nil
+}
+
+// GetRevisionPlan returns the action plan record, associated with the revision. If revision doesn't have plan
+// recorded, nil is returned
+func (ds *defaultStore) GetRevisionPlan(gen runtime.Generation) (*engine.RevisionPlan, error) {
+	obj, err := ds.store.Get(runtime.KeyFromParts(runtime.SystemNS, engine.RevisionPlanObject.Kind, engine.GetRevisionPlanName(gen)))
+	if err != nil {
+		return nil, err
+	}
+	if obj == nil {
+		return nil, nil
+	}
+	revisionPlan, ok := obj.(*engine.RevisionPlan)
+	if !ok {
+		return nil, fmt.Errorf("tried to load revision plan from the store, but loaded %v", obj)
+	}
+
+	return revisionPlan, nil
+}
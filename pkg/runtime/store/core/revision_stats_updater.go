@@ -52,12 +52,31 @@ func (updater *RevisionResultUpdaterImpl) AddSkipped() {
 	updater.save()
 }
 
+// SetCancelled marks revision result as cancelled
+func (updater *RevisionResultUpdaterImpl) SetCancelled() {
+	updater.revision.Result.Cancelled = true
+	updater.save()
+}
+
+// SetAborted marks revision result as aborted, recording the reason
+func (updater *RevisionResultUpdaterImpl) SetAborted(reason string) {
+	updater.revision.Result.Aborted = true
+	updater.revision.Result.AbortReason = reason
+	updater.save()
+}
+
 // Done saves the revision when all actions have been processed
 func (updater *RevisionResultUpdaterImpl) Done() *action.ApplyResult {
 	if updater.revision.Result.Success+updater.revision.Result.Failed+updater.revision.Result.Skipped != updater.revision.Result.Total {
 		panic(fmt.Sprintf("error while applying actions: %d (success) + %d (failed) + %d (skipped) != %d (total)", updater.revision.Result.Success, updater.revision.Result.Failed, updater.revision.Result.Skipped, updater.revision.Result.Total))
 	}
 	updater.revision.Status = engine.RevisionStatusCompleted
+	if len(updater.revision.Deferred) > 0 || len(updater.revision.Paused) > 0 {
+		updater.revision.Status = engine.RevisionStatusDeferred
+	}
+	if len(updater.revision.PendingApproval) > 0 {
+		updater.revision.Status = engine.RevisionStatusAwaitingApproval
+	}
 	updater.revision.AppliedAt = time.Now()
 	updater.save()
 	return updater.revision.Result
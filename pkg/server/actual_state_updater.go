@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"runtime/debug"
 	"sync"
@@ -76,6 +77,7 @@ func (server *Server) actualStateUpdate() error {
 
 func refreshEndpoints(desiredPolicy *lang.Policy, actualState *resolve.PolicyResolution, actualStateUpdater actual.StateUpdater, plugins plugin.Registry, eventLog *event.Log, maxConcurrentActions int, noop bool) {
 	context := action.NewContext(
+		context.Background(),
 		desiredPolicy,
 		nil, // not needed for endpoints action
 		actualStateUpdater,
@@ -0,0 +1,106 @@
+package server
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	log "github.com/sirupsen/logrus"
+)
+
+func (server *Server) dependencyExpireLoop() error {
+	for {
+		err := server.dependencyExpire()
+		if err != nil {
+			log.Errorf("error while removing expired dependencies: %s", err)
+		}
+
+		time.Sleep(server.cfg.Expirer.Interval)
+	}
+}
+
+func (server *Server) dependencyExpire() error {
+	server.dependencyExpirationIdx++
+
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("panic while removing expired dependencies: %s", err)
+			log.Errorf(string(debug.Stack()))
+		}
+	}()
+
+	// Make sure to take the mutex, so policy doesn't change while we are removing expired dependencies
+	server.policyAndRevisionUpdateMutex.Lock()
+	defer server.policyAndRevisionUpdateMutex.Unlock()
+
+	// Get the latest policy
+	policy, _, err := server.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		return fmt.Errorf("error while getting last policy: %s", err)
+	}
+
+	// if policy is not found, it means it somehow was not initialized correctly. let's return error
+	if policy == nil {
+		return fmt.Errorf("last policy is nil, does not exist in the store")
+	}
+
+	// find expired dependencies
+	now := time.Now()
+	expired := []lang.Base{}
+	for _, obj := range policy.GetObjectsByKind(lang.DependencyObject.Kind) {
+		dependency := obj.(*lang.Dependency) // nolint: errcheck
+		if dependency.IsExpired(now) {
+			expiresAt, _ := dependency.GetExpirationTime()
+			log.Infof("(expire-%d) Dependency %s expired at %s, removing it from policy", server.dependencyExpirationIdx, runtime.KeyForStorable(dependency), expiresAt)
+			expired = append(expired, dependency)
+		}
+	}
+	if len(expired) == 0 {
+		return nil
+	}
+
+	// remove expired dependencies and make sure policy is still valid
+	for _, obj := range expired {
+		policy.RemoveObject(obj)
+	}
+	err = policy.Validate()
+	if err != nil {
+		return fmt.Errorf("policy is invalid after removing expired dependencies: %s", err)
+	}
+
+	// resolve updated policy
+	actualState, err := server.store.GetActualState()
+	if err != nil {
+		return fmt.Errorf("error while loading actual state: %s", err)
+	}
+	eventLog := event.NewLog(log.DebugLevel, fmt.Sprintf("expire-%d", server.dependencyExpirationIdx)).AddConsoleHook(server.cfg.GetLogLevel())
+	desiredState := resolve.NewPolicyResolver(policy, server.externalData, eventLog).WithDeployNames(actualState).ResolveAllDependencies()
+	err = desiredState.Validate(policy)
+	if err != nil {
+		return fmt.Errorf("expired dependencies cannot be removed: %s", err)
+	}
+
+	// remove expired dependencies from the policy on behalf of the system user
+	changed, policyData, err := server.store.DeleteFromPolicy(expired, runtime.SystemUser)
+	if err != nil {
+		return fmt.Errorf("error while removing expired dependencies from policy: %s", err)
+	}
+
+	// if policy changed, create a new revision and trigger enforcement, so instances get destroyed
+	if changed {
+		revision, revisionErr := server.store.NewRevision(policyData.GetGeneration(), desiredState, false)
+		if revisionErr != nil {
+			return fmt.Errorf("unable to create new revision for policy gen %d: %s", policyData.GetGeneration(), revisionErr)
+		}
+
+		log.Infof("(expire-%d) Removed %d expired dependencies, policy gen %d, revision %d", server.dependencyExpirationIdx, len(expired), policyData.GetGeneration(), revision.GetGeneration())
+
+		server.runDesiredStateEnforcement <- true
+	}
+
+	return nil
+}
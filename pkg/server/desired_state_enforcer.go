@@ -2,6 +2,8 @@ package server
 
 import (
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Aptomi/aptomi/pkg/engine"
@@ -36,6 +38,16 @@ func (server *Server) desiredStateEnforceLoop() error {
 	)
 	prometheus.MustRegister(server.desiredStateEnforcementDuration)
 
+	server.enforcerPauses = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "aptomi_desired_state_enforcer_paused",
+			Help:        "Whether desired state enforcement is paused (globally, or for a namespace or cluster)",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"pause"},
+	)
+	prometheus.MustRegister(server.enforcerPauses)
+
 	for {
 		err := server.desiredStateEnforce()
 		if err != nil {
@@ -77,18 +89,121 @@ func (server *Server) getRevisionForProcessing() (*engine.Revision, error) {
 		return nil, fmt.Errorf("unable to load latest revision: %s", err)
 	}
 
-	// now, given that we retrieved the last revision, when do we need to retry it? in one of two cases:
+	// now, given that we retrieved the last revision, when do we need to retry it? in one of four cases:
 	// - it's either in error status (something really bad happened)
-	// - it completed, but some actions failed and they need to be retried
-	if lastRevision != nil && (lastRevision.Status == engine.RevisionStatusError || (lastRevision.Status == engine.RevisionStatusCompleted && lastRevision.Result.Failed > 0)) {
+	// - it has been cancelled, so the rest of its actions need to be applied (after retry backoff, so cancellation
+	//   doesn't get overridden immediately)
+	// - it has been processed in scope, while it replaced the previous revision for the same policy, so failed, deferred
+	//   and held actions outside of its scope need to be applied as well
+	// - it completed (or it's awaiting approval or deferred), but some actions failed and they need to be retried (unless
+	//   all failed component instances are still waiting for their next retry)
+	if lastRevision != nil && lastRevision.Status == engine.RevisionStatusError {
 		log.Infof("(enforce-%d) Found last revision %d which needs to be retried", server.desiredStateEnforcementIdx, lastRevision.GetGeneration())
 		return lastRevision, nil
 	}
+	if lastRevision != nil && lastRevision.Result.Cancelled {
+		if lastRevision.Status != engine.RevisionStatusInProgress && server.isCancelledRetryDue(lastRevision) {
+			log.Infof("(enforce-%d) Found last revision %d which has been cancelled and needs to be processed again", server.desiredStateEnforcementIdx, lastRevision.GetGeneration())
+			return lastRevision, nil
+		}
+		return nil, nil
+	}
+	if lastRevision != nil && (lastRevision.Status == engine.RevisionStatusCompleted || lastRevision.Status == engine.RevisionStatusAwaitingApproval || lastRevision.Status == engine.RevisionStatusDeferred) && !lastRevision.Scope.IsEmpty() {
+		log.Infof("(enforce-%d) Found last revision %d which has been processed in scope (%s) and needs to be processed again for all component instances", server.desiredStateEnforcementIdx, lastRevision.GetGeneration(), lastRevision.Scope)
+		return lastRevision, nil
+	}
+	if lastRevision != nil && (lastRevision.Status == engine.RevisionStatusCompleted || lastRevision.Status == engine.RevisionStatusAwaitingApproval || lastRevision.Status == engine.RevisionStatusDeferred) && lastRevision.Result.Failed > 0 {
+		retryDue, retryErr := server.isRetryDue(lastRevision.PolicyGen)
+		if retryErr != nil {
+			return nil, fmt.Errorf("unable to load component retry states: %s", retryErr)
+		}
+		if retryDue {
+			log.Infof("(enforce-%d) Found last revision %d which needs to be retried", server.desiredStateEnforcementIdx, lastRevision.GetGeneration())
+			return lastRevision, nil
+		}
+	}
+
+	// the last revision also needs to be processed again, once deferred changes are expected to be allowed by
+	// maintenance (or on every run, if it's not known when it's going to happen or enforcement is paused for some of
+	// component instances)
+	if lastRevision != nil && (isDeferredDue(lastRevision) || len(lastRevision.Paused) > 0) && lastRevision.Status != engine.RevisionStatusInProgress {
+		log.Infof("(enforce-%d) Found last revision %d with deferred changes, which may be allowed now", server.desiredStateEnforcementIdx, lastRevision.GetGeneration())
+		return lastRevision, nil
+	}
+
+	// the last revision also needs to be processed again, if drifted component instances have to be repaired (unless
+	// some actions failed, so they are getting retried with backoff as usual)
+	if lastRevision != nil && (lastRevision.Status == engine.RevisionStatusCompleted || lastRevision.Status == engine.RevisionStatusAwaitingApproval || lastRevision.Status == engine.RevisionStatusDeferred) && lastRevision.Result.Failed == 0 {
+		driftRepair, driftErr := server.isDriftRepairNeeded(lastRevision)
+		if driftErr != nil {
+			return nil, fmt.Errorf("unable to load actual state: %s", driftErr)
+		}
+		if driftRepair {
+			log.Infof("(enforce-%d) Found last revision %d which needs to be processed to repair drifted component instances", server.desiredStateEnforcementIdx, lastRevision.GetGeneration())
+			return lastRevision, nil
+		}
+	}
 
 	// nothing to process
 	return nil, nil
 }
 
+// withoutKeys returns a list of given keys, excluding the ones which are present in a given exclusion list
+func withoutKeys(keys []string, excluded []string) []string {
+	excludedMap := make(map[string]bool)
+	for _, key := range excluded {
+		excludedMap[key] = true
+	}
+	result := []string{}
+	for _, key := range keys {
+		if !excludedMap[key] {
+			result = append(result, key)
+		}
+	}
+	return result
+}
+
+// withKeys returns a sorted list of given keys together with additional ones, without duplicates
+func withKeys(keys []string, additional []string) []string {
+	result := append(withoutKeys(keys, additional), additional...)
+	sort.Strings(result)
+	return result
+}
+
+// isDeferredDue returns true if changes deferred by maintenance in a given revision may be allowed now
+func isDeferredDue(revision *engine.Revision) bool {
+	return len(revision.Deferred) > 0 && !time.Now().Before(revision.DeferredUntil)
+}
+
+// isCancelledRetryDue returns true if a given cancelled revision can be processed again, which happens once retry
+// backoff has passed since it was cancelled
+func (server *Server) isCancelledRetryDue(revision *engine.Revision) bool {
+	return !time.Now().Before(revision.AppliedAt.Add(server.cfg.Enforcer.RetryBackoff))
+}
+
+// isRetryDue returns true if at least one of failed component instances can be retried now. If there are no failed
+// component instances being tracked (e.g. their retry states have been reset), it returns true as well
+func (server *Server) isRetryDue(policyGen runtime.Generation) (bool, error) {
+	states, err := server.store.GetComponentRetryStates()
+	if err != nil {
+		return false, err
+	}
+
+	tracked := false
+	now := time.Now()
+	for _, state := range states {
+		if !state.IsActive(policyGen) {
+			continue
+		}
+		if state.IsDue(now) {
+			return true, nil
+		}
+		tracked = true
+	}
+
+	return !tracked, nil
+}
+
 func (server *Server) desiredStateEnforce() error {
 	start := time.Now()
 	server.desiredStateEnforcementIdx++
@@ -103,6 +218,18 @@ func (server *Server) desiredStateEnforce() error {
 		}
 	}()
 
+	// enforcement can be paused globally (nothing gets processed) or for some of namespaces and clusters
+	pauses, err := server.getEnforcerPauses()
+	if err != nil {
+		return fmt.Errorf("unable to load enforcer pauses: %s", err)
+	}
+	for _, pause := range pauses {
+		if pause.IsGlobal() {
+			log.Infof("(enforce-%d) Enforcement is paused by '%s' since %s: %s", server.desiredStateEnforcementIdx, pause.PausedBy, pause.PausedAt.Format(time.RFC3339), pause.Reason)
+			return nil
+		}
+	}
+
 	// get the revision for processing
 	revision, err := server.getRevisionForProcessing()
 	if err != nil {
@@ -112,6 +239,12 @@ func (server *Server) desiredStateEnforce() error {
 		return nil
 	}
 
+	// scope only restricts the first run of revision. Once processed, scoped revision becomes the last revision for
+	// its policy, so it gets processed again (e.g. to retry failed actions) with all pending actions
+	if revision.Status != engine.RevisionStatusWaiting && revision.Status != engine.RevisionStatusInProgress && !revision.Scope.IsEmpty() {
+		revision.Scope = nil
+	}
+
 	// reset revision status and result
 	revision.Status = engine.RevisionStatusWaiting
 	revision.Result = &action.ApplyResult{}
@@ -146,8 +279,33 @@ func (server *Server) desiredStateEnforce() error {
 		stateDiff = diff.NewPolicyResolutionDiff(desiredState, actualState)
 	}
 
+	// if revision is scoped, only actions for component instances in its scope (and their prerequisites) get applied
+	actionPlan := stateDiff.ScopedActionPlan(revision.Scope)
+	if !revision.Scope.IsEmpty() {
+		log.Infof("(enforce-%d) Revision %d, policy gen %d: applying only actions in scope (%s)", server.desiredStateEnforcementIdx, revision.GetGeneration(), policyGen, revision.Scope)
+	}
+
+	// deletion of protected component instances is held until approved by a user (only deletions approved for this
+	// revision are released, while any new protected deletions get held again)
+	revision.PendingApproval = withoutKeys(apply.FindProtectedDeletions(actionPlan, policy, actualState), revision.Approved)
+	if len(revision.PendingApproval) > 0 {
+		log.Warningf("(enforce-%d) Revision %d, policy gen %d: deletion of %d protected component instances is awaiting approval: %s", server.desiredStateEnforcementIdx, revision.GetGeneration(), policyGen, len(revision.PendingApproval), strings.Join(revision.PendingApproval, ", "))
+	}
+
+	// changes to component instances, which aren't allowed by maintenance windows and freezes at the moment, are deferred
+	revision.Deferred, revision.DeferredUntil = apply.FindDeferredChanges(actionPlan, policy, desiredState, actualState, time.Now())
+	if len(revision.Deferred) > 0 {
+		log.Warningf("(enforce-%d) Revision %d, policy gen %d: changes to %d component instances are deferred by maintenance until %s: %s", server.desiredStateEnforcementIdx, revision.GetGeneration(), policyGen, len(revision.Deferred), revision.DeferredUntil, strings.Join(revision.Deferred, ", "))
+	}
+
+	// changes to component instances in namespaces and clusters, for which enforcement is paused, are held as well
+	revision.Paused = findPausedChanges(actionPlan, desiredState, actualState, pauses)
+	if len(revision.Paused) > 0 {
+		log.Warningf("(enforce-%d) Revision %d, policy gen %d: changes to %d component instances are held while enforcement is paused: %s", server.desiredStateEnforcementIdx, revision.GetGeneration(), policyGen, len(revision.Paused), strings.Join(revision.Paused, ", "))
+	}
+
 	// policy changes while no actions needed to achieve desired state
-	actionCnt := stateDiff.ActionPlan.NumberOfActions()
+	actionCnt := actionPlan.NumberOfActions()
 	if actionCnt > 0 {
 		log.Infof("(enforce-%d) Revision %d, policy gen %d: %d actions need to be applied", server.desiredStateEnforcementIdx, revision.GetGeneration(), policyGen, actionCnt)
 	} else {
@@ -161,21 +319,69 @@ func (server *Server) desiredStateEnforce() error {
 		log.Infof("(enforce-%d) Applying actions", server.desiredStateEnforcementIdx)
 	}
 
-	// apply
+	// apply (revision can be cancelled via API while actions are being applied)
 	pluginRegistry := server.enforcerPluginRegistryFactory()
 	applyLog := event.NewLog(log.DebugLevel, fmt.Sprintf("enforce-%d-apply", server.desiredStateEnforcementIdx)).AddConsoleHook(server.cfg.GetLogLevel())
-	applier := apply.NewEngineApply(policy, desiredState, server.store.NewActualStateUpdater(actualState), server.externalData, pluginRegistry, stateDiff.ActionPlan, applyLog, server.store.NewRevisionResultUpdater(revision))
-	_, _ = applier.Apply(server.cfg.Enforcer.MaxConcurrentActions)
+	retryTracker, err := server.store.NewRetryTracker(action.RetryConfig{
+		Backoff:     server.cfg.Enforcer.RetryBackoff,
+		MaxBackoff:  server.cfg.Enforcer.RetryMaxBackoff,
+		MaxAttempts: server.cfg.Enforcer.RetryMaxAttempts,
+	}, policyGen)
+	if err != nil {
+		return fmt.Errorf("error while loading component retry states: %s", err)
+	}
+	failureBudget, err := server.getFailureBudget(revision)
+	if err != nil {
+		return err
+	}
+	concurrencyLimits, err := server.getConcurrencyLimits()
+	if err != nil {
+		return err
+	}
+	applier := apply.NewEngineApply(policy, desiredState, server.store.NewActualStateUpdater(actualState), server.externalData, pluginRegistry, actionPlan, applyLog, server.store.NewRevisionResultUpdater(revision), retryTracker).HoldDeletions(revision.PendingApproval).DeferChanges(append(append([]string{}, revision.Deferred...), revision.Paused...)).CheckPauses(server.isChangePaused).WaitForReadiness(server.cfg.Enforcer.ReadinessTimeout)
+	planErr := server.store.SaveRevisionPlan(revision, applier.GetPlanRecord())
+	if planErr != nil {
+		return fmt.Errorf("error while saving action plan: %s", planErr)
+	}
+	deployNames := getDeployNames(actualState)
+	ctx, done := server.revisionCanceller.Start(revision.GetGeneration())
+	_, _ = applier.Apply(ctx, concurrencyLimits, server.cfg.Enforcer.ActionTimeout, failureBudget)
+	done()
+	if revision.Result.Cancelled {
+		applyLog.NewEntry().Warningf("Revision %d has been cancelled", revision.GetGeneration())
+	}
+	if revision.Result.Aborted {
+		applyLog.NewEntry().Warningf("Revision %d has been aborted: %s", revision.GetGeneration(), revision.Result.AbortReason)
+	}
 
-	// save apply log
+	// changes, which got paused while actions were being applied, are held as well until enforcement is resumed
+	if pausedChanges := applier.GetPausedChanges(); len(pausedChanges) > 0 {
+		revision.Paused = withKeys(revision.Paused, pausedChanges)
+		if revision.Status == engine.RevisionStatusCompleted {
+			revision.Status = engine.RevisionStatusDeferred
+		}
+		log.Warningf("(enforce-%d) Revision %d, policy gen %d: changes to %d component instances are held, as enforcement has been paused while actions were being applied: %s", server.desiredStateEnforcementIdx, revision.GetGeneration(), policyGen, len(pausedChanges), strings.Join(pausedChanges, ", "))
+	}
+
+	// save apply log and the outcome of every action
 	revision.ApplyLog = applyLog.AsAPIEvents()
 	saveErr := server.store.UpdateRevision(revision)
 	if saveErr != nil {
 		return fmt.Errorf("error while saving revision with apply log: %s", saveErr)
 	}
+	planErr = server.store.SaveRevisionPlan(revision, applier.GetPlanRecord())
+	if planErr != nil {
+		return fmt.Errorf("error while saving action plan with action outcomes: %s", planErr)
+	}
 
 	log.Infof("(enforce-%d) Revision %d processed (actions: %d succeeded, %d failed, %d skipped)", server.desiredStateEnforcementIdx, revision.GetGeneration(), revision.Result.Success, revision.Result.Failed, revision.Result.Skipped)
 
+	// once code got swapped by blue/green update, consumers have to be updated to discover the running copy of code
+	swapErr := server.updateConsumersAfterSwap(deployNames)
+	if swapErr != nil {
+		return fmt.Errorf("error while updating consumers after blue/green swap: %s", swapErr)
+	}
+
 	// let's try again immediately until no actions were successfully applied
 	if revision.Result.Success > 0 {
 		// trigger enforcement again
@@ -186,3 +392,102 @@ func (server *Server) desiredStateEnforce() error {
 
 	return nil
 }
+
+// getDeployNames returns deploy names of all code component instances in a given actual state
+func getDeployNames(actualState *resolve.PolicyResolution) map[string]string {
+	result := make(map[string]string)
+	for key, instance := range actualState.ComponentInstanceMap {
+		if instance.IsCode {
+			result[key] = instance.GetDeployName()
+		}
+	}
+	return result
+}
+
+// updateConsumersAfterSwap checks whether code of any component instance got swapped by blue/green update (i.e. it's
+// now deployed under a different name than before) and, if so, resolves the latest policy against the updated actual
+// state. If it changes desired state (e.g. discovery params of consumers), a new revision gets created
+func (server *Server) updateConsumersAfterSwap(deployNamesBefore map[string]string) error {
+	actualState, err := server.store.GetActualState()
+	if err != nil {
+		return fmt.Errorf("error while getting actual state: %s", err)
+	}
+
+	swapped := []string{}
+	for key, deployName := range getDeployNames(actualState) {
+		if deployNameBefore, ok := deployNamesBefore[key]; ok && deployNameBefore != deployName {
+			swapped = append(swapped, key)
+		}
+	}
+	if len(swapped) == 0 {
+		return nil
+	}
+
+	// Make sure to take the mutex, so policy doesn't change while we are creating a new revision
+	server.policyAndRevisionUpdateMutex.Lock()
+	defer server.policyAndRevisionUpdateMutex.Unlock()
+
+	policy, policyGen, err := server.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		return fmt.Errorf("error while getting last policy: %s", err)
+	}
+	revision, err := server.store.GetLastRevisionForPolicy(policyGen)
+	if err != nil {
+		return fmt.Errorf("error while getting last revision for policy gen %d: %s", policyGen, err)
+	}
+	desiredState, err := server.store.GetDesiredState(revision)
+	if err != nil {
+		return fmt.Errorf("can't load desired state from revision: %s", err)
+	}
+
+	eventLog := event.NewLog(log.DebugLevel, fmt.Sprintf("enforce-%d-swap", server.desiredStateEnforcementIdx)).AddConsoleHook(server.cfg.GetLogLevel())
+	desiredStateUpdated := resolve.NewPolicyResolver(policy, server.externalData, eventLog).WithDeployNames(actualState).ResolveAllDependencies()
+	err = desiredStateUpdated.Validate(policy)
+	if err != nil {
+		return fmt.Errorf("policy gen %d can't be resolved after blue/green swap: %s", policyGen, err)
+	}
+	if diff.NewPolicyResolutionDiff(desiredStateUpdated, desiredState).ActionPlan.NumberOfActions() == 0 {
+		return nil
+	}
+
+	revisionUpdated, err := server.store.NewRevision(policyGen, desiredStateUpdated, false)
+	if err != nil {
+		return fmt.Errorf("unable to create new revision for policy gen %d: %s", policyGen, err)
+	}
+	log.Infof("(enforce-%d) Code of %d component instances got swapped by blue/green update (%s), created revision %d to update their consumers", server.desiredStateEnforcementIdx, len(swapped), strings.Join(swapped, ", "), revisionUpdated.GetGeneration())
+
+	server.runDesiredStateEnforcement <- true
+
+	return nil
+}
+
+// getFailureBudget returns failure budget for a given revision. Failure budget set on the revision itself takes
+// precedence over the one set in enforcer config
+func (server *Server) getFailureBudget(revision *engine.Revision) (*action.FailureBudget, error) {
+	value := server.cfg.Enforcer.FailureBudget
+	if len(revision.FailureBudget) > 0 {
+		value = revision.FailureBudget
+	}
+	failureBudget, err := action.ParseFailureBudget(value)
+	if err != nil {
+		return nil, fmt.Errorf("error while parsing failure budget for revision %d: %s", revision.GetGeneration(), err)
+	}
+	return failureBudget, nil
+}
+
+// getConcurrencyLimits returns concurrency limits for applying actions, as defined in enforcer config
+func (server *Server) getConcurrencyLimits() (action.ConcurrencyLimits, error) {
+	clusterLimits, err := action.ParseConcurrencyLimits(server.cfg.Enforcer.ClusterMaxConcurrentActions)
+	if err != nil {
+		return action.ConcurrencyLimits{}, fmt.Errorf("error while parsing cluster concurrency limits: %s", err)
+	}
+	codeTypeLimits, err := action.ParseConcurrencyLimits(server.cfg.Enforcer.CodeTypeMaxConcurrentActions)
+	if err != nil {
+		return action.ConcurrencyLimits{}, fmt.Errorf("error while parsing code type concurrency limits: %s", err)
+	}
+	return action.ConcurrencyLimits{
+		Global:   server.cfg.Enforcer.MaxConcurrentActions,
+		Cluster:  clusterLimits,
+		CodeType: codeTypeLimits,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action/component"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+func (server *Server) driftDetectLoop() error {
+	server.driftedComponentInstances = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name:        "aptomi_drifted_component_instances",
+			Help:        "Number of component instances, for which deployed code doesn't match the actual state",
+			ConstLabels: prometheus.Labels{"service": serviceName},
+		},
+		[]string{"drift"},
+	)
+	prometheus.MustRegister(server.driftedComponentInstances)
+
+	for {
+		err := server.driftDetect()
+		if err != nil {
+			log.Errorf("error while detecting drift: %s", err)
+		}
+
+		time.Sleep(server.cfg.Drift.Interval)
+	}
+}
+
+func (server *Server) driftDetect() error {
+	server.driftDetectionIdx++
+
+	defer func() {
+		if err := recover(); err != nil {
+			log.Errorf("panic while detecting drift: %s", err)
+			log.Errorf(string(debug.Stack()))
+		}
+	}()
+
+	// Get desired policy
+	desiredPolicy, _, err := server.store.GetPolicy(runtime.LastGen)
+	if err != nil {
+		return fmt.Errorf("error while getting last policy: %s", err)
+	}
+
+	// if policy is not found, it means it somehow was not initialized correctly. let's return error
+	if desiredPolicy == nil {
+		return fmt.Errorf("last policy is nil, does not exist in the store")
+	}
+
+	// Get actual state
+	actualState, err := server.store.GetActualState()
+	if err != nil {
+		return fmt.Errorf("error while getting actual state: %s", err)
+	}
+
+	// Make an event log
+	eventLog := event.NewLog(log.DebugLevel, fmt.Sprintf("drift-%d", server.driftDetectionIdx)).AddConsoleHook(server.cfg.GetLogLevel())
+
+	actualStateUpdater := server.store.NewActualStateUpdater(actualState)
+	context := action.NewContext(
+		context.Background(),
+		desiredPolicy,
+		nil, // not needed for drift action
+		actualStateUpdater,
+		nil, // not needed for drift action
+		server.updaterPluginRegistryFactory(),
+		eventLog,
+	)
+
+	// make sure we are converting panics into errors
+	fn := action.WrapParallelWithLimit(server.cfg.Drift.MaxConcurrentActions, func(act action.Interface) (errResult error) {
+		defer func() {
+			if err := recover(); err != nil {
+				errResult = fmt.Errorf("panic: %s\n%s", err, string(debug.Stack()))
+			}
+		}()
+		err := act.Apply(context)
+		if err != nil {
+			context.EventLog.NewEntry().Errorf("error while applying action '%s': %s", act, err)
+		}
+		return err
+	})
+
+	// check all code component instances
+	var wg sync.WaitGroup
+	for _, instance := range actualState.ComponentInstanceMap {
+		if !instance.IsCode {
+			continue
+		}
+		wg.Add(1)
+		go func(act action.Interface) {
+			defer wg.Done()
+
+			// if an error or panic happened in the action, we don't have to do anything special, we will just retry it next time
+			fn(act) // nolint: errcheck
+		}(component.NewDriftAction(instance.GetKey(), server.cfg.Drift.Repair))
+	}
+
+	// wait until all go routines are over
+	wg.Wait()
+
+	// report drifted component instances
+	missing, changed, repair := 0, 0, 0
+	for _, instance := range actualStateUpdater.GetUpdatedActualState().ComponentInstanceMap {
+		if !instance.IsDrifted() {
+			continue
+		}
+		if instance.DriftMissing {
+			missing++
+		} else {
+			changed++
+		}
+		if instance.DriftRepair {
+			repair++
+		}
+	}
+	server.driftedComponentInstances.WithLabelValues("missing").Set(float64(missing))
+	server.driftedComponentInstances.WithLabelValues("changed").Set(float64(changed))
+
+	log.Infof("(drift-%d) Drift detection completed (drifted component instances: %d missing, %d changed)", server.driftDetectionIdx, missing, changed)
+
+	// if drifted component instances have to be repaired, trigger enforcement
+	if repair > 0 {
+		server.runDesiredStateEnforcement <- true
+	}
+
+	return nil
+}
+
+// isDriftRepairNeeded returns true if there are drifted component instances, which have to be re-created or updated.
+// Component instances, changes to which are held in a given revision (deferred, paused or awaiting approval), are not
+// taken into account, as they will stay drifted until their changes are allowed
+func (server *Server) isDriftRepairNeeded(revision *engine.Revision) (bool, error) {
+	actualState, err := server.store.GetActualState()
+	if err != nil {
+		return false, err
+	}
+
+	held := make(map[string]bool)
+	for _, keys := range [][]string{revision.Deferred, revision.Paused, revision.PendingApproval} {
+		for _, key := range keys {
+			held[key] = true
+		}
+	}
+
+	for key, instance := range actualState.ComponentInstanceMap {
+		if instance.IsDrifted() && instance.DriftRepair && !held[key] {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,188 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/builder"
+	"github.com/Aptomi/aptomi/pkg/plugin"
+	"github.com/Aptomi/aptomi/pkg/plugin/fake"
+	"github.com/Aptomi/aptomi/pkg/runtime"
+	"github.com/Aptomi/aptomi/pkg/runtime/store"
+	"github.com/Aptomi/aptomi/pkg/runtime/store/core"
+	"github.com/Aptomi/aptomi/pkg/runtime/store/generic/bolt"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDriftDetect(t *testing.T) {
+	registry, tracker := mockRegistryDrift()
+	server, cleanup := newTestServer(t, registry)
+	defer cleanup()
+	server.cfg.Drift.Repair = true
+	key := makeTestComponentInstances(t, server)
+
+	// nothing has drifted
+	assert.NoError(t, server.driftDetect(), "Drift detection should succeed")
+	actualState, err := server.store.GetActualState()
+	assert.NoError(t, err, "Actual state should be loaded")
+	assert.False(t, actualState.ComponentInstanceMap[key].IsDrifted(), "Component instance should not be drifted")
+	assert.Len(t, server.runDesiredStateEnforcement, 0, "Enforcement should not be triggered")
+
+	// missing code is recorded in actual state and triggers enforcement to repair it
+	tracker.drift = &plugin.Drift{Missing: true, Details: "release not found"}
+	assert.NoError(t, server.driftDetect(), "Drift detection should succeed")
+	actualState, err = server.store.GetActualState()
+	assert.NoError(t, err, "Actual state should be loaded")
+	instance := actualState.ComponentInstanceMap[key]
+	assert.Equal(t, "release not found", instance.Drift, "Drift should be recorded in actual state")
+	assert.True(t, instance.DriftMissing, "Missing code should be recorded in actual state")
+	assert.True(t, instance.DriftRepair, "Drifted component instance should be marked for repair")
+	assert.Len(t, server.runDesiredStateEnforcement, 1, "Enforcement should be triggered to repair drifted component instance")
+
+	// repair is needed, unless changes to drifted component instance are held
+	for _, revision := range []*engine.Revision{
+		{},
+		{Deferred: []string{"other"}},
+	} {
+		repair, repairErr := server.isDriftRepairNeeded(revision)
+		assert.NoError(t, repairErr, "Drift repair check should succeed")
+		assert.True(t, repair, "Drifted component instance should be repaired")
+	}
+	for _, revision := range []*engine.Revision{
+		{Deferred: []string{key}},
+		{Paused: []string{key}},
+		{PendingApproval: []string{key}},
+	} {
+		repair, repairErr := server.isDriftRepairNeeded(revision)
+		assert.NoError(t, repairErr, "Drift repair check should succeed")
+		assert.False(t, repair, "Drifted component instance, changes to which are held, should not be repaired")
+	}
+
+	// drift gets reset, once deployed code matches actual state again
+	tracker.drift = nil
+	assert.NoError(t, server.driftDetect(), "Drift detection should succeed")
+	actualState, err = server.store.GetActualState()
+	assert.NoError(t, err, "Actual state should be loaded")
+	assert.False(t, actualState.ComponentInstanceMap[key].IsDrifted(), "Drift should be reset")
+	repair, err := server.isDriftRepairNeeded(&engine.Revision{})
+	assert.NoError(t, err, "Drift repair check should succeed")
+	assert.False(t, repair, "Drift repair should not be needed")
+}
+
+// newTestServer creates a server with a temporary store and a given plugin registry
+func newTestServer(t *testing.T, registry plugin.Registry) (*Server, func()) {
+	t.Helper()
+
+	file, err := ioutil.TempFile("", "aptomi-server-test-")
+	if err != nil {
+		t.Fatalf("unable to create temp file: %s", err)
+	}
+	assert.NoError(t, file.Close(), "Temp file should be closed")
+
+	b := bolt.NewGenericStore(runtime.NewRegistry().Append(store.Objects...))
+	err = b.Open(config.DB{Connection: file.Name()})
+	if err != nil {
+		t.Fatalf("unable to open store: %s", err)
+	}
+
+	server := NewServer(&config.Server{
+		Drift: config.DriftDetector{MaxConcurrentActions: 2},
+	})
+	server.store = core.NewStore(b)
+	server.enforcerPluginRegistryFactory = func() plugin.Registry { return registry }
+	server.updaterPluginRegistryFactory = func() plugin.Registry { return registry }
+	server.driftedComponentInstances = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_drifted_component_instances"}, []string{"drift"})
+
+	err = server.store.InitPolicy()
+	if err != nil {
+		t.Fatalf("unable to init policy: %s", err)
+	}
+
+	return server, func() {
+		assert.NoError(t, b.Close(), "Store should be closed")
+		assert.NoError(t, os.Remove(file.Name()), "Temp file should be removed")
+	}
+}
+
+// makeTestComponentInstances saves a policy with a single code component into the store and creates its component
+// instances in the actual state. It returns the key of the code component instance
+func makeTestComponentInstances(t *testing.T, server *Server) string {
+	t.Helper()
+
+	b := builder.NewPolicyBuilder()
+	service := b.AddService()
+	b.AddServiceComponent(service, b.CodeComponent(util.NestedParameterMap{"param": "value"}, nil))
+	contract := b.AddContract(service, b.CriteriaTrue())
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelTarget, cluster.Name)))
+	b.AddDependency(b.AddUser(), contract)
+
+	objects := []lang.Base{}
+	for _, info := range lang.PolicyObjects {
+		for _, obj := range b.Policy().GetObjectsByKind(info.Kind) {
+			objects = append(objects, obj.(lang.Base))
+		}
+	}
+	_, _, err := server.store.UpdatePolicy(objects, "test")
+	if !assert.NoError(t, err, "Policy should be saved") {
+		t.FailNow()
+	}
+
+	resolution := resolve.NewPolicyResolver(b.Policy(), b.External(), event.NewLog(logrus.WarnLevel, "test-resolve")).ResolveAllDependencies()
+	updater := server.store.NewActualStateUpdater(resolve.NewPolicyResolution())
+	key := ""
+	for _, instance := range resolution.ComponentInstanceMap {
+		if !assert.NoError(t, updater.CreateComponentInstance(instance), "Component instance should be created") {
+			t.FailNow()
+		}
+		if instance.IsCode {
+			key = instance.GetKey()
+		}
+	}
+	if !assert.NotEmpty(t, key, "Code component instance should be created") {
+		t.FailNow()
+	}
+
+	return key
+}
+
+// driftTrackingPlugin is a code plugin, which reports a given drift for all deployed code
+type driftTrackingPlugin struct {
+	plugin.CodePlugin
+	tracker *driftTracker
+}
+
+type driftTracker struct {
+	drift *plugin.Drift
+}
+
+func (p *driftTrackingPlugin) Drift(ctx context.Context, invocation *plugin.CodePluginInvocationParams) (*plugin.Drift, error) {
+	return p.tracker.drift, nil
+}
+
+func mockRegistryDrift() (plugin.Registry, *driftTracker) {
+	clusterTypes := make(map[string]plugin.ClusterPluginConstructor)
+	codeTypes := make(map[string]map[string]plugin.CodePluginConstructor)
+	tracker := &driftTracker{}
+
+	clusterTypes["kubernetes"] = func(cluster *lang.Cluster, cfg config.Plugins) (plugin.ClusterPlugin, error) {
+		return fake.NewNoOpClusterPlugin(0), nil
+	}
+
+	codeTypes["kubernetes"] = make(map[string]plugin.CodePluginConstructor)
+	codeTypes["kubernetes"]["helm"] = func(cluster plugin.ClusterPlugin, cfg config.Plugins) (plugin.CodePlugin, error) {
+		return &driftTrackingPlugin{CodePlugin: fake.NewNoOpCodePlugin(0), tracker: tracker}, nil
+	}
+
+	return plugin.NewRegistry(config.Plugins{}, clusterTypes, codeTypes), tracker
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"sort"
+
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/engine/apply/action"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	log "github.com/sirupsen/logrus"
+)
+
+// getEnforcerPauses loads pauses of desired state enforcement from the store and reports them via metrics
+func (server *Server) getEnforcerPauses() ([]*engine.EnforcerPause, error) {
+	pauses, err := server.store.GetEnforcerPauses()
+	if err != nil {
+		return nil, err
+	}
+
+	server.enforcerPauses.Reset()
+	server.enforcerPauses.WithLabelValues(engine.EnforcerPauseName("", "")).Set(0)
+	for _, pause := range pauses {
+		server.enforcerPauses.WithLabelValues(pause.GetName()).Set(1)
+	}
+
+	return pauses, nil
+}
+
+// isChangePaused returns true if changes to a given component instance are paused at the moment. Pauses get loaded
+// from the store every time, so the ones created while revision is being applied take effect right away. If pauses
+// can't be loaded, changes are considered paused, so nothing gets changed without being able to check
+func (server *Server) isChangePaused(instance *resolve.ComponentInstance) bool {
+	pauses, err := server.store.GetEnforcerPauses()
+	if err != nil {
+		log.Warningf("(enforce-%d) Unable to load enforcer pauses, holding changes to component instance %s: %s", server.desiredStateEnforcementIdx, instance.GetKey(), err)
+		return true
+	}
+	for _, pause := range pauses {
+		if pause.Matches(instance) {
+			return true
+		}
+	}
+	return false
+}
+
+// findPausedChanges returns a sorted list of keys of component instances, which are going to be changed by a given
+// action plan, but changes to which are paused for their namespace or cluster
+func findPausedChanges(plan *action.Plan, desiredState *resolve.PolicyResolution, actualState *resolve.PolicyResolution, pauses []*engine.EnforcerPause) []string {
+	result := []string{}
+	if len(pauses) <= 0 {
+		return result
+	}
+
+	for key, node := range plan.NodeMap {
+		if len(node.Actions) <= 0 {
+			continue
+		}
+
+		// component instance may only be present in actual state (e.g. when it's being deleted)
+		instance := desiredState.ComponentInstanceMap[key]
+		if instance == nil {
+			instance = actualState.ComponentInstanceMap[key]
+		}
+		if instance == nil {
+			continue
+		}
+
+		for _, pause := range pauses {
+			if pause.Matches(instance) {
+				result = append(result, key)
+				break
+			}
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
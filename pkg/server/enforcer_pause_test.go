@@ -0,0 +1,80 @@
+package server
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/Aptomi/aptomi/pkg/engine"
+	"github.com/Aptomi/aptomi/pkg/engine/diff"
+	"github.com/Aptomi/aptomi/pkg/engine/resolve"
+	"github.com/Aptomi/aptomi/pkg/event"
+	"github.com/Aptomi/aptomi/pkg/lang"
+	"github.com/Aptomi/aptomi/pkg/lang/builder"
+	"github.com/Aptomi/aptomi/pkg/util"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindPausedChanges(t *testing.T) {
+	b := builder.NewPolicyBuilder()
+	service := b.AddService()
+	b.AddServiceComponent(service, b.CodeComponent(util.NestedParameterMap{"param": "value"}, nil))
+	contract := b.AddContract(service, b.CriteriaTrue())
+	cluster := b.AddCluster()
+	b.AddRule(b.CriteriaTrue(), b.RuleActions(lang.NewLabelOperationsSetSingleLabel(lang.LabelTarget, cluster.Name)))
+	b.AddDependency(b.AddUser(), contract)
+
+	desiredState := resolve.NewPolicyResolver(b.Policy(), b.External(), event.NewLog(logrus.WarnLevel, "test-resolve")).ResolveAllDependencies()
+	actualState := resolve.NewPolicyResolution()
+	plan := diff.NewPolicyResolutionDiff(desiredState, actualState).ActionPlan
+
+	allKeys := []string{}
+	for key := range desiredState.ComponentInstanceMap {
+		allKeys = append(allKeys, key)
+	}
+	sort.Strings(allKeys)
+
+	// nothing is paused without pauses, as well as with pauses for other namespaces and clusters
+	assert.Empty(t, findPausedChanges(plan, desiredState, actualState, nil), "Changes should not be paused")
+	assert.Empty(t, findPausedChanges(plan, desiredState, actualState, []*engine.EnforcerPause{
+		engine.NewEnforcerPause("other", "", "reason", "alice"),
+		engine.NewEnforcerPause("", "other", "reason", "alice"),
+	}), "Changes should not be paused by pauses for other namespaces and clusters")
+
+	// changes to component instances in paused namespace or cluster are paused
+	assert.Equal(t, allKeys, findPausedChanges(plan, desiredState, actualState, []*engine.EnforcerPause{
+		engine.NewEnforcerPause(b.Namespace(), "", "reason", "alice"),
+	}), "Changes in paused namespace should be paused")
+	assert.Equal(t, allKeys, findPausedChanges(plan, desiredState, actualState, []*engine.EnforcerPause{
+		engine.NewEnforcerPause("", cluster.Name, "reason", "alice"),
+	}), "Changes in paused cluster should be paused")
+
+	// deletions of component instances, which are only present in actual state, are paused as well
+	plan = diff.NewPolicyResolutionDiff(resolve.NewPolicyResolution(), desiredState).ActionPlan
+	assert.Equal(t, allKeys, findPausedChanges(plan, resolve.NewPolicyResolution(), desiredState, []*engine.EnforcerPause{
+		engine.NewEnforcerPause("", cluster.Name, "reason", "alice"),
+	}), "Deletions in paused cluster should be paused")
+}
+
+func TestIsChangePaused(t *testing.T) {
+	registry, _ := mockRegistryDrift()
+	server, cleanup := newTestServer(t, registry)
+	defer cleanup()
+	key := makeTestComponentInstances(t, server)
+
+	actualState, err := server.store.GetActualState()
+	if !assert.NoError(t, err, "Actual state should be loaded") {
+		return
+	}
+	instance := actualState.ComponentInstanceMap[key]
+	assert.False(t, server.isChangePaused(instance), "Changes should not be paused")
+
+	// pause, which gets saved into the store, takes effect right away
+	assert.NoError(t, server.store.SaveEnforcerPause(engine.NewEnforcerPause("", instance.Metadata.Key.ClusterName, "reason", "alice")), "Enforcer pause should be saved")
+	assert.True(t, server.isChangePaused(instance), "Changes should be paused once enforcer pause is saved")
+
+	deleted, err := server.store.DeleteEnforcerPause(engine.EnforcerPauseName("", instance.Metadata.Key.ClusterName))
+	assert.NoError(t, err, "Enforcer pause should be deleted")
+	assert.True(t, deleted, "Enforcer pause should be deleted")
+	assert.False(t, server.isChangePaused(instance), "Changes should not be paused once enforcement is resumed")
+}
@@ -7,12 +7,14 @@ import (
 	"os/signal"
 	"runtime/pprof"
 	"runtime/trace"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/Aptomi/aptomi/pkg/api"
 	"github.com/Aptomi/aptomi/pkg/api/middleware"
 	"github.com/Aptomi/aptomi/pkg/config"
+	"github.com/Aptomi/aptomi/pkg/engine"
 	"github.com/Aptomi/aptomi/pkg/external"
 	"github.com/Aptomi/aptomi/pkg/external/secrets"
 	"github.com/Aptomi/aptomi/pkg/external/users"
@@ -55,8 +57,20 @@ type Server struct {
 	actualStateUpdateIdx         uint
 	updaterPluginRegistryFactory plugin.RegistryFactory
 
+	dependencyExpirationIdx uint
+
+	driftDetectionIdx uint
+
+	// policyAndRevisionUpdateMutex must be taken before making any policy and revision changes
+	policyAndRevisionUpdateMutex sync.Mutex
+
+	// revisionCanceller allows to cancel the revision, which is currently being applied by the enforcer
+	revisionCanceller *engine.RevisionCanceller
+
 	desiredStateEnforcements        prometheus.Counter
 	desiredStateEnforcementDuration prometheus.Histogram
+	driftedComponentInstances       *prometheus.GaugeVec
+	enforcerPauses                  *prometheus.GaugeVec
 }
 
 // NewServer creates a new Aptomi Server
@@ -66,6 +80,7 @@ func NewServer(cfg *config.Server) *Server {
 		backgroundErrors:           make(chan string),
 		runDesiredStateEnforcement: make(chan bool, 2048),
 		runActualStateUpdate:       make(chan bool, 2048),
+		revisionCanceller:          engine.NewRevisionCanceller(),
 	}
 
 	return s
@@ -81,10 +96,12 @@ func (server *Server) Start() {
 	server.initPluginRegistryFactory()
 	server.initPolicyOnFirstRun()
 
-	// Start API, UI, Enforcer and ActualStateUpdater
+	// Start API, UI, Enforcer, ActualStateUpdater, DependencyExpirer and DriftDetector
 	server.startHTTPServer()
 	server.startDesiredStateEnforcer()
 	server.startActualStateUpdater()
+	server.startDependencyExpirer()
+	server.startDriftDetector()
 
 	// Wait for jobs to complete (it essentially hangs forever)
 	server.wait()
@@ -223,7 +240,7 @@ func (server *Server) startHTTPServer() {
 		log.Warnf("The auth.secret not specified in config, using insecure default one")
 	}
 
-	api.Serve(router, server.store, server.externalData, server.enforcerPluginRegistryFactory, server.cfg.Auth.Secret, server.cfg.GetLogLevel(), server.runDesiredStateEnforcement)
+	api.Serve(router, server.store, server.externalData, server.enforcerPluginRegistryFactory, server.cfg.Auth.Secret, server.cfg.GetLogLevel(), server.runDesiredStateEnforcement, &server.policyAndRevisionUpdateMutex, server.revisionCanceller)
 	server.serveUI(router)
 
 	var handler http.Handler = router
@@ -287,3 +304,19 @@ func (server *Server) startActualStateUpdater() {
 		})
 	}
 }
+
+func (server *Server) startDependencyExpirer() {
+	if !server.cfg.Expirer.Disabled {
+		server.runInBackground("Dependency Expirer", true, func() {
+			panic(server.dependencyExpireLoop())
+		})
+	}
+}
+
+func (server *Server) startDriftDetector() {
+	if !server.cfg.Drift.Disabled {
+		server.runInBackground("Drift Detector", true, func() {
+			panic(server.driftDetectLoop())
+		})
+	}
+}
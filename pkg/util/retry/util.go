@@ -2,6 +2,7 @@ package retry
 
 import (
 	"fmt"
+	"math"
 	"time"
 )
 
@@ -52,3 +53,24 @@ func Do2(maxTime time.Duration, maxInterval time.Duration, f Func) bool {
 
 	return false
 }
+
+// Backoff returns the delay before the next attempt, given the number of failed attempts so far. The delay starts
+// at initial and doubles after every failed attempt until it reaches max. Zero max means no cap
+func Backoff(attempts int, initial time.Duration, max time.Duration) time.Duration {
+	if attempts <= 0 || initial <= 0 {
+		return 0
+	}
+
+	delay := initial
+	for i := 1; i < attempts && delay <= math.MaxInt64/2; i++ {
+		delay *= 2
+		if max > 0 && delay >= max {
+			return max
+		}
+	}
+
+	if max > 0 && delay > max {
+		return max
+	}
+	return delay
+}
@@ -0,0 +1,32 @@
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		initial  time.Duration
+		max      time.Duration
+		expected time.Duration
+	}{
+		{0, time.Second, time.Minute, 0},
+		{1, time.Second, time.Minute, time.Second},
+		{2, time.Second, time.Minute, 2 * time.Second},
+		{4, time.Second, time.Minute, 8 * time.Second},
+		{7, time.Second, time.Minute, time.Minute},
+		{1000, time.Second, time.Minute, time.Minute},
+		{3, 0, time.Minute, 0},
+		{3, time.Second, 0, 4 * time.Second},
+	}
+	for _, test := range tests {
+		assert.Equal(t, test.expected, Backoff(test.attempts, test.initial, test.max), "Backoff for %d attempts (initial = %s, max = %s)", test.attempts, test.initial, test.max)
+	}
+
+	// make sure there is no overflow when there is no cap
+	assert.True(t, Backoff(1000, time.Second, 0) > 0, "Backoff without cap should not overflow")
+}
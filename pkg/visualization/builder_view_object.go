@@ -1,6 +1,8 @@
 package visualization
 
 import (
+	"fmt"
+
 	"github.com/Aptomi/aptomi/pkg/engine/resolve"
 	"github.com/Aptomi/aptomi/pkg/lang"
 	"github.com/Aptomi/aptomi/pkg/runtime"
@@ -44,6 +46,9 @@ func (b *GraphBuilder) traceContract(contract *lang.Contract, last graphNode, la
 		if len(context.Allocation.Keys) > 0 {
 			contextName += " (+)"
 		}
+		if context.Traffic != nil {
+			contextName += fmt.Sprintf(" (%d%%)", context.Traffic.Percent)
+		}
 		b.traceService(service, ctrNode, contextName, level+1, cfg)
 	}
 }